@@ -3,6 +3,7 @@ module github.com/I-Van-Radkov/corporate-messenger/api-gateway
 go 1.24.2
 
 require (
+	github.com/I-Van-Radkov/corporate-messenger/pkg v0.0.0
 	github.com/gin-gonic/gin v1.11.0
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/ilyakaznacheev/cleanenv v1.5.0
@@ -44,3 +45,5 @@ require (
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3 // indirect
 )
+
+replace github.com/I-Van-Radkov/corporate-messenger/pkg => ../pkg
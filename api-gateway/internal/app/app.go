@@ -12,6 +12,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/auth"
 	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/clients/identity"
 	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/config"
 	v1 "github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/controller/http/v1"
@@ -23,9 +24,15 @@ type App struct {
 
 func NewApp(cfg *config.Config) (*App, error) {
 	authclient := identity.NewHTTPClient(cfg.IdentityServiceConfig)
+	jwksClient := identity.NewJWKSClient(cfg.IdentityServiceConfig)
 
-	server := v1.NewServer(cfg, authclient)
-	err := server.RegisterHandlers()
+	authenticator, err := auth.NewAuthenticator(cfg.AuthConfig, authclient, jwksClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authenticator: %w", err)
+	}
+
+	server := v1.NewServer(cfg, authenticator)
+	err = server.RegisterHandlers()
 	if err != nil {
 		return nil, fmt.Errorf("failed to register handlers: %w", err)
 	}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/clients/identity"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Identity - пользователь, от имени которого gateway проксирует запрос
+type Identity struct {
+	UserID string
+	Role   string
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+func NewAuthenticator(cfg AuthConfig, identityClient identity.Client, jwksClient *identity.JWKSClient) (Authenticator, error) {
+	switch cfg.Mode {
+	case ModeJWKS:
+		return &JWKSAuthenticator{keys: jwksClient}, nil
+	case ModeIntrospect:
+		return &IntrospectAuthenticator{client: identityClient}, nil
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", cfg.Mode)
+	}
+}
+
+// JWKSAuthenticator проверяет токен локально, без запроса в identity-service
+type JWKSAuthenticator struct {
+	keys *identity.JWKSClient
+}
+
+func (a *JWKSAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	parsed, err := jwt.Parse(token, a.keys.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !parsed.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return identityFromClaims(claims)
+}
+
+// IntrospectAuthenticator спрашивает identity-service о каждом токене: отзыв сессии
+// и деактивация аккаунта видны сразу, ценой запроса на каждый вызов
+type IntrospectAuthenticator struct {
+	client identity.Client
+}
+
+func (a *IntrospectAuthenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	resp, err := a.client.IntrospectToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Active {
+		return nil, ErrInvalidToken
+	}
+
+	// Пользователь берётся из проверенного ответа identity-service, а не из самого токена
+	if resp.Sub == "" || resp.Role == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &Identity{
+		UserID: resp.Sub,
+		Role:   resp.Role,
+	}, nil
+}
+
+func identityFromClaims(claims jwt.MapClaims) (*Identity, error) {
+	userId, _ := claims["user_id"].(string)
+	userRole, _ := claims["user_role"].(string)
+
+	if userId == "" || userRole == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &Identity{
+		UserID: userId,
+		Role:   userRole,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/clients/identity"
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type fakeIntrospector struct {
+	resp *identity.IntrospectResponse
+}
+
+func (f *fakeIntrospector) IntrospectToken(context.Context, string) (*identity.IntrospectResponse, error) {
+	return f.resp, nil
+}
+
+func TestDefaultModeChecksSessionState(t *testing.T) {
+	var cfg AuthConfig
+	if err := cleanenv.ReadEnv(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	authenticator, err := NewAuthenticator(cfg, &fakeIntrospector{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := authenticator.(*IntrospectAuthenticator); !ok {
+		t.Fatalf("default authenticator is %T, want introspection", authenticator)
+	}
+}
+
+func TestIntrospectAuthenticator(t *testing.T) {
+	tests := []struct {
+		name    string
+		resp    *identity.IntrospectResponse
+		want    *Identity
+		wantErr bool
+	}{
+		{
+			name: "active",
+			resp: &identity.IntrospectResponse{Active: true, Sub: "user-1", Role: "admin"},
+			want: &Identity{UserID: "user-1", Role: "admin"},
+		},
+		{name: "revoked session", resp: &identity.IntrospectResponse{Active: false, Reason: "session_revoked"}, wantErr: true},
+		{name: "deactivated account", resp: &identity.IntrospectResponse{Active: false, Reason: "account_inactive"}, wantErr: true},
+		{name: "no role", resp: &identity.IntrospectResponse{Active: true, Sub: "user-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &IntrospectAuthenticator{client: &fakeIntrospector{resp: tt.resp}}
+
+			got, err := a.Authenticate(context.Background(), "token")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("expected ErrInvalidToken, got %v", err)
+				}
+				return
+			}
+			if err != nil || *got != *tt.want {
+				t.Fatalf("got %+v, %v", got, err)
+			}
+		})
+	}
+}
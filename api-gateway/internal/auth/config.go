@@ -0,0 +1,15 @@
+package auth
+
+const (
+	// ModeJWKS - подпись и срок токена проверяются локально по ключам из JWKS identity-service.
+	// Отзыв сессии и деактивация аккаунта не видны до истечения токена, поэтому режим
+	// годится только там, где за gateway нет сервисов, доверяющих X-User-ID/X-User-Role
+	ModeJWKS = "jwks"
+	// ModeIntrospect - каждый токен проверяется запросом introspect в identity-service
+	// (ответы кэшируются на IDENTITY_INTROSPECT_CACHE_TTL)
+	ModeIntrospect = "introspect"
+)
+
+type AuthConfig struct {
+	Mode string `env:"AUTH_MODE" env-default:"introspect"`
+}
@@ -0,0 +1,110 @@
+package identity
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("identity service circuit breaker is open")
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// CircuitBreaker размыкается после FailureThreshold подряд неудачных запросов и
+// Timeout не пропускает запросы. Затем переходит в half-open: пропускает по одному
+// пробному запросу и замыкается после SuccessThreshold успехов подряд
+type CircuitBreaker struct {
+	failureThreshold int
+	successThreshold int
+	timeout          time.Duration
+
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	successes int
+	openedAt  time.Time
+	probing   bool
+
+	now func() time.Time
+}
+
+func NewCircuitBreaker(failureThreshold, successThreshold int, timeout time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		failureThreshold: max(failureThreshold, 1),
+		successThreshold: max(successThreshold, 1),
+		timeout:          timeout,
+		now:              time.Now,
+	}
+}
+
+// Allow сообщает, можно ли выполнить запрос. Пропущенный запрос обязательно
+// завершается вызовом Success или Failure
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.timeout {
+			return false
+		}
+		b.state = stateHalfOpen
+		b.successes = 0
+		b.probing = true
+		return true
+	case stateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateHalfOpen:
+		b.probing = false
+		b.successes++
+		if b.successes >= b.successThreshold {
+			b.state = stateClosed
+			b.failures = 0
+		}
+	default:
+		b.failures = 0
+	}
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateHalfOpen:
+		b.trip()
+	default:
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.trip()
+		}
+	}
+}
+
+func (b *CircuitBreaker) trip() {
+	b.state = stateOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.successes = 0
+	b.probing = false
+}
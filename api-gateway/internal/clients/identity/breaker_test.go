@@ -0,0 +1,106 @@
+package identity
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestBreaker(failures, successes int, timeout time.Duration) (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	b := NewCircuitBreaker(failures, successes, timeout)
+	b.now = clock.Now
+
+	return b, clock
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	b, _ := newTestBreaker(3, 1, time.Minute)
+
+	for range 2 {
+		if !b.Allow() {
+			t.Fatal("closed breaker rejected request")
+		}
+		b.Failure()
+	}
+
+	// Успех сбрасывает счётчик неудач
+	b.Allow()
+	b.Success()
+	for range 2 {
+		b.Allow()
+		b.Failure()
+	}
+	if !b.Allow() {
+		t.Fatal("breaker opened on non-consecutive failures")
+	}
+	b.Failure()
+
+	if b.Allow() {
+		t.Fatal("breaker is still closed after 3 consecutive failures")
+	}
+}
+
+func TestBreakerHalfOpenAllowsSingleProbe(t *testing.T) {
+	b, clock := newTestBreaker(1, 2, time.Minute)
+
+	b.Allow()
+	b.Failure()
+
+	clock.Advance(59 * time.Second)
+	if b.Allow() {
+		t.Fatal("open breaker allowed request before timeout")
+	}
+
+	clock.Advance(time.Second)
+	if !b.Allow() {
+		t.Fatal("breaker did not let a probe through after timeout")
+	}
+	if b.Allow() {
+		t.Fatal("half-open breaker allowed a second concurrent probe")
+	}
+
+	// Первый успех из двух нужных: остаёмся в half-open
+	b.Success()
+	if !b.Allow() {
+		t.Fatal("half-open breaker rejected the next probe")
+	}
+	if b.Allow() {
+		t.Fatal("half-open breaker allowed a concurrent probe")
+	}
+
+	b.Success()
+	for range 3 {
+		if !b.Allow() {
+			t.Fatal("breaker did not close after successful probes")
+		}
+	}
+}
+
+func TestBreakerReopensOnFailedProbe(t *testing.T) {
+	b, clock := newTestBreaker(1, 1, time.Minute)
+
+	b.Allow()
+	b.Failure()
+	clock.Advance(time.Minute)
+
+	if !b.Allow() {
+		t.Fatal("breaker did not let a probe through after timeout")
+	}
+	b.Failure()
+
+	if b.Allow() {
+		t.Fatal("breaker did not reopen after failed probe")
+	}
+	clock.Advance(time.Minute)
+	if !b.Allow() {
+		t.Fatal("reopened breaker did not restart its timeout")
+	}
+}
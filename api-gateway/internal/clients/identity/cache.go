@@ -0,0 +1,88 @@
+package identity
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	resp      *IntrospectResponse
+	expiresAt time.Time
+}
+
+// introspectionCache - короткоживущий кэш ответов introspect. Сами токены не хранятся, только их хеши
+type introspectionCache struct {
+	ttl     time.Duration
+	maxSize int
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]cacheEntry
+
+	now func() time.Time
+}
+
+func newIntrospectionCache(ttl time.Duration, maxSize int) *introspectionCache {
+	return &introspectionCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[[sha256.Size]byte]cacheEntry),
+		now:     time.Now,
+	}
+}
+
+func (c *introspectionCache) get(token string) (*IntrospectResponse, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	key := sha256.Sum256([]byte(token))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	return entry.resp, true
+}
+
+func (c *introspectionCache) set(token string, resp *IntrospectResponse) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	key := sha256.Sum256([]byte(token))
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.maxSize {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		// Кэш заполнен живыми записями - новую не сохраняем, чтобы не расти без границ
+		if len(c.entries) >= c.maxSize {
+			return
+		}
+	}
+
+	// Активный токен не должен пережить в кэше собственный срок действия
+	expiresAt := now.Add(c.ttl)
+	if resp.Active && resp.Exp > 0 {
+		if exp := time.Unix(resp.Exp, 0); exp.Before(expiresAt) {
+			expiresAt = exp
+		}
+	}
+
+	c.entries[key] = cacheEntry{resp: resp, expiresAt: expiresAt}
+}
@@ -4,20 +4,37 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"time"
 )
 
 type Client interface {
-	IntrospectToken(ctx context.Context, token string) (bool, error)
+	IntrospectToken(ctx context.Context, token string) (*IntrospectResponse, error)
 }
 
+// errPermanent - ответ, который бессмысленно повторять (4xx)
+type errPermanent struct {
+	err error
+}
+
+func (e *errPermanent) Error() string { return e.err.Error() }
+func (e *errPermanent) Unwrap() error { return e.err }
+
 type HTTPClient struct {
 	baseURL    string
 	httpClient *http.Client
 	timeout    time.Duration
+
+	retryCount     int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+
+	breaker *CircuitBreaker
+	cache   *introspectionCache
 }
 
 func NewHTTPClient(cfg IdentityServiceConfig) *HTTPClient {
@@ -32,10 +49,94 @@ func NewHTTPClient(cfg IdentityServiceConfig) *HTTPClient {
 			},
 		},
 		timeout: cfg.Timeout,
+
+		retryCount:     cfg.RetryCount,
+		retryBaseDelay: cfg.RetryBaseDelay,
+		retryMaxDelay:  cfg.RetryMaxDelay,
+
+		breaker: NewCircuitBreaker(cfg.CircuitBreaker.FailureThreshold, cfg.CircuitBreaker.SuccessThreshold, cfg.CircuitBreaker.Timeout),
+		cache:   newIntrospectionCache(cfg.IntrospectCacheTTL, cfg.IntrospectCacheSize),
+	}
+}
+
+func (c *HTTPClient) IntrospectToken(ctx context.Context, token string) (*IntrospectResponse, error) {
+	if resp, ok := c.cache.get(token); ok {
+		return resp, nil
+	}
+
+	if !c.breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	resp, err := c.introspectWithRetry(ctx, token)
+	if err != nil {
+		var permanent *errPermanent
+		if errors.As(err, &permanent) {
+			// identity-service ответил, просто не так, как ожидалось - он доступен
+			c.breaker.Success()
+		} else {
+			c.breaker.Failure()
+		}
+		return nil, err
 	}
+
+	c.breaker.Success()
+	c.cache.set(token, resp)
+
+	return resp, nil
 }
 
-func (c *HTTPClient) IntrospectToken(ctx context.Context, token string) (bool, error) {
+func (c *HTTPClient) introspectWithRetry(ctx context.Context, token string) (*IntrospectResponse, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= c.retryCount; attempt++ {
+		if attempt > 0 {
+			if err := sleepCtx(ctx, c.backoff(attempt)); err != nil {
+				return nil, fmt.Errorf("introspection retry aborted: %w (last error: %v)", err, lastErr)
+			}
+		}
+
+		resp, err := c.introspect(ctx, token)
+		if err == nil {
+			return resp, nil
+		}
+
+		var permanent *errPermanent
+		if errors.As(err, &permanent) || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// backoff - экспоненциальная задержка с full jitter: случайная в [0, min(max, base*2^(attempt-1))]
+func (c *HTTPClient) backoff(attempt int) time.Duration {
+	delay := c.retryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > c.retryMaxDelay {
+		delay = c.retryMaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return rand.N(delay + 1)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *HTTPClient) introspect(ctx context.Context, token string) (*IntrospectResponse, error) {
 	introspectRequest := IntrospectRequest{
 		Token: token,
 	}
@@ -43,36 +144,48 @@ func (c *HTTPClient) IntrospectToken(ctx context.Context, token string) (bool, e
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return false, fmt.Errorf("failed to create request: %w", err)
+		return nil, &errPermanent{fmt.Errorf("failed to create request: %w", err)}
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return false, fmt.Errorf("failed to do request: %w", err)
+		return nil, fmt.Errorf("failed to do request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Читаем тело ответа для отладки
-	bodyBytes, _ := io.ReadAll(resp.Body)
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Неразборчивый или чужой токен identity-service отклоняет с 400 - это неактивный токен
+	if resp.StatusCode == http.StatusBadRequest {
+		return &IntrospectResponse{Active: false, Reason: "invalid"}, nil
+	}
 
 	if resp.StatusCode != http.StatusOK {
 		// Пытаемся распарсить ошибку из ответа
 		var errorResp struct {
 			Error string `json:"error"`
 		}
+		message := string(bodyBytes)
 		if err := json.Unmarshal(bodyBytes, &errorResp); err == nil && errorResp.Error != "" {
-			return false, fmt.Errorf("identity service returned %d: %s", resp.StatusCode, errorResp.Error)
+			message = errorResp.Error
+		}
+
+		err := fmt.Errorf("identity service returned %d: %s", resp.StatusCode, message)
+		if resp.StatusCode < http.StatusInternalServerError && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, &errPermanent{err}
 		}
-		// Если не удалось распарсить JSON, возвращаем сырой ответ
-		return false, fmt.Errorf("identity service returned %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, err
 	}
 
 	var userResp IntrospectResponse
 	if err := json.Unmarshal(bodyBytes, &userResp); err != nil {
-		return false, fmt.Errorf("failed to decode data to IntrospectResponse: %w", err)
+		return nil, &errPermanent{fmt.Errorf("failed to decode data to IntrospectResponse: %w", err)}
 	}
 
-	return userResp.Active, nil
+	return &userResp, nil
 }
@@ -0,0 +1,331 @@
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeIdentity - introspect identity-service, отвечающий заданным статусом
+type fakeIdentity struct {
+	server *httptest.Server
+	calls  atomic.Int32
+
+	mu     sync.Mutex
+	status int
+	resp   IntrospectResponse
+}
+
+func newFakeIdentity(t *testing.T) *fakeIdentity {
+	t.Helper()
+
+	f := &fakeIdentity{status: http.StatusOK}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.calls.Add(1)
+
+		var req IntrospectRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		f.mu.Lock()
+		status, resp := f.status, f.resp
+		f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_ = json.NewEncoder(w).Encode(resp)
+		} else {
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": http.StatusText(status)})
+		}
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeIdentity) respond(status int, resp IntrospectResponse) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.status = status
+	f.resp = resp
+}
+
+type clientOptions struct {
+	retries   int
+	failures  int
+	cacheTTL  time.Duration
+	cacheSize int
+}
+
+func (f *fakeIdentity) client(opts clientOptions) (*HTTPClient, *fakeClock) {
+	var cfg IdentityServiceConfig
+	cfg.URL = f.server.URL
+	cfg.Timeout = 5 * time.Second
+	cfg.RetryCount = opts.retries
+	cfg.CircuitBreaker.FailureThreshold = max(opts.failures, 1)
+	cfg.CircuitBreaker.SuccessThreshold = 1
+	cfg.CircuitBreaker.Timeout = time.Minute
+	cfg.IntrospectCacheTTL = opts.cacheTTL
+	cfg.IntrospectCacheSize = max(opts.cacheSize, 100)
+
+	c := NewHTTPClient(cfg)
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	c.breaker.now = clock.Now
+	c.cache.now = clock.Now
+
+	return c, clock
+}
+
+var activeResponse = IntrospectResponse{Active: true, Sub: "user-1", Role: "user"}
+
+func TestIntrospectRetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+		permanent bool
+	}{
+		{name: "server error is retried", status: http.StatusServiceUnavailable, wantCalls: 3},
+		{name: "rate limit is retried", status: http.StatusTooManyRequests, wantCalls: 3},
+		{name: "client error is not retried", status: http.StatusForbidden, wantCalls: 1, permanent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIdentity(t)
+			f.respond(tt.status, IntrospectResponse{})
+			c, _ := f.client(clientOptions{retries: 2, failures: 10})
+
+			_, err := c.IntrospectToken(context.Background(), "token")
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			var permanent *errPermanent
+			if errors.As(err, &permanent) != tt.permanent {
+				t.Fatalf("permanent = %v, want %v: %v", !tt.permanent, tt.permanent, err)
+			}
+			if got := f.calls.Load(); got != tt.wantCalls {
+				t.Fatalf("got %d calls, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestIntrospectRecoversAfterRetry(t *testing.T) {
+	f := newFakeIdentity(t)
+	var calls atomic.Int32
+	handler := f.server.Config.Handler
+	f.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	f.respond(http.StatusOK, activeResponse)
+	c, _ := f.client(clientOptions{retries: 3})
+
+	resp, err := c.IntrospectToken(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Active || resp.Sub != "user-1" {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("got %d calls, want 2", got)
+	}
+}
+
+func TestIntrospectCircuitBreaker(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.respond(http.StatusInternalServerError, IntrospectResponse{})
+	c, clock := f.client(clientOptions{failures: 2})
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.IntrospectToken(ctx, "token"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("expected upstream error, got %v", err)
+		}
+	}
+
+	// Разомкнут: запросы не доходят до identity-service
+	if _, err := c.IntrospectToken(ctx, "token"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if got := f.calls.Load(); got != 2 {
+		t.Fatalf("open breaker let requests through: %d calls", got)
+	}
+
+	// Half-open: неудачная проба снова размыкает
+	clock.Advance(time.Minute)
+	if _, err := c.IntrospectToken(ctx, "token"); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected probe to reach upstream, got %v", err)
+	}
+	if _, err := c.IntrospectToken(ctx, "token"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen after failed probe, got %v", err)
+	}
+
+	// Удачная проба замыкает
+	f.respond(http.StatusOK, activeResponse)
+	clock.Advance(time.Minute)
+	for _, token := range []string{"probe", "after-close"} {
+		if _, err := c.IntrospectToken(ctx, token); err != nil {
+			t.Fatalf("expected breaker to close, got %v", err)
+		}
+	}
+	if got := f.calls.Load(); got != 5 {
+		t.Fatalf("got %d calls, want 5", got)
+	}
+}
+
+func TestIntrospectPermanentErrorsDoNotOpenBreaker(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.respond(http.StatusForbidden, IntrospectResponse{})
+	c, _ := f.client(clientOptions{failures: 1})
+
+	for range 3 {
+		if _, err := c.IntrospectToken(context.Background(), "token"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("4xx response opened the breaker")
+		}
+	}
+}
+
+func TestIntrospectCacheTTL(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.respond(http.StatusOK, activeResponse)
+	c, clock := f.client(clientOptions{cacheTTL: 10 * time.Second})
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := c.IntrospectToken(ctx, "token"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := f.calls.Load(); got != 1 {
+		t.Fatalf("cached response not reused: %d calls", got)
+	}
+
+	// Другой токен кэшируется отдельно
+	if _, err := c.IntrospectToken(ctx, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.calls.Load(); got != 2 {
+		t.Fatalf("got %d calls, want 2", got)
+	}
+
+	clock.Advance(10 * time.Second)
+	if _, err := c.IntrospectToken(ctx, "token"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.calls.Load(); got != 3 {
+		t.Fatalf("expired entry served from cache: %d calls", got)
+	}
+}
+
+func TestIntrospectCacheRespectsTokenExpiry(t *testing.T) {
+	f := newFakeIdentity(t)
+	c, clock := f.client(clientOptions{cacheTTL: time.Minute})
+	resp := activeResponse
+	resp.Exp = clock.Now().Add(5 * time.Second).Unix()
+	f.respond(http.StatusOK, resp)
+	ctx := context.Background()
+
+	if _, err := c.IntrospectToken(ctx, "token"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(5 * time.Second)
+	if _, err := c.IntrospectToken(ctx, "token"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.calls.Load(); got != 2 {
+		t.Fatalf("token cached past its exp: %d calls", got)
+	}
+}
+
+func TestIntrospectCachesNegativeResponses(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.respond(http.StatusOK, IntrospectResponse{Active: false, Reason: "revoked"})
+	c, clock := f.client(clientOptions{cacheTTL: 10 * time.Second})
+	ctx := context.Background()
+
+	for range 3 {
+		resp, err := c.IntrospectToken(ctx, "revoked")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Active {
+			t.Fatal("revoked token reported active")
+		}
+	}
+
+	// 400 на неразборчивый токен - тоже неактивный токен, и тоже кэшируется
+	for range 2 {
+		resp, err := c.IntrospectToken(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Active || resp.Reason != "invalid" {
+			t.Fatalf("unexpected response %+v", resp)
+		}
+	}
+	if got := f.calls.Load(); got != 2 {
+		t.Fatalf("negative responses not cached: %d calls", got)
+	}
+
+	// Отрицательный ответ тоже живёт не дольше TTL
+	f.respond(http.StatusOK, activeResponse)
+	clock.Advance(10 * time.Second)
+	resp, err := c.IntrospectToken(ctx, "revoked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Active {
+		t.Fatal("negative response outlived cache TTL")
+	}
+}
+
+func TestIntrospectErrorsAreNotCached(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.respond(http.StatusServiceUnavailable, IntrospectResponse{})
+	c, _ := f.client(clientOptions{cacheTTL: time.Minute, failures: 10})
+	ctx := context.Background()
+
+	if _, err := c.IntrospectToken(ctx, "token"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	f.respond(http.StatusOK, activeResponse)
+	resp, err := c.IntrospectToken(ctx, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Active {
+		t.Fatal("failed lookup was cached")
+	}
+}
+
+func TestIntrospectCacheIsBounded(t *testing.T) {
+	cache := newIntrospectionCache(time.Minute, 2)
+
+	cache.set("a", &activeResponse)
+	cache.set("b", &activeResponse)
+	cache.set("c", &activeResponse)
+
+	if _, ok := cache.get("c"); ok {
+		t.Fatal("full cache accepted a new entry")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Fatal("existing entry evicted")
+	}
+}
@@ -3,15 +3,26 @@ package identity
 import "time"
 
 type IdentityServiceConfig struct {
-	URL             string        `env:"AUTH_SERVICE_PATH" env-default:"http://localhost:8081/auth/introspect"`
-	Timeout         time.Duration `env:"DIRECTORY_SERVICE_TIMEOUT" env-default:"5s"`
-	MaxIdleConns    int           `env:"DIRECTORY_MAX_IDLE_CONNS" env-default:"100"`
-	MaxConnsPerHost int           `env:"DIRECTORY_MAX_CONNS_PER_HOST" env-default:"50"`
-	IdleConnTimeout time.Duration `env:"DIRECTORY_IDLE_CONN_TIMEOUT" env-default:"90s"`
-	RetryCount      int           `env:"DIRECTORY_RETRY_COUNT" env-default:"3"`
+	URL             string        `env:"IDENTITY_INTROSPECT_URL" env-default:"http://localhost:8081/auth/introspect"`
+	Timeout         time.Duration `env:"IDENTITY_SERVICE_TIMEOUT" env-default:"5s"`
+	MaxIdleConns    int           `env:"IDENTITY_MAX_IDLE_CONNS" env-default:"100"`
+	MaxConnsPerHost int           `env:"IDENTITY_MAX_CONNS_PER_HOST" env-default:"50"`
+	IdleConnTimeout time.Duration `env:"IDENTITY_IDLE_CONN_TIMEOUT" env-default:"90s"`
+	RetryCount      int           `env:"IDENTITY_RETRY_COUNT" env-default:"3"`
+	RetryBaseDelay  time.Duration `env:"IDENTITY_RETRY_BASE_DELAY" env-default:"100ms"`
+	RetryMaxDelay   time.Duration `env:"IDENTITY_RETRY_MAX_DELAY" env-default:"1s"`
 	CircuitBreaker  struct {
-		FailureThreshold int           `env:"DIRECTORY_CB_FAILURE_THRESHOLD" env-default:"5"`
-		SuccessThreshold int           `env:"DIRECTORY_CB_SUCCESS_THRESHOLD" env-default:"1"`
-		Timeout          time.Duration `env:"DIRECTORY_CB_TIMEOUT" env-default:"30s"`
+		FailureThreshold int           `env:"IDENTITY_CB_FAILURE_THRESHOLD" env-default:"5"`
+		SuccessThreshold int           `env:"IDENTITY_CB_SUCCESS_THRESHOLD" env-default:"1"`
+		Timeout          time.Duration `env:"IDENTITY_CB_TIMEOUT" env-default:"30s"`
 	}
+
+	// Кэш ответов introspect: ключ - sha256 токена, хранятся и положительные, и отрицательные ответы.
+	// TTL - сколько отозванный токен ещё может проходить через gateway
+	IntrospectCacheTTL  time.Duration `env:"IDENTITY_INTROSPECT_CACHE_TTL" env-default:"2s"`
+	IntrospectCacheSize int           `env:"IDENTITY_INTROSPECT_CACHE_SIZE" env-default:"10000"`
+
+	JWKSURL                string        `env:"IDENTITY_JWKS_URL" env-default:"http://localhost:8081/.well-known/jwks.json"`
+	JWKSCacheTTL           time.Duration `env:"JWKS_CACHE_TTL" env-default:"5m"`
+	JWKSMinRefreshInterval time.Duration `env:"JWKS_MIN_REFRESH_INTERVAL" env-default:"10s"`
 }
@@ -0,0 +1,15 @@
+package identity
+
+import "github.com/I-Van-Radkov/corporate-messenger/pkg/jwks"
+
+// JWKSClient - кэш публичных ключей identity-service, общий для сервисов
+type JWKSClient = jwks.Client
+
+func NewJWKSClient(cfg IdentityServiceConfig) *JWKSClient {
+	return jwks.NewClient(jwks.Config{
+		URL:                cfg.JWKSURL,
+		Timeout:            cfg.Timeout,
+		CacheTTL:           cfg.JWKSCacheTTL,
+		MinRefreshInterval: cfg.JWKSMinRefreshInterval,
+	})
+}
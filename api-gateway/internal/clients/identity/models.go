@@ -4,6 +4,15 @@ type IntrospectRequest struct {
 	Token string `json:"token"`
 }
 
+// IntrospectResponse - ответ introspect identity-service (RFC 7662)
 type IntrospectResponse struct {
-	Active bool `json:"active"`
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Role      string `json:"user_role,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sid       string `json:"sid,omitempty"`
+	Reason    string `json:"reason,omitempty"`
 }
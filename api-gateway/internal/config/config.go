@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/auth"
 	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/clients/identity"
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -14,6 +15,16 @@ type RoutesConfig struct {
 	ChatServicePath      string `env:"CHAT_SERVICE_PATH" env-default:"http://localhost:8083"`
 }
 
+// Прокси (балансировщики) перед gateway, которым можно доверить X-Forwarded-For.
+// Пусто - gateway стоит на краю сети и адрес клиента берётся из соединения
+type ProxyConfig struct {
+	TrustedProxies []string `env:"TRUSTED_PROXIES" env-separator:","`
+}
+
+type WebsocketConfig struct {
+	MaxConnsPerUser int `env:"WS_MAX_CONNS_PER_USER" env-default:"5"`
+}
+
 type Config struct {
 	Port         int           `env:"PORT" env-default:"8080"`
 	ReadTimeout  time.Duration `env:"HTTP_READ_TIMEOUT" env-default:"30s"`
@@ -23,7 +34,13 @@ type Config struct {
 
 	RoutesConfig
 
+	ProxyConfig
+
+	WebsocketConfig
+
 	identity.IdentityServiceConfig
+
+	auth.AuthConfig
 }
 
 func ParseConfigFromEnv() (*Config, error) {
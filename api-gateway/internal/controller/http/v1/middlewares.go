@@ -1,16 +1,35 @@
 package v1
 
 import (
-	"context"
+	"errors"
 	"log"
 	"strings"
 
-	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/auth"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(authClient identity.Client) gin.HandlerFunc {
+// Заголовки, которым сервисы за gateway доверяют. Их выставляет только gateway,
+// пришедшие от клиента значения отбрасываются
+var trustedHeaders = []string{"X-User-ID", "X-User-Role", "X-Internal-Token", "X-Real-IP"}
+
+// StripTrustedHeaders отбрасывает доверенные заголовки клиента и проставляет X-Real-IP -
+// адрес клиента, по которому сервисы ограничивают попытки входа. X-Forwarded-For
+// учитывается, только если запрос пришёл от прокси из TRUSTED_PROXIES
+func StripTrustedHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientIP := c.ClientIP()
+
+		for _, name := range trustedHeaders {
+			c.Request.Header.Del(name)
+		}
+		c.Request.Header.Set("X-Real-IP", clientIP)
+
+		c.Next()
+	}
+}
+
+func AuthMiddleware(authenticator auth.Authenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		authHeader := c.GetHeader("Authorization")
@@ -27,51 +46,36 @@ func AuthMiddleware(authClient identity.Client) gin.HandlerFunc {
 
 		token := parts[1]
 
-		ok, err := authClient.IntrospectToken(context.Background(), token)
-		if err != nil {
-			log.Println(err.Error())
-			c.AbortWithStatusJSON(503, gin.H{
-				"error": "Indentity service unavailable",
-			})
-			return
-		}
-
-		if !ok {
-			c.AbortWithStatusJSON(401, gin.H{"error": "Invalid token"})
-			return
-		}
-
-		claims, err := parseJWTUnverified(token)
-		if err != nil {
-			c.AbortWithStatusJSON(401, gin.H{"error": "Invalid token format"})
+		if !authenticate(c, authenticator, token) {
 			return
 		}
 
-		userId, _ := claims["user_id"].(string)
-		userRole, _ := claims["user_role"].(string)
-
-		if userId == "" || userRole == "" {
-			c.AbortWithStatusJSON(401, gin.H{"error": "Invalid token payload"})
-			return
-		}
-
-		c.Request.Header.Set("X-User-ID", userId)
-		c.Request.Header.Set("X-User-Role", userRole)
-
-		c.Set("user_id", userId)
-		c.Set("user_role", userRole)
-
 		c.Next()
 	}
 }
 
-func parseJWTUnverified(tokenString string) (jwt.MapClaims, error) {
-	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
-
-	token, _, err := parser.ParseUnverified(tokenString, jwt.MapClaims{})
+// authenticate проверяет токен и проставляет доверенные заголовки
+// X-User-ID / X-User-Role для сервисов за gateway
+func authenticate(c *gin.Context, authenticator auth.Authenticator, token string) bool {
+	identity, err := authenticator.Authenticate(c.Request.Context(), token)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, auth.ErrInvalidToken) {
+			c.AbortWithStatusJSON(401, gin.H{"error": "Invalid token"})
+			return false
+		}
+
+		log.Println(err.Error())
+		c.AbortWithStatusJSON(503, gin.H{
+			"error": "Indentity service unavailable",
+		})
+		return false
 	}
 
-	return token.Claims.(jwt.MapClaims), nil
+	c.Request.Header.Set("X-User-ID", identity.UserID)
+	c.Request.Header.Set("X-User-Role", identity.Role)
+
+	c.Set("user_id", identity.UserID)
+	c.Set("user_role", identity.Role)
+
+	return true
 }
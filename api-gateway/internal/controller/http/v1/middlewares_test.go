@@ -0,0 +1,55 @@
+package v1
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStripTrustedHeadersSetsRealIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		wantIP         string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", forwardedFor: "198.51.100.1", wantIP: "203.0.113.7"},
+		{name: "untrusted proxy", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "203.0.113.7:5000", forwardedFor: "198.51.100.1", wantIP: "203.0.113.7"},
+		{name: "trusted proxy", trustedProxies: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.2:5000", forwardedFor: "198.51.100.1", wantIP: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			if err := router.SetTrustedProxies(tt.trustedProxies); err != nil {
+				t.Fatal(err)
+			}
+
+			var got http.Header
+			router.Use(StripTrustedHeaders())
+			router.GET("/", func(c *gin.Context) {
+				got = c.Request.Header.Clone()
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			req.Header.Set("X-Real-IP", "192.0.2.99")
+			req.Header.Set("X-User-ID", "spoofed")
+			req.Header.Set("X-User-Role", "admin")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if ip := got.Get("X-Real-IP"); ip != tt.wantIP {
+				t.Fatalf("X-Real-IP = %q, want %q", ip, tt.wantIP)
+			}
+			if got.Get("X-User-ID") != "" || got.Get("X-User-Role") != "" {
+				t.Fatal("client identity headers were not stripped")
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ func LoadRoutes(cfg config.RoutesConfig) []Route {
 		{Pattern: "/api/v1/admin/*", Target: cfg.AuthServicePath + "/admin", Public: false},
 		{Pattern: "/api/v1/auth/*", Target: cfg.AuthServicePath + "/auth", Public: true},
 		{Pattern: "/api/v1/directory/*", Target: cfg.DirectoryServicePath + "/directory", Public: false},
+		{Pattern: "/api/v1/scim/v2/*", Target: cfg.DirectoryServicePath + "/scim/v2", Public: true},
 		{Pattern: "/api/v1/chats/*", Target: cfg.ChatServicePath + "/chats", Public: false},
 	}
 
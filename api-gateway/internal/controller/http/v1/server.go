@@ -6,19 +6,24 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/auth"
 	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/config"
 	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/proxy"
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	srv        *http.Server
-	authClient identity.Client
-	routes     []Route
+	srv           *http.Server
+	authenticator auth.Authenticator
+	routes        []Route
+
+	wsTarget  string
+	wsLimiter *ConnLimiter
+
+	trustedProxies []string
 }
 
-func NewServer(cfg *config.Config, authClient identity.Client) *Server {
+func NewServer(cfg *config.Config, authenticator auth.Authenticator) *Server {
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%v", cfg.Port),
 		ReadTimeout:  cfg.ReadTimeout,
@@ -29,23 +34,30 @@ func NewServer(cfg *config.Config, authClient identity.Client) *Server {
 	routes := LoadRoutes(cfg.RoutesConfig)
 
 	return &Server{
-		srv:        srv,
-		authClient: authClient,
-		routes:     routes,
+		srv:           srv,
+		authenticator: authenticator,
+		routes:        routes,
+		wsTarget:      cfg.ChatServicePath,
+		wsLimiter:     NewConnLimiter(cfg.MaxConnsPerUser),
+
+		trustedProxies: cfg.TrustedProxies,
 	}
 }
 
 func (s *Server) RegisterHandlers() error {
 	router := gin.Default()
+	if err := router.SetTrustedProxies(s.trustedProxies); err != nil {
+		return fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
 
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "http://localhost:3000")
 		c.Header("Access-Control-Allow-Credentials", "true")
 		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS,PATCH")
 		c.Header("Access-Control-Allow-Headers",
-			"Content-Type,Authorization,Accept,Origin,X-Requested-With,X-User-ID,X-User-Role")
+			"Content-Type,Authorization,Accept,Origin,X-Requested-With,X-User-ID,X-User-Role,Idempotency-Key")
 		c.Header("Access-Control-Expose-Headers",
-			"Content-Length,Content-Range,Authorization,X-User-ID,X-User-Role")
+			"Content-Length,Content-Range,Authorization,X-User-ID,X-User-Role,Idempotent-Replayed")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -54,11 +66,13 @@ func (s *Server) RegisterHandlers() error {
 		c.Next()
 	})
 
+	router.Use(StripTrustedHeaders())
+
 	factory := proxy.NewFactory()
 	proxyHandlers := NewProxyHandlers(factory)
 
 	protected := router.Group("/")
-	protected.Use(AuthMiddleware(s.authClient))
+	protected.Use(AuthMiddleware(s.authenticator))
 
 	public := router.Group("/")
 
@@ -74,6 +88,9 @@ func (s *Server) RegisterHandlers() error {
 		group.Any(pattern, proxyHandlers.ProxyTo(route.Target))
 	}
 
+	// Websocket chat-service: аутентификация handshake и долгоживущий прокси
+	router.GET("/api/v1/ws", WebsocketAuthMiddleware(s.authenticator), proxyHandlers.ProxyWebsocket(s.wsTarget, s.wsLimiter))
+
 	s.srv.Handler = router
 	return nil
 }
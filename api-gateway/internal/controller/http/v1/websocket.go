@@ -0,0 +1,186 @@
+package v1
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/api-gateway/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// Браузерный WebSocket API не умеет ставить Authorization, поэтому токен можно
+// передать в query (?token=) или элементом Sec-WebSocket-Protocol вида "bearer.<token>"
+const bearerProtocolPrefix = "bearer."
+
+// WebsocketAuthMiddleware аутентифицирует handshake и приводит токен к заголовку
+// Authorization: из query и списка подпротоколов он дальше не передаётся
+func WebsocketAuthMiddleware(authenticator auth.Authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, protocols := splitBearerProtocol(c.GetHeader("Sec-WebSocket-Protocol"))
+
+		if token != "" && len(protocols) == 0 {
+			// Клиент, предложивший подпротоколы, ждёт один из них в ответе,
+			// а "bearer.<token>" сервер выбрать не может
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Sec-WebSocket-Protocol must contain a messenger subprotocol besides the bearer token",
+			})
+			return
+		}
+
+		if token == "" {
+			token = c.Query("token")
+		}
+		if token == "" {
+			if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+				token = parts[1]
+			}
+		}
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization required"})
+			return
+		}
+
+		if !authenticate(c, authenticator, token) {
+			return
+		}
+
+		query := c.Request.URL.Query()
+		query.Del("token")
+		c.Request.URL.RawQuery = query.Encode()
+
+		if len(protocols) > 0 {
+			c.Request.Header.Set("Sec-WebSocket-Protocol", strings.Join(protocols, ", "))
+		} else {
+			c.Request.Header.Del("Sec-WebSocket-Protocol")
+		}
+		c.Request.Header.Set("Authorization", "Bearer "+token)
+
+		c.Next()
+	}
+}
+
+// splitBearerProtocol отделяет токен от остальных подпротоколов
+func splitBearerProtocol(header string) (string, []string) {
+	var token string
+	var protocols []string
+
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+		case strings.HasPrefix(strings.ToLower(item), bearerProtocolPrefix):
+			token = item[len(bearerProtocolPrefix):]
+		default:
+			protocols = append(protocols, item)
+		}
+	}
+
+	return token, protocols
+}
+
+// ConnLimiter ограничивает число одновременных websocket-соединений пользователя
+type ConnLimiter struct {
+	mu    sync.Mutex
+	max   int
+	conns map[string]int
+}
+
+func NewConnLimiter(maxPerUser int) *ConnLimiter {
+	return &ConnLimiter{
+		max:   maxPerUser,
+		conns: make(map[string]int),
+	}
+}
+
+func (l *ConnLimiter) Acquire(userID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.max > 0 && l.conns[userID] >= l.max {
+		return false
+	}
+
+	l.conns[userID]++
+	return true
+}
+
+func (l *ConnLimiter) Release(userID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.conns[userID]--
+	if l.conns[userID] <= 0 {
+		delete(l.conns, userID)
+	}
+}
+
+// Hop-by-hop заголовки (RFC 9110, 7.6.1), кроме Connection и Upgrade, нужных для handshake
+var hopHeaders = []string{
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+}
+
+// sanitizeUpgradeHeaders оставляет в handshake только end-to-end заголовки: перечисленные
+// клиентом в Connection и прочие hop-by-hop удаляются, Connection сводится к Upgrade
+func sanitizeUpgradeHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name != "" && !strings.EqualFold(name, "Upgrade") {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+
+	header.Set("Connection", "Upgrade")
+	header.Set("Upgrade", "websocket")
+}
+
+// ProxyWebsocket проксирует upgrade-запрос в chat-service. ServeHTTP возвращается
+// только после закрытия соединения, поэтому слот лимита держится всю сессию
+func (h *ProxyHandlers) ProxyWebsocket(target string, limiter *ConnLimiter) gin.HandlerFunc {
+	revProxy := h.factory.Get(target)
+	return func(c *gin.Context) {
+		if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Websocket upgrade required"})
+			return
+		}
+
+		userID := c.GetString("user_id")
+		if !limiter.Acquire(userID) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many websocket connections"})
+			return
+		}
+		defer limiter.Release(userID)
+
+		// ReadTimeout/WriteTimeout сервера выставлены как дедлайны соединения и
+		// после hijack остаются на нём - снимаем их, иначе сессия оборвётся через WriteTimeout
+		rc := http.NewResponseController(c.Writer)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			log.Printf("[Proxy] failed to reset read deadline: %v", err)
+		}
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Printf("[Proxy] failed to reset write deadline: %v", err)
+		}
+
+		sanitizeUpgradeHeaders(c.Request.Header)
+
+		originalPath := c.Request.URL.Path
+		c.Request.URL.Path = "/ws"
+
+		log.Printf("[Proxy] websocket %s -> %s/ws (user %s)", originalPath, target, userID)
+
+		revProxy.ServeHTTP(c.Writer, c.Request)
+	}
+}
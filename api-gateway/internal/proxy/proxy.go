@@ -45,13 +45,8 @@ func (f *Factory) Get(target string) *httputil.ReverseProxy {
 		r.URL.Scheme = u.Scheme
 		r.URL.Host = u.Host
 
-		// КОРОННОЕ ИСПРАВЛЕНИЕ: передаем все заголовки оригинала
-		// Особенно важны X-User-ID и X-User-Role
-		for key, values := range r.Header {
-			for _, value := range values {
-				r.Header.Add(key, value)
-			}
-		}
+		// Заголовки исходящего запроса уже скопированы ReverseProxy; X-User-ID и X-User-Role
+		// выставлены gateway после аутентификации (см. StripTrustedHeaders)
 
 		// Удаляем заголовки, которые могут мешать
 		r.Header.Del("Accept-Encoding")
@@ -0,0 +1,63 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// JoinDepartmentChat добавляет пользователя в чат отдела, создавая чат при первом
+// обращении. Повторный вызов ничего не меняет
+func (r *ChatRepo) JoinDepartmentChat(ctx context.Context, depID uuid.UUID, name string, userID uuid.UUID) (uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Чат отдела создаётся один раз, даже при одновременном онбординге нескольких сотрудников
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))", depID.String()); err != nil {
+		return uuid.Nil, fmt.Errorf("lock department chat: %w", err)
+	}
+
+	var chatID uuid.UUID
+	err = tx.QueryRow(ctx, "SELECT chat_id FROM department_chats WHERE department_id = $1", depID).Scan(&chatID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		chatSQL, chatArgs, _ := r.builder.Insert("chats").
+			Columns("type", "name", "created_by").
+			Values(models.ChatDepartment, name, userID).
+			Suffix("RETURNING chat_id").
+			ToSql()
+		if err := tx.QueryRow(ctx, chatSQL, chatArgs...).Scan(&chatID); err != nil {
+			return uuid.Nil, fmt.Errorf("insert chat: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx,
+			"INSERT INTO department_chats (department_id, chat_id) VALUES ($1, $2)",
+			depID, chatID); err != nil {
+			return uuid.Nil, fmt.Errorf("insert department chat: %w", err)
+		}
+	} else if err != nil {
+		return uuid.Nil, fmt.Errorf("select department chat: %w", err)
+	}
+
+	membersSQL, membersArgs, _ := r.builder.Insert("chat_members").
+		Columns("chat_id", "user_id", "role", "joined_at").
+		Values(chatID, userID, models.RoleMember, time.Now()).
+		Suffix("ON CONFLICT (chat_id, user_id) DO NOTHING").
+		ToSql()
+	if _, err := tx.Exec(ctx, membersSQL, membersArgs...); err != nil {
+		return uuid.Nil, fmt.Errorf("insert chat member: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return chatID, nil
+}
@@ -1,39 +1,64 @@
 package adapter
 
 import (
-	"sync"
+	"context"
+	"fmt"
 
+	"github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// OfflineStorage - очередь событий для пользователей, которые не смогли их получить по websocket.
+// Ключ очереди - пользователь без открытых сессий или отдельная отстающая сессия
 type OfflineStorage struct {
-	messages map[uuid.UUID][][]byte
-	mu       sync.Mutex
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
 }
 
-func NewOfflineStorage() *OfflineStorage {
+func NewOfflineStorage(db *pgxpool.Pool) *OfflineStorage {
 	return &OfflineStorage{
-		messages: make(map[uuid.UUID][][]byte),
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
 	}
 }
 
-func (s *OfflineStorage) SendMessage(userId uuid.UUID, data []byte) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *OfflineStorage) SendMessage(ctx context.Context, userId uuid.UUID, data []byte) error {
+	sqlStr, args, err := s.builder.Insert("offline_messages").
+		Columns("user_id", "payload").
+		Values(userId, data).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
 
-	s.messages[userId] = append(s.messages[userId], data)
-}
+	if _, err := s.db.Exec(ctx, sqlStr, args...); err != nil {
+		return fmt.Errorf("failed to insert offline message: %w", err)
+	}
 
-func (s *OfflineStorage) GetMessages(userId uuid.UUID) ([][]byte, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	return nil
+}
 
-	messages, exists := s.messages[userId]
-	if !exists {
-		return [][]byte{}, nil
+func (s *OfflineStorage) GetMessages(ctx context.Context, userId uuid.UUID) ([][]byte, error) {
+	// Забираем очередь целиком и сразу удаляем её, сохраняя порядок вставки
+	rows, err := s.db.Query(ctx, `
+		WITH taken AS (
+			DELETE FROM offline_messages WHERE user_id = $1 RETURNING id, payload
+		)
+		SELECT payload FROM taken ORDER BY id`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take offline messages: %w", err)
 	}
+	defer rows.Close()
 
-	delete(s.messages, userId)
+	messages := [][]byte{}
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("failed to scan offline message: %w", err)
+		}
+		messages = append(messages, payload)
+	}
 
-	return messages, nil
+	return messages, rows.Err()
 }
@@ -27,8 +27,8 @@ func NewApp(cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	server := v1.NewServer(cfg.Port, cfg.ReadTimeout, cfg.WriteTimeout, db.Pool)
-	err = server.RegisterHandlers()
+	server := v1.NewServer(cfg.Port, cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdentityServiceConfig, db.Pool)
+	err = server.RegisterHandlers(cfg.WebsocketConfig, cfg.AuthConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to register handlers: %w", err)
 	}
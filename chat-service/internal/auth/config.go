@@ -0,0 +1,11 @@
+package auth
+
+import "time"
+
+type AuthConfig struct {
+	// Сколько помнить ответ identity-service о том, что аккаунт активен
+	IntrospectionCacheTTL time.Duration `env:"INTROSPECTION_CACHE_TTL" env-default:"30s"`
+
+	// Общий секрет для вызовов /internal от других сервисов. Пустой - /internal закрыт
+	InternalToken string `env:"INTERNAL_API_TOKEN"`
+}
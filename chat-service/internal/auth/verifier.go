@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	stdErrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/errors"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims - проверенные данные access-токена
+type Claims struct {
+	UserID    string
+	Role      string
+	ExpiresAt time.Time
+}
+
+type cachedIntrospection struct {
+	userID    string
+	active    bool
+	expiresAt time.Time
+}
+
+type KeySet interface {
+	Keyfunc(t *jwt.Token) (any, error)
+}
+
+// Verifier проверяет подпись (по JWKS identity-service) и срок действия токена локально,
+// а состояние аккаунта и сессии - через introspection с кэшированием ответа
+type Verifier struct {
+	cfg            AuthConfig
+	identityClient identity.Client
+	keys           KeySet
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cachedIntrospection
+}
+
+func NewVerifier(cfg AuthConfig, identityClient identity.Client, keys KeySet) *Verifier {
+	return &Verifier{
+		cfg:            cfg,
+		identityClient: identityClient,
+		keys:           keys,
+		cache:          make(map[[sha256.Size]byte]cachedIntrospection),
+	}
+}
+
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	token, err := jwt.Parse(tokenString, v.keys.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		if stdErrors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.ErrTokenExpired
+		}
+		return nil, errors.ErrInvalidToken
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.ErrInvalidToken
+	}
+
+	userID, _ := mapClaims["user_id"].(string)
+	role, _ := mapClaims["user_role"].(string)
+	exp, err := mapClaims.GetExpirationTime()
+	if userID == "" || role == "" || err != nil || exp == nil {
+		return nil, errors.ErrInvalidToken
+	}
+
+	active, err := v.isActive(ctx, tokenString, userID, exp.Time)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, errors.ErrAccountInactive
+	}
+
+	return &Claims{
+		UserID:    userID,
+		Role:      role,
+		ExpiresAt: exp.Time,
+	}, nil
+}
+
+func (v *Verifier) isActive(ctx context.Context, tokenString, userID string, tokenExp time.Time) (bool, error) {
+	key := sha256.Sum256([]byte(tokenString))
+	now := time.Now()
+
+	v.mu.Lock()
+	cached, ok := v.cache[key]
+	v.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.active, nil
+	}
+
+	active, err := v.identityClient.IntrospectToken(ctx, tokenString)
+	if err != nil {
+		return false, fmt.Errorf("failed to introspect token: %w", err)
+	}
+
+	expiresAt := now.Add(v.cfg.IntrospectionCacheTTL)
+	if tokenExp.Before(expiresAt) {
+		expiresAt = tokenExp
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Заодно вычищаем устаревшие записи, чтобы кэш не рос бесконечно
+	for k, entry := range v.cache {
+		if now.After(entry.expiresAt) {
+			delete(v.cache, k)
+		}
+	}
+	v.cache[key] = cachedIntrospection{userID: userID, active: active, expiresAt: expiresAt}
+
+	return active, nil
+}
+
+// ForgetUser сбрасывает закэшированные ответы по токенам пользователя, чтобы
+// следующая проверка заново спросила identity-service
+func (v *Verifier) ForgetUser(userID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for k, entry := range v.cache {
+		if entry.userID == userID {
+			delete(v.cache, k)
+		}
+	}
+}
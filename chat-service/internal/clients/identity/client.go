@@ -0,0 +1,70 @@
+package identity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type Client interface {
+	IntrospectToken(ctx context.Context, token string) (bool, error)
+}
+
+type HTTPClient struct {
+	baseURL    string
+	httpClient *http.Client
+	timeout    time.Duration
+}
+
+func NewHTTPClient(cfg IdentityServiceConfig) *HTTPClient {
+	return &HTTPClient{
+		baseURL: cfg.URL,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:    cfg.MaxIdleConns,
+				MaxConnsPerHost: cfg.MaxConnsPerHost,
+				IdleConnTimeout: cfg.IdleConnTimeout,
+			},
+		},
+		timeout: cfg.Timeout,
+	}
+}
+
+func (c *HTTPClient) IntrospectToken(ctx context.Context, token string) (bool, error) {
+	jsonBody, _ := json.Marshal(IntrospectRequest{Token: token})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, _ := io.ReadAll(resp.Body)
+
+	// identity-service отвечает 400 на токены, которые не смог разобрать
+	if resp.StatusCode == http.StatusBadRequest {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("identity service returned %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var introspectResp IntrospectResponse
+	if err := json.Unmarshal(bodyBytes, &introspectResp); err != nil {
+		return false, fmt.Errorf("failed to decode data to IntrospectResponse: %w", err)
+	}
+
+	return introspectResp.Active, nil
+}
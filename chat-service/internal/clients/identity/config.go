@@ -0,0 +1,15 @@
+package identity
+
+import "time"
+
+type IdentityServiceConfig struct {
+	URL             string        `env:"IDENTITY_INTROSPECT_URL" env-default:"http://localhost:8081/auth/introspect"`
+	Timeout         time.Duration `env:"IDENTITY_SERVICE_TIMEOUT" env-default:"5s"`
+	MaxIdleConns    int           `env:"IDENTITY_MAX_IDLE_CONNS" env-default:"100"`
+	MaxConnsPerHost int           `env:"IDENTITY_MAX_CONNS_PER_HOST" env-default:"50"`
+	IdleConnTimeout time.Duration `env:"IDENTITY_IDLE_CONN_TIMEOUT" env-default:"90s"`
+
+	JWKSURL                string        `env:"IDENTITY_JWKS_URL" env-default:"http://localhost:8081/.well-known/jwks.json"`
+	JWKSCacheTTL           time.Duration `env:"JWKS_CACHE_TTL" env-default:"5m"`
+	JWKSMinRefreshInterval time.Duration `env:"JWKS_MIN_REFRESH_INTERVAL" env-default:"10s"`
+}
@@ -0,0 +1,9 @@
+package identity
+
+type IntrospectRequest struct {
+	Token string `json:"token"`
+}
+
+type IntrospectResponse struct {
+	Active bool `json:"active"`
+}
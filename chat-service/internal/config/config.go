@@ -5,6 +5,9 @@ import (
 	"os"
 	"time"
 
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/auth"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/controller/websocket"
 	postgres "github.com/I-Van-Radkov/corporate-messenger/chat-service/pkg/db"
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -17,6 +20,12 @@ type Config struct {
 	GHTimeout time.Duration `env:"GRACEFUL_SHUTDOWN_TIMEOUT"`
 
 	postgres.PostgresConfig
+
+	WebsocketConfig websocket.Config
+
+	identity.IdentityServiceConfig
+
+	auth.AuthConfig
 }
 
 func ParseConfigFromEnv() (*Config, error) {
@@ -89,7 +89,11 @@ func (h *Chathandlers) GetUserChats(c *gin.Context) {
 func (h *Chathandlers) GetChatMessages(c *gin.Context) {
 	userID, _ := uuid.Parse(c.GetString("user_id"))
 	var req dto.GetMessagesRequest
-	if err := c.ShouldBind(&req); err != nil {
+	if err := c.ShouldBindUri(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid params"})
+		return
+	}
+	if err := c.ShouldBindQuery(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid params"})
 		return
 	}
@@ -100,7 +104,14 @@ func (h *Chathandlers) GetChatMessages(c *gin.Context) {
 
 	resp, err := h.chatusecase.GetChatMessages(c.Request.Context(), req.ChatID, userID, req.Limit, req.Before)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch err {
+		case errors.ErrChatNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.ErrUserNotInChat:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SessionTerminator interface {
+	DisconnectUser(userID uuid.UUID, code int, reason string) int
+}
+
+type TokenCache interface {
+	ForgetUser(userID string)
+}
+
+type DepartmentChats interface {
+	JoinDepartmentChat(ctx context.Context, depID uuid.UUID, req *dto.JoinDepartmentChatRequest, userID uuid.UUID) (*dto.DepartmentChatResponse, error)
+}
+
+// InternalHandlers - вызовы от других сервисов (identity-service, directory-service)
+type InternalHandlers struct {
+	sessions   SessionTerminator
+	tokenCache TokenCache
+	closeCode  int
+	depChats   DepartmentChats
+}
+
+func NewInternalHandlers(sessions SessionTerminator, tokenCache TokenCache, closeCode int, depChats DepartmentChats) *InternalHandlers {
+	return &InternalHandlers{
+		sessions:   sessions,
+		tokenCache: tokenCache,
+		closeCode:  closeCode,
+		depChats:   depChats,
+	}
+}
+
+// AccountDeactivated отключает пользователя сразу после деактивации аккаунта
+func (h *InternalHandlers) AccountDeactivated(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	h.tokenCache.ForgetUser(userID.String())
+	closed := h.sessions.DisconnectUser(userID, h.closeCode, "account deactivated")
+
+	log.Printf("account %s deactivated, closed %d websocket sessions", userID, closed)
+
+	c.Status(http.StatusNoContent)
+}
+
+// JoinDepartmentChat добавляет нового сотрудника в чат его отдела
+func (h *InternalHandlers) JoinDepartmentChat(c *gin.Context) {
+	depID, err := uuid.Parse(c.Param("department_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department ID"})
+		return
+	}
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req dto.JoinDepartmentChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.depChats.JoinDepartmentChat(c.Request.Context(), depID, &req, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
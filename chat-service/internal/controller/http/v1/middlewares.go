@@ -1,28 +1,47 @@
 package v1
 
 import (
+	"context"
+	"crypto/subtle"
+	stdErrors "errors"
 	"log"
 	"net/http"
 	"strings"
 
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/auth"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/errors"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
-func ExtractUserInfoMiddleware() gin.HandlerFunc {
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*auth.Claims, error)
+}
+
+// ExtractUserInfoMiddleware берёт пользователя из проверенного access-токена.
+// Заголовкам X-User-ID / X-User-Role не доверяем: запрос мог прийти в обход gateway
+func ExtractUserInfoMiddleware(verifier TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID := c.GetHeader("X-User-ID")
-		role := c.GetHeader("X-User-Role")
+		token, ok := bearerToken(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Authorization required",
+			})
+			return
+		}
+
+		claims, ok := verifyToken(c, verifier, token)
+		if !ok {
+			return
+		}
 
-		if userID == "" || role == "" {
+		if headerID := c.GetHeader("X-User-ID"); headerID != "" && headerID != claims.UserID {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "User info missing",
+				"error": "User info does not match token",
 			})
 			return
 		}
 
-		c.Set("user_id", userID)
-		c.Set("user_role", role)
+		setClaims(c, claims)
 
 		c.Next()
 	}
@@ -39,7 +58,7 @@ func RequireRoles(allowedRoles ...ClientRole) gin.HandlerFunc {
 		}
 
 		for _, allowedRole := range allowedRoles {
-			if userRole == allowedRole {
+			if userRole == string(allowedRole) {
 				c.Next()
 				return
 			}
@@ -59,57 +78,83 @@ func RequireStaff() gin.HandlerFunc {
 	return RequireRoles(adminRole, moderatorRole, supportRole)
 }
 
-func ExtractUserIdForWs() gin.HandlerFunc {
+func ExtractUserIdForWs(verifier TokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Для WebSocket сначала проверяем query параметры
 		token := c.Query("token")
 
 		// Если нет в query, пробуем из заголовков (для HTTP запросов)
 		if token == "" {
-			authHeader := c.GetHeader("Authorization")
-			if authHeader == "" {
+			var ok bool
+			token, ok = bearerToken(c)
+			if !ok {
 				c.AbortWithStatusJSON(401, gin.H{"error": "Authorization required"})
 				return
 			}
-
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-				c.AbortWithStatusJSON(401, gin.H{"error": "Invalid auth format"})
-				return
-			}
-			token = parts[1]
 		}
 
-		if token == "" {
-			c.AbortWithStatusJSON(401, gin.H{"error": "Token required"})
+		claims, ok := verifyToken(c, verifier, token)
+		if !ok {
 			return
 		}
 
-		claims, err := parseJWTUnverified(token)
-		if err != nil {
-			c.AbortWithStatusJSON(401, gin.H{"error": "Invalid token format"})
-			return
-		}
+		setClaims(c, claims)
+		// Токен сессии периодически перепроверяется, пока соединение открыто
+		c.Set("access_token", token)
+		c.Next()
+	}
+}
 
-		userId, _ := claims["user_id"].(string)
-		if userId == "" {
-			c.AbortWithStatusJSON(401, gin.H{"error": "Invalid token payload"})
-			return
-		}
+func bearerToken(c *gin.Context) (string, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return "", false
+	}
 
-		c.Set("user_id", userId)
-		log.Println(userId)
-		c.Next()
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || parts[1] == "" {
+		return "", false
 	}
+
+	return parts[1], true
 }
 
-func parseJWTUnverified(tokenString string) (jwt.MapClaims, error) {
-	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+func verifyToken(c *gin.Context, verifier TokenVerifier, token string) (*auth.Claims, bool) {
+	claims, err := verifier.Verify(c.Request.Context(), token)
+	if err == nil {
+		return claims, true
+	}
 
-	token, _, err := parser.ParseUnverified(tokenString, jwt.MapClaims{})
-	if err != nil {
-		return nil, err
+	switch {
+	case stdErrors.Is(err, errors.ErrTokenExpired):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
+	case stdErrors.Is(err, errors.ErrAccountInactive):
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account is deactivated"})
+	case stdErrors.Is(err, errors.ErrInvalidToken):
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+	default:
+		log.Println(err.Error())
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Identity service unavailable"})
 	}
 
-	return token.Claims.(jwt.MapClaims), nil
+	return nil, false
+}
+
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("user_role", claims.Role)
+	c.Set("token_expires_at", claims.ExpiresAt)
+}
+
+// RequireInternalToken пропускает только вызовы других сервисов с общим секретом
+func RequireInternalToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader("X-Internal-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Internal access only"})
+			return
+		}
+
+		c.Next()
+	}
 }
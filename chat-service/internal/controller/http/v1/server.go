@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/adapter"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/auth"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/clients/identity"
 	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/controller/http/v1/handlers"
 	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/controller/websocket"
 	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/usecase"
@@ -15,11 +17,14 @@ import (
 )
 
 type Server struct {
-	srv *http.Server
-	db  *pgxpool.Pool
+	srv            *http.Server
+	identityClient identity.Client
+	jwksClient     *identity.JWKSClient
+	db             *pgxpool.Pool
+	wsHandlers     *websocket.WebsocketHandlers
 }
 
-func NewServer(port int, readTimeout, writeTimeout time.Duration, db *pgxpool.Pool) *Server {
+func NewServer(port int, readTimeout, writeTimeout time.Duration, identityCfg identity.IdentityServiceConfig, db *pgxpool.Pool) *Server {
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%v", port),
 		ReadTimeout:  readTimeout,
@@ -27,19 +32,28 @@ func NewServer(port int, readTimeout, writeTimeout time.Duration, db *pgxpool.Po
 		Handler:      nil,
 	}
 
+	identityClient := identity.NewHTTPClient(identityCfg)
+	jwksClient := identity.NewJWKSClient(identityCfg)
+
 	return &Server{
-		srv: srv,
-		db:  db,
+		srv:            srv,
+		identityClient: identityClient,
+		jwksClient:     jwksClient,
+		db:             db,
 	}
 }
 
-func (s *Server) RegisterHandlers() error {
+func (s *Server) RegisterHandlers(wsCfg websocket.Config, authCfg auth.AuthConfig) error {
+	verifier := auth.NewVerifier(authCfg, s.identityClient, s.jwksClient)
+
 	chatRepo := adapter.NewChatRepo(s.db)
-	msgStorage := adapter.NewOfflineStorage()
+	msgStorage := adapter.NewOfflineStorage(s.db)
 	chatUsecase := usecase.NewChatUsecase(chatRepo, msgStorage)
 
-	wsHandlers := websocket.NewWebsockethandlers(chatUsecase)
+	wsHandlers := websocket.NewWebsockethandlers(chatUsecase, verifier, wsCfg)
+	s.wsHandlers = wsHandlers
 	chatHandlers := handlers.NewChatHandlers(chatUsecase)
+	internalHandlers := handlers.NewInternalHandlers(wsHandlers, verifier, websocket.CloseAccountDeactivated, chatUsecase)
 
 	router := gin.Default()
 	router.Use(func(c *gin.Context) {
@@ -55,20 +69,22 @@ func (s *Server) RegisterHandlers() error {
 		c.Next()
 	})
 
+	router.GET("/ws/schema", wsHandlers.GetSchema)
+
 	wsGroup := router.Group("/ws")
-	wsGroup.Use(ExtractUserIdForWs())
+	wsGroup.Use(ExtractUserIdForWs(verifier))
 	{
 		wsGroup.GET("", wsHandlers.HandleConnection)
 	}
 
 	chats := router.Group("/chats")
-	chats.Use(ExtractUserInfoMiddleware())
+	chats.Use(ExtractUserInfoMiddleware(verifier))
 	{
 		// Все пользователи
 		chats.GET("/c", chatHandlers.GetUserChats)
 		chats.POST("/c", chatHandlers.CreateChat)
 		chats.GET("/:chat_id/members", chatHandlers.GetChatMembers)
-		router.GET("/:chat_id", chatHandlers.GetChatMessages)
+		chats.GET("/:chat_id", chatHandlers.GetChatMessages)
 
 		// Только staff (admin/moderator/support)
 		staffOnly := chats.Group("")
@@ -78,8 +94,18 @@ func (s *Server) RegisterHandlers() error {
 			staffOnly.POST("/:chat_id/members/:user_id/role", chatHandlers.ChangeMemberRole)
 			staffOnly.POST("/:chat_id/members", chatHandlers.AddMembers)
 			staffOnly.DELETE("/:chat_id", chatHandlers.RemoveChat)
+
+			staffOnly.GET("/ws/stats", wsHandlers.GetStats)
 		}
 	}
+
+	internal := router.Group("/internal")
+	internal.Use(RequireInternalToken(authCfg.InternalToken))
+	{
+		internal.POST("/accounts/:user_id/deactivated", internalHandlers.AccountDeactivated)
+		internal.PUT("/departments/:department_id/members/:user_id", internalHandlers.JoinDepartmentChat)
+	}
+
 	s.srv.Handler = router
 
 	return nil
@@ -90,5 +116,13 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Stop(ctx context.Context) error {
-	return s.srv.Shutdown(ctx)
+	if err := s.srv.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	// Соединения закрыты, дописываем офлайн-очередь до закрытия пула БД
+	if s.wsHandlers != nil {
+		return s.wsHandlers.Close(ctx)
+	}
+	return nil
 }
@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// Codec переводит кадры между каноническим JSON, с которым работают обработчики
+// и офлайн-очередь, и кодировкой, согласованной с клиентом
+type Codec interface {
+	FrameType() int
+	Encode(canonical []byte) ([]byte, error)
+	Decode(frame []byte) ([]byte, error)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) FrameType() int {
+	return websocket.TextMessage
+}
+
+func (jsonCodec) Encode(canonical []byte) ([]byte, error) {
+	return canonical, nil
+}
+
+func (jsonCodec) Decode(frame []byte) ([]byte, error) {
+	return frame, nil
+}
+
+// msgpackCodec перекодирует JSON-дерево в MessagePack один к одному: UUID и время
+// остаются строками, числа - целыми или float64, поэтому семантика кадров не зависит от кодировки
+type msgpackCodec struct {
+	handle *codec.MsgpackHandle
+}
+
+func newMsgpackCodec() *msgpackCodec {
+	h := &codec.MsgpackHandle{}
+	h.MapType = reflect.TypeOf(map[string]any(nil))
+	h.RawToString = true
+	h.WriteExt = true
+
+	return &msgpackCodec{handle: h}
+}
+
+func (c *msgpackCodec) FrameType() int {
+	return websocket.BinaryMessage
+}
+
+func (c *msgpackCodec) Encode(canonical []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(canonical))
+	dec.UseNumber()
+
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("failed to decode canonical frame: %w", err)
+	}
+
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, c.handle).Encode(normalizeNumbers(tree)); err != nil {
+		return nil, fmt.Errorf("failed to encode msgpack frame: %w", err)
+	}
+
+	return out, nil
+}
+
+func (c *msgpackCodec) Decode(frame []byte) ([]byte, error) {
+	var tree any
+	if err := codec.NewDecoderBytes(frame, c.handle).Decode(&tree); err != nil {
+		return nil, fmt.Errorf("failed to decode msgpack frame: %w", err)
+	}
+
+	canonical, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode canonical frame: %w", err)
+	}
+
+	return canonical, nil
+}
+
+func normalizeNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	}
+
+	return value
+}
+
+var ErrFrameTypeMismatch = errors.New("frame type does not match negotiated subprotocol")
+
+// decodeFrame приводит входящий кадр к каноническому JSON кодировкой согласованного
+// подпротокола: в cm.v1.json принимаются только текстовые кадры, в cm.v1.msgpack - только бинарные
+func decodeFrame(c Codec, messageType int, frame []byte) ([]byte, error) {
+	if messageType != c.FrameType() {
+		return nil, ErrFrameTypeMismatch
+	}
+
+	return c.Decode(frame)
+}
@@ -0,0 +1,179 @@
+package websocket
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/dto"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+var (
+	testChatID = uuid.MustParse("0b6b2b58-33a4-4bb8-9d0a-3a4f26c1d001")
+	testUserID = uuid.MustParse("0b6b2b58-33a4-4bb8-9d0a-3a4f26c1d002")
+)
+
+// requestFrames - по одному корректному кадру каждого типа запроса cm.v1
+var requestFrames = map[string]string{
+	"send_message":       `{"type":"send_message","request_id":"r1","chat_id":"` + testChatID.String() + `","payload":{"content":"привет","type":"text","reply_to":"` + testUserID.String() + `"}}`,
+	"create_chat":        `{"type":"create_chat","request_id":"r2","payload":{"type":"group","name":"Команда","member_ids":["` + testUserID.String() + `"]}}`,
+	"get_chats":          `{"type":"get_chats","request_id":"r3"}`,
+	"get_messages":       `{"type":"get_messages","request_id":"r4","chat_id":"` + testChatID.String() + `","payload":{"limit":50,"before":"` + testUserID.String() + `"}}`,
+	"get_members":        `{"type":"get_members","request_id":"r5","chat_id":"` + testChatID.String() + `"}`,
+	"add_members":        `{"type":"add_members","request_id":"r6","chat_id":"` + testChatID.String() + `","payload":{"user_ids":["` + testUserID.String() + `"]}}`,
+	"remove_member":      `{"type":"remove_member","request_id":"r7","chat_id":"` + testChatID.String() + `","payload":{"user_id":"` + testUserID.String() + `"}}`,
+	"change_member_role": `{"type":"change_member_role","request_id":"r8","chat_id":"` + testChatID.String() + `","payload":{"user_id":"` + testUserID.String() + `","role":"admin"}}`,
+	"remove_chat":        `{"type":"remove_chat","request_id":"r9","chat_id":"` + testChatID.String() + `"}`,
+}
+
+// eventFrames - кадры каждого типа событий сервера в том виде, в каком их строит фабрика
+func eventFrames(t *testing.T) map[dto.EventType][]byte {
+	t.Helper()
+
+	factory := NewMessageFactory()
+	sentAt := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC)
+
+	events := map[dto.EventType]*dto.OutgoingMessage{
+		dto.EventMessageSent: factory.NewOutgoingMessage(&dto.MessageDTO{
+			ID: uuid.New(), ChatID: testChatID, SenderID: testUserID, Content: "привет", Type: "text", SentAt: sentAt,
+		}),
+		dto.EventError:          factory.NewError(dto.ErrInvalidPayload, "Ошибка", "payload.content: must not be empty", "r1"),
+		dto.EventResyncRequired: factory.NewResyncRequired("slow_consumer", 1<<40),
+		dto.EventAck: factory.NewAck(dto.TypeSendMessage, &dto.SendMessageAckResult{
+			MessageID: uuid.New(), SentAt: sentAt,
+		}, testChatID, "r1"),
+	}
+
+	frames := make(map[dto.EventType][]byte, len(events))
+	for eventType, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("failed to marshal %s: %v", eventType, err)
+		}
+		frames[eventType] = data
+	}
+
+	return frames
+}
+
+func assertSameJSON(t *testing.T, want, got []byte) {
+	t.Helper()
+
+	decode := func(data []byte) any {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			t.Fatalf("invalid JSON %s: %v", data, err)
+		}
+		return value
+	}
+
+	if !reflect.DeepEqual(decode(want), decode(got)) {
+		t.Fatalf("frames differ:\nwant %s\ngot  %s", want, got)
+	}
+}
+
+func TestCodecRoundTripRequests(t *testing.T) {
+	schema := protocols[ProtocolV1JSON].schema
+
+	for name, frame := range requestFrames {
+		for _, protocol := range supportedProtocols {
+			t.Run(name+"/"+protocol, func(t *testing.T) {
+				c := protocols[protocol].codec
+
+				encoded, err := c.Encode([]byte(frame))
+				if err != nil {
+					t.Fatalf("encode: %v", err)
+				}
+
+				canonical, err := decodeFrame(c, c.FrameType(), encoded)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+
+				assertSameJSON(t, []byte(frame), canonical)
+				if err := schema.ValidateRequest(name, canonical); err != nil {
+					t.Fatalf("decoded frame fails schema: %v", err)
+				}
+			})
+		}
+	}
+}
+
+func TestCodecRoundTripEvents(t *testing.T) {
+	for eventType, frame := range eventFrames(t) {
+		for _, protocol := range supportedProtocols {
+			t.Run(string(eventType)+"/"+protocol, func(t *testing.T) {
+				c := protocols[protocol].codec
+
+				encoded, err := c.Encode(frame)
+				if err != nil {
+					t.Fatalf("encode: %v", err)
+				}
+
+				decoded, err := c.Decode(encoded)
+				if err != nil {
+					t.Fatalf("decode: %v", err)
+				}
+
+				assertSameJSON(t, frame, decoded)
+			})
+		}
+	}
+}
+
+func TestMsgpackKeepsValueKinds(t *testing.T) {
+	frame := []byte(`{"s":"` + testUserID.String() + `","i":42,"big":1099511627776,"f":0.5,"neg":-3,"b":true,"n":null,"a":[1,"x"],"o":{"k":"v"}}`)
+
+	encoded, err := binaryCodec.Encode(frame)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	decoded, err := binaryCodec.Decode(encoded)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	assertSameJSON(t, frame, decoded)
+}
+
+func TestDecodeFrameRejectsMismatchedFrameType(t *testing.T) {
+	frame := []byte(requestFrames["get_chats"])
+
+	packed, err := binaryCodec.Encode(frame)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		protocol    string
+		messageType int
+		frame       []byte
+	}{
+		{name: "binary on json", protocol: ProtocolV1JSON, messageType: websocket.BinaryMessage, frame: packed},
+		{name: "text on msgpack", protocol: ProtocolV1MsgPack, messageType: websocket.TextMessage, frame: frame},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeFrame(protocols[tt.protocol].codec, tt.messageType, tt.frame)
+			if !errors.Is(err, ErrFrameTypeMismatch) {
+				t.Fatalf("expected ErrFrameTypeMismatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMsgpackDecodeRejectsGarbage(t *testing.T) {
+	if _, err := binaryCodec.Decode([]byte{0xc1}); err == nil {
+		t.Fatal("expected error for invalid msgpack")
+	}
+}
@@ -0,0 +1,21 @@
+package websocket
+
+import "time"
+
+type Config struct {
+	SendBufferSize    int           `env:"WS_SEND_BUFFER_SIZE" env-default:"256"`
+	SlowConsumerGrace time.Duration `env:"WS_SLOW_CONSUMER_GRACE" env-default:"30s"`
+	SpillQueueSize    int           `env:"WS_SPILL_QUEUE_SIZE" env-default:"4096"`
+
+	// Как часто перепроверять токены открытых сессий: деактивация аккаунта или отзыв
+	// сессии закрывают соединение, даже если уведомление identity-service не дошло. 0 - не проверять
+	SessionRecheckInterval time.Duration `env:"WS_SESSION_RECHECK_INTERVAL" env-default:"1m"`
+}
+
+// Коды закрытия соединения (диапазон 4000-4999 зарезервирован под приложение)
+const (
+	CloseTokenExpired       = 4001
+	CloseAccountDeactivated = 4002
+	CloseSessionRevoked     = 4003
+	CloseSlowConsumer       = 4008
+)
@@ -24,14 +24,38 @@ func (f *MessageFactory) NewOutgoingMessage(msg *dto.MessageDTO) *dto.OutgoingMe
 	return f.createOutgoingMessage(dto.EventMessageSent, payload, msg.ChatID)
 }
 
-func (f *MessageFactory) NewError(code dto.ErrorType, message, details string) *dto.OutgoingMessage {
+func (f *MessageFactory) NewError(code dto.ErrorType, message, details, requestID string) *dto.OutgoingMessage {
 	payload := dto.ErrorPayload{
 		Code:    code,
 		Message: message,
 		Details: details,
 	}
 
-	return f.createOutgoingMessage(dto.EventError, payload, uuid.Nil)
+	msg := f.createOutgoingMessage(dto.EventError, payload, uuid.Nil)
+	msg.Meta.RequestID = requestID
+
+	return msg
+}
+
+func (f *MessageFactory) NewAck(reqType dto.RequestType, result any, chatID uuid.UUID, requestID string) *dto.OutgoingMessage {
+	payload := dto.AckPayload{
+		RequestType: reqType,
+		Result:      result,
+	}
+
+	msg := f.createOutgoingMessage(dto.EventAck, payload, chatID)
+	msg.Meta.RequestID = requestID
+
+	return msg
+}
+
+func (f *MessageFactory) NewResyncRequired(reason string, missed int64) *dto.OutgoingMessage {
+	payload := dto.ResyncRequiredPayload{
+		Reason: reason,
+		Missed: missed,
+	}
+
+	return f.createOutgoingMessage(dto.EventResyncRequired, payload, uuid.Nil)
 }
 
 func (f *MessageFactory) createOutgoingMessage(eventtype dto.EventType, payload interface{}, chatID uuid.UUID) *dto.OutgoingMessage {
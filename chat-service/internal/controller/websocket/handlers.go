@@ -18,26 +18,48 @@ import (
 
 type Client struct {
 	UserID    uuid.UUID
+	Role      string
 	SessionID uuid.UUID
 	Conn      *websocket.Conn
 	Send      chan []byte
 
+	// Момент истечения access-токена, с которым открыта сессия
+	ExpiresAt time.Time
+	token     string
+
+	// Согласованный подпротокол, его схема и кодировка исходящих кадров
+	Protocol string
+	schema   *Schema
+	codec    Codec
+
 	IsClosed  atomic.Bool
 	closeOnce sync.Once
 	mu        sync.Mutex
 
+	// Состояние переполнения буфера: момент начала (unix nano, 0 - буфер в норме)
+	// и число событий, ушедших мимо буфера за это время
+	overflowSince atomic.Int64
+	missed        atomic.Int64
+	resyncQueued  atomic.Bool
+	// В офлайн-очереди сессии могли остаться события, см. releaseSpill
+	spilled atomic.Bool
+
 	cancelFunc context.CancelFunc
 	ctx        context.Context
 }
 
-func NewClient(userID uuid.UUID, conn *websocket.Conn) *Client {
+func NewClient(userID uuid.UUID, role string, conn *websocket.Conn, protocol string, bufferSize int) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	client := &Client{
 		UserID:     userID,
+		Role:       role,
 		SessionID:  uuid.New(),
 		Conn:       conn,
-		Send:       make(chan []byte, 256),
+		Send:       make(chan []byte, bufferSize),
+		Protocol:   protocol,
+		schema:     protocols[protocol].schema,
+		codec:      protocols[protocol].codec,
 		ctx:        ctx,
 		cancelFunc: cancel,
 	}
@@ -47,6 +69,10 @@ func NewClient(userID uuid.UUID, conn *websocket.Conn) *Client {
 }
 
 func (c *Client) close() {
+	c.closeWithCode(websocket.CloseNormalClosure, "")
+}
+
+func (c *Client) closeWithCode(code int, reason string) {
 	c.closeOnce.Do(func() {
 		c.mu.Lock()
 		defer c.mu.Unlock()
@@ -58,7 +84,8 @@ func (c *Client) close() {
 		}
 
 		if c.Conn != nil {
-			c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+			closeMsg := websocket.FormatCloseMessage(code, reason)
+			c.Conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
 			c.Conn.Close()
 		}
 
@@ -66,12 +93,58 @@ func (c *Client) close() {
 	})
 }
 
+func (c *Client) isStaff() bool {
+	for _, role := range staffRoles {
+		if c.Role == role {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *Client) getIsClosed() bool {
 	return c.IsClosed.Load()
 }
 
+// trySend кладёт данные в буфер без блокировки. Возвращает false, если буфер полон
+func (c *Client) trySend(data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.IsClosed.Load() {
+		return false
+	}
+
+	select {
+	case c.Send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Client) isOverflowing() bool {
+	return c.overflowSince.Load() != 0
+}
+
+func (c *Client) markOverflow() {
+	c.overflowSince.CompareAndSwap(0, time.Now().UnixNano())
+	c.missed.Add(1)
+}
+
+// overflowFor - как долго клиент не успевает разбирать буфер
+func (c *Client) overflowFor() time.Duration {
+	since := c.overflowSince.Load()
+	if since == 0 {
+		return 0
+	}
+
+	return time.Since(time.Unix(0, since))
+}
+
 type ChatUsecase interface {
-	SendMsgToStorage(ctx context.Context, userId uuid.UUID, msg []byte)
+	SendMsgToStorage(ctx context.Context, userId uuid.UUID, msg []byte) error
 	GetMessagesFromStorage(ctx context.Context, userId uuid.UUID) [][]byte
 
 	SendMessageToDb(ctx context.Context, msg *dto.MessageDTO) (string, error)
@@ -80,18 +153,37 @@ type ChatUsecase interface {
 	//MarkAsRead(ctx context.Context, userID, chatID, messageID string) error
 	GetChatMembers(ctx context.Context, chatID uuid.UUID) (*dto.ChatMembers, error)
 	IsUserInChat(ctx context.Context, userID, chatID uuid.UUID) (bool, error)
+
+	CreateChat(ctx context.Context, req *dto.CreateChatRequest, creatorID uuid.UUID) (*dto.CreateChatResponse, error)
+	RemoveChat(ctx context.Context, chatID uuid.UUID) error
+	GetUserChats(ctx context.Context, userID uuid.UUID) (*dto.GetUserChatsResponse, error)
+	GetChatMessages(ctx context.Context, chatID, userID uuid.UUID, limit int, before *uuid.UUID) (*dto.GetMessagesResponse, error)
+	AddMembers(ctx context.Context, chatID uuid.UUID, userIDs []uuid.UUID, adderID uuid.UUID) error
+	RemoveMember(ctx context.Context, chatID, userID, removerID uuid.UUID) error
+	ChangeMemberRole(ctx context.Context, chatID, userID uuid.UUID, role dto.MemberRole, changerID uuid.UUID) error
 }
 
 type WebsocketHandlers struct {
 	chatUsecase ChatUsecase
+	verifier    SessionVerifier
 	factory     *MessageFactory
+	cfg         Config
+	metrics     *Metrics
 
 	upgrader websocket.Upgrader
 	conns    map[uuid.UUID][]*Client
 	mu       sync.Mutex
+
+	// Фоновая запись в офлайн-очередь, см. spill.go
+	spills      chan spillTask
+	stopSpiller context.CancelFunc
+	spillerDone chan struct{}
+
+	// Перепроверка токенов открытых сессий, см. sessions.go
+	stopChecker context.CancelFunc
 }
 
-func NewWebsockethandlers(chatUsecase ChatUsecase) *WebsocketHandlers {
+func NewWebsockethandlers(chatUsecase ChatUsecase, verifier SessionVerifier, cfg Config) *WebsocketHandlers {
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool {
 			return true
@@ -101,13 +193,28 @@ func NewWebsockethandlers(chatUsecase ChatUsecase) *WebsocketHandlers {
 	}
 
 	factoryMsg := NewMessageFactory()
+	spillerCtx, stopSpiller := context.WithCancel(context.Background())
+	checkerCtx, stopChecker := context.WithCancel(context.Background())
 
-	return &WebsocketHandlers{
+	h := &WebsocketHandlers{
 		chatUsecase: chatUsecase,
+		verifier:    verifier,
 		factory:     factoryMsg,
+		cfg:         cfg,
+		metrics:     &Metrics{},
 		upgrader:    upgrader,
 		conns:       make(map[uuid.UUID][]*Client),
+		spills:      make(chan spillTask, cfg.SpillQueueSize),
+		stopSpiller: stopSpiller,
+		spillerDone: make(chan struct{}),
+		stopChecker: stopChecker,
 	}
+	go h.runSpiller(spillerCtx)
+	if verifier != nil && cfg.SessionRecheckInterval > 0 {
+		go h.runSessionChecker(checkerCtx, cfg.SessionRecheckInterval)
+	}
+
+	return h
 }
 
 func (h *WebsocketHandlers) HandleConnection(c *gin.Context) {
@@ -121,14 +228,30 @@ func (h *WebsocketHandlers) HandleConnection(c *gin.Context) {
 		return
 	}
 
-	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	protocol, explicit, err := negotiateProtocol(c.Request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     err.Error(),
+			"supported": supportedProtocols,
+		})
+		return
+	}
+
+	var respHeader http.Header
+	if explicit {
+		respHeader = http.Header{"Sec-Websocket-Protocol": {protocol}}
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, respHeader)
 	if err != nil {
 		log.Println(err.Error())
 		c.JSON(400, gin.H{"error": fmt.Errorf("failed to upgrade: %w", err)})
 		return
 	}
 
-	client := NewClient(userId, conn)
+	client := NewClient(userId, c.GetString("user_role"), conn, protocol, h.cfg.SendBufferSize)
+	client.ExpiresAt = c.GetTime("token_expires_at")
+	client.token = c.GetString("access_token")
 
 	h.addClient(client)
 
@@ -145,26 +268,107 @@ func (h *WebsocketHandlers) HandleConnection(c *gin.Context) {
 	go h.readPump(client)
 	go h.writePump(client)
 
-	h.downloadMessages(client)
+	h.downloadMessages(client, client.UserID)
 
 	c.Status(http.StatusOK)
 
 	log.Println("соединение установлено!")
 }
 
-func (h *WebsocketHandlers) downloadMessages(client *Client) {
-	messages := h.chatUsecase.GetMessagesFromStorage(context.Background(), client.UserID)
+// downloadMessages доставляет клиенту офлайн-очередь: пользователя - при подключении,
+// сессии - после переполнения
+func (h *WebsocketHandlers) downloadMessages(client *Client, queue uuid.UUID) {
+	messages := h.chatUsecase.GetMessagesFromStorage(context.Background(), queue)
 	if len(messages) == 0 {
 		return
 	}
 
 	for _, msg := range messages {
-		select {
-		case client.Send <- msg:
-			// отправлено
-		default:
-			go h.removeClient(client.UserID, client.SessionID)
-		}
+		h.deliver(context.Background(), client, msg, true)
+	}
+}
+
+// GetSchema отдаёт машиночитаемую схему подпротокола (?protocol=cm.v1.json)
+func (h *WebsocketHandlers) GetSchema(c *gin.Context) {
+	protocol := c.DefaultQuery("protocol", defaultProtocol)
+
+	spec, ok := protocols[protocol]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":     ErrUnsupportedProtocol.Error(),
+			"supported": supportedProtocols,
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/schema+json", spec.schema.Raw())
+}
+
+func (h *WebsocketHandlers) GetStats(c *gin.Context) {
+	h.mu.Lock()
+	sessions := 0
+	for _, clients := range h.conns {
+		sessions += len(clients)
+	}
+	h.mu.Unlock()
+
+	c.JSON(http.StatusOK, h.metrics.Snapshot(sessions))
+}
+
+// deliver отправляет событие клиенту. Если клиент не успевает разбирать буфер,
+// durable-события уходят в офлайн-очередь этой сессии (запись идёт в фоне и не тормозит
+// рассылку остальным), а после освобождения буфера клиент получает resync_required.
+// Клиент, не восстановившийся за SlowConsumerGrace, отключается
+func (h *WebsocketHandlers) deliver(ctx context.Context, client *Client, data []byte, durable bool) {
+	if client.getIsClosed() {
+		return
+	}
+
+	// Пока клиент в переполнении, новые события не обгоняют уже отложенные
+	if !client.isOverflowing() && client.trySend(data) {
+		return
+	}
+
+	client.markOverflow()
+	h.metrics.droppedEvents.Add(1)
+
+	if durable {
+		client.spilled.Store(true)
+		h.spillEvent(client.SessionID, data)
+	}
+
+	h.checkSlowConsumer(client)
+}
+
+func (h *WebsocketHandlers) checkSlowConsumer(client *Client) {
+	if client.overflowFor() > h.cfg.SlowConsumerGrace {
+		h.metrics.evictions.Add(1)
+		go h.removeClientWithCode(client.UserID, client.SessionID, CloseSlowConsumer, "slow consumer")
+	}
+}
+
+// resync вызывается из очереди записи, когда переполненный клиент разобрал буфер, а его
+// отложенные события сохранены: сообщает о пропуске и дозагружает их из офлайн-очереди
+func (h *WebsocketHandlers) resync(client *Client) {
+	missed := client.missed.Swap(0)
+	client.overflowSince.Store(0)
+
+	event := h.factory.NewResyncRequired("slow_consumer", missed)
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal event: %v", err)
+		return
+	}
+
+	if !client.trySend(data) {
+		client.markOverflow()
+		return
+	}
+	h.metrics.resyncsRequested.Add(1)
+
+	// Дозагрузка - после событий, отложенных до сброса переполнения
+	if !h.enqueueSpill(spillTask{kind: spillDownload, client: client}) {
+		go h.downloadMessages(client, client.SessionID)
 	}
 }
 
@@ -176,6 +380,10 @@ func (h *WebsocketHandlers) addClient(client *Client) {
 }
 
 func (h *WebsocketHandlers) removeClient(userId, sessionId uuid.UUID) {
+	h.removeClientWithCode(userId, sessionId, websocket.CloseNormalClosure, "")
+}
+
+func (h *WebsocketHandlers) removeClientWithCode(userId, sessionId uuid.UUID, code int, reason string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -186,19 +394,44 @@ func (h *WebsocketHandlers) removeClient(userId, sessionId uuid.UUID) {
 
 	for i, client := range clients {
 		if client.SessionID == sessionId {
-			client.close()
+			client.closeWithCode(code, reason)
 			h.conns[userId] = append(clients[:i], clients[i+1:]...)
 
 			if len(h.conns[userId]) == 0 {
 				delete(h.conns, userId)
 			}
+			h.releaseSpill(client)
 			return
 		}
 	}
 }
 
+// DisconnectUser закрывает все websocket-сессии пользователя и возвращает их число
+func (h *WebsocketHandlers) DisconnectUser(userId uuid.UUID, code int, reason string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	clients := h.conns[userId]
+	delete(h.conns, userId)
+	for _, client := range clients {
+		client.closeWithCode(code, reason)
+		h.releaseSpill(client)
+	}
+
+	return len(clients)
+}
+
 func (h *WebsocketHandlers) handlePingPong(client *Client) {
 	ticker := time.NewTicker(25 * time.Second)
+
+	// Сессия живёт не дольше токена, с которым была открыта
+	var expired <-chan time.Time
+	if !client.ExpiresAt.IsZero() {
+		expiryTimer := time.NewTimer(time.Until(client.ExpiresAt))
+		defer expiryTimer.Stop()
+		expired = expiryTimer.C
+	}
+
 	defer func() {
 		ticker.Stop()
 		h.removeClient(client.UserID, client.SessionID)
@@ -206,11 +439,18 @@ func (h *WebsocketHandlers) handlePingPong(client *Client) {
 
 	for {
 		select {
+		case <-expired:
+			h.removeClientWithCode(client.UserID, client.SessionID, CloseTokenExpired, "token expired")
+			return
 		case <-ticker.C:
 			if client.getIsClosed() {
 				return
 			}
 
+			if client.isOverflowing() {
+				h.checkSlowConsumer(client)
+			}
+
 			err := client.Conn.WriteControl(websocket.PingMessage, []byte(time.Now().String()), time.Now().Add(10*time.Second))
 			if err != nil {
 				return
@@ -239,19 +479,19 @@ func (h *WebsocketHandlers) writePump(client *Client) {
 				return
 			}
 
-			w, err := client.Conn.NextWriter(websocket.TextMessage)
+			frame, err := client.codec.Encode(message)
 			if err != nil {
-				return
-			}
-			w.Write(message)
-			n := len(client.Send)
-			for i := 0; i < n; i++ {
-				w.Write(<-client.Send)
+				log.Printf("Failed to encode frame for %s: %v", client.Protocol, err)
+				continue
 			}
 
-			if err := w.Close(); err != nil {
+			if err := client.Conn.WriteMessage(client.codec.FrameType(), frame); err != nil {
 				return
 			}
+
+			if client.isOverflowing() && len(client.Send) == 0 {
+				h.requestResync(client)
+			}
 		case <-ticker.C:
 			client.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := client.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
@@ -285,9 +525,17 @@ func (h *WebsocketHandlers) readPump(client *Client) {
 				return
 			}
 
-			if messageType == websocket.TextMessage {
-				h.handleIncomingMessage(client, message)
+			if messageType != websocket.TextMessage && messageType != websocket.BinaryMessage {
+				continue
+			}
+
+			frame, err := decodeFrame(client.codec, messageType, message)
+			if err != nil {
+				h.sendErrorDetails(client, "", dto.ErrInvalidMsgFormat, "Неверный формат сообщения", err.Error())
+				continue
 			}
+
+			h.handleIncomingMessage(client, frame)
 		}
 	}
 }
@@ -296,82 +544,131 @@ func (h *WebsocketHandlers) handleIncomingMessage(client *Client, message []byte
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Сначала определяем только тип и request_id: остальные поля проверяются схемой этого типа
+	var envelope struct {
+		Type      dto.RequestType `json:"type"`
+		RequestID string          `json:"request_id"`
+	}
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		h.sendError(client, "", dto.ErrInvalidMsgFormat, "Неверный формат сообщения")
+		return
+	}
+
+	if !client.schema.HasRequest(string(envelope.Type)) {
+		h.sendError(client, envelope.RequestID, dto.ErrInvalidMsgType, fmt.Sprintf("Неподдерживаемый тип сообщения: %s", envelope.Type))
+		return
+	}
+
+	if err := client.schema.ValidateRequest(string(envelope.Type), message); err != nil {
+		h.sendErrorDetails(client, envelope.RequestID, dto.ErrInvalidPayload, "Запрос не соответствует схеме протокола", err.Error())
+		return
+	}
+
 	var incoming dto.IncomingMessage
 	if err := json.Unmarshal(message, &incoming); err != nil {
-		h.sendError(client, dto.ErrInvalidMsgFormat, "Неверный формат сообщения")
+		h.sendError(client, envelope.RequestID, dto.ErrInvalidMsgFormat, "Неверный формат сообщения")
 		return
 	}
 
-	switch incoming.Type {
-	case dto.TypeSendMessage:
-		h.handleSendMessage(ctx, client, incoming.Payload, incoming.ChatID)
-	default:
-		h.sendError(client, dto.ErrInvalidMsgType, fmt.Sprintf("Неподдерживаемый тип сообщения: %s", incoming.Type))
+	op, ok := operations[incoming.Type]
+	if !ok {
+		h.sendError(client, incoming.RequestID, dto.ErrInvalidMsgType, fmt.Sprintf("Неподдерживаемый тип сообщения: %s", incoming.Type))
+		return
+	}
+
+	req := &wsRequest{
+		client:    client,
+		requestID: incoming.RequestID,
+		reqType:   incoming.Type,
+		chatID:    incoming.ChatID,
+		payload:   incoming.Payload,
 	}
+
+	if op.staffOnly && !client.isStaff() {
+		h.sendError(client, req.requestID, dto.ErrAccessDenied, "Операция доступна только сотрудникам поддержки")
+		return
+	}
+
+	op.handle(h, ctx, req)
 }
 
-func (h *WebsocketHandlers) handleSendMessage(ctx context.Context, client *Client, payload json.RawMessage, chatId uuid.UUID) {
-	var req dto.SendMessageIncPayload
-	if err := json.Unmarshal(payload, &req); err != nil {
-		h.sendError(client, dto.ErrInvalidPayload, "Неверный формат запроса")
+func (h *WebsocketHandlers) handleSendMessage(ctx context.Context, req *wsRequest) {
+	client := req.client
+
+	var payload dto.SendMessageIncPayload
+	if !h.decodePayload(req, &payload) {
 		return
 	}
 
-	if chatId == uuid.Nil {
-		h.sendError(client, dto.ErrDataIsEmpty, "ID сообщения не может быть пустым")
+	if req.chatID == uuid.Nil {
+		h.sendError(client, req.requestID, dto.ErrDataIsEmpty, "ID сообщения не может быть пустым")
 		return
 	}
-	if req.Content == "" {
-		h.sendError(client, dto.ErrDataIsEmpty, "Содержание сообщения не может быть пустым")
+	if payload.Content == "" {
+		h.sendError(client, req.requestID, dto.ErrDataIsEmpty, "Содержание сообщения не может быть пустым")
 		return
 	}
-	if req.Type == "" {
-		req.Type = "text"
+	if payload.Type == "" {
+		payload.Type = "text"
 	}
 
-	hasAccess, err := h.chatUsecase.IsUserInChat(ctx, client.UserID, chatId)
+	hasAccess, err := h.chatUsecase.IsUserInChat(ctx, client.UserID, req.chatID)
 	if err != nil || !hasAccess {
-		h.sendError(client, dto.ErrAccessDenied, "Нет доступа к чату")
+		h.sendError(client, req.requestID, dto.ErrAccessDenied, "Нет доступа к чату")
 		return
 	}
 
 	msg := &dto.MessageDTO{
 		ID:       uuid.New(),
-		ChatID:   chatId,
+		ChatID:   req.chatID,
 		SenderID: client.UserID,
-		Content:  req.Content,
-		Type:     req.Type,
-		ReplyTo:  req.ReplyTo,
+		Content:  payload.Content,
+		Type:     payload.Type,
+		ReplyTo:  payload.ReplyTo,
 		SentAt:   time.Now(),
 	}
 
 	savedId, err := h.chatUsecase.SendMessageToDb(ctx, msg)
 	if err != nil {
-		h.sendError(client, dto.ErrSaveFailed, "Не удалось сохранить сообщение")
+		h.sendError(client, req.requestID, dto.ErrSaveFailed, "Не удалось сохранить сообщение")
 		return
 	}
 
 	msg.ID = uuid.MustParse(savedId)
 
+	h.sendAck(req, &dto.SendMessageAckResult{
+		MessageID: msg.ID,
+		SentAt:    msg.SentAt,
+	})
+
 	outgoing := h.factory.NewOutgoingMessage(msg)
 
 	h.broadcastToChat(ctx, msg.ChatID, outgoing)
 }
 
-func (h *WebsocketHandlers) sendError(client *Client, code dto.ErrorType, message string) {
-	errorMsg := h.factory.NewError(code, message, "")
+// sendAck подтверждает успешное выполнение запроса. result - данные ответа (может быть nil)
+func (h *WebsocketHandlers) sendAck(req *wsRequest, result any) {
+	ack := h.factory.NewAck(req.reqType, result, req.chatID, req.requestID)
+	h.reply(req.client, ack)
+}
+
+func (h *WebsocketHandlers) sendError(client *Client, requestID string, code dto.ErrorType, message string) {
+	h.sendErrorDetails(client, requestID, code, message, "")
+}
+
+func (h *WebsocketHandlers) sendErrorDetails(client *Client, requestID string, code dto.ErrorType, message, details string) {
+	h.reply(client, h.factory.NewError(code, message, details, requestID))
+}
 
-	data, err := json.Marshal(errorMsg)
+// reply отправляет ответ конкретной сессии
+func (h *WebsocketHandlers) reply(client *Client, event *dto.OutgoingMessage) {
+	data, err := json.Marshal(event)
 	if err != nil {
 		return
 	}
 
-	select {
-	case client.Send <- data:
-		// отправлено
-	default:
-		go h.removeClient(client.UserID, client.SessionID)
-	}
+	// Прямые ответы адресованы конкретной сессии, в офлайн-очередь их не сохраняем
+	h.deliver(context.Background(), client, data, false)
 }
 
 func (h *WebsocketHandlers) broadcastToChat(ctx context.Context, chatID uuid.UUID, event *dto.OutgoingMessage) {
@@ -393,7 +690,7 @@ func (h *WebsocketHandlers) broadcastToChat(ctx context.Context, chatID uuid.UUI
 	for _, member := range members.Members {
 		clients, exists := h.conns[member.UserID]
 		if !exists {
-			h.chatUsecase.SendMsgToStorage(ctx, member.UserID, data)
+			h.spillEvent(member.UserID, data)
 			continue
 		}
 
@@ -402,12 +699,7 @@ func (h *WebsocketHandlers) broadcastToChat(ctx context.Context, chatID uuid.UUI
 			case <-ctx.Done():
 				// таймаут
 			default:
-				select {
-				case client.Send <- data:
-					// отправлено
-				default:
-					go h.removeClient(client.UserID, client.SessionID)
-				}
+				h.deliver(ctx, client, data, true)
 			}
 		}
 	}
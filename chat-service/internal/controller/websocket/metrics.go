@@ -0,0 +1,27 @@
+package websocket
+
+import (
+	"sync/atomic"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/dto"
+)
+
+// Metrics - счётчики доставки событий по websocket
+type Metrics struct {
+	droppedEvents    atomic.Int64 // события, не поместившиеся в буфер клиента
+	spilledEvents    atomic.Int64 // события, сохранённые в офлайн-очередь вместо буфера
+	lostEvents       atomic.Int64 // события, которые не удалось сохранить в офлайн-очередь
+	evictions        atomic.Int64 // сессии, закрытые как медленные потребители
+	resyncsRequested atomic.Int64 // отправленные события resync_required
+}
+
+func (m *Metrics) Snapshot(activeSessions int) *dto.WsStats {
+	return &dto.WsStats{
+		ActiveSessions:   activeSessions,
+		DroppedEvents:    m.droppedEvents.Load(),
+		SpilledEvents:    m.spilledEvents.Load(),
+		LostEvents:       m.lostEvents.Load(),
+		Evictions:        m.evictions.Load(),
+		ResyncsRequested: m.resyncsRequested.Load(),
+	}
+}
@@ -0,0 +1,193 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	stdErrors "errors"
+	"log"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/dto"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/errors"
+	"github.com/google/uuid"
+)
+
+// Роли, которым доступны операции управления чатами (как RequireStaff в http/v1)
+var staffRoles = []string{"admin", "moderator", "support"}
+
+// wsRequest - разобранный запрос клиента
+type wsRequest struct {
+	client    *Client
+	requestID string
+	reqType   dto.RequestType
+	chatID    uuid.UUID
+	payload   json.RawMessage
+}
+
+type operation struct {
+	handle    func(h *WebsocketHandlers, ctx context.Context, req *wsRequest)
+	staffOnly bool
+}
+
+// operations - запросы, которые можно выполнять через websocket. Набор и права
+// совпадают с HTTP API /chats
+var operations = map[dto.RequestType]operation{
+	dto.TypeSendMessage:      {handle: (*WebsocketHandlers).handleSendMessage},
+	dto.TypeCreateChat:       {handle: (*WebsocketHandlers).handleCreateChat},
+	dto.TypeGetChats:         {handle: (*WebsocketHandlers).handleGetChats},
+	dto.TypeGetMessages:      {handle: (*WebsocketHandlers).handleGetMessages},
+	dto.TypeGetMembers:       {handle: (*WebsocketHandlers).handleGetMembers},
+	dto.TypeAddMembers:       {handle: (*WebsocketHandlers).handleAddMembers, staffOnly: true},
+	dto.TypeRemoveMember:     {handle: (*WebsocketHandlers).handleRemoveMember, staffOnly: true},
+	dto.TypeChangeMemberRole: {handle: (*WebsocketHandlers).handleChangeMemberRole, staffOnly: true},
+	dto.TypeRemoveChat:       {handle: (*WebsocketHandlers).handleRemoveChat, staffOnly: true},
+}
+
+func (h *WebsocketHandlers) handleCreateChat(ctx context.Context, req *wsRequest) {
+	var payload dto.CreateChatRequest
+	if !h.decodePayload(req, &payload) {
+		return
+	}
+
+	resp, err := h.chatUsecase.CreateChat(ctx, &payload, req.client.UserID)
+	if err != nil {
+		h.sendUsecaseError(req, err)
+		return
+	}
+
+	h.sendAck(req, resp)
+}
+
+func (h *WebsocketHandlers) handleGetChats(ctx context.Context, req *wsRequest) {
+	resp, err := h.chatUsecase.GetUserChats(ctx, req.client.UserID)
+	if err != nil {
+		h.sendUsecaseError(req, err)
+		return
+	}
+
+	h.sendAck(req, resp)
+}
+
+func (h *WebsocketHandlers) handleGetMessages(ctx context.Context, req *wsRequest) {
+	var payload dto.GetMessagesIncPayload
+	if !h.decodePayload(req, &payload) {
+		return
+	}
+
+	if payload.Limit == 0 {
+		payload.Limit = 50
+	}
+
+	resp, err := h.chatUsecase.GetChatMessages(ctx, req.chatID, req.client.UserID, payload.Limit, payload.Before)
+	if err != nil {
+		h.sendUsecaseError(req, err)
+		return
+	}
+
+	h.sendAck(req, resp)
+}
+
+func (h *WebsocketHandlers) handleGetMembers(ctx context.Context, req *wsRequest) {
+	if !h.requireMembership(ctx, req) {
+		return
+	}
+
+	resp, err := h.chatUsecase.GetChatMembers(ctx, req.chatID)
+	if err != nil {
+		h.sendUsecaseError(req, err)
+		return
+	}
+
+	h.sendAck(req, resp)
+}
+
+func (h *WebsocketHandlers) handleAddMembers(ctx context.Context, req *wsRequest) {
+	var payload dto.AddMembersIncPayload
+	if !h.decodePayload(req, &payload) {
+		return
+	}
+
+	if err := h.chatUsecase.AddMembers(ctx, req.chatID, payload.UserIDs, req.client.UserID); err != nil {
+		h.sendUsecaseError(req, err)
+		return
+	}
+
+	h.sendAck(req, nil)
+}
+
+func (h *WebsocketHandlers) handleRemoveMember(ctx context.Context, req *wsRequest) {
+	var payload dto.RemoveMemberIncPayload
+	if !h.decodePayload(req, &payload) {
+		return
+	}
+
+	if err := h.chatUsecase.RemoveMember(ctx, req.chatID, payload.UserID, req.client.UserID); err != nil {
+		h.sendUsecaseError(req, err)
+		return
+	}
+
+	h.sendAck(req, nil)
+}
+
+func (h *WebsocketHandlers) handleChangeMemberRole(ctx context.Context, req *wsRequest) {
+	var payload dto.ChangeMemberRoleIncPayload
+	if !h.decodePayload(req, &payload) {
+		return
+	}
+
+	if err := h.chatUsecase.ChangeMemberRole(ctx, req.chatID, payload.UserID, payload.Role, req.client.UserID); err != nil {
+		h.sendUsecaseError(req, err)
+		return
+	}
+
+	h.sendAck(req, nil)
+}
+
+func (h *WebsocketHandlers) handleRemoveChat(ctx context.Context, req *wsRequest) {
+	if err := h.chatUsecase.RemoveChat(ctx, req.chatID); err != nil {
+		h.sendUsecaseError(req, err)
+		return
+	}
+
+	h.sendAck(req, nil)
+}
+
+// decodePayload разбирает payload запроса. Пустой payload допустим для запросов без параметров
+func (h *WebsocketHandlers) decodePayload(req *wsRequest, dst any) bool {
+	if len(req.payload) == 0 || string(req.payload) == "null" {
+		return true
+	}
+
+	if err := json.Unmarshal(req.payload, dst); err != nil {
+		h.sendErrorDetails(req.client, req.requestID, dto.ErrInvalidPayload, "Неверный формат запроса", err.Error())
+		return false
+	}
+
+	return true
+}
+
+func (h *WebsocketHandlers) requireMembership(ctx context.Context, req *wsRequest) bool {
+	ok, err := h.chatUsecase.IsUserInChat(ctx, req.client.UserID, req.chatID)
+	if err != nil {
+		h.sendError(req.client, req.requestID, dto.ErrInternalError, "Не удалось проверить доступ к чату")
+		return false
+	}
+	if !ok {
+		h.sendError(req.client, req.requestID, dto.ErrAccessDenied, "Нет доступа к чату")
+		return false
+	}
+
+	return true
+}
+
+func (h *WebsocketHandlers) sendUsecaseError(req *wsRequest, err error) {
+	switch {
+	case stdErrors.Is(err, errors.ErrChatNotFound):
+		h.sendError(req.client, req.requestID, dto.ErrNotFound, "Чат не найден")
+	case stdErrors.Is(err, errors.ErrUserNotInChat):
+		h.sendError(req.client, req.requestID, dto.ErrAccessDenied, "Нет доступа к чату")
+	default:
+		// Текст внутренних ошибок (SQL, драйвер, идентификаторы) клиенту не отдаём
+		log.Printf("Websocket %s failed for %s: %v", req.reqType, req.client.UserID, err)
+		h.sendError(req.client, req.requestID, dto.ErrInternalError, "Не удалось выполнить запрос")
+	}
+}
@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// Поддерживаемые подпротоколы Sec-WebSocket-Protocol: cm.<версия>.<кодировка>
+const (
+	ProtocolV1JSON    = "cm.v1.json"
+	ProtocolV1MsgPack = "cm.v1.msgpack"
+
+	// Протокол по умолчанию для клиентов, не указавших Sec-WebSocket-Protocol
+	defaultProtocol = ProtocolV1JSON
+)
+
+var supportedProtocols = []string{ProtocolV1JSON, ProtocolV1MsgPack}
+
+// protocolSpec - схема и кодировка подпротокола. Все кодировки одной версии
+// описываются одной схемой: валидируется канонический JSON
+type protocolSpec struct {
+	schema *Schema
+	codec  Codec
+}
+
+var (
+	schemaV1Parsed = mustSchema(schemaV1)
+	binaryCodec    = newMsgpackCodec()
+)
+
+var protocols = map[string]protocolSpec{
+	ProtocolV1JSON:    {schema: schemaV1Parsed, codec: jsonCodec{}},
+	ProtocolV1MsgPack: {schema: schemaV1Parsed, codec: binaryCodec},
+}
+
+var ErrUnsupportedProtocol = errors.New("unsupported websocket subprotocol")
+
+// negotiateProtocol выбирает первый поддерживаемый подпротокол из предложенных клиентом.
+// explicit=false означает, что клиент подпротоколов не предлагал и заголовок в ответе не нужен
+func negotiateProtocol(r *http.Request) (protocol string, explicit bool, err error) {
+	requested := websocket.Subprotocols(r)
+	if len(requested) == 0 {
+		return defaultProtocol, false, nil
+	}
+
+	for _, candidate := range requested {
+		if _, ok := protocols[candidate]; ok {
+			return candidate, true, nil
+		}
+	}
+
+	return "", false, ErrUnsupportedProtocol
+}
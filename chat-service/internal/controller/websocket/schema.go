@@ -0,0 +1,334 @@
+package websocket
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+//go:embed schema/cm.v1.json
+var schemaV1 []byte
+
+// Schema - JSON Schema протокола. Валидатор поддерживает подмножество draft 2020-12,
+// которое используется в schema/*.json: $ref, type, const, enum, required, properties,
+// additionalProperties, items, minLength/maxLength, minItems/maxItems, minimum/maximum, format (uuid, date-time)
+type Schema struct {
+	raw  []byte
+	root map[string]any
+}
+
+// SchemaError - описание первого найденного несоответствия схеме
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+func NewSchema(raw []byte) (*Schema, error) {
+	var root map[string]any
+	if err := json.Unmarshal(raw, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+
+	return &Schema{
+		raw:  raw,
+		root: root,
+	}, nil
+}
+
+func mustSchema(raw []byte) *Schema {
+	s, err := NewSchema(raw)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func (s *Schema) Raw() []byte {
+	return s.raw
+}
+
+// HasRequest сообщает, описан ли тип входящего запроса в схеме
+func (s *Schema) HasRequest(requestType string) bool {
+	_, ok := s.definition("x-requests", requestType)
+	return ok
+}
+
+// ValidateRequest проверяет входящий кадр по схеме запроса его типа
+func (s *Schema) ValidateRequest(requestType string, frame []byte) error {
+	def, ok := s.definition("x-requests", requestType)
+	if !ok {
+		return &SchemaError{Path: "type", Message: fmt.Sprintf("unknown request type %q", requestType)}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(frame))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return &SchemaError{Message: "frame is not valid JSON"}
+	}
+
+	return s.validate(def, value, "")
+}
+
+func (s *Schema) definition(section, name string) (map[string]any, bool) {
+	defs, ok := s.root[section].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+
+	def, ok := defs[name].(map[string]any)
+	return def, ok
+}
+
+func (s *Schema) resolve(ref string) (map[string]any, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q", ref)
+	}
+
+	var node any = s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+
+		obj, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		node = obj[part]
+	}
+
+	def, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unresolvable $ref %q", ref)
+	}
+
+	return def, nil
+}
+
+func (s *Schema) validate(def map[string]any, value any, path string) error {
+	if ref, ok := def["$ref"].(string); ok {
+		target, err := s.resolve(ref)
+		if err != nil {
+			return &SchemaError{Path: path, Message: err.Error()}
+		}
+		return s.validate(target, value, path)
+	}
+
+	if expected, ok := def["const"]; ok && !jsonEqual(expected, value) {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be %v", expected)}
+	}
+
+	if enum, ok := def["enum"].([]any); ok {
+		matched := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must be one of %v", enum)}
+		}
+	}
+
+	if t, ok := def["type"]; ok {
+		if err := checkType(t, value, path); err != nil {
+			return err
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		return validateString(def, v, path)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return nil
+		}
+		if minimum, ok := def["minimum"].(float64); ok && f < minimum {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must be >= %v", minimum)}
+		}
+		if maximum, ok := def["maximum"].(float64); ok && f > maximum {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must be <= %v", maximum)}
+		}
+	case []any:
+		return s.validateArray(def, v, path)
+	case map[string]any:
+		return s.validateObject(def, v, path)
+	}
+
+	return nil
+}
+
+func validateString(def map[string]any, v, path string) error {
+	length := utf8.RuneCountInString(v)
+	if minLength, ok := def["minLength"].(float64); ok && float64(length) < minLength {
+		if minLength == 1 {
+			return &SchemaError{Path: path, Message: "must not be empty"}
+		}
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be at least %v characters", minLength)}
+	}
+	if maxLength, ok := def["maxLength"].(float64); ok && float64(length) > maxLength {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must be at most %v characters", maxLength)}
+	}
+
+	switch def["format"] {
+	case "uuid":
+		if _, err := uuid.Parse(v); err != nil {
+			return &SchemaError{Path: path, Message: "must be a UUID"}
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return &SchemaError{Path: path, Message: "must be an RFC 3339 date-time"}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateArray(def map[string]any, v []any, path string) error {
+	if minItems, ok := def["minItems"].(float64); ok && float64(len(v)) < minItems {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must contain at least %v items", minItems)}
+	}
+	if maxItems, ok := def["maxItems"].(float64); ok && float64(len(v)) > maxItems {
+		return &SchemaError{Path: path, Message: fmt.Sprintf("must contain at most %v items", maxItems)}
+	}
+
+	items, ok := def["items"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	for i, item := range v {
+		if err := s.validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) validateObject(def map[string]any, v map[string]any, path string) error {
+	if required, ok := def["required"].([]any); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, exists := v[key]; !exists {
+				return &SchemaError{Path: joinPath(path, key), Message: "is required"}
+			}
+		}
+	}
+
+	properties, _ := def["properties"].(map[string]any)
+	additional, restricted := def["additionalProperties"].(bool)
+
+	// Обходим поля в стабильном порядке, чтобы клиент получал одну и ту же ошибку
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := v[key]
+		prop, known := properties[key].(map[string]any)
+		if !known {
+			if restricted && !additional {
+				return &SchemaError{Path: joinPath(path, key), Message: "unknown field"}
+			}
+			continue
+		}
+
+		if err := s.validate(prop, field, joinPath(path, key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkType(t any, value any, path string) error {
+	var allowed []string
+	switch tv := t.(type) {
+	case string:
+		allowed = []string{tv}
+	case []any:
+		for _, item := range tv {
+			if name, ok := item.(string); ok {
+				allowed = append(allowed, name)
+			}
+		}
+	}
+
+	actual := jsonType(value)
+	for _, name := range allowed {
+		if name == actual || (name == "number" && actual == "integer") {
+			return nil
+		}
+	}
+
+	return &SchemaError{Path: path, Message: fmt.Sprintf("must be %s, got %s", strings.Join(allowed, " or "), actual)}
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return "unknown"
+	}
+}
+
+// jsonEqual сравнивает значение из схемы (числа - float64) со значением кадра (числа - json.Number)
+func jsonEqual(expected, actual any) bool {
+	if n, ok := actual.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return false
+		}
+		actual = f
+	}
+
+	return reflect.DeepEqual(expected, actual)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
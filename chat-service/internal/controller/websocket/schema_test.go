@@ -0,0 +1,147 @@
+package websocket
+
+import (
+	"errors"
+	"testing"
+)
+
+const testSchema = `{
+  "x-requests": {
+    "probe": {"$ref": "#/$defs/Probe"}
+  },
+  "$defs": {
+    "UUID": {"type": "string", "format": "uuid"},
+    "Probe": {
+      "type": "object",
+      "required": ["type", "id"],
+      "additionalProperties": false,
+      "properties": {
+        "type": {"const": "probe"},
+        "id": {"$ref": "#/$defs/UUID"},
+        "name": {"type": "string", "minLength": 1, "maxLength": 5},
+        "kind": {"enum": ["a", "b"]},
+        "count": {"type": "integer", "minimum": 1, "maximum": 10},
+        "ratio": {"type": "number"},
+        "flag": {"type": "boolean"},
+        "note": {"type": ["string", "null"]},
+        "at": {"type": "string", "format": "date-time"},
+        "tags": {"type": "array", "minItems": 1, "maxItems": 2, "items": {"type": "string"}},
+        "nested": {
+          "type": "object",
+          "required": ["inner"],
+          "additionalProperties": false,
+          "properties": {
+            "inner": {"type": "object", "properties": {"deep": {"$ref": "#/$defs/UUID"}}}
+          }
+        },
+        "open": {"type": "object", "properties": {"known": {"type": "string"}}}
+      }
+    }
+  }
+}`
+
+const testID = `"6f1c1d7e-3b0a-4a8e-9a51-5b7f3f1d2c10"`
+
+func TestSchemaValidateRequest(t *testing.T) {
+	schema := mustSchema([]byte(testSchema))
+
+	tests := []struct {
+		name    string
+		frame   string
+		wantErr string
+	}{
+		{name: "minimal", frame: `{"type":"probe","id":` + testID + `}`},
+		{
+			name: "all fields",
+			frame: `{"type":"probe","id":` + testID + `,"name":"abc","kind":"b","count":3,"ratio":0.5,"flag":true,` +
+				`"note":null,"at":"2024-05-01T10:00:00Z","tags":["x"],"nested":{"inner":{"deep":` + testID + `}},"open":{"known":"k","extra":1}}`,
+		},
+		{name: "missing required", frame: `{"type":"probe"}`, wantErr: "id: is required"},
+		{name: "wrong const", frame: `{"type":"other","id":` + testID + `}`, wantErr: "type: must be probe"},
+		{name: "bad uuid via ref", frame: `{"type":"probe","id":"nope"}`, wantErr: "id: must be a UUID"},
+		{name: "wrong type string", frame: `{"type":"probe","id":` + testID + `,"name":5}`, wantErr: "name: must be string, got integer"},
+		{name: "wrong type boolean", frame: `{"type":"probe","id":` + testID + `,"flag":"yes"}`, wantErr: "flag: must be boolean, got string"},
+		{name: "integer rejects fraction", frame: `{"type":"probe","id":` + testID + `,"count":1.5}`, wantErr: "count: must be integer, got number"},
+		{name: "number accepts integer", frame: `{"type":"probe","id":` + testID + `,"ratio":2}`},
+		{name: "union type", frame: `{"type":"probe","id":` + testID + `,"note":1}`, wantErr: "note: must be string or null, got integer"},
+		{name: "below minimum", frame: `{"type":"probe","id":` + testID + `,"count":0}`, wantErr: "count: must be >= 1"},
+		{name: "above maximum", frame: `{"type":"probe","id":` + testID + `,"count":11}`, wantErr: "count: must be <= 10"},
+		{name: "empty string", frame: `{"type":"probe","id":` + testID + `,"name":""}`, wantErr: "name: must not be empty"},
+		{name: "max length counts runes", frame: `{"type":"probe","id":` + testID + `,"name":"привет"}`, wantErr: "name: must be at most 5 characters"},
+		{name: "enum", frame: `{"type":"probe","id":` + testID + `,"kind":"c"}`, wantErr: "kind: must be one of [a b]"},
+		{name: "date-time", frame: `{"type":"probe","id":` + testID + `,"at":"yesterday"}`, wantErr: "at: must be an RFC 3339 date-time"},
+		{name: "min items", frame: `{"type":"probe","id":` + testID + `,"tags":[]}`, wantErr: "tags: must contain at least 1 items"},
+		{name: "max items", frame: `{"type":"probe","id":` + testID + `,"tags":["a","b","c"]}`, wantErr: "tags: must contain at most 2 items"},
+		{name: "item type", frame: `{"type":"probe","id":` + testID + `,"tags":["a",1]}`, wantErr: "tags[1]: must be string, got integer"},
+		{name: "additional property", frame: `{"type":"probe","id":` + testID + `,"extra":1}`, wantErr: "extra: unknown field"},
+		{name: "nested required", frame: `{"type":"probe","id":` + testID + `,"nested":{}}`, wantErr: "nested.inner: is required"},
+		{name: "nested additional", frame: `{"type":"probe","id":` + testID + `,"nested":{"inner":{},"x":1}}`, wantErr: "nested.x: unknown field"},
+		{name: "deep nested ref", frame: `{"type":"probe","id":` + testID + `,"nested":{"inner":{"deep":"nope"}}}`, wantErr: "nested.inner.deep: must be a UUID"},
+		{name: "not an object", frame: `[]`, wantErr: "must be object, got array"},
+		{name: "invalid json", frame: `{`, wantErr: "frame is not valid JSON"},
+		{name: "first error is stable", frame: `{"type":"probe","id":` + testID + `,"zzz":1,"aaa":1}`, wantErr: "aaa: unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.ValidateRequest("probe", []byte(tt.frame))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error %q, got nil", tt.wantErr)
+			}
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("expected *SchemaError, got %T", err)
+			}
+			if err.Error() != tt.wantErr {
+				t.Fatalf("expected error %q, got %q", tt.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestSchemaUnknownRequest(t *testing.T) {
+	schema := mustSchema([]byte(testSchema))
+
+	if schema.HasRequest("missing") {
+		t.Fatal("HasRequest reported an undeclared type")
+	}
+	if err := schema.ValidateRequest("missing", []byte(`{}`)); err == nil {
+		t.Fatal("expected error for undeclared request type")
+	}
+}
+
+func TestSchemaUnresolvableRef(t *testing.T) {
+	schema := mustSchema([]byte(`{"x-requests":{"probe":{"$ref":"#/$defs/Missing"}}}`))
+
+	if err := schema.ValidateRequest("probe", []byte(`{}`)); err == nil {
+		t.Fatal("expected error for unresolvable $ref")
+	}
+}
+
+func TestSchemaV1SendMessage(t *testing.T) {
+	schema := protocols[ProtocolV1JSON].schema
+
+	for _, requestType := range []string{"send_message", "create_chat", "get_chats", "get_messages", "get_members",
+		"add_members", "remove_member", "change_member_role", "remove_chat"} {
+		if !schema.HasRequest(requestType) {
+			t.Errorf("cm.v1 does not describe %s", requestType)
+		}
+	}
+
+	valid := `{"type":"send_message","request_id":"r1","chat_id":` + testID + `,"payload":{"content":"hi"}}`
+	if err := schema.ValidateRequest("send_message", []byte(valid)); err != nil {
+		t.Fatalf("valid send_message rejected: %v", err)
+	}
+
+	invalid := `{"type":"send_message","chat_id":` + testID + `,"payload":{"content":""}}`
+	if err := schema.ValidateRequest("send_message", []byte(invalid)); err == nil {
+		t.Fatal("send_message with empty content accepted")
+	}
+}
@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"context"
+	stdErrors "errors"
+	"log"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/auth"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/errors"
+)
+
+const sessionRecheckTimeout = 5 * time.Second
+
+// SessionVerifier проверяет токен открытой сессии (auth.Verifier)
+type SessionVerifier interface {
+	Verify(ctx context.Context, token string) (*auth.Claims, error)
+}
+
+// runSessionChecker периодически перепроверяет токены открытых сессий. Handshake
+// проверяет токен один раз, а уведомление identity-service о деактивации может не дойти
+func (h *WebsocketHandlers) runSessionChecker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.recheckSessions(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *WebsocketHandlers) recheckSessions(ctx context.Context) {
+	h.mu.Lock()
+	var clients []*Client
+	for _, userClients := range h.conns {
+		clients = append(clients, userClients...)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		if client.token == "" || client.getIsClosed() {
+			continue
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, sessionRecheckTimeout)
+		_, err := h.verifier.Verify(checkCtx, client.token)
+		cancel()
+
+		switch {
+		case err == nil, stdErrors.Is(err, errors.ErrTokenExpired):
+			// Истёкший токен закрывает сессию по таймеру в handlePingPong
+		case stdErrors.Is(err, errors.ErrAccountInactive), stdErrors.Is(err, errors.ErrInvalidToken):
+			h.removeClientWithCode(client.UserID, client.SessionID, CloseSessionRevoked, "session is no longer active")
+		default:
+			// identity-service недоступен - не рвём сессии, проверим в следующий раз
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Failed to recheck websocket session of %s: %v", client.UserID, err)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package websocket
+
+import (
+	"context"
+	stdErrors "errors"
+	"testing"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/auth"
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/errors"
+	"github.com/google/uuid"
+)
+
+type fakeVerifier map[string]error
+
+func (v fakeVerifier) Verify(_ context.Context, token string) (*auth.Claims, error) {
+	return nil, v[token]
+}
+
+func TestRecheckSessionsClosesInactiveSessions(t *testing.T) {
+	verifier := fakeVerifier{
+		"active":      nil,
+		"expired":     errors.ErrTokenExpired,
+		"deactivated": errors.ErrAccountInactive,
+		"invalid":     errors.ErrInvalidToken,
+		"unavailable": stdErrors.New("identity service unavailable"),
+	}
+	h := &WebsocketHandlers{verifier: verifier, conns: make(map[uuid.UUID][]*Client)}
+
+	clients := make(map[string]*Client)
+	for token := range verifier {
+		client := NewClient(uuid.New(), "user", nil, ProtocolV1JSON, 1)
+		client.token = token
+		clients[token] = client
+		h.addClient(client)
+	}
+
+	h.recheckSessions(context.Background())
+
+	for token, client := range clients {
+		wantClosed := token == "deactivated" || token == "invalid"
+		if client.getIsClosed() != wantClosed {
+			t.Errorf("session with %s token: closed = %v, want %v", token, client.getIsClosed(), wantClosed)
+		}
+		if _, registered := h.conns[client.UserID]; registered == wantClosed {
+			t.Errorf("session with %s token: registered = %v, want %v", token, registered, !wantClosed)
+		}
+	}
+}
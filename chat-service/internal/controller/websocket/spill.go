@@ -0,0 +1,159 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const spillWriteTimeout = 5 * time.Second
+
+type spillKind int
+
+const (
+	spillEvent spillKind = iota
+	// Все события клиента до метки сохранены: можно сообщать о пропуске
+	spillResync
+	// События, отложенные до сброса переполнения, сохранены: можно дозагружать
+	spillDownload
+	// Сессия закрыта: её очередь больше некому дозагружать
+	spillRelease
+)
+
+// spillTask - задача фоновой записи в офлайн-очередь. Очередь задач одна и разбирается
+// по порядку, поэтому метки клиента выполняются после всех его событий до них.
+// queue - пользователь без открытых сессий или отстающая сессия: событие, пропущенное
+// сессией, откладывается только для неё, и дозагружает его только она
+type spillTask struct {
+	kind   spillKind
+	queue  uuid.UUID
+	data   []byte
+	client *Client
+}
+
+// enqueueSpill ставит задачу в очередь без блокировки. Возвращает false, если очередь полна
+func (h *WebsocketHandlers) enqueueSpill(task spillTask) bool {
+	select {
+	case h.spills <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// spillEvent откладывает событие в офлайн-очередь, не дожидаясь записи в БД
+func (h *WebsocketHandlers) spillEvent(queue uuid.UUID, data []byte) {
+	if !h.enqueueSpill(spillTask{kind: spillEvent, queue: queue, data: data}) {
+		h.metrics.lostEvents.Add(1)
+		log.Printf("Spill queue is full, event for %s is lost", queue)
+	}
+}
+
+// releaseSpill разбирает очередь закрытой сессии после всех её отложенных событий
+func (h *WebsocketHandlers) releaseSpill(client *Client) {
+	if !client.spilled.Load() {
+		return
+	}
+
+	task := spillTask{kind: spillRelease, client: client}
+	if !h.enqueueSpill(task) {
+		go h.handleSpill(task)
+	}
+}
+
+// releaseSessionQueue переносит события закрытой сессии в офлайн-очередь пользователя,
+// если других сессий у него нет. Иначе события уже получили остальные сессии
+func (h *WebsocketHandlers) releaseSessionQueue(client *Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), spillWriteTimeout)
+	defer cancel()
+
+	messages := h.chatUsecase.GetMessagesFromStorage(ctx, client.SessionID)
+
+	h.mu.Lock()
+	_, online := h.conns[client.UserID]
+	h.mu.Unlock()
+	if online {
+		return
+	}
+
+	for _, msg := range messages {
+		if err := h.chatUsecase.SendMsgToStorage(ctx, client.UserID, msg); err != nil {
+			h.metrics.lostEvents.Add(1)
+			log.Printf("Failed to move session events to offline storage: %v", err)
+		}
+	}
+}
+
+// requestResync запускает resync после того, как отложенные события клиента будут сохранены
+func (h *WebsocketHandlers) requestResync(client *Client) {
+	if !client.resyncQueued.CompareAndSwap(false, true) {
+		return
+	}
+
+	if !h.enqueueSpill(spillTask{kind: spillResync, client: client}) {
+		// Очередь полна: клиент останется в переполнении и повторит запрос со следующим кадром
+		client.resyncQueued.Store(false)
+	}
+}
+
+func (h *WebsocketHandlers) runSpiller(ctx context.Context) {
+	defer close(h.spillerDone)
+
+	for {
+		select {
+		case task := <-h.spills:
+			h.handleSpill(task)
+		case <-ctx.Done():
+			// Дописываем то, что уже принято в очередь
+			for {
+				select {
+				case task := <-h.spills:
+					h.handleSpill(task)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (h *WebsocketHandlers) handleSpill(task spillTask) {
+	switch task.kind {
+	case spillEvent:
+		ctx, cancel := context.WithTimeout(context.Background(), spillWriteTimeout)
+		defer cancel()
+
+		if err := h.chatUsecase.SendMsgToStorage(ctx, task.queue, task.data); err != nil {
+			h.metrics.lostEvents.Add(1)
+			log.Printf("Failed to spill event to offline storage: %v", err)
+			return
+		}
+		h.metrics.spilledEvents.Add(1)
+	case spillResync:
+		task.client.resyncQueued.Store(false)
+		if !task.client.getIsClosed() {
+			h.resync(task.client)
+		}
+	case spillDownload:
+		if !task.client.getIsClosed() {
+			go h.downloadMessages(task.client, task.client.SessionID)
+		}
+	case spillRelease:
+		h.releaseSessionQueue(task.client)
+	}
+}
+
+// Close останавливает фоновые задачи и дожидается записи принятых в офлайн-очередь событий
+func (h *WebsocketHandlers) Close(ctx context.Context) error {
+	h.stopChecker()
+	h.stopSpiller()
+
+	select {
+	case <-h.spillerDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
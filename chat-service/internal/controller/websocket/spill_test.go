@@ -0,0 +1,184 @@
+package websocket
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/chat-service/internal/dto"
+	"github.com/google/uuid"
+)
+
+// fakeStorage - офлайн-очереди в памяти и состав одного чата
+type fakeStorage struct {
+	ChatUsecase
+
+	mu      sync.Mutex
+	queues  map[uuid.UUID][][]byte
+	members []uuid.UUID
+}
+
+func (s *fakeStorage) SendMsgToStorage(_ context.Context, queue uuid.UUID, msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queues[queue] = append(s.queues[queue], msg)
+	return nil
+}
+
+func (s *fakeStorage) GetMessagesFromStorage(_ context.Context, queue uuid.UUID) [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := s.queues[queue]
+	delete(s.queues, queue)
+	return messages
+}
+
+func (s *fakeStorage) GetChatMembers(context.Context, uuid.UUID) (*dto.ChatMembers, error) {
+	members := &dto.ChatMembers{}
+	for _, userID := range s.members {
+		members.Members = append(members.Members, dto.ChatMemberDTO{UserID: userID})
+	}
+	return members, nil
+}
+
+func (s *fakeStorage) queued(queue uuid.UUID) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.queues[queue])
+}
+
+func newSpillTestHandlers(storage *fakeStorage) *WebsocketHandlers {
+	return &WebsocketHandlers{
+		chatUsecase: storage,
+		factory:     NewMessageFactory(),
+		cfg:         Config{SlowConsumerGrace: time.Minute},
+		metrics:     &Metrics{},
+		conns:       make(map[uuid.UUID][]*Client),
+		spills:      make(chan spillTask, 64),
+	}
+}
+
+// drainSpills выполняет принятые задачи так же, как runSpiller
+func drainSpills(h *WebsocketHandlers) {
+	for {
+		select {
+		case task := <-h.spills:
+			h.handleSpill(task)
+		default:
+			return
+		}
+	}
+}
+
+// laggingClient - сессия с заполненным буфером
+func laggingClient(h *WebsocketHandlers, userID uuid.UUID) *Client {
+	client := NewClient(userID, "user", nil, ProtocolV1JSON, 1)
+	client.Send <- []byte("backlog")
+	h.addClient(client)
+	return client
+}
+
+func broadcast(t *testing.T, h *WebsocketHandlers) {
+	t.Helper()
+
+	h.broadcastToChat(context.Background(), uuid.New(), h.factory.NewOutgoingMessage(&dto.MessageDTO{
+		ID:      uuid.New(),
+		Content: "hello",
+		Type:    "text",
+		SentAt:  time.Now(),
+	}))
+	drainSpills(h)
+}
+
+// Событие, пропущенное отстающей сессией, откладывается один раз для неё и не
+// попадает ни в очередь пользователя, ни к другим его сессиям
+func TestSpillIsKeyedBySession(t *testing.T) {
+	userID := uuid.New()
+	storage := &fakeStorage{queues: make(map[uuid.UUID][][]byte), members: []uuid.UUID{userID}}
+	h := newSpillTestHandlers(storage)
+
+	first := laggingClient(h, userID)
+	second := laggingClient(h, userID)
+
+	broadcast(t, h)
+
+	if got := storage.queued(userID); got != 0 {
+		t.Fatalf("user queue holds %d events, want 0", got)
+	}
+	for name, client := range map[string]*Client{"first": first, "second": second} {
+		if got := storage.queued(client.SessionID); got != 1 {
+			t.Fatalf("%s session queue holds %d events, want 1", name, got)
+		}
+	}
+
+	// Первая сессия разобрала буфер и дозагружает только свою очередь
+	<-first.Send
+	first.overflowSince.Store(0)
+	h.downloadMessages(first, first.SessionID)
+
+	if got := len(first.Send); got != 1 {
+		t.Fatalf("first session received %d events, want 1", got)
+	}
+	if got := storage.queued(second.SessionID); got != 1 {
+		t.Fatalf("second session queue holds %d events after the first resynced, want 1", got)
+	}
+}
+
+func TestSpillForOfflineUser(t *testing.T) {
+	online, offline := uuid.New(), uuid.New()
+	storage := &fakeStorage{queues: make(map[uuid.UUID][][]byte), members: []uuid.UUID{online, offline}}
+	h := newSpillTestHandlers(storage)
+
+	client := NewClient(online, "user", nil, ProtocolV1JSON, 1)
+	h.addClient(client)
+
+	broadcast(t, h)
+
+	if got := storage.queued(offline); got != 1 {
+		t.Fatalf("offline user queue holds %d events, want 1", got)
+	}
+	if got := len(client.Send); got != 1 || client.spilled.Load() {
+		t.Fatalf("online session: buffered %d events, spilled %v", got, client.spilled.Load())
+	}
+}
+
+// Очередь закрытой сессии переходит пользователю, только если других сессий нет
+func TestReleaseSessionQueue(t *testing.T) {
+	tests := []struct {
+		name          string
+		otherSessions bool
+		wantUserQueue int
+	}{
+		{name: "last session", wantUserQueue: 1},
+		{name: "other session is open", otherSessions: true, wantUserQueue: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			storage := &fakeStorage{queues: make(map[uuid.UUID][][]byte), members: []uuid.UUID{userID}}
+			h := newSpillTestHandlers(storage)
+
+			lagging := laggingClient(h, userID)
+			if tt.otherSessions {
+				h.addClient(NewClient(userID, "user", nil, ProtocolV1JSON, 1))
+			}
+
+			broadcast(t, h)
+
+			h.removeClientWithCode(userID, lagging.SessionID, CloseSlowConsumer, "slow consumer")
+			drainSpills(h)
+
+			if got := storage.queued(lagging.SessionID); got != 0 {
+				t.Fatalf("closed session queue holds %d events, want 0", got)
+			}
+			if got := storage.queued(userID); got != tt.wantUserQueue {
+				t.Fatalf("user queue holds %d events, want %d", got, tt.wantUserQueue)
+			}
+		})
+	}
+}
@@ -31,6 +31,15 @@ type CreateChatResponse struct {
 	ChatID uuid.UUID `json:"chat_id"`
 }
 
+// JoinDepartmentChatRequest - добавление сотрудника в чат отдела (от directory-service)
+type JoinDepartmentChatRequest struct {
+	DepartmentName string `json:"department_name" binding:"required"`
+}
+
+type DepartmentChatResponse struct {
+	ChatID uuid.UUID `json:"chat_id"`
+}
+
 type GetUserChatsResponse struct {
 	Chats []ChatPreview `json:"chats"`
 	Total int           `json:"total"`
@@ -15,11 +15,21 @@ type ErrorType string
 
 const (
 	// Входящие типы (от клиента)
-	TypeSendMessage RequestType = "send_message"
+	TypeSendMessage      RequestType = "send_message"
+	TypeCreateChat       RequestType = "create_chat"
+	TypeRemoveChat       RequestType = "remove_chat"
+	TypeGetChats         RequestType = "get_chats"
+	TypeGetMessages      RequestType = "get_messages"
+	TypeGetMembers       RequestType = "get_members"
+	TypeAddMembers       RequestType = "add_members"
+	TypeRemoveMember     RequestType = "remove_member"
+	TypeChangeMemberRole RequestType = "change_member_role"
 
 	// Исходящие типы (к клиенту)
-	EventMessageSent EventType = "message.sent"
-	EventError       EventType = "error"
+	EventMessageSent    EventType = "message.sent"
+	EventError          EventType = "error"
+	EventResyncRequired EventType = "resync_required"
+	EventAck            EventType = "ack"
 
 	// Типы ошибок
 	ErrSendMsg          ErrorType = "send_message_error"
@@ -30,13 +40,15 @@ const (
 	ErrAccessDenied     ErrorType = "access_denied"
 	ErrSaveFailed       ErrorType = "save_failed"
 	ErrInternalError    ErrorType = "internal_error"
+	ErrNotFound         ErrorType = "not_found"
 )
 
 // IncomingMessage - входящее сообщение от клиента
 type IncomingMessage struct {
-	Type    RequestType     `json:"type"`    // Тип операции
-	Payload json.RawMessage `json:"payload"` // Сырые данные\
-	ChatID  uuid.UUID       `json:"chat_id"`
+	Type      RequestType     `json:"type"`    // Тип операции
+	Payload   json.RawMessage `json:"payload"` // Сырые данные
+	ChatID    uuid.UUID       `json:"chat_id"`
+	RequestID string          `json:"request_id,omitempty"` // Идентификатор запроса, возвращается в meta ответов
 }
 
 type SendMessageIncPayload struct {
@@ -45,6 +57,24 @@ type SendMessageIncPayload struct {
 	ReplyTo *uuid.UUID `json:"reply_to,omitempty"`
 }
 
+type GetMessagesIncPayload struct {
+	Limit  int        `json:"limit,omitempty"`
+	Before *uuid.UUID `json:"before,omitempty"`
+}
+
+type AddMembersIncPayload struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
+}
+
+type RemoveMemberIncPayload struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type ChangeMemberRoleIncPayload struct {
+	UserID uuid.UUID  `json:"user_id"`
+	Role   MemberRole `json:"role"`
+}
+
 // OutgoingMessage - исходящее сообщение к клиенту
 type OutgoingMessage struct {
 	Type    EventType       `json:"type"`    // Тип события
@@ -57,10 +87,28 @@ type MessageSentOutPayload struct {
 	Message MessageDTO `json:"message"`
 }
 
+// ResyncRequiredPayload - клиент пропустил события и должен досинхронизироваться
+type ResyncRequiredPayload struct {
+	Reason string `json:"reason"`
+	Missed int64  `json:"missed"`
+}
+
+// AckPayload - подтверждение успешного выполнения запроса
+type AckPayload struct {
+	RequestType RequestType `json:"request_type"`
+	Result      any         `json:"result,omitempty"`
+}
+
+type SendMessageAckResult struct {
+	MessageID uuid.UUID `json:"message_id"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
 type MessageMeta struct {
 	Timestamp time.Time `json:"timestamp"`
 	EventID   uuid.UUID `json:"event_id,omitempty"`
 	ChatID    uuid.UUID `json:"chat_id,omitempty"`
+	RequestID string    `json:"request_id,omitempty"` // Только в прямых ответах на запрос клиента
 }
 
 // ErrorPayload - сообщение об ошибке
@@ -82,3 +130,13 @@ type MessageDTO struct {
 	EditedAt  *time.Time `json:"edited_at,omitempty"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
+
+// WsStats - счётчики websocket-сессий
+type WsStats struct {
+	ActiveSessions   int   `json:"active_sessions"`
+	DroppedEvents    int64 `json:"dropped_events"`
+	SpilledEvents    int64 `json:"spilled_events"`
+	LostEvents       int64 `json:"lost_events"`
+	Evictions        int64 `json:"evictions"`
+	ResyncsRequested int64 `json:"resyncs_requested"`
+}
@@ -5,4 +5,8 @@ import "errors"
 var (
 	ErrChatNotFound  = errors.New("user not found")
 	ErrUserNotInChat = errors.New("user not in chat")
+
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrTokenExpired    = errors.New("token is expired")
+	ErrAccountInactive = errors.New("account is inactive")
 )
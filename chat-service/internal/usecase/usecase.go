@@ -25,6 +25,7 @@ type ChatRepo interface {
 	AddMembers(ctx context.Context, chatID uuid.UUID, userIDs []uuid.UUID, adderID uuid.UUID) error
 	RemoveMember(ctx context.Context, chatID, userID, removerID uuid.UUID) error
 	ChangeMemberRole(ctx context.Context, chatID, userID uuid.UUID, newRole models.MemberRole, changerID uuid.UUID) error
+	JoinDepartmentChat(ctx context.Context, depID uuid.UUID, name string, userID uuid.UUID) (uuid.UUID, error)
 
 	//GetUserChats(ctx context.Context, userID string, limit, offset int) ([]*models.Chat, error)
 
@@ -37,8 +38,8 @@ type ChatRepo interface {
 }
 
 type OfflineMessageStorage interface {
-	SendMessage(userId uuid.UUID, data []byte)
-	GetMessages(userId uuid.UUID) ([][]byte, error)
+	SendMessage(ctx context.Context, userId uuid.UUID, data []byte) error
+	GetMessages(ctx context.Context, userId uuid.UUID) ([][]byte, error)
 }
 
 type ChatUsecase struct {
@@ -48,16 +49,21 @@ type ChatUsecase struct {
 
 func NewChatUsecase(chatRepo ChatRepo, msgStorage OfflineMessageStorage) *ChatUsecase {
 	return &ChatUsecase{
-		chatRepo: chatRepo,
+		chatRepo:   chatRepo,
+		msgStorage: msgStorage,
 	}
 }
 
-func (u *ChatUsecase) SendMsgToStorage(ctx context.Context, userId uuid.UUID, msg []byte) {
-	u.msgStorage.SendMessage(userId, msg)
+func (u *ChatUsecase) SendMsgToStorage(ctx context.Context, userId uuid.UUID, msg []byte) error {
+	if err := u.msgStorage.SendMessage(ctx, userId, msg); err != nil {
+		return fmt.Errorf("failed to save message to offline storage: %w", err)
+	}
+
+	return nil
 }
 
 func (u *ChatUsecase) GetMessagesFromStorage(ctx context.Context, userId uuid.UUID) [][]byte {
-	messages, err := u.msgStorage.GetMessages(userId)
+	messages, err := u.msgStorage.GetMessages(ctx, userId)
 	if err != nil {
 		return nil
 	}
@@ -121,6 +127,19 @@ func (u *ChatUsecase) CreateChat(ctx context.Context, req *dto.CreateChatRequest
 
 	return resp, nil
 }
+
+// JoinDepartmentChat добавляет сотрудника в чат его отдела
+func (u *ChatUsecase) JoinDepartmentChat(ctx context.Context, depID uuid.UUID, req *dto.JoinDepartmentChatRequest, userID uuid.UUID) (*dto.DepartmentChatResponse, error) {
+	chatID, err := u.chatRepo.JoinDepartmentChat(ctx, depID, req.DepartmentName, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to join department chat: %w", err)
+	}
+
+	return &dto.DepartmentChatResponse{
+		ChatID: chatID,
+	}, nil
+}
+
 func (u *ChatUsecase) RemoveChat(ctx context.Context, chatID uuid.UUID) error {
 	_, err := u.chatRepo.GetChat(ctx, chatID)
 	if err != nil {
@@ -140,7 +159,7 @@ func (u *ChatUsecase) RemoveChat(ctx context.Context, chatID uuid.UUID) error {
 }
 
 func (u *ChatUsecase) GetUserChats(ctx context.Context, userID uuid.UUID) (*dto.GetUserChatsResponse, error) {
-	chats, err := u.chatRepo.GetUserChats(ctx, uuid.NewString())
+	chats, err := u.chatRepo.GetUserChats(ctx, userID.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chats: %w", err)
 	}
@@ -155,20 +174,22 @@ func (u *ChatUsecase) GetUserChats(ctx context.Context, userID uuid.UUID) (*dto.
 			return nil, fmt.Errorf("failed to get last message in chat: %w", err)
 		}
 
-		lastMsgPreview := &dto.MessagePreview{
-			MessageID: lastMsg.MessageID,
-			Content:   lastMsg.Content,
-			Type:      dto.MessageType(lastMsg.Type),
-			SenderID:  lastMsg.SenderID,
-			SentAt:    lastMsg.SentAt,
+		chatsPreviews[i] = dto.ChatPreview{
+			ChatID: chat.ChatID,
+			Type:   dto.ChatType(chat.Type),
+			Name:   chat.Name,
 		}
 
-		chatsPreviews[i] = dto.ChatPreview{
-			ChatID:          chat.ChatID,
-			Type:            dto.ChatType(chat.Type),
-			Name:            chat.Name,
-			LastMessage:     lastMsgPreview,
-			LastMessageTime: &lastMsg.SentAt,
+		// В новом чате сообщений ещё нет
+		if lastMsg != nil {
+			chatsPreviews[i].LastMessage = &dto.MessagePreview{
+				MessageID: lastMsg.MessageID,
+				Content:   lastMsg.Content,
+				Type:      dto.MessageType(lastMsg.Type),
+				SenderID:  lastMsg.SenderID,
+				SentAt:    lastMsg.SentAt,
+			}
+			chatsPreviews[i].LastMessageTime = &lastMsg.SentAt
 		}
 	}
 
@@ -311,7 +332,7 @@ func (u *ChatUsecase) ChangeMemberRole(ctx context.Context, chatID, userID uuid.
 		return errors.ErrUserNotInChat
 	}
 
-	err = u.chatRepo.ChangeMemberRole(ctx, changerID, userID, models.MemberRole(role), changerID)
+	err = u.chatRepo.ChangeMemberRole(ctx, chatID, userID, models.MemberRole(role), changerID)
 	if err != nil {
 		return fmt.Errorf("failed to change member role from db: %w", err)
 	}
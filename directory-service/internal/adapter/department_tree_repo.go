@@ -0,0 +1,332 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Ключ pg_advisory_xact_lock: параллельные переносы отделов не должны вместе образовать цикл
+const departmentTreeLockID = 7_420_002
+
+// MoveDepartment сохраняет отдел, проверив под блокировкой, что новый родитель не лежит
+// в его поддереве. false - перенос образовал бы цикл, изменения не применены
+func (r *DirectoryRepo) MoveDepartment(ctx context.Context, dep *models.Department) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", departmentTreeLockID); err != nil {
+		return false, fmt.Errorf("failed to lock department tree: %w", err)
+	}
+
+	if dep.ParentID != nil {
+		// Поднимаемся от нового родителя к корню; UNION останавливает обход,
+		// даже если в данных уже есть цикл
+		var cycle bool
+		err = tx.QueryRow(ctx, `
+			WITH RECURSIVE ancestors AS (
+				SELECT department_id, parent_id FROM departments WHERE department_id = $1
+				UNION
+				SELECT d.department_id, d.parent_id FROM departments d
+				JOIN ancestors a ON d.department_id = a.parent_id
+			)
+			SELECT EXISTS (SELECT 1 FROM ancestors WHERE department_id = $2)`,
+			*dep.ParentID, dep.DepartmentID).Scan(&cycle)
+		if err != nil {
+			return false, fmt.Errorf("failed to check department ancestors: %w", err)
+		}
+		if cycle {
+			return false, nil
+		}
+	}
+
+	query := r.builder.Update("departments").
+		Set("name", dep.Name).
+		Set("parent_id", dep.ParentID).
+		Set("head_user_id", dep.HeadUserID).
+		Set("updated_at", dep.UpdatedAt).
+		Where(squirrel.Eq{"department_id": dep.DepartmentID})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(ctx, sqlQuery, args...); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// GetDepartmentSubtree - отдел и все вложенные неархивные отделы до глубины maxDepth
+// (0 - без ограничения), от корня выборки вниз. Численность считается по активным сотрудникам;
+// TotalMemberCount включает всё поддерево, даже если оно глубже maxDepth
+func (r *DirectoryRepo) GetDepartmentSubtree(ctx context.Context, id uuid.UUID, maxDepth int) ([]*models.DepartmentNode, error) {
+	sqlQuery := `
+		WITH RECURSIVE subtree AS (
+			SELECT department_id, 0 AS depth, ARRAY[department_id] AS path
+			FROM departments WHERE department_id = $1
+			UNION ALL
+			SELECT d.department_id, t.depth + 1, t.path || d.department_id
+			FROM departments d
+			JOIN subtree t ON d.parent_id = t.department_id
+			WHERE NOT d.department_id = ANY(t.path) AND d.archived_at IS NULL
+		),
+		counts AS (
+			SELECT t.department_id, t.depth, t.path,
+				(SELECT COUNT(*) FROM users u WHERE u.department_id = t.department_id AND u.is_active) AS members
+			FROM subtree t
+		)
+		SELECT d.department_id, d.name, d.parent_id, d.head_user_id, d.created_at, d.updated_at, d.archived_at,
+			c.depth, c.members,
+			(SELECT COALESCE(SUM(c2.members), 0) FROM counts c2 WHERE c.department_id = ANY(c2.path)) AS total_members
+		FROM counts c
+		JOIN departments d ON d.department_id = c.department_id
+		WHERE $2 <= 0 OR c.depth <= $2
+		ORDER BY c.depth, d.name`
+
+	rows, err := r.db.Query(ctx, sqlQuery, id, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []*models.DepartmentNode
+	for rows.Next() {
+		var node models.DepartmentNode
+		err = rows.Scan(&node.DepartmentID, &node.Name, &node.ParentID, &node.HeadUserID, &node.CreatedAt, &node.UpdatedAt, &node.ArchivedAt,
+			&node.Depth, &node.MemberCount, &node.TotalMemberCount)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, &node)
+	}
+
+	return nodes, rows.Err()
+}
+
+// GetDepartmentPath - цепочка отделов от корня до заданного включительно; пусто, если отдела нет
+func (r *DirectoryRepo) GetDepartmentPath(ctx context.Context, id uuid.UUID) ([]*models.Department, error) {
+	sqlQuery := `
+		WITH RECURSIVE ancestors AS (
+			SELECT department_id, parent_id, 0 AS level, ARRAY[department_id] AS path
+			FROM departments WHERE department_id = $1
+			UNION ALL
+			SELECT d.department_id, d.parent_id, a.level + 1, a.path || d.department_id
+			FROM departments d
+			JOIN ancestors a ON d.department_id = a.parent_id
+			WHERE NOT d.department_id = ANY(a.path)
+		)
+		SELECT d.department_id, d.name, d.parent_id, d.head_user_id, d.created_at, d.updated_at, d.archived_at
+		FROM ancestors a
+		JOIN departments d ON d.department_id = a.department_id
+		ORDER BY a.level DESC`
+
+	rows, err := r.db.Query(ctx, sqlQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deps []*models.Department
+	for rows.Next() {
+		var dep models.Department
+		err = rows.Scan(&dep.DepartmentID, &dep.Name, &dep.ParentID, &dep.HeadUserID, &dep.CreatedAt, &dep.UpdatedAt, &dep.ArchivedAt)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, &dep)
+	}
+
+	return deps, rows.Err()
+}
+
+func (r *DirectoryRepo) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
+		From("users").
+		Where(squirrel.Eq{"user_id": ids})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// RemoveDepartment удаляет или архивирует отдел в одной транзакции под блокировкой
+// дерева: проверка занятости, перенос сотрудников и дочерних отделов и само удаление
+// не разделяются параллельными изменениями
+func (r *DirectoryRepo) RemoveDepartment(ctx context.Context, removal *models.DepartmentRemoval) (*models.DepartmentRemovalResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", departmentTreeLockID); err != nil {
+		return nil, fmt.Errorf("failed to lock department tree: %w", err)
+	}
+
+	result := &models.DepartmentRemovalResult{}
+	depID := removal.DepartmentID
+
+	var parentID *uuid.UUID
+	err = tx.QueryRow(ctx, "SELECT parent_id FROM departments WHERE department_id = $1 AND archived_at IS NULL FOR UPDATE", depID).
+		Scan(&parentID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			result.NotFound = true
+			return result, nil
+		}
+		return nil, err
+	}
+
+	if removal.ReassignTo != nil {
+		var targetExists, inSubtree bool
+		err = tx.QueryRow(ctx, `
+			WITH RECURSIVE ancestors AS (
+				SELECT department_id, parent_id FROM departments WHERE department_id = $1
+				UNION
+				SELECT d.department_id, d.parent_id FROM departments d
+				JOIN ancestors a ON d.department_id = a.parent_id
+			)
+			SELECT
+				EXISTS (SELECT 1 FROM departments WHERE department_id = $1 AND archived_at IS NULL),
+				EXISTS (SELECT 1 FROM ancestors WHERE department_id = $2)`,
+			*removal.ReassignTo, depID).Scan(&targetExists, &inSubtree)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check reassignment target: %w", err)
+		}
+		if !targetExists {
+			result.TargetNotFound = true
+			return result, nil
+		}
+		if inSubtree {
+			result.TargetInSubtree = true
+			return result, nil
+		}
+	} else {
+		err = tx.QueryRow(ctx, `
+			SELECT
+				EXISTS (SELECT 1 FROM users WHERE department_id = $1 AND is_active),
+				EXISTS (SELECT 1 FROM departments WHERE parent_id = $1 AND archived_at IS NULL)`,
+			depID).Scan(&result.HasActiveUsers, &result.HasChildren)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check department usage: %w", err)
+		}
+		if removal.DetachUsers {
+			// Активные сотрудники останутся без отдела
+			result.HasActiveUsers = false
+		}
+		if result.HasActiveUsers || result.HasChildren {
+			return result, nil
+		}
+	}
+
+	// При архивации уволенные сотрудники и архивные дочерние отделы остаются на месте,
+	// чтобы сохранить историю. При удалении их тоже нужно куда-то деть
+	childParentID := removal.ReassignTo
+	if !removal.Archive && removal.ReassignTo == nil {
+		// Остались только архивные дочерние отделы - поднимаем их к родителю удаляемого
+		childParentID = parentID
+	}
+
+	users := r.builder.Update("users").
+		Set("department_id", removal.ReassignTo).
+		Set("updated_at", removal.RemovedAt).
+		Where(squirrel.Eq{"department_id": depID})
+	children := r.builder.Update("departments").
+		Set("parent_id", childParentID).
+		Set("updated_at", removal.RemovedAt).
+		Where(squirrel.Eq{"parent_id": depID})
+	if removal.Archive {
+		users = users.Where(squirrel.Eq{"is_active": true})
+		children = children.Where(squirrel.Eq{"archived_at": nil})
+	}
+
+	if result.MovedUsers, err = execTxCount(ctx, tx, users); err != nil {
+		return nil, fmt.Errorf("failed to move users: %w", err)
+	}
+	if result.MovedChildren, err = execTxCount(ctx, tx, children); err != nil {
+		return nil, fmt.Errorf("failed to move child departments: %w", err)
+	}
+
+	if removal.Archive {
+		query := r.builder.Update("departments").
+			Set("archived_at", removal.RemovedAt).
+			Set("updated_at", removal.RemovedAt).
+			Where(squirrel.Eq{"department_id": depID})
+		if err := execTx(ctx, tx, query); err != nil {
+			return nil, fmt.Errorf("failed to archive department: %w", err)
+		}
+
+		if removal.UnlinkSource != "" {
+			links := r.builder.Delete("directory_sync_links").
+				Where(squirrel.Eq{"source": removal.UnlinkSource, "object_type": models.SyncObjectDepartment, "local_id": depID})
+			if err := execTx(ctx, tx, links); err != nil {
+				return nil, fmt.Errorf("failed to delete sync link: %w", err)
+			}
+		}
+	} else {
+		links := r.builder.Delete("directory_sync_links").
+			Where(squirrel.Eq{"object_type": models.SyncObjectDepartment, "local_id": depID})
+		if err := execTx(ctx, tx, links); err != nil {
+			return nil, fmt.Errorf("failed to delete sync links: %w", err)
+		}
+
+		query := r.builder.Delete("departments").
+			Where(squirrel.Eq{"department_id": depID})
+		if err := execTx(ctx, tx, query); err != nil {
+			return nil, fmt.Errorf("failed to delete department: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Removed = true
+	return result, nil
+}
+
+func execTxCount(ctx context.Context, tx pgx.Tx, query squirrel.Sqlizer) (int64, error) {
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, sqlQuery, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
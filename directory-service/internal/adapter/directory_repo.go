@@ -3,6 +3,8 @@ package adapter
 import (
 	"context"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
 	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
@@ -26,8 +28,8 @@ func NewDirectoryRepo(db *pgxpool.Pool) *DirectoryRepo {
 
 func (r *DirectoryRepo) CreateDepartment(ctx context.Context, dep *models.Department) (uuid.UUID, error) {
 	query := r.builder.Insert("departments").
-		Columns("department_id", "name", "parent_id", "created_at", "updated_at").
-		Values(dep.DepartmentID, dep.Name, dep.ParentID, dep.CreatedAt, dep.UpdatedAt).
+		Columns("department_id", "name", "parent_id", "head_user_id", "created_at", "updated_at").
+		Values(dep.DepartmentID, dep.Name, dep.ParentID, dep.HeadUserID, dep.CreatedAt, dep.UpdatedAt).
 		Suffix("RETURNING department_id")
 
 	sqlQuery, args, err := query.ToSql()
@@ -45,7 +47,7 @@ func (r *DirectoryRepo) CreateDepartment(ctx context.Context, dep *models.Depart
 }
 
 func (r *DirectoryRepo) GetDepartmentByID(ctx context.Context, id uuid.UUID) (*models.Department, error) {
-	query := r.builder.Select("department_id, name, parent_id, created_at, updated_at").
+	query := r.builder.Select("department_id, name, parent_id, head_user_id, created_at, updated_at, archived_at").
 		From("departments").
 		Where(squirrel.Eq{"department_id": id})
 
@@ -57,7 +59,7 @@ func (r *DirectoryRepo) GetDepartmentByID(ctx context.Context, id uuid.UUID) (*m
 	row := r.db.QueryRow(ctx, sqlQuery, args...)
 
 	var dep models.Department
-	err = row.Scan(&dep.DepartmentID, &dep.Name, &dep.ParentID, &dep.CreatedAt, &dep.UpdatedAt)
+	err = row.Scan(&dep.DepartmentID, &dep.Name, &dep.ParentID, &dep.HeadUserID, &dep.CreatedAt, &dep.UpdatedAt, &dep.ArchivedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -68,22 +70,29 @@ func (r *DirectoryRepo) GetDepartmentByID(ctx context.Context, id uuid.UUID) (*m
 	return &dep, nil
 }
 
-func (r *DirectoryRepo) GetDepartments(ctx context.Context, limit, offset int) ([]*models.Department, int, error) {
+// GetDepartments - страница отделов; архивные возвращаются только с includeArchived
+func (r *DirectoryRepo) GetDepartments(ctx context.Context, limit, offset int, includeArchived bool) ([]*models.Department, int, error) {
 	countQuery := r.builder.Select("COUNT(*)").From("departments")
-	sqlCount, _, err := countQuery.ToSql()
+	if !includeArchived {
+		countQuery = countQuery.Where(squirrel.Eq{"archived_at": nil})
+	}
+	sqlCount, argsCount, err := countQuery.ToSql()
 	if err != nil {
 		return nil, 0, err
 	}
 	var total int
-	err = r.db.QueryRow(ctx, sqlCount).Scan(&total)
+	err = r.db.QueryRow(ctx, sqlCount, argsCount...).Scan(&total)
 	if err != nil {
 		return nil, 0, err
 	}
 
-	query := r.builder.Select("department_id, name, parent_id, created_at, updated_at").
+	query := r.builder.Select("department_id, name, parent_id, head_user_id, created_at, updated_at, archived_at").
 		From("departments").
 		Limit(uint64(limit)).
 		Offset(uint64(offset))
+	if !includeArchived {
+		query = query.Where(squirrel.Eq{"archived_at": nil})
+	}
 
 	sqlQuery, args, err := query.ToSql()
 	if err != nil {
@@ -99,7 +108,7 @@ func (r *DirectoryRepo) GetDepartments(ctx context.Context, limit, offset int) (
 	var deps []*models.Department
 	for rows.Next() {
 		var dep models.Department
-		err = rows.Scan(&dep.DepartmentID, &dep.Name, &dep.ParentID, &dep.CreatedAt, &dep.UpdatedAt)
+		err = rows.Scan(&dep.DepartmentID, &dep.Name, &dep.ParentID, &dep.HeadUserID, &dep.CreatedAt, &dep.UpdatedAt, &dep.ArchivedAt)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -110,7 +119,7 @@ func (r *DirectoryRepo) GetDepartments(ctx context.Context, limit, offset int) (
 }
 
 func (r *DirectoryRepo) GetAllDepartments(ctx context.Context) ([]*models.Department, error) {
-	query := r.builder.Select("department_id, name, parent_id, created_at, updated_at").
+	query := r.builder.Select("department_id, name, parent_id, head_user_id, created_at, updated_at, archived_at").
 		From("departments")
 
 	sqlQuery, args, err := query.ToSql()
@@ -127,7 +136,7 @@ func (r *DirectoryRepo) GetAllDepartments(ctx context.Context) ([]*models.Depart
 	var deps []*models.Department
 	for rows.Next() {
 		var dep models.Department
-		err = rows.Scan(&dep.DepartmentID, &dep.Name, &dep.ParentID, &dep.CreatedAt, &dep.UpdatedAt)
+		err = rows.Scan(&dep.DepartmentID, &dep.Name, &dep.ParentID, &dep.HeadUserID, &dep.CreatedAt, &dep.UpdatedAt, &dep.ArchivedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -137,22 +146,14 @@ func (r *DirectoryRepo) GetAllDepartments(ctx context.Context) ([]*models.Depart
 	return deps, nil
 }
 
-func (r *DirectoryRepo) DeleteDepartment(ctx context.Context, id uuid.UUID) error {
-	query := r.builder.Delete("departments").
-		Where(squirrel.Eq{"department_id": id})
-
-	sqlQuery, args, err := query.ToSql()
-	if err != nil {
-		return err
+func (r *DirectoryRepo) GetDepartmentMembers(ctx context.Context, depID uuid.UUID, limit, offset int, includeInactive bool) ([]*models.User, int, error) {
+	where := squirrel.Eq{"department_id": depID}
+	if !includeInactive {
+		where["is_active"] = true
 	}
 
-	_, err = r.db.Exec(ctx, sqlQuery, args...)
-	return err
-}
-
-func (r *DirectoryRepo) GetDepartmentMembers(ctx context.Context, depID uuid.UUID, limit, offset int) ([]*models.User, int, error) {
 	countQuery := r.builder.Select("COUNT(*)").From("users").
-		Where(squirrel.Eq{"department_id": depID})
+		Where(where)
 	sqlCount, argsCount, err := countQuery.ToSql()
 	if err != nil {
 		return nil, 0, err
@@ -163,9 +164,9 @@ func (r *DirectoryRepo) GetDepartmentMembers(ctx context.Context, depID uuid.UUI
 		return nil, 0, err
 	}
 
-	query := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at").
+	query := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
 		From("users").
-		Where(squirrel.Eq{"department_id": depID}).
+		Where(where).
 		Limit(uint64(limit)).
 		Offset(uint64(offset))
 
@@ -194,8 +195,8 @@ func (r *DirectoryRepo) GetDepartmentMembers(ctx context.Context, depID uuid.UUI
 
 func (r *DirectoryRepo) CreateUser(ctx context.Context, user *models.User) (uuid.UUID, error) {
 	query := r.builder.Insert("users").
-		Columns("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at").
-		Values(user.UserID, user.Email, user.FirstName, user.LastName, user.Position, user.DepartmentID, user.AvatarURL, user.IsActive, user.CreatedAt, user.UpdatedAt).
+		Columns("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
+		Values(user.UserID, user.Email, user.FirstName, user.LastName, user.Position, user.DepartmentID, user.AvatarURL, user.IsActive, user.CreatedAt, user.UpdatedAt, deactivatedAt(user)).
 		Suffix("RETURNING user_id")
 
 	sqlQuery, args, err := query.ToSql()
@@ -213,7 +214,7 @@ func (r *DirectoryRepo) CreateUser(ctx context.Context, user *models.User) (uuid
 }
 
 func (r *DirectoryRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-	query := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at").
+	query := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
 		From("users").
 		Where(squirrel.Eq{"user_id": id})
 
@@ -236,7 +237,7 @@ func (r *DirectoryRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*models.
 }
 
 func (r *DirectoryRepo) GetUsers(ctx context.Context, filter *dto.GetUsersRequest) ([]*models.User, int, error) {
-	qb := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at").
+	qb := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
 		From("users")
 
 	if filter.DepartmentID != nil {
@@ -245,6 +246,9 @@ func (r *DirectoryRepo) GetUsers(ctx context.Context, filter *dto.GetUsersReques
 	if filter.IsActive != nil {
 		qb = qb.Where(squirrel.Eq{"is_active": *filter.IsActive})
 	}
+	if filter.Email != nil {
+		qb = qb.Where(squirrel.Eq{"lower(email)": strings.ToLower(*filter.Email)})
+	}
 
 	countQuery := r.builder.Select("COUNT(*)").FromSelect(qb, "t")
 	sqlCount, argsCount, err := countQuery.ToSql()
@@ -281,9 +285,66 @@ func (r *DirectoryRepo) GetUsers(ctx context.Context, filter *dto.GetUsersReques
 	return users, total, nil
 }
 
-func (r *DirectoryRepo) DeleteUser(ctx context.Context, id uuid.UUID) error {
+// PurgeUser удаляет пользователя без следа. Только для отката только что созданной
+// записи (онбординг, SCIM); увольнение - DeactivateUser
+func (r *DirectoryRepo) PurgeUser(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	unsetHead := r.builder.Update("departments").
+		Set("head_user_id", nil).
+		Where(squirrel.Eq{"head_user_id": id})
+	if err := execTx(ctx, tx, unsetHead); err != nil {
+		return err
+	}
+
 	query := r.builder.Delete("users").
 		Where(squirrel.Eq{"user_id": id})
+	if err := execTx(ctx, tx, query); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// DeactivateUser увольняет пользователя: запись и её id остаются для истории,
+// а руководство отделами снимается. Повторный вызов не сдвигает deactivated_at
+func (r *DirectoryRepo) DeactivateUser(ctx context.Context, id uuid.UUID, at time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	unsetHead := r.builder.Update("departments").
+		Set("head_user_id", nil).
+		Set("updated_at", at).
+		Where(squirrel.Eq{"head_user_id": id})
+	if err := execTx(ctx, tx, unsetHead); err != nil {
+		return err
+	}
+
+	query := r.builder.Update("users").
+		Set("is_active", false).
+		Set("deactivated_at", at).
+		Set("updated_at", at).
+		Where(squirrel.Eq{"user_id": id, "is_active": true})
+	if err := execTx(ctx, tx, query); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *DirectoryRepo) ReactivateUser(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := r.builder.Update("users").
+		Set("is_active", true).
+		Set("deactivated_at", nil).
+		Set("updated_at", at).
+		Where(squirrel.Eq{"user_id": id, "is_active": false})
 
 	sqlQuery, args, err := query.ToSql()
 	if err != nil {
@@ -294,6 +355,23 @@ func (r *DirectoryRepo) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+// deactivatedAt - значение deactivated_at для новой записи
+func deactivatedAt(user *models.User) *time.Time {
+	if user.IsActive {
+		return nil
+	}
+	if user.DeactivatedAt != nil {
+		return user.DeactivatedAt
+	}
+	return &user.UpdatedAt
+}
+
+// deactivatedAtExpr сохраняет исходный момент увольнения при обновлении записи
+// и сбрасывает его при восстановлении
+func deactivatedAtExpr(user *models.User) squirrel.Sqlizer {
+	return squirrel.Expr("CASE WHEN ? THEN NULL ELSE COALESCE(deactivated_at, ?) END", user.IsActive, user.UpdatedAt)
+}
+
 func scanUser(scanner pgx.Row) (*models.User, error) {
 	var user models.User
 	err := scanner.Scan(
@@ -307,6 +385,7 @@ func scanUser(scanner pgx.Row) (*models.User, error) {
 		&user.IsActive,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.DeactivatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -314,3 +393,72 @@ func scanUser(scanner pgx.Row) (*models.User, error) {
 
 	return &user, nil
 }
+
+func (r *DirectoryRepo) UpdateUser(ctx context.Context, user *models.User) error {
+	query := r.builder.Update("users").
+		Set("email", user.Email).
+		Set("first_name", user.FirstName).
+		Set("last_name", user.LastName).
+		Set("position", user.Position).
+		Set("department_id", user.DepartmentID).
+		Set("avatar_url", user.AvatarURL).
+		Set("is_active", user.IsActive).
+		Set("deactivated_at", deactivatedAtExpr(user)).
+		Set("updated_at", user.UpdatedAt).
+		Where(squirrel.Eq{"user_id": user.UserID})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, sqlQuery, args...)
+	return err
+}
+
+func (r *DirectoryRepo) UpdateDepartment(ctx context.Context, dep *models.Department) error {
+	query := r.builder.Update("departments").
+		Set("name", dep.Name).
+		Set("parent_id", dep.ParentID).
+		Set("head_user_id", dep.HeadUserID).
+		Set("updated_at", dep.UpdatedAt).
+		Where(squirrel.Eq{"department_id": dep.DepartmentID})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, sqlQuery, args...)
+	return err
+}
+
+// GetAllDepartmentMembers - все сотрудники отдела без пагинации
+func (r *DirectoryRepo) GetAllDepartmentMembers(ctx context.Context, depID uuid.UUID) ([]*models.User, error) {
+	query := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
+		From("users").
+		Where(squirrel.Eq{"department_id": depID}).
+		OrderBy("last_name", "first_name")
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
@@ -0,0 +1,118 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/Masterminds/squirrel"
+)
+
+// ClaimOnboarding занимает ключ идемпотентности. Новый ключ сохраняется из rec; существующий
+// занимается заново, если запрос тот же и он прерван (failed или in_progress, не
+// обновлявшийся с staleBefore), а также если запись создана до expiredBefore.
+// claimed=false - ключ занят другим запросом или уже выполнен; тогда возвращается сохранённая запись
+func (r *DirectoryRepo) ClaimOnboarding(ctx context.Context, rec *models.OnboardingRequest, staleBefore, expiredBefore time.Time) (*models.OnboardingRequest, bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	insert := r.builder.Insert("onboarding_requests").
+		Columns("idempotency_key", "request_hash", "user_id", "status", "result", "created_at", "updated_at").
+		Values(rec.IdempotencyKey, rec.RequestHash, rec.UserID, rec.Status, rec.Result, rec.CreatedAt, rec.UpdatedAt).
+		Suffix("ON CONFLICT (idempotency_key) DO NOTHING")
+
+	sqlQuery, args, err := insert.ToSql()
+	if err != nil {
+		return nil, false, err
+	}
+
+	tag, err := tx.Exec(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to save onboarding request: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		query := r.builder.Select("idempotency_key, request_hash, user_id, status, result, created_at, updated_at").
+			From("onboarding_requests").
+			Where(squirrel.Eq{"idempotency_key": rec.IdempotencyKey}).
+			Suffix("FOR UPDATE")
+
+		sqlQuery, args, err := query.ToSql()
+		if err != nil {
+			return nil, false, err
+		}
+
+		var existing models.OnboardingRequest
+		err = tx.QueryRow(ctx, sqlQuery, args...).Scan(&existing.IdempotencyKey, &existing.RequestHash, &existing.UserID,
+			&existing.Status, &existing.Result, &existing.CreatedAt, &existing.UpdatedAt)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get onboarding request: %w", err)
+		}
+
+		switch {
+		case existing.CreatedAt.Before(expiredBefore):
+			// Просроченный ключ можно использовать для нового запроса
+		case existing.RequestHash != rec.RequestHash || existing.Status == models.OnboardingCompleted:
+			return &existing, false, nil
+		case existing.Status == models.OnboardingInProgress && !existing.UpdatedAt.Before(staleBefore):
+			return &existing, false, nil
+		default:
+			// Продолжаем прерванный запрос с тем же сотрудником
+			rec.UserID = existing.UserID
+			rec.Result = existing.Result
+			rec.CreatedAt = existing.CreatedAt
+		}
+
+		update := r.builder.Update("onboarding_requests").
+			Set("request_hash", rec.RequestHash).
+			Set("user_id", rec.UserID).
+			Set("status", rec.Status).
+			Set("result", rec.Result).
+			Set("created_at", rec.CreatedAt).
+			Set("updated_at", rec.UpdatedAt).
+			Where(squirrel.Eq{"idempotency_key": rec.IdempotencyKey})
+		if err := execTx(ctx, tx, update); err != nil {
+			return nil, false, fmt.Errorf("failed to claim onboarding request: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return rec, true, nil
+}
+
+func (r *DirectoryRepo) SaveOnboarding(ctx context.Context, rec *models.OnboardingRequest) error {
+	query := r.builder.Update("onboarding_requests").
+		Set("status", rec.Status).
+		Set("result", rec.Result).
+		Set("updated_at", rec.UpdatedAt).
+		Where(squirrel.Eq{"idempotency_key": rec.IdempotencyKey})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, sqlQuery, args...)
+	return err
+}
+
+// DeleteOnboarding освобождает ключ: после отката саги повтор начнётся заново
+func (r *DirectoryRepo) DeleteOnboarding(ctx context.Context, key string) error {
+	query := r.builder.Delete("onboarding_requests").
+		Where(squirrel.Eq{"idempotency_key": key})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, sqlQuery, args...)
+	return err
+}
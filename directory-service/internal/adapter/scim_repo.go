@@ -0,0 +1,439 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/pkg/scim"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type scimColumnKind int
+
+const (
+	scimString scimColumnKind = iota
+	scimBool
+	scimTime
+	scimUUID
+)
+
+// scimColumn - колонка, соответствующая атрибуту SCIM. Если задан subquery, условие
+// строится по колонке подзапроса и подставляется в него вместо %s
+type scimColumn struct {
+	name         string
+	kind         scimColumnKind
+	caseExact    bool
+	subquery     string
+	subqueryArgs []any
+}
+
+var scimUserColumns = map[string]scimColumn{
+	"id":                {name: "user_id", kind: scimUUID},
+	"username":          {name: "email", kind: scimString},
+	"emails":            {name: "email", kind: scimString},
+	"emails.value":      {name: "email", kind: scimString},
+	"name.givenname":    {name: "first_name", kind: scimString},
+	"name.familyname":   {name: "last_name", kind: scimString},
+	"title":             {name: "position", kind: scimString},
+	"active":            {name: "is_active", kind: scimBool},
+	"meta.created":      {name: "created_at", kind: scimTime},
+	"meta.lastmodified": {name: "updated_at", kind: scimTime},
+	"externalid": {
+		name:         "external_id",
+		kind:         scimString,
+		caseExact:    true,
+		subquery:     "user_id IN (SELECT local_id FROM directory_sync_links WHERE source = ? AND object_type = ? AND %s)",
+		subqueryArgs: []any{models.SyncSourceSCIM, models.SyncObjectUser},
+	},
+	"groups.value": {name: "department_id", kind: scimUUID},
+}
+
+var scimGroupColumns = map[string]scimColumn{
+	"id":                {name: "department_id", kind: scimUUID},
+	"displayname":       {name: "name", kind: scimString},
+	"meta.created":      {name: "created_at", kind: scimTime},
+	"meta.lastmodified": {name: "updated_at", kind: scimTime},
+	"externalid": {
+		name:         "external_id",
+		kind:         scimString,
+		caseExact:    true,
+		subquery:     "department_id IN (SELECT local_id FROM directory_sync_links WHERE source = ? AND object_type = ? AND %s)",
+		subqueryArgs: []any{models.SyncSourceSCIM, models.SyncObjectDepartment},
+	},
+	"members.value": {
+		name:     "user_id",
+		kind:     scimUUID,
+		subquery: "department_id IN (SELECT department_id FROM users WHERE %s)",
+	},
+}
+
+// FindUsersByFilter - пользователи, подходящие под фильтр SCIM; nil - все пользователи
+func (r *DirectoryRepo) FindUsersByFilter(ctx context.Context, filter scim.Filter, limit, offset int) ([]*models.User, int, error) {
+	query := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
+		From("users")
+
+	if filter != nil {
+		cond, err := scimCondition(filter, scimUserColumns)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where(cond)
+	}
+
+	countQuery := r.builder.Select("COUNT(*)").FromSelect(query, "u")
+	countSql, countArgs, err := countQuery.ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, countSql, countArgs...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query = query.OrderBy("created_at", "user_id").Limit(uint64(limit)).Offset(uint64(offset))
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+
+	return users, total, rows.Err()
+}
+
+// FindDepartmentsByFilter - неархивные отделы, подходящие под фильтр SCIM; nil - все отделы
+func (r *DirectoryRepo) FindDepartmentsByFilter(ctx context.Context, filter scim.Filter, limit, offset int) ([]*models.Department, int, error) {
+	query := r.builder.Select("department_id, name, parent_id, head_user_id, created_at, updated_at, archived_at").
+		From("departments").
+		Where(squirrel.Eq{"archived_at": nil})
+
+	if filter != nil {
+		cond, err := scimCondition(filter, scimGroupColumns)
+		if err != nil {
+			return nil, 0, err
+		}
+		query = query.Where(cond)
+	}
+
+	countQuery := r.builder.Select("COUNT(*)").FromSelect(query, "d")
+	countSql, countArgs, err := countQuery.ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, countSql, countArgs...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query = query.OrderBy("created_at", "department_id").Limit(uint64(limit)).Offset(uint64(offset))
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var deps []*models.Department
+	for rows.Next() {
+		var dep models.Department
+		err = rows.Scan(&dep.DepartmentID, &dep.Name, &dep.ParentID, &dep.HeadUserID, &dep.CreatedAt, &dep.UpdatedAt, &dep.ArchivedAt)
+		if err != nil {
+			return nil, 0, err
+		}
+		deps = append(deps, &dep)
+	}
+
+	return deps, total, rows.Err()
+}
+
+// scimCondition переводит фильтр SCIM в условие WHERE
+func scimCondition(filter scim.Filter, columns map[string]scimColumn) (squirrel.Sqlizer, error) {
+	switch f := filter.(type) {
+	case *scim.LogicalExpr:
+		left, err := scimCondition(f.Left, columns)
+		if err != nil {
+			return nil, err
+		}
+		right, err := scimCondition(f.Right, columns)
+		if err != nil {
+			return nil, err
+		}
+		if f.Op == "or" {
+			return squirrel.Or{left, right}, nil
+		}
+		return squirrel.And{left, right}, nil
+
+	case *scim.NotExpr:
+		cond, err := scimCondition(f.Filter, columns)
+		if err != nil {
+			return nil, err
+		}
+		return notCondition{cond}, nil
+
+	case *scim.ValuePathExpr:
+		// emails[value eq "x"] -> фильтр по податрибутам emails.*
+		prefix := strings.ToLower(f.Attr) + "."
+		subColumns := make(map[string]scimColumn)
+		for attr, column := range columns {
+			if sub, ok := strings.CutPrefix(attr, prefix); ok {
+				subColumns[sub] = column
+			}
+		}
+		if len(subColumns) == 0 {
+			return nil, scim.BadRequest(scim.TypeInvalidFilter, "filtering by %q is not supported", f.Attr)
+		}
+		return scimCondition(f.Filter, subColumns)
+
+	case *scim.AttrExpr:
+		column, ok := columns[f.Path.String()]
+		if !ok {
+			return nil, scim.BadRequest(scim.TypeInvalidFilter, "filtering by %q is not supported", f.Path.String())
+		}
+
+		cond, err := scimColumnCondition(column, f.Op, f.Value)
+		if err != nil {
+			return nil, err
+		}
+		if column.subquery == "" {
+			return cond, nil
+		}
+
+		sql, args, err := cond.ToSql()
+		if err != nil {
+			return nil, err
+		}
+		return squirrel.Expr(fmt.Sprintf(column.subquery, sql), append(append([]any{}, column.subqueryArgs...), args...)...), nil
+	}
+
+	return nil, scim.BadRequest(scim.TypeInvalidFilter, "unsupported filter")
+}
+
+func scimColumnCondition(column scimColumn, op string, value any) (squirrel.Sqlizer, error) {
+	col := column.name
+
+	if op == scim.OpPresent {
+		if column.kind == scimString {
+			return squirrel.Expr(fmt.Sprintf("(%s IS NOT NULL AND %s <> '')", col, col)), nil
+		}
+		return squirrel.Expr(col + " IS NOT NULL"), nil
+	}
+
+	switch column.kind {
+	case scimString:
+		str, ok := value.(string)
+		if !ok {
+			return nil, scim.BadRequest(scim.TypeInvalidFilter, "attribute %s expects a string value", col)
+		}
+
+		// externalId сравнивается с учётом регистра (caseExact в RFC 7643)
+		if column.caseExact {
+			switch op {
+			case scim.OpEqual:
+				return squirrel.Eq{col: str}, nil
+			case scim.OpNotEqual:
+				return squirrel.Expr(fmt.Sprintf("(%s IS NULL OR %s <> ?)", col, col), str), nil
+			}
+		}
+
+		switch op {
+		case scim.OpEqual:
+			return squirrel.Expr(fmt.Sprintf("lower(%s) = lower(?)", col), str), nil
+		case scim.OpNotEqual:
+			return squirrel.Expr(fmt.Sprintf("(%s IS NULL OR lower(%s) <> lower(?))", col, col), str), nil
+		case scim.OpContains:
+			return squirrel.Expr(fmt.Sprintf("lower(%s) LIKE ?", col), "%"+escapeLike(strings.ToLower(str))+"%"), nil
+		case scim.OpStartsWith:
+			return squirrel.Expr(fmt.Sprintf("lower(%s) LIKE ?", col), escapeLike(strings.ToLower(str))+"%"), nil
+		case scim.OpEndsWith:
+			return squirrel.Expr(fmt.Sprintf("lower(%s) LIKE ?", col), "%"+escapeLike(strings.ToLower(str))), nil
+		}
+		return orderingCondition(fmt.Sprintf("lower(%s)", col), op, strings.ToLower(str))
+
+	case scimBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, scim.BadRequest(scim.TypeInvalidFilter, "attribute %s expects a boolean value", col)
+		}
+
+		switch op {
+		case scim.OpEqual:
+			return squirrel.Eq{col: b}, nil
+		case scim.OpNotEqual:
+			return squirrel.NotEq{col: b}, nil
+		}
+
+	case scimTime:
+		str, _ := value.(string)
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			return nil, scim.BadRequest(scim.TypeInvalidFilter, "attribute %s expects an RFC 3339 timestamp", col)
+		}
+
+		switch op {
+		case scim.OpEqual:
+			return squirrel.Eq{col: t}, nil
+		case scim.OpNotEqual:
+			return squirrel.NotEq{col: t}, nil
+		}
+		return orderingCondition(col, op, t)
+
+	case scimUUID:
+		str, _ := value.(string)
+		id, err := uuid.Parse(str)
+
+		switch op {
+		case scim.OpEqual:
+			if err != nil {
+				return squirrel.Expr("FALSE"), nil
+			}
+			return squirrel.Eq{col: id}, nil
+		case scim.OpNotEqual:
+			if err != nil {
+				return squirrel.Expr("TRUE"), nil
+			}
+			return squirrel.Expr(fmt.Sprintf("(%s IS NULL OR %s <> ?)", col, col), id), nil
+		}
+	}
+
+	return nil, scim.BadRequest(scim.TypeInvalidFilter, "operator %q is not supported for attribute %s", op, col)
+}
+
+func orderingCondition(col, op string, value any) (squirrel.Sqlizer, error) {
+	switch op {
+	case scim.OpGreater:
+		return squirrel.Expr(col+" > ?", value), nil
+	case scim.OpGreaterOrEqual:
+		return squirrel.Expr(col+" >= ?", value), nil
+	case scim.OpLess:
+		return squirrel.Expr(col+" < ?", value), nil
+	case scim.OpLessOrEqual:
+		return squirrel.Expr(col+" <= ?", value), nil
+	}
+
+	return nil, scim.BadRequest(scim.TypeInvalidFilter, "operator %q is not supported for attribute %s", op, col)
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+type notCondition struct {
+	cond squirrel.Sqlizer
+}
+
+func (n notCondition) ToSql() (string, []any, error) {
+	sql, args, err := n.cond.ToSql()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return "NOT (" + sql + ")", args, nil
+}
+
+// SaveGroup создаёт или заменяет отдел группы SCIM вместе с составом и externalId в одной
+// транзакции. false - заменяемый отдел удалён или архивирован, изменения не применены
+func (r *DirectoryRepo) SaveGroup(ctx context.Context, change *models.GroupChange) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	dep := change.Department
+	if change.Create {
+		query := r.builder.Insert("departments").
+			Columns("department_id", "name", "parent_id", "head_user_id", "created_at", "updated_at").
+			Values(dep.DepartmentID, dep.Name, dep.ParentID, dep.HeadUserID, dep.CreatedAt, dep.UpdatedAt)
+		if err := execTx(ctx, tx, query); err != nil {
+			return false, fmt.Errorf("failed to create department: %w", err)
+		}
+	} else {
+		// Блокировка строки отдела не даёт параллельной архивации разойтись с заменой
+		var locked uuid.UUID
+		err = tx.QueryRow(ctx, "SELECT department_id FROM departments WHERE department_id = $1 AND archived_at IS NULL FOR UPDATE", dep.DepartmentID).
+			Scan(&locked)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to lock department: %w", err)
+		}
+
+		query := r.builder.Update("departments").
+			Set("name", dep.Name).
+			Set("updated_at", dep.UpdatedAt).
+			Where(squirrel.Eq{"department_id": dep.DepartmentID})
+		if err := execTx(ctx, tx, query); err != nil {
+			return false, fmt.Errorf("failed to update department: %w", err)
+		}
+
+		// Состав задаётся целиком: не перечисленные сотрудники остаются без отдела
+		removed := r.builder.Update("users").
+			Set("department_id", nil).
+			Set("updated_at", dep.UpdatedAt).
+			Where(squirrel.Eq{"department_id": dep.DepartmentID}).
+			Where(squirrel.NotEq{"user_id": change.MemberIDs})
+		if err := execTx(ctx, tx, removed); err != nil {
+			return false, fmt.Errorf("failed to remove group members: %w", err)
+		}
+	}
+
+	if len(change.MemberIDs) > 0 {
+		added := r.builder.Update("users").
+			Set("department_id", dep.DepartmentID).
+			Set("updated_at", dep.UpdatedAt).
+			Where(squirrel.Eq{"user_id": change.MemberIDs}).
+			Where("department_id IS DISTINCT FROM ?", dep.DepartmentID)
+		if err := execTx(ctx, tx, added); err != nil {
+			return false, fmt.Errorf("failed to add group members: %w", err)
+		}
+	}
+
+	links := r.builder.Delete("directory_sync_links").
+		Where(squirrel.Eq{"source": models.SyncSourceSCIM, "object_type": models.SyncObjectDepartment, "local_id": dep.DepartmentID})
+	if err := execTx(ctx, tx, links); err != nil {
+		return false, fmt.Errorf("failed to delete sync link: %w", err)
+	}
+	if change.ExternalID != "" {
+		query := r.builder.Insert("directory_sync_links").
+			Columns("source", "object_type", "external_id", "local_id", "synced_at").
+			Values(models.SyncSourceSCIM, models.SyncObjectDepartment, change.ExternalID, dep.DepartmentID, dep.UpdatedAt).
+			Suffix("ON CONFLICT (source, object_type, external_id) DO UPDATE SET local_id = EXCLUDED.local_id, synced_at = EXCLUDED.synced_at")
+		if err := execTx(ctx, tx, query); err != nil {
+			return false, fmt.Errorf("failed to save sync link: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,150 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/Masterminds/squirrel"
+)
+
+// Прибавка к релевантности, если имя, фамилия или email начинаются с запроса
+const searchPrefixBoost = 0.2
+
+// searchColumns - выражения, по которым считается сходство. Они совпадают с
+// выражениями триграммных индексов (migrations/000001_users_search_trgm.up.sql),
+// иначе фильтр %> не использует индекс
+var searchColumns = []struct {
+	field  string
+	expr   string
+	weight float64
+}{
+	{field: models.UserSearchName, expr: "(u.first_name || ' ' || u.last_name)", weight: 1},
+	{field: models.UserSearchName, expr: "(u.last_name || ' ' || u.first_name)", weight: 1},
+	{field: models.UserSearchEmail, expr: "split_part(u.email, '@', 1)", weight: 1},
+	// Должность общая у многих, поэтому весит меньше имени
+	{field: models.UserSearchPosition, expr: "coalesce(u.position, '')", weight: 0.8},
+}
+
+// SearchUsers ищет пользователей по триграммному сходству (расширение pg_trgm).
+// Кандидаты отбираются по индексу оператором %>, затем ранжируются: релевантность -
+// лучшее word_similarity по всем вариантам запроса и полям
+func (r *DirectoryRepo) SearchUsers(ctx context.Context, search *models.UserSearch) ([]*models.UserMatch, error) {
+	var (
+		scores     []string
+		args       []any
+		candidates squirrel.Or
+	)
+	for _, term := range search.Terms {
+		for _, column := range searchColumns {
+			if !slices.Contains(search.Fields, column.field) {
+				continue
+			}
+			scores = append(scores, fmt.Sprintf("%v * word_similarity(?, %s)", column.weight, column.expr))
+			args = append(args, term)
+			candidates = append(candidates, squirrel.Expr(column.expr+" %> ?", term))
+		}
+	}
+	if len(scores) == 0 {
+		return nil, nil
+	}
+
+	var prefixes []string
+	for _, term := range search.Terms {
+		pattern := escapeLike(term) + "%"
+		prefixes = append(prefixes, "lower(u.first_name) LIKE ?", "lower(u.last_name) LIKE ?")
+		args = append(args, pattern, pattern)
+		if slices.Contains(search.Fields, models.UserSearchEmail) {
+			prefixes = append(prefixes, "lower(u.email) LIKE ?")
+			args = append(args, pattern)
+		}
+	}
+
+	score := fmt.Sprintf("LEAST(1, GREATEST(%s) + CASE WHEN %s THEN %v ELSE 0 END)",
+		strings.Join(scores, ", "), strings.Join(prefixes, " OR "), searchPrefixBoost)
+
+	inner := r.builder.Select("u.user_id, u.email, u.first_name, u.last_name, u.position, u.department_id, u.avatar_url, u.is_active, u.created_at, u.updated_at, u.deactivated_at").
+		Column(squirrel.Alias(squirrel.Expr(score, args...), "score")).
+		From("users u").
+		Where(candidates)
+
+	if search.ActiveOnly {
+		inner = inner.Where(squirrel.Eq{"u.is_active": true})
+	}
+	if search.DepartmentID != nil {
+		if search.Subtree {
+			inner = inner.Where(`u.department_id IN (
+				WITH RECURSIVE subtree AS (
+					SELECT department_id FROM departments WHERE department_id = ? AND archived_at IS NULL
+					UNION
+					SELECT d.department_id FROM departments d JOIN subtree t ON d.parent_id = t.department_id
+					WHERE d.archived_at IS NULL
+				)
+				SELECT department_id FROM subtree)`, *search.DepartmentID)
+		} else {
+			inner = inner.Where(squirrel.Eq{"u.department_id": *search.DepartmentID})
+		}
+	}
+
+	query := r.builder.Select("*").
+		FromSelect(inner, "s").
+		Where("s.score >= ?", search.MinSimilarity).
+		OrderBy("s.score DESC", "s.last_name", "s.first_name", "s.user_id").
+		Limit(uint64(search.Limit))
+
+	sqlQuery, queryArgs, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Порог %> - нижняя граница итоговой релевантности: прибавка за префикс и вес
+	// поля могут поднять или опустить оценку, точный отбор - по score ниже
+	threshold := max(search.MinSimilarity-searchPrefixBoost, 0)
+	_, err = tx.Exec(ctx, "SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)",
+		strconv.FormatFloat(threshold, 'f', -1, 64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set similarity threshold: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, sqlQuery, queryArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []*models.UserMatch
+	for rows.Next() {
+		var user models.User
+		var match models.UserMatch
+		err := rows.Scan(
+			&user.UserID,
+			&user.Email,
+			&user.FirstName,
+			&user.LastName,
+			&user.Position,
+			&user.DepartmentID,
+			&user.AvatarURL,
+			&user.IsActive,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.DeactivatedAt,
+			&match.Score,
+		)
+		if err != nil {
+			return nil, err
+		}
+		match.User = &user
+		matches = append(matches, &match)
+	}
+
+	return matches, rows.Err()
+}
@@ -0,0 +1,266 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Ключ pg_advisory_xact_lock: применять синхронизацию одновременно может только один инстанс
+const directorySyncLockID = 7_420_001
+
+func (r *DirectoryRepo) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	query := r.builder.Select("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
+		From("users")
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *DirectoryRepo) GetSyncLinks(ctx context.Context, source string) ([]*models.SyncLink, error) {
+	query := r.builder.Select("source, object_type, external_id, local_id, synced_at").
+		From("directory_sync_links").
+		Where(squirrel.Eq{"source": source})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []*models.SyncLink
+	for rows.Next() {
+		var link models.SyncLink
+		err = rows.Scan(&link.Source, &link.ObjectType, &link.ExternalID, &link.LocalID, &link.SyncedAt)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, &link)
+	}
+
+	return links, rows.Err()
+}
+
+// ApplySyncChanges применяет изменения прогона синхронизации в одной транзакции
+func (r *DirectoryRepo) ApplySyncChanges(ctx context.Context, changes *models.SyncChanges) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", directorySyncLockID); err != nil {
+		return fmt.Errorf("failed to lock directory sync: %w", err)
+	}
+
+	for _, dep := range changes.CreateDepartments {
+		query := r.builder.Insert("departments").
+			Columns("department_id", "name", "parent_id", "created_at", "updated_at").
+			Values(dep.DepartmentID, dep.Name, dep.ParentID, dep.CreatedAt, dep.UpdatedAt)
+		if err := execTx(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to create department %s: %w", dep.Name, err)
+		}
+	}
+
+	for _, dep := range changes.UpdateDepartments {
+		query := r.builder.Update("departments").
+			Set("name", dep.Name).
+			Set("parent_id", dep.ParentID).
+			Set("updated_at", dep.UpdatedAt).
+			Where(squirrel.Eq{"department_id": dep.DepartmentID})
+		if err := execTx(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to update department %s: %w", dep.Name, err)
+		}
+	}
+
+	for _, user := range changes.CreateUsers {
+		query := r.builder.Insert("users").
+			Columns("user_id, email, first_name, last_name, position, department_id, avatar_url, is_active, created_at, updated_at, deactivated_at").
+			Values(user.UserID, user.Email, user.FirstName, user.LastName, user.Position, user.DepartmentID, user.AvatarURL, user.IsActive, user.CreatedAt, user.UpdatedAt, deactivatedAt(user))
+		if err := execTx(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to create user %s: %w", user.Email, err)
+		}
+	}
+
+	for _, user := range changes.UpdateUsers {
+		query := r.builder.Update("users").
+			Set("email", user.Email).
+			Set("first_name", user.FirstName).
+			Set("last_name", user.LastName).
+			Set("position", user.Position).
+			Set("department_id", user.DepartmentID).
+			Set("is_active", user.IsActive).
+			Set("deactivated_at", deactivatedAtExpr(user)).
+			Set("updated_at", user.UpdatedAt).
+			Where(squirrel.Eq{"user_id": user.UserID})
+		if err := execTx(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to update user %s: %w", user.Email, err)
+		}
+	}
+
+	if len(changes.DeactivateUsers) > 0 {
+		// Как и при ручном увольнении, руководство отделами снимается
+		unsetHead := r.builder.Update("departments").
+			Set("head_user_id", nil).
+			Set("updated_at", changes.UpdatedAt).
+			Where(squirrel.Eq{"head_user_id": changes.DeactivateUsers})
+		if err := execTx(ctx, tx, unsetHead); err != nil {
+			return fmt.Errorf("failed to unset department heads: %w", err)
+		}
+
+		query := r.builder.Update("users").
+			Set("is_active", false).
+			Set("deactivated_at", squirrel.Expr("COALESCE(deactivated_at, ?)", changes.UpdatedAt)).
+			Set("updated_at", changes.UpdatedAt).
+			Where(squirrel.Eq{"user_id": changes.DeactivateUsers})
+		if err := execTx(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to deactivate users: %w", err)
+		}
+	}
+
+	for _, link := range changes.Links {
+		query := r.builder.Insert("directory_sync_links").
+			Columns("source", "object_type", "external_id", "local_id", "synced_at").
+			Values(link.Source, link.ObjectType, link.ExternalID, link.LocalID, link.SyncedAt).
+			Suffix("ON CONFLICT (source, object_type, external_id) DO UPDATE SET local_id = EXCLUDED.local_id, synced_at = EXCLUDED.synced_at")
+		if err := execTx(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to save sync link: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func execTx(ctx context.Context, tx pgx.Tx, query squirrel.Sqlizer) error {
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, sqlQuery, args...)
+	return err
+}
+
+// FindSyncLink - связь по внешнему идентификатору, nil если не найдена
+func (r *DirectoryRepo) FindSyncLink(ctx context.Context, source string, objectType models.SyncObjectType, externalID string) (*models.SyncLink, error) {
+	query := r.builder.Select("source, object_type, external_id, local_id, synced_at").
+		From("directory_sync_links").
+		Where(squirrel.Eq{"source": source, "object_type": objectType, "external_id": externalID})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var link models.SyncLink
+	err = r.db.QueryRow(ctx, sqlQuery, args...).Scan(&link.Source, &link.ObjectType, &link.ExternalID, &link.LocalID, &link.SyncedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// GetExternalIDs - внешние идентификаторы локальных записей источника
+func (r *DirectoryRepo) GetExternalIDs(ctx context.Context, source string, objectType models.SyncObjectType, localIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	externalIDs := make(map[uuid.UUID]string, len(localIDs))
+	if len(localIDs) == 0 {
+		return externalIDs, nil
+	}
+
+	query := r.builder.Select("local_id, external_id").
+		From("directory_sync_links").
+		Where(squirrel.Eq{"source": source, "object_type": objectType, "local_id": localIDs})
+
+	sqlQuery, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			localID    uuid.UUID
+			externalID string
+		)
+		if err := rows.Scan(&localID, &externalID); err != nil {
+			return nil, err
+		}
+		externalIDs[localID] = externalID
+	}
+
+	return externalIDs, rows.Err()
+}
+
+// SetExternalID заменяет внешний идентификатор локальной записи; пустой externalID удаляет связь
+func (r *DirectoryRepo) SetExternalID(ctx context.Context, source string, objectType models.SyncObjectType, localID uuid.UUID, externalID string, syncedAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := r.builder.Delete("directory_sync_links").
+		Where(squirrel.Eq{"source": source, "object_type": objectType, "local_id": localID})
+	if err := execTx(ctx, tx, query); err != nil {
+		return fmt.Errorf("failed to delete sync link: %w", err)
+	}
+
+	if externalID != "" {
+		query := r.builder.Insert("directory_sync_links").
+			Columns("source", "object_type", "external_id", "local_id", "synced_at").
+			Values(source, objectType, externalID, localID, syncedAt).
+			Suffix("ON CONFLICT (source, object_type, external_id) DO UPDATE SET local_id = EXCLUDED.local_id, synced_at = EXCLUDED.synced_at")
+		if err := execTx(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to save sync link: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
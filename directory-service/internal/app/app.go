@@ -27,8 +27,9 @@ func NewApp(cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	server := v1.NewServer(cfg.Port, cfg.ReadTimeout, cfg.WriteTimeout, db.Pool)
-	err = server.RegisterHandlers()
+	server := v1.NewServer(cfg.Port, cfg.ReadTimeout, cfg.WriteTimeout, cfg.LDAPConfig, cfg.IdentityServiceConfig,
+		cfg.ChatServiceConfig, cfg.SMTPConfig, db.Pool)
+	err = server.RegisterHandlers(cfg.SyncConfig, cfg.SCIMConfig, cfg.OnboardingConfig, cfg.ProfileConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to register handlers: %w", err)
 	}
@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Client interface {
+	JoinDepartmentChat(ctx context.Context, depID uuid.UUID, depName string, userID uuid.UUID) (uuid.UUID, error)
+}
+
+type HTTPClient struct {
+	baseURL       string
+	internalToken string
+	httpClient    *http.Client
+	timeout       time.Duration
+}
+
+func NewHTTPClient(cfg ChatServiceConfig) *HTTPClient {
+	return &HTTPClient{
+		baseURL:       cfg.URL,
+		internalToken: cfg.InternalToken,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		timeout: cfg.Timeout,
+	}
+}
+
+type joinDepartmentChatRequest struct {
+	DepartmentName string `json:"department_name"`
+}
+
+type departmentChatResponse struct {
+	ChatID uuid.UUID `json:"chat_id"`
+}
+
+// JoinDepartmentChat добавляет сотрудника в чат отдела; чат создаётся при первом вызове
+func (c *HTTPClient) JoinDepartmentChat(ctx context.Context, depID uuid.UUID, depName string, userID uuid.UUID) (uuid.UUID, error) {
+	body, err := json.Marshal(joinDepartmentChatRequest{DepartmentName: depName})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/departments/%s/members/%s", c.baseURL, depID, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return uuid.Nil, fmt.Errorf("chat service returned %d", resp.StatusCode)
+	}
+
+	var chat departmentChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to decode data to departmentChatResponse: %w", err)
+	}
+
+	return chat.ChatID, nil
+}
@@ -0,0 +1,159 @@
+package identity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Client interface {
+	ProvisionAccount(ctx context.Context, userID uuid.UUID, req *ProvisionAccountRequest) (*AccountResponse, error)
+	DeprovisionAccount(ctx context.Context, userID uuid.UUID) error
+	ReactivateAccount(ctx context.Context, userID uuid.UUID) error
+	IssuePasswordReset(ctx context.Context, userID uuid.UUID) (*PasswordResetResponse, error)
+}
+
+type HTTPClient struct {
+	baseURL       string
+	internalToken string
+	httpClient    *http.Client
+	timeout       time.Duration
+}
+
+func NewHTTPClient(cfg IdentityServiceConfig) *HTTPClient {
+	return &HTTPClient{
+		baseURL:       cfg.URL,
+		internalToken: cfg.InternalToken,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		timeout: cfg.Timeout,
+	}
+}
+
+// ProvisionAccount создаёт аккаунт сотрудника в identity-service или обновляет существующий
+func (c *HTTPClient) ProvisionAccount(ctx context.Context, userID uuid.UUID, provision *ProvisionAccountRequest) (*AccountResponse, error) {
+	body, err := json.Marshal(provision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/accounts/%s", c.baseURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+	case http.StatusConflict:
+		return nil, ErrEmailTaken
+	case http.StatusBadRequest:
+		var errResp errorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAccount, errResp.Error)
+	default:
+		return nil, fmt.Errorf("identity service returned %d", resp.StatusCode)
+	}
+
+	var account AccountResponse
+	if err := json.NewDecoder(resp.Body).Decode(&account); err != nil {
+		return nil, fmt.Errorf("failed to decode data to AccountResponse: %w", err)
+	}
+
+	return &account, nil
+}
+
+// DeprovisionAccount деактивирует аккаунт сотрудника
+func (c *HTTPClient) DeprovisionAccount(ctx context.Context, userID uuid.UUID) error {
+	url := fmt.Sprintf("%s/accounts/%s", c.baseURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrAccountNotFound
+	default:
+		return fmt.Errorf("identity service returned %d", resp.StatusCode)
+	}
+}
+
+// ReactivateAccount снова открывает вход в аккаунт восстановленного сотрудника
+func (c *HTTPClient) ReactivateAccount(ctx context.Context, userID uuid.UUID) error {
+	url := fmt.Sprintf("%s/accounts/%s/reactivate", c.baseURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrAccountNotFound
+	default:
+		return fmt.Errorf("identity service returned %d", resp.StatusCode)
+	}
+}
+
+// IssuePasswordReset выдаёт одноразовый токен, по которому сотрудник задаст пароль
+func (c *HTTPClient) IssuePasswordReset(ctx context.Context, userID uuid.UUID) (*PasswordResetResponse, error) {
+	url := fmt.Sprintf("%s/accounts/%s/password-reset", c.baseURL, userID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrAccountNotFound
+	default:
+		return nil, fmt.Errorf("identity service returned %d", resp.StatusCode)
+	}
+
+	var reset PasswordResetResponse
+	if err := json.NewDecoder(resp.Body).Decode(&reset); err != nil {
+		return nil, fmt.Errorf("failed to decode data to PasswordResetResponse: %w", err)
+	}
+
+	return &reset, nil
+}
@@ -0,0 +1,9 @@
+package identity
+
+import "time"
+
+type IdentityServiceConfig struct {
+	URL           string        `env:"IDENTITY_INTERNAL_URL" env-default:"http://localhost:8081/internal"`
+	Timeout       time.Duration `env:"IDENTITY_SERVICE_TIMEOUT" env-default:"5s"`
+	InternalToken string        `env:"INTERNAL_API_TOKEN"`
+}
@@ -0,0 +1,38 @@
+package identity
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrEmailTaken      = errors.New("account with this email already exists")
+	ErrInvalidAccount  = errors.New("identity service rejected account")
+)
+
+// ProvisionAccountRequest - желаемое состояние аккаунта сотрудника. Пустая роль
+// оставляет текущую (у нового аккаунта - user), пустой пароль - не меняет его
+type ProvisionAccountRequest struct {
+	Email    string  `json:"email"`
+	Role     string  `json:"role,omitempty"`
+	IsActive *bool   `json:"is_active,omitempty"`
+	Password *string `json:"password,omitempty"`
+}
+
+type AccountResponse struct {
+	AccountID string `json:"account_id"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	IsActive  bool   `json:"is_active"`
+}
+
+type PasswordResetResponse struct {
+	ResetToken string    `json:"reset_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
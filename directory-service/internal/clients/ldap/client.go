@@ -0,0 +1,295 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// Флаг ACCOUNTDISABLE в userAccountControl Active Directory
+const accountDisabledFlag = 0x2
+
+type Client struct {
+	cfg LDAPConfig
+}
+
+func NewClient(cfg LDAPConfig) *Client {
+	return &Client{
+		cfg: cfg,
+	}
+}
+
+// FetchDirectory читает отделы и пользователей под BaseDN и приводит их к моделям синхронизации
+func (c *Client) FetchDirectory(ctx context.Context) (*Snapshot, error) {
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// go-ldap не принимает контекст - прерываем запросы закрытием соединения
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	attrs := c.cfg.Attributes
+
+	depEntries, err := c.search(conn, c.cfg.DepartmentFilter, []string{attrs.DepartmentID, attrs.DepartmentName})
+	if err != nil {
+		return nil, err
+	}
+	userEntries, err := c.search(conn, c.cfg.UserFilter, compactAttributes(
+		attrs.UserID, attrs.Email, attrs.FirstName, attrs.LastName, attrs.Position, attrs.Department, attrs.UserAccountControl,
+	))
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	departments, byDN, err := c.mapDepartments(depEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*Department, len(departments))
+	for _, dep := range departments {
+		byName[strings.ToLower(dep.Name)] = dep
+	}
+
+	users := make([]*User, 0, len(userEntries))
+	for _, entry := range userEntries {
+		user, err := c.mapUser(entry, byDN, byName)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return &Snapshot{
+		Departments: departments,
+		Users:       users,
+	}, nil
+}
+
+func (c *Client) connect() (*goldap.Conn, error) {
+	opts := []goldap.DialOpt{goldap.DialWithDialer(&net.Dialer{Timeout: c.cfg.Timeout})}
+	tlsCfg := &tls.Config{InsecureSkipVerify: c.cfg.InsecureSkipVerify}
+	if strings.HasPrefix(strings.ToLower(c.cfg.URL), "ldaps://") {
+		opts = append(opts, goldap.DialWithTLSConfig(tlsCfg))
+	}
+
+	conn, err := goldap.DialURL(c.cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrConnectFailed, err)
+	}
+	conn.SetTimeout(c.cfg.Timeout)
+
+	if c.cfg.StartTLS {
+		if err := conn.StartTLS(tlsCfg); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: starttls: %v", ErrConnectFailed, err)
+		}
+	}
+
+	if c.cfg.BindDN != "" {
+		if err := conn.Bind(c.cfg.BindDN, c.cfg.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: %v", ErrBindFailed, err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *Client) search(conn *goldap.Conn, filter string, attributes []string) ([]*goldap.Entry, error) {
+	req := goldap.NewSearchRequest(
+		c.cfg.BaseDN,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases,
+		0, 0, false,
+		filter,
+		attributes,
+		nil,
+	)
+
+	// AD отдаёт не больше 1000 записей за запрос, поэтому читаем постранично
+	result, err := conn.SearchWithPaging(req, c.cfg.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrSearchFailed, filter, err)
+	}
+
+	return result.Entries, nil
+}
+
+// mapDepartments строит иерархию: родитель отдела - ближайший предок по DN, который сам является отделом
+func (c *Client) mapDepartments(entries []*goldap.Entry) ([]*Department, map[string]*Department, error) {
+	type parsed struct {
+		dep  *Department
+		keys []string
+	}
+
+	items := make([]parsed, 0, len(entries))
+	byDN := make(map[string]*Department, len(entries))
+	for _, entry := range entries {
+		keys, err := dnKeys(entry.DN)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dep := &Department{
+			ExternalID: c.externalID(entry, c.cfg.Attributes.DepartmentID),
+			DN:         entry.DN,
+			Name:       entry.GetEqualFoldAttributeValue(c.cfg.Attributes.DepartmentName),
+		}
+		if dep.Name == "" {
+			dep.Name = firstRDNValue(entry.DN)
+		}
+
+		items = append(items, parsed{dep: dep, keys: keys})
+		byDN[keys[0]] = dep
+	}
+
+	for _, item := range items {
+		if parent := nearestAncestor(item.keys, byDN); parent != nil {
+			item.dep.ParentID = parent.ExternalID
+		}
+	}
+
+	// Чем короче DN, тем выше отдел в дереве
+	sort.SliceStable(items, func(i, j int) bool {
+		return len(items[i].keys) < len(items[j].keys)
+	})
+
+	departments := make([]*Department, len(items))
+	for i, item := range items {
+		departments[i] = item.dep
+	}
+
+	return departments, byDN, nil
+}
+
+func (c *Client) mapUser(entry *goldap.Entry, byDN, byName map[string]*Department) (*User, error) {
+	attrs := c.cfg.Attributes
+
+	user := &User{
+		ExternalID: c.externalID(entry, attrs.UserID),
+		DN:         entry.DN,
+		Email:      strings.TrimSpace(entry.GetEqualFoldAttributeValue(attrs.Email)),
+		FirstName:  entry.GetEqualFoldAttributeValue(attrs.FirstName),
+		LastName:   entry.GetEqualFoldAttributeValue(attrs.LastName),
+	}
+
+	if attrs.Position != "" {
+		if position := entry.GetEqualFoldAttributeValue(attrs.Position); position != "" {
+			user.Position = &position
+		}
+	}
+
+	if attrs.UserAccountControl != "" {
+		if uac := entry.GetEqualFoldAttributeValue(attrs.UserAccountControl); uac != "" {
+			flags, err := strconv.ParseInt(uac, 10, 64)
+			if err == nil && flags&accountDisabledFlag != 0 {
+				user.Disabled = true
+			}
+		}
+	}
+
+	var dep *Department
+	if attrs.Department != "" {
+		dep = byName[strings.ToLower(entry.GetEqualFoldAttributeValue(attrs.Department))]
+	} else {
+		keys, err := dnKeys(entry.DN)
+		if err != nil {
+			return nil, err
+		}
+		dep = nearestAncestor(keys, byDN)
+	}
+	if dep != nil {
+		user.DepartmentID = dep.ExternalID
+	}
+
+	return user, nil
+}
+
+// externalID - устойчивый идентификатор записи; без атрибута используется DN
+func (c *Client) externalID(entry *goldap.Entry, attribute string) string {
+	raw := entry.GetEqualFoldRawAttributeValue(attribute)
+	switch {
+	case len(raw) == 0:
+		return strings.ToLower(entry.DN)
+	case strings.EqualFold(attribute, "objectGUID") && len(raw) == 16:
+		return formatObjectGUID(raw)
+	case utf8.Valid(raw):
+		return string(raw)
+	default:
+		return hex.EncodeToString(raw)
+	}
+}
+
+// formatObjectGUID - objectGUID хранится в AD как GUID в смешанном порядке байт
+func formatObjectGUID(b []byte) string {
+	return fmt.Sprintf("%02x%02x%02x%02x-%02x%02x-%02x%02x-%02x%02x-%x",
+		b[3], b[2], b[1], b[0], b[5], b[4], b[7], b[6], b[8], b[9], b[10:])
+}
+
+// dnKeys возвращает нормализованный DN записи и всех её предков: keys[0] - сама запись
+func dnKeys(dn string) ([]string, error) {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dn %q: %w", dn, err)
+	}
+	if len(parsed.RDNs) == 0 {
+		return nil, fmt.Errorf("invalid dn %q: empty", dn)
+	}
+
+	rdns := make([]string, len(parsed.RDNs))
+	for i, rdn := range parsed.RDNs {
+		rdns[i] = strings.ToLower(rdn.String())
+	}
+
+	keys := make([]string, len(rdns))
+	for i := range rdns {
+		keys[i] = strings.Join(rdns[i:], ",")
+	}
+
+	return keys, nil
+}
+
+func nearestAncestor(keys []string, byDN map[string]*Department) *Department {
+	for _, key := range keys[1:] {
+		if dep, ok := byDN[key]; ok {
+			return dep
+		}
+	}
+
+	return nil
+}
+
+func firstRDNValue(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return dn
+	}
+
+	return parsed.RDNs[0].Attributes[0].Value
+}
+
+func compactAttributes(attributes ...string) []string {
+	result := make([]string, 0, len(attributes))
+	for _, attr := range attributes {
+		if attr != "" {
+			result = append(result, attr)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,36 @@
+package ldap
+
+import "time"
+
+// LDAPConfig - подключение к корпоративному каталогу (Active Directory / OpenLDAP)
+type LDAPConfig struct {
+	Enabled            bool          `env:"LDAP_ENABLED" env-default:"false"`
+	URL                string        `env:"LDAP_URL"`
+	BindDN             string        `env:"LDAP_BIND_DN"`
+	BindPassword       string        `env:"LDAP_BIND_PASSWORD"`
+	BaseDN             string        `env:"LDAP_BASE_DN"`
+	StartTLS           bool          `env:"LDAP_START_TLS" env-default:"false"`
+	InsecureSkipVerify bool          `env:"LDAP_TLS_INSECURE_SKIP_VERIFY" env-default:"false"`
+	UserFilter         string        `env:"LDAP_USER_FILTER" env-default:"(&(objectClass=user)(objectCategory=person))"`
+	DepartmentFilter   string        `env:"LDAP_DEPARTMENT_FILTER" env-default:"(objectClass=organizationalUnit)"`
+	PageSize           uint32        `env:"LDAP_PAGE_SIZE" env-default:"500"`
+	Timeout            time.Duration `env:"LDAP_TIMEOUT" env-default:"30s"`
+
+	Attributes AttributeMapping
+}
+
+// AttributeMapping - какие атрибуты каталога соответствуют полям пользователей и отделов.
+// Если Department пуст, отдел пользователя определяется по ближайшему родительскому
+// отделу в DN, иначе - по имени отдела из атрибута
+type AttributeMapping struct {
+	UserID             string `env:"LDAP_ATTR_USER_ID" env-default:"objectGUID"`
+	Email              string `env:"LDAP_ATTR_EMAIL" env-default:"mail"`
+	FirstName          string `env:"LDAP_ATTR_FIRST_NAME" env-default:"givenName"`
+	LastName           string `env:"LDAP_ATTR_LAST_NAME" env-default:"sn"`
+	Position           string `env:"LDAP_ATTR_POSITION" env-default:"title"`
+	Department         string `env:"LDAP_ATTR_DEPARTMENT"`
+	UserAccountControl string `env:"LDAP_ATTR_USER_ACCOUNT_CONTROL" env-default:"userAccountControl"`
+
+	DepartmentID   string `env:"LDAP_ATTR_DEPARTMENT_ID" env-default:"objectGUID"`
+	DepartmentName string `env:"LDAP_ATTR_DEPARTMENT_NAME" env-default:"ou"`
+}
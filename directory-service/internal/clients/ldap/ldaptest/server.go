@@ -0,0 +1,442 @@
+// Package ldaptest - LDAP-сервер в памяти для проверки синхронизации каталога без
+// Active Directory. Поддерживает simple bind, поиск с фильтрами and/or/not/equality/
+// substrings/present и все области поиска; управляющие параметры (paging) игнорируются
+package ldaptest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+const (
+	resultSuccess                 = 0
+	resultProtocolError           = 2
+	resultNoSuchObject            = 32
+	resultInvalidCredentials      = 49
+	resultInsufficientAccessRight = 50
+	resultUnwillingToPerform      = 53
+)
+
+type entry struct {
+	dn    *goldap.DN
+	raw   string
+	attrs map[string][]string
+}
+
+type Server struct {
+	listener net.Listener
+	bindDN   string
+	password string
+
+	mu      sync.RWMutex
+	entries []*entry
+	conns   map[net.Conn]struct{}
+	wg      sync.WaitGroup
+}
+
+// NewServer запускает сервер на случайном локальном порту. При пустом bindDN
+// разрешён анонимный доступ
+func NewServer(bindDN, password string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener: listener,
+		bindDN:   bindDN,
+		password: password,
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// URL - адрес сервера для LDAP_URL
+func (s *Server) URL() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// Put добавляет запись или заменяет атрибуты существующей
+func (s *Server) Put(dn string, attrs map[string][]string) error {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return fmt.Errorf("invalid dn %q: %w", dn, err)
+	}
+
+	copied := make(map[string][]string, len(attrs))
+	for name, values := range attrs {
+		copied[name] = append([]string(nil), values...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range s.entries {
+		if e.dn.EqualFold(parsed) {
+			e.attrs = copied
+			return nil
+		}
+	}
+	s.entries = append(s.entries, &entry{dn: parsed, raw: dn, attrs: copied})
+
+	return nil
+}
+
+// Delete удаляет запись; дочерние записи остаются
+func (s *Server) Delete(dn string) error {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return fmt.Errorf("invalid dn %q: %w", dn, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, e := range s.entries {
+		if e.dn.EqualFold(parsed) {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("entry %q not found", dn)
+}
+
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	bound := s.bindDN == ""
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		if op.ClassType != ber.ClassApplication {
+			return
+		}
+
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			code := s.bind(op)
+			bound = code == resultSuccess
+			err = writeResult(conn, messageID, goldap.ApplicationBindResponse, code, "")
+		case goldap.ApplicationSearchRequest:
+			if !bound {
+				err = writeResult(conn, messageID, goldap.ApplicationSearchResultDone, resultInsufficientAccessRight, "bind required")
+				break
+			}
+			err = s.search(conn, messageID, op)
+		case goldap.ApplicationUnbindRequest:
+			return
+		case goldap.ApplicationAbandonRequest:
+		default:
+			// Ответ на любую неподдерживаемую операцию имеет следующий номер приложения
+			err = writeResult(conn, messageID, op.Tag+1, resultUnwillingToPerform, "operation not supported")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 {
+		return resultProtocolError
+	}
+
+	name := packetString(op.Children[1])
+	password := packetString(op.Children[2])
+
+	if s.bindDN == "" && name == "" {
+		return resultSuccess
+	}
+
+	bindDN, err := goldap.ParseDN(name)
+	if err != nil {
+		return resultInvalidCredentials
+	}
+	expected, err := goldap.ParseDN(s.bindDN)
+	if err != nil || !expected.EqualFold(bindDN) || password != s.password {
+		return resultInvalidCredentials
+	}
+
+	return resultSuccess
+}
+
+func (s *Server) search(w io.Writer, messageID int64, op *ber.Packet) error {
+	if len(op.Children) < 8 {
+		return writeResult(w, messageID, goldap.ApplicationSearchResultDone, resultProtocolError, "malformed search request")
+	}
+
+	base, err := goldap.ParseDN(packetString(op.Children[0]))
+	if err != nil {
+		return writeResult(w, messageID, goldap.ApplicationSearchResultDone, resultProtocolError, "invalid base dn")
+	}
+	scope, _ := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+
+	var requested []string
+	for _, attr := range op.Children[7].Children {
+		requested = append(requested, packetString(attr))
+	}
+
+	s.mu.RLock()
+	var found bool
+	var matched []*entry
+	for _, e := range s.entries {
+		if !inScope(base, e.dn, scope) {
+			continue
+		}
+		found = true
+
+		ok, err := matchFilter(filter, e)
+		if err != nil {
+			s.mu.RUnlock()
+			return writeResult(w, messageID, goldap.ApplicationSearchResultDone, resultProtocolError, err.Error())
+		}
+		if ok {
+			matched = append(matched, e)
+		}
+	}
+	s.mu.RUnlock()
+
+	if !found && len(base.RDNs) > 0 {
+		return writeResult(w, messageID, goldap.ApplicationSearchResultDone, resultNoSuchObject, "")
+	}
+
+	for _, e := range matched {
+		if err := writeEntry(w, messageID, e, requested); err != nil {
+			return err
+		}
+	}
+
+	return writeResult(w, messageID, goldap.ApplicationSearchResultDone, resultSuccess, "")
+}
+
+func inScope(base, dn *goldap.DN, scope int64) bool {
+	switch scope {
+	case goldap.ScopeBaseObject:
+		return base.EqualFold(dn)
+	case goldap.ScopeSingleLevel:
+		return len(dn.RDNs) == len(base.RDNs)+1 && base.AncestorOfFold(dn)
+	default:
+		return base.EqualFold(dn) || base.AncestorOfFold(dn)
+	}
+}
+
+func matchFilter(filter *ber.Packet, e *entry) (bool, error) {
+	if filter.ClassType != ber.ClassContext {
+		return false, errors.New("invalid filter")
+	}
+
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			ok, err := matchFilter(child, e)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			ok, err := matchFilter(child, e)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case goldap.FilterNot:
+		if len(filter.Children) != 1 {
+			return false, errors.New("invalid not filter")
+		}
+		ok, err := matchFilter(filter.Children[0], e)
+		return !ok, err
+	case goldap.FilterPresent:
+		return len(e.values(packetString(filter))) > 0, nil
+	case goldap.FilterEqualityMatch, goldap.FilterApproxMatch, goldap.FilterGreaterOrEqual, goldap.FilterLessOrEqual:
+		if len(filter.Children) != 2 {
+			return false, errors.New("invalid comparison filter")
+		}
+		expected := strings.ToLower(packetString(filter.Children[1]))
+		for _, value := range e.values(packetString(filter.Children[0])) {
+			value = strings.ToLower(value)
+			switch {
+			case filter.Tag == goldap.FilterGreaterOrEqual && value >= expected,
+				filter.Tag == goldap.FilterLessOrEqual && value <= expected,
+				value == expected:
+				return true, nil
+			}
+		}
+		return false, nil
+	case goldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false, errors.New("invalid substrings filter")
+		}
+		for _, value := range e.values(packetString(filter.Children[0])) {
+			if matchSubstrings(strings.ToLower(value), filter.Children[1].Children) {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported filter %s", goldap.FilterMap[uint64(filter.Tag)])
+	}
+}
+
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		sub := strings.ToLower(packetString(part))
+		switch part.Tag {
+		case goldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, sub) {
+				return false
+			}
+			value = value[len(sub):]
+		case goldap.FilterSubstringsAny:
+			idx := strings.Index(value, sub)
+			if idx < 0 {
+				return false
+			}
+			value = value[idx+len(sub):]
+		case goldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, sub) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// values - значения атрибута без учёта регистра имени
+func (e *entry) values(name string) []string {
+	for attr, values := range e.attrs {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+
+	return nil
+}
+
+func writeEntry(w io.Writer, messageID int64, e *entry, requested []string) error {
+	all := len(requested) == 0
+	for _, attr := range requested {
+		if attr == "*" {
+			all = true
+		}
+	}
+
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range e.attrs {
+		if !all && !containsFold(requested, name) {
+			continue
+		}
+
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.raw, "Object Name"))
+	op.AppendChild(attrs)
+
+	return writeMessage(w, messageID, op)
+}
+
+func writeResult(w io.Writer, messageID int64, application ber.Tag, code int, message string) error {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, application, nil, goldap.ApplicationMap[uint8(application)])
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+
+	return writeMessage(w, messageID, op)
+}
+
+func writeMessage(w io.Writer, messageID int64, op *ber.Packet) error {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+
+	_, err := w.Write(packet.Bytes())
+	return err
+}
+
+func packetString(p *ber.Packet) string {
+	if value, ok := p.Value.(string); ok {
+		return value
+	}
+
+	return p.Data.String()
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+
+	return false
+}
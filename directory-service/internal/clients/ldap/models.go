@@ -0,0 +1,36 @@
+package ldap
+
+import "errors"
+
+var (
+	ErrConnectFailed = errors.New("failed to connect to ldap server")
+	ErrBindFailed    = errors.New("failed to bind to ldap server")
+	ErrSearchFailed  = errors.New("ldap search failed")
+)
+
+// Department - отдел из каталога. ParentID - внешний идентификатор родителя,
+// пустой для корневых отделов
+type Department struct {
+	ExternalID string
+	DN         string
+	Name       string
+	ParentID   string
+}
+
+// User - пользователь из каталога. DepartmentID - внешний идентификатор отдела
+type User struct {
+	ExternalID   string
+	DN           string
+	Email        string
+	FirstName    string
+	LastName     string
+	Position     *string
+	DepartmentID string
+	Disabled     bool
+}
+
+// Snapshot - содержимое каталога; родительские отделы идут раньше дочерних
+type Snapshot struct {
+	Departments []*Department
+	Users       []*User
+}
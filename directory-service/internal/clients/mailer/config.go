@@ -0,0 +1,13 @@
+package mailer
+
+import "time"
+
+// SMTPConfig - почтовый сервер для приглашений. Без SMTP_HOST отправка отключена
+type SMTPConfig struct {
+	Host     string        `env:"SMTP_HOST"`
+	Port     int           `env:"SMTP_PORT" env-default:"587"`
+	Username string        `env:"SMTP_USERNAME"`
+	Password string        `env:"SMTP_PASSWORD"`
+	From     string        `env:"SMTP_FROM" env-default:"no-reply@corp.local"`
+	Timeout  time.Duration `env:"SMTP_TIMEOUT" env-default:"10s"`
+}
@@ -5,6 +5,11 @@ import (
 	"os"
 	"time"
 
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/chat"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/ldap"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/mailer"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/usecase"
 	postgres "github.com/I-Van-Radkov/corporate-messenger/directory-service/pkg/db"
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -17,6 +22,22 @@ type Config struct {
 	GHTimeout time.Duration `env:"GRACEFUL_SHUTDOWN_TIMEOUT"`
 
 	postgres.PostgresConfig
+
+	ldap.LDAPConfig
+
+	usecase.SyncConfig
+
+	identity.IdentityServiceConfig
+
+	usecase.SCIMConfig
+
+	chat.ChatServiceConfig
+
+	mailer.SMTPConfig
+
+	usecase.OnboardingConfig
+
+	usecase.ProfileConfig
 }
 
 func ParseConfigFromEnv() (*Config, error) {
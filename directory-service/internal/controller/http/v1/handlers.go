@@ -2,9 +2,12 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"net/http"
 
 	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -12,12 +15,18 @@ import (
 type DirectoryUsecase interface {
 	CreateDepartment(ctx context.Context, req *dto.CreateDepartmentRequest) (*dto.CreateDepartmentResponse, error)
 	GetDepartments(ctx context.Context, req *dto.GetDepartmentsRequest) (*dto.GetDepartmentsResponse, error)
-	RemoveDepartment(ctx context.Context, id uuid.UUID) error
+	UpdateDepartment(ctx context.Context, id uuid.UUID, req *dto.UpdateDepartmentRequest) (*models.Department, error)
+	GetDepartmentSubtree(ctx context.Context, id uuid.UUID, req *dto.GetDepartmentSubtreeRequest) (*dto.DepartmentTreeNode, error)
+	GetDepartmentAncestors(ctx context.Context, id uuid.UUID) (*dto.GetDepartmentAncestorsResponse, error)
+	GetDepartmentPath(ctx context.Context, id uuid.UUID) (*dto.GetDepartmentPathResponse, error)
+	RemoveDepartment(ctx context.Context, id uuid.UUID, req *dto.RemoveDepartmentQuery) (*dto.RemoveDepartmentResponse, error)
 	GetDepartmentMembers(ctx context.Context, req *dto.GetDepartmentMembersRequest) (*dto.GetDepartmentMembersResponse, error)
 	CreateUser(ctx context.Context, req *dto.CreateUserRequest) (*dto.CreateUserResponse, error)
 	GetUsers(ctx context.Context, req *dto.GetUsersRequest) (*dto.GetUsersResponse, error)
 	GetUser(ctx context.Context, id uuid.UUID) (*dto.UserResponse, error)
-	RemoveUser(ctx context.Context, id uuid.UUID) error
+	UpdateUser(ctx context.Context, id uuid.UUID, req *dto.UpdateUserRequest) (*models.User, error)
+	RemoveUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+	ReactivateUser(ctx context.Context, id uuid.UUID) (*models.User, error)
 }
 
 type DirectoryHandlers struct {
@@ -40,7 +49,14 @@ func (h *DirectoryHandlers) CreateDepartment(c *gin.Context) {
 
 	resp, err := h.usecase.CreateDepartment(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, errModels.ErrDepartmentNotFound), errors.Is(err, errModels.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrValidationFailed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
@@ -63,6 +79,99 @@ func (h *DirectoryHandlers) GetDepartments(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+func (h *DirectoryHandlers) UpdateDepartment(c *gin.Context) {
+	depID, err := uuid.Parse(c.Param("department_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department_id"})
+		return
+	}
+
+	var req dto.UpdateDepartmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	dep, err := h.usecase.UpdateDepartment(c.Request.Context(), depID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errModels.ErrDepartmentNotFound), errors.Is(err, errModels.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrValidationFailed), errors.Is(err, errModels.ErrSelfParentReference):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrCircularReference):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dep)
+}
+
+func (h *DirectoryHandlers) GetDepartmentSubtree(c *gin.Context) {
+	depID, err := uuid.Parse(c.Param("department_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department_id"})
+		return
+	}
+
+	var req dto.GetDepartmentSubtreeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tree, err := h.usecase.GetDepartmentSubtree(c.Request.Context(), depID, &req)
+	if err != nil {
+		departmentLookupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tree)
+}
+
+func (h *DirectoryHandlers) GetDepartmentAncestors(c *gin.Context) {
+	depID, err := uuid.Parse(c.Param("department_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department_id"})
+		return
+	}
+
+	resp, err := h.usecase.GetDepartmentAncestors(c.Request.Context(), depID)
+	if err != nil {
+		departmentLookupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *DirectoryHandlers) GetDepartmentPath(c *gin.Context) {
+	depID, err := uuid.Parse(c.Param("department_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid department_id"})
+		return
+	}
+
+	resp, err := h.usecase.GetDepartmentPath(c.Request.Context(), depID)
+	if err != nil {
+		departmentLookupError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func departmentLookupError(c *gin.Context, err error) {
+	if errors.Is(err, errModels.ErrDepartmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
 func (h *DirectoryHandlers) RemoveDepartment(c *gin.Context) {
 	depIdStr := c.Param("department_id")
 	depId, err := uuid.Parse(depIdStr)
@@ -71,13 +180,31 @@ func (h *DirectoryHandlers) RemoveDepartment(c *gin.Context) {
 		return
 	}
 
-	err = h.usecase.RemoveDepartment(c.Request.Context(), depId)
+	var req dto.RemoveDepartmentQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bindQueryUUID(c, "reassign_to", &req.ReassignTo) {
+		return
+	}
+
+	resp, err := h.usecase.RemoveDepartment(c.Request.Context(), depId, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		switch {
+		case errors.Is(err, errModels.ErrDepartmentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrInvalidReassignTarget):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrDepartmentHasUsers), errors.Is(err, errModels.ErrDepartmentHasChildren):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	c.Status(http.StatusOK)
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *DirectoryHandlers) GetDepartmentMembers(c *gin.Context) {
@@ -126,6 +253,9 @@ func (h *DirectoryHandlers) GetUsers(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !bindQueryUUID(c, "department_id", &req.DepartmentID) {
+		return
+	}
 
 	resp, err := h.usecase.GetUsers(c.Request.Context(), &req)
 	if err != nil {
@@ -157,6 +287,35 @@ func (h *DirectoryHandlers) GetUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+func (h *DirectoryHandlers) UpdateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+		return
+	}
+
+	var req dto.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.usecase.UpdateUser(c.Request.Context(), userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errModels.ErrUserNotFound), errors.Is(err, errModels.ErrDepartmentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrValidationFailed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
 func (h *DirectoryHandlers) RemoveUser(c *gin.Context) {
 	userIDStr := c.Param("user_id")
 	userID, err := uuid.Parse(userIDStr)
@@ -165,11 +324,56 @@ func (h *DirectoryHandlers) RemoveUser(c *gin.Context) {
 		return
 	}
 
-	err = h.usecase.RemoveUser(c.Request.Context(), userID)
+	user, err := h.usecase.RemoveUser(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		userStatusError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *DirectoryHandlers) ReactivateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+		return
+	}
+
+	user, err := h.usecase.ReactivateUser(c.Request.Context(), userID)
+	if err != nil {
+		userStatusError(c, err)
 		return
 	}
 
-	c.Status(http.StatusOK)
+	c.JSON(http.StatusOK, user)
+}
+
+func userStatusError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errModels.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errModels.ErrAccountSyncFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// bindQueryUUID разбирает необязательный UUID из query-параметра: биндинг gin
+// не умеет заполнять uuid.UUID, поэтому такие поля помечены form:"-"
+func bindQueryUUID(c *gin.Context, name string, dst **uuid.UUID) bool {
+	raw := c.Query(name)
+	if raw == "" {
+		return true
+	}
+
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return false
+	}
+	*dst = &id
+
+	return true
 }
@@ -1,8 +1,11 @@
 package v1
 
 import (
+	"crypto/subtle"
 	"net/http"
+	"strings"
 
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/pkg/scim"
 	"github.com/gin-gonic/gin"
 )
 
@@ -35,8 +38,9 @@ func RequireRoles(allowedRoles ...ClientRole) gin.HandlerFunc {
 			return
 		}
 
+		role, _ := userRole.(string)
 		for _, allowedRole := range allowedRoles {
-			if userRole == allowedRole {
+			if ClientRole(role) == allowedRole {
 				c.Next()
 				return
 			}
@@ -55,3 +59,17 @@ func RequireAdminOnly() gin.HandlerFunc {
 func RequireStaff() gin.HandlerFunc {
 	return RequireRoles(adminRole, moderatorRole, supportRole)
 }
+
+// RequireBearerToken пропускает запросы SCIM-провайдера с заданным bearer-токеном
+func RequireBearerToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			scimError(c, scim.NewError(http.StatusUnauthorized, "", "invalid bearer token"))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/gin-gonic/gin"
+)
+
+type OnboardingUsecase interface {
+	Onboard(ctx context.Context, key string, req *dto.OnboardingRequest) (*dto.OnboardingResponse, bool, error)
+}
+
+type OnboardingHandlers struct {
+	usecase OnboardingUsecase
+}
+
+func NewOnboardingHandlers(usecase OnboardingUsecase) *OnboardingHandlers {
+	return &OnboardingHandlers{
+		usecase: usecase,
+	}
+}
+
+// Onboard создаёт сотрудника вместе с аккаунтом. Повтор с тем же Idempotency-Key
+// возвращает результат первого запроса с заголовком Idempotent-Replayed
+func (h *OnboardingHandlers) Onboard(c *gin.Context) {
+	var req dto.OnboardingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, replayed, err := h.usecase.Onboard(c.Request.Context(), c.GetHeader("Idempotency-Key"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, errModels.ErrIdempotencyKeyRequired), errors.Is(err, errModels.ErrValidationFailed):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrDepartmentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrUserEmailExists), errors.Is(err, errModels.ErrAccountEmailExists),
+			errors.Is(err, errModels.ErrOnboardingInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrIdempotencyKeyReused):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrOnboardingIncomplete):
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrInvitationsDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
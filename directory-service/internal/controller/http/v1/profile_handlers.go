@@ -0,0 +1,201 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ProfileUsecase interface {
+	GetMyProfile(ctx context.Context, viewer usecase.Viewer) (*dto.ProfileResponse, error)
+	UpdateMyProfile(ctx context.Context, viewer usecase.Viewer, req *dto.UpdateMyProfileRequest) (*dto.ProfileResponse, error)
+	GetColleague(ctx context.Context, viewer usecase.Viewer, id uuid.UUID) (*dto.ProfileResponse, error)
+	ListColleagues(ctx context.Context, viewer usecase.Viewer, req *dto.GetColleaguesRequest) (*dto.GetColleaguesResponse, error)
+	SearchColleagues(ctx context.Context, viewer usecase.Viewer, req *dto.SearchUsersRequest) (*dto.SearchUsersResponse, error)
+	GetManagerChain(ctx context.Context, viewer usecase.Viewer, userID uuid.UUID) (*dto.ManagerChainResponse, error)
+}
+
+// ProfileHandlers - самообслуживание и поиск коллег, доступные любому пользователю
+type ProfileHandlers struct {
+	usecase ProfileUsecase
+}
+
+func NewProfileHandlers(usecase ProfileUsecase) *ProfileHandlers {
+	return &ProfileHandlers{
+		usecase: usecase,
+	}
+}
+
+func (h *ProfileHandlers) GetMe(c *gin.Context) {
+	viewer, ok := viewerFromContext(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.usecase.GetMyProfile(c.Request.Context(), viewer)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ProfileHandlers) UpdateMe(c *gin.Context) {
+	viewer, ok := viewerFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateMyProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.usecase.UpdateMyProfile(c.Request.Context(), viewer, &req)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ProfileHandlers) GetColleagues(c *gin.Context) {
+	viewer, ok := viewerFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req dto.GetColleaguesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bindQueryUUID(c, "department_id", &req.DepartmentID) {
+		return
+	}
+
+	resp, err := h.usecase.ListColleagues(c.Request.Context(), viewer, &req)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *ProfileHandlers) GetColleague(c *gin.Context) {
+	viewer, ok := viewerFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+		return
+	}
+
+	profile, err := h.usecase.GetColleague(c.Request.Context(), viewer, userID)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *ProfileHandlers) Search(c *gin.Context) {
+	viewer, ok := viewerFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req dto.SearchUsersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !bindQueryUUID(c, "department_id", &req.DepartmentID) {
+		return
+	}
+
+	resp, err := h.usecase.SearchColleagues(c.Request.Context(), viewer, &req)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *ProfileHandlers) GetMyManagers(c *gin.Context) {
+	viewer, ok := viewerFromContext(c)
+	if !ok {
+		return
+	}
+
+	resp, err := h.usecase.GetManagerChain(c.Request.Context(), viewer, viewer.UserID)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *ProfileHandlers) GetColleagueManagers(c *gin.Context) {
+	viewer, ok := viewerFromContext(c)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id"})
+		return
+	}
+
+	resp, err := h.usecase.GetManagerChain(c.Request.Context(), viewer, userID)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// viewerFromContext берёт пользователя из заголовков шлюза, выставленных ExtractUserInfoMiddleware
+func viewerFromContext(c *gin.Context) (usecase.Viewer, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return usecase.Viewer{}, false
+	}
+
+	return usecase.Viewer{
+		UserID:  userID,
+		IsAdmin: ClientRole(c.GetString("user_role")) == adminRole,
+	}, true
+}
+
+func profileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errModels.ErrUserNotFound), errors.Is(err, errModels.ErrDepartmentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errModels.ErrFieldNotEditable), errors.Is(err, errModels.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, errModels.ErrValidationFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,276 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/pkg/scim"
+	"github.com/gin-gonic/gin"
+)
+
+type SCIMUsecase interface {
+	ListUsers(ctx context.Context, req *scim.ListRequest) (*scim.ListResponse, error)
+	GetUser(ctx context.Context, id string) (*dto.SCIMUser, error)
+	CreateUser(ctx context.Context, in *dto.SCIMUser) (*dto.SCIMUser, error)
+	ReplaceUser(ctx context.Context, id string, in *dto.SCIMUser) (*dto.SCIMUser, error)
+	PatchUser(ctx context.Context, id string, req *scim.PatchRequest) (*dto.SCIMUser, error)
+	DeleteUser(ctx context.Context, id string) error
+
+	ListGroups(ctx context.Context, req *scim.ListRequest) (*scim.ListResponse, error)
+	GetGroup(ctx context.Context, id string, withMembers bool) (*dto.SCIMGroup, error)
+	CreateGroup(ctx context.Context, in *dto.SCIMGroup) (*dto.SCIMGroup, error)
+	ReplaceGroup(ctx context.Context, id string, in *dto.SCIMGroup) (*dto.SCIMGroup, error)
+	PatchGroup(ctx context.Context, id string, req *scim.PatchRequest) (*dto.SCIMGroup, error)
+	DeleteGroup(ctx context.Context, id string) error
+}
+
+type SCIMHandlers struct {
+	usecase    SCIMUsecase
+	maxResults int
+}
+
+func NewSCIMHandlers(usecase SCIMUsecase, maxResults int) *SCIMHandlers {
+	return &SCIMHandlers{
+		usecase:    usecase,
+		maxResults: maxResults,
+	}
+}
+
+func (h *SCIMHandlers) ListUsers(c *gin.Context) {
+	var req scim.ListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		scimError(c, scim.BadRequest(scim.TypeInvalidValue, "%s", err.Error()))
+		return
+	}
+
+	resp, err := h.usecase.ListUsers(c.Request.Context(), &req)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, resp)
+}
+
+func (h *SCIMHandlers) GetUser(c *gin.Context) {
+	user, err := h.usecase.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, user)
+}
+
+func (h *SCIMHandlers) CreateUser(c *gin.Context) {
+	var in dto.SCIMUser
+	if err := bindSCIM(c, &in); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	user, err := h.usecase.CreateUser(c.Request.Context(), &in)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	c.Header("Location", user.Meta.Location)
+	scimJSON(c, http.StatusCreated, user)
+}
+
+func (h *SCIMHandlers) ReplaceUser(c *gin.Context) {
+	var in dto.SCIMUser
+	if err := bindSCIM(c, &in); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	user, err := h.usecase.ReplaceUser(c.Request.Context(), c.Param("id"), &in)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, user)
+}
+
+func (h *SCIMHandlers) PatchUser(c *gin.Context) {
+	var req scim.PatchRequest
+	if err := bindSCIM(c, &req); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	user, err := h.usecase.PatchUser(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, user)
+}
+
+func (h *SCIMHandlers) DeleteUser(c *gin.Context) {
+	if err := h.usecase.DeleteUser(c.Request.Context(), c.Param("id")); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandlers) ListGroups(c *gin.Context) {
+	var req scim.ListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		scimError(c, scim.BadRequest(scim.TypeInvalidValue, "%s", err.Error()))
+		return
+	}
+
+	resp, err := h.usecase.ListGroups(c.Request.Context(), &req)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, resp)
+}
+
+func (h *SCIMHandlers) GetGroup(c *gin.Context) {
+	req := scim.ListRequest{ExcludedAttributes: c.Query("excludedAttributes")}
+
+	group, err := h.usecase.GetGroup(c.Request.Context(), c.Param("id"), !req.Excludes("members"))
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+func (h *SCIMHandlers) CreateGroup(c *gin.Context) {
+	var in dto.SCIMGroup
+	if err := bindSCIM(c, &in); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	group, err := h.usecase.CreateGroup(c.Request.Context(), &in)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	c.Header("Location", group.Meta.Location)
+	scimJSON(c, http.StatusCreated, group)
+}
+
+func (h *SCIMHandlers) ReplaceGroup(c *gin.Context) {
+	var in dto.SCIMGroup
+	if err := bindSCIM(c, &in); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	group, err := h.usecase.ReplaceGroup(c.Request.Context(), c.Param("id"), &in)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+func (h *SCIMHandlers) PatchGroup(c *gin.Context) {
+	var req scim.PatchRequest
+	if err := bindSCIM(c, &req); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	group, err := h.usecase.PatchGroup(c.Request.Context(), c.Param("id"), &req)
+	if err != nil {
+		scimError(c, err)
+		return
+	}
+
+	scimJSON(c, http.StatusOK, group)
+}
+
+func (h *SCIMHandlers) DeleteGroup(c *gin.Context) {
+	if err := h.usecase.DeleteGroup(c.Request.Context(), c.Param("id")); err != nil {
+		scimError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ServiceProviderConfig описывает возможности провайдера (RFC 7643, раздел 5)
+func (h *SCIMHandlers) ServiceProviderConfig(c *gin.Context) {
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{scim.SchemaServiceProviderConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": h.maxResults},
+		"changePassword": gin.H{"supported": true},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with a static bearer token",
+			"primary":     true,
+		}},
+	})
+}
+
+// bindSCIM разбирает тело запроса: провайдеры присылают application/scim+json,
+// который не распознаёт биндинг gin
+func bindSCIM(c *gin.Context, out any) error {
+	if err := json.NewDecoder(c.Request.Body).Decode(out); err != nil {
+		return scim.BadRequest(scim.TypeInvalidSyntax, "invalid request body: %v", err)
+	}
+
+	return nil
+}
+
+func scimJSON(c *gin.Context, status int, body any) {
+	c.Render(status, scimRender{body})
+}
+
+func scimError(c *gin.Context, err error) {
+	var scimErr *scim.Error
+	switch {
+	case errors.As(err, &scimErr):
+	case errors.Is(err, errModels.ErrUserNotFound), errors.Is(err, errModels.ErrDepartmentNotFound):
+		scimErr = scim.NewError(http.StatusNotFound, "", "%s", err.Error())
+	case errors.Is(err, errModels.ErrDepartmentHasChildren):
+		scimErr = scim.NewError(http.StatusConflict, scim.TypeMutability, "%s", err.Error())
+	default:
+		log.Printf("scim request %s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+		scimErr = scim.NewError(http.StatusInternalServerError, "", "internal server error")
+	}
+
+	c.Abort()
+	scimJSON(c, scimErr.Status, scimErr.Response())
+}
+
+// scimRender - JSON с Content-Type application/scim+json
+type scimRender struct {
+	body any
+}
+
+func (r scimRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.body)
+}
+
+func (r scimRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", scim.ContentType)
+}
@@ -7,17 +7,29 @@ import (
 	"time"
 
 	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/adapter"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/chat"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/ldap"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/mailer"
 	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/usecase"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Server struct {
-	srv *http.Server
-	db  *pgxpool.Pool
+	srv            *http.Server
+	ldapClient     *ldap.Client
+	identityClient identity.Client
+	chatClient     chat.Client
+	mailer         usecase.Mailer
+	db             *pgxpool.Pool
+
+	// Останавливает фоновые задачи (синхронизацию с LDAP)
+	stopBackground context.CancelFunc
 }
 
-func NewServer(port int, readTimeout, writeTimeout time.Duration, db *pgxpool.Pool) *Server {
+func NewServer(port int, readTimeout, writeTimeout time.Duration, ldapCfg ldap.LDAPConfig, identityCfg identity.IdentityServiceConfig,
+	chatCfg chat.ChatServiceConfig, smtpCfg mailer.SMTPConfig, db *pgxpool.Pool) *Server {
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%v", port),
 		ReadTimeout:  readTimeout,
@@ -25,24 +37,55 @@ func NewServer(port int, readTimeout, writeTimeout time.Duration, db *pgxpool.Po
 		Handler:      nil,
 	}
 
+	// Синхронизация включается только при настроенном каталоге
+	var ldapClient *ldap.Client
+	if ldapCfg.Enabled {
+		ldapClient = ldap.NewClient(ldapCfg)
+	}
+
+	// Без почтового сервера приглашения при онбординге недоступны
+	var mailClient usecase.Mailer
+	if smtpCfg.Host != "" {
+		mailClient = mailer.NewClient(smtpCfg)
+	}
+
 	return &Server{
-		srv: srv,
-		db:  db,
+		srv:            srv,
+		ldapClient:     ldapClient,
+		identityClient: identity.NewHTTPClient(identityCfg),
+		chatClient:     chat.NewHTTPClient(chatCfg),
+		mailer:         mailClient,
+		db:             db,
 	}
 }
 
-func (s *Server) RegisterHandlers() error {
+func (s *Server) RegisterHandlers(syncCfg usecase.SyncConfig, scimCfg usecase.SCIMConfig, onboardingCfg usecase.OnboardingConfig,
+	profileCfg usecase.ProfileConfig) error {
 	dirRepo := adapter.NewDirectoryRepo(s.db)
-	dirUsecase := usecase.NewDirectoryUsecase(dirRepo)
+	dirUsecase := usecase.NewDirectoryUsecase(dirRepo, s.identityClient)
+
+	var source usecase.DirectorySource
+	if s.ldapClient != nil {
+		source = s.ldapClient
+	}
+	directorySync := usecase.NewDirectorySync(dirRepo, source, s.identityClient, syncCfg)
+
+	backgroundCtx, cancel := context.WithCancel(context.Background())
+	s.stopBackground = cancel
+	go directorySync.Run(backgroundCtx)
 
 	dirhandlers := NewDirectoryHandlers(dirUsecase)
+	syncHandlers := NewSyncHandlers(directorySync)
+	onboardingHandlers := NewOnboardingHandlers(usecase.NewOnboardingUsecase(dirRepo, s.identityClient, s.chatClient, s.mailer, onboardingCfg))
+	profileHandlers := NewProfileHandlers(usecase.NewProfileUsecase(dirRepo, profileCfg))
+	scimHandlers := NewSCIMHandlers(usecase.NewSCIMUsecase(dirRepo, s.identityClient, scimCfg), scimCfg.MaxResults)
 
 	router := gin.Default()
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "http://localhost:3000")
 		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type,Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type,Authorization,Idempotency-Key")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -50,20 +93,67 @@ func (s *Server) RegisterHandlers() error {
 		}
 		c.Next()
 	})
-	router.Use(ExtractUserInfoMiddleware())
 
-	adminAvail := router.Group("/directory")
+	directory := router.Group("/directory")
+	directory.Use(ExtractUserInfoMiddleware())
+
+	// Доступно любому пользователю
+	{
+		directory.GET("/me", profileHandlers.GetMe)
+		directory.PATCH("/me", profileHandlers.UpdateMe)
+		directory.GET("/colleagues", profileHandlers.GetColleagues)
+		directory.GET("/colleagues/:user_id", profileHandlers.GetColleague)
+		directory.GET("/search", profileHandlers.Search)
+		directory.GET("/me/managers", profileHandlers.GetMyManagers)
+		directory.GET("/colleagues/:user_id/managers", profileHandlers.GetColleagueManagers)
+
+		directory.GET("/departments/:department_id/subtree", dirhandlers.GetDepartmentSubtree)
+		directory.GET("/departments/:department_id/ancestors", dirhandlers.GetDepartmentAncestors)
+		directory.GET("/departments/:department_id/path", dirhandlers.GetDepartmentPath)
+	}
+
+	adminAvail := directory.Group("")
 	adminAvail.Use(RequireAdminOnly())
 	{
 		adminAvail.POST("/departments", dirhandlers.CreateDepartment)
 		adminAvail.GET("/departments", dirhandlers.GetDepartments)
+		adminAvail.PATCH("/departments/:department_id", dirhandlers.UpdateDepartment)
 		adminAvail.DELETE("/departments/:department_id", dirhandlers.RemoveDepartment)
 		adminAvail.GET("/departments/:department_id/users", dirhandlers.GetDepartmentMembers)
 
 		adminAvail.POST("/users", dirhandlers.CreateUser)
 		adminAvail.GET("/users", dirhandlers.GetUsers)
 		adminAvail.GET("/users/:user_id", dirhandlers.GetUser)
+		adminAvail.PATCH("/users/:user_id", dirhandlers.UpdateUser)
 		adminAvail.DELETE("/users/:user_id", dirhandlers.RemoveUser)
+		adminAvail.POST("/users/:user_id/reactivate", dirhandlers.ReactivateUser)
+
+		adminAvail.POST("/onboarding", onboardingHandlers.Onboard)
+
+		adminAvail.POST("/sync/ldap", syncHandlers.SyncLDAP)
+	}
+
+	// SCIM-провайдер идёт напрямую, без заголовков пользователя от шлюза
+	if scimCfg.Token != "" {
+		scimAvail := router.Group("/scim/v2")
+		scimAvail.Use(RequireBearerToken(scimCfg.Token))
+		{
+			scimAvail.GET("/ServiceProviderConfig", scimHandlers.ServiceProviderConfig)
+
+			scimAvail.GET("/Users", scimHandlers.ListUsers)
+			scimAvail.POST("/Users", scimHandlers.CreateUser)
+			scimAvail.GET("/Users/:id", scimHandlers.GetUser)
+			scimAvail.PUT("/Users/:id", scimHandlers.ReplaceUser)
+			scimAvail.PATCH("/Users/:id", scimHandlers.PatchUser)
+			scimAvail.DELETE("/Users/:id", scimHandlers.DeleteUser)
+
+			scimAvail.GET("/Groups", scimHandlers.ListGroups)
+			scimAvail.POST("/Groups", scimHandlers.CreateGroup)
+			scimAvail.GET("/Groups/:id", scimHandlers.GetGroup)
+			scimAvail.PUT("/Groups/:id", scimHandlers.ReplaceGroup)
+			scimAvail.PATCH("/Groups/:id", scimHandlers.PatchGroup)
+			scimAvail.DELETE("/Groups/:id", scimHandlers.DeleteGroup)
+		}
 	}
 
 	s.srv.Handler = router
@@ -76,5 +166,9 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Stop(ctx context.Context) error {
+	if s.stopBackground != nil {
+		s.stopBackground()
+	}
+
 	return s.srv.Shutdown(ctx)
 }
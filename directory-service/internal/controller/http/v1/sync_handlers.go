@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/gin-gonic/gin"
+)
+
+type SyncUsecase interface {
+	Sync(ctx context.Context, dryRun bool) (*dto.SyncReport, error)
+}
+
+type SyncHandlers struct {
+	usecase SyncUsecase
+}
+
+func NewSyncHandlers(usecase SyncUsecase) *SyncHandlers {
+	return &SyncHandlers{
+		usecase: usecase,
+	}
+}
+
+// SyncLDAP запускает синхронизацию с каталогом; без dry_run=false только возвращает отчёт
+func (h *SyncHandlers) SyncLDAP(c *gin.Context) {
+	var req dto.SyncRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.usecase.Sync(c.Request.Context(), req.DryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, errModels.ErrSyncDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrSyncInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, errModels.ErrSyncSourceFailed), errors.Is(err, errModels.ErrSyncEmptySource):
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -7,8 +7,9 @@ import (
 
 // CreateDepartmentRequest - запрос на создание отдела
 type CreateDepartmentRequest struct {
-	Name     string     `json:"name" binding:"required"`
-	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Name       string     `json:"name" binding:"required"`
+	ParentID   *uuid.UUID `json:"parent_id,omitempty"`
+	HeadUserID *uuid.UUID `json:"head_user_id,omitempty"`
 }
 
 // CreateDepartmentResponse - ответ на создание отдела
@@ -21,6 +22,8 @@ type GetDepartmentsRequest struct {
 	Limit  int  `form:"limit,default=20"`
 	Offset int  `form:"offset,default=0"`
 	Tree   bool `form:"tree,default=false"` // Флаг для возврата иерархической структуры
+
+	IncludeArchived bool `form:"include_archived,default=false"`
 }
 
 // GetDepartmentsResponse - ответ на список отделов
@@ -29,16 +32,74 @@ type GetDepartmentsResponse struct {
 	Total       int                  `json:"total"`
 }
 
+// UpdateDepartmentRequest - частичное изменение отдела. parent_id: null переносит отдел
+// в корень, head_user_id: null снимает руководителя
+type UpdateDepartmentRequest struct {
+	Name       *string             `json:"name,omitempty"`
+	ParentID   Nullable[uuid.UUID] `json:"parent_id"`
+	HeadUserID Nullable[uuid.UUID] `json:"head_user_id"`
+}
+
+// GetDepartmentSubtreeRequest - поддерево отдела; depth ограничивает глубину (0 - без ограничения)
+type GetDepartmentSubtreeRequest struct {
+	Depth int `form:"depth,default=0" binding:"min=0"`
+}
+
+// DepartmentTreeNode - узел оргструктуры с численностью активных сотрудников
+type DepartmentTreeNode struct {
+	DepartmentID     uuid.UUID             `json:"department_id"`
+	Name             string                `json:"name"`
+	ParentID         *uuid.UUID            `json:"parent_id,omitempty"`
+	HeadUserID       *uuid.UUID            `json:"head_user_id,omitempty"`
+	Depth            int                   `json:"depth"`
+	MemberCount      int                   `json:"member_count"`
+	TotalMemberCount int                   `json:"total_member_count"`
+	Children         []*DepartmentTreeNode `json:"children"`
+}
+
+// DepartmentRef - краткая ссылка на отдел для хлебных крошек
+type DepartmentRef struct {
+	DepartmentID uuid.UUID `json:"department_id"`
+	Name         string    `json:"name"`
+}
+
+// GetDepartmentAncestorsResponse - вышестоящие отделы, начиная с непосредственного родителя
+type GetDepartmentAncestorsResponse struct {
+	Ancestors []*models.Department `json:"ancestors"`
+}
+
+// GetDepartmentPathResponse - путь от корня до отдела включительно
+type GetDepartmentPathResponse struct {
+	Path []*DepartmentRef `json:"path"`
+}
+
 // RemoveDepartmentRequest - запрос на удаление отдела (по ID в пути)
 type RemoveDepartmentRequest struct {
 	DepartmentID uuid.UUID `uri:"department_id" binding:"required"`
 }
 
+// RemoveDepartmentQuery - параметры удаления отдела: reassign_to переносит сотрудников
+// и дочерние отделы в другой отдел, archive оставляет отдел в архиве вместо удаления
+type RemoveDepartmentQuery struct {
+	ReassignTo *uuid.UUID `form:"-"`
+	Archive    bool       `form:"archive,default=false"`
+}
+
+// RemoveDepartmentResponse - итог удаления отдела
+type RemoveDepartmentResponse struct {
+	DepartmentID  uuid.UUID  `json:"department_id"`
+	Archived      bool       `json:"archived"`
+	ReassignedTo  *uuid.UUID `json:"reassigned_to,omitempty"`
+	MovedUsers    int64      `json:"moved_users"`
+	MovedChildren int64      `json:"moved_children"`
+}
+
 // GetDepartmentMembersRequest - запрос на членов отдела (с пагинацией)
 type GetDepartmentMembersRequest struct {
-	DepartmentID uuid.UUID `uri:"department_id" binding:"required"`
-	Limit        int       `form:"limit,default=20"`
-	Offset       int       `form:"offset,default=0"`
+	DepartmentID    uuid.UUID `uri:"department_id" binding:"required"`
+	Limit           int       `form:"limit,default=20"`
+	Offset          int       `form:"offset,default=0"`
+	IncludeInactive bool      `form:"include_inactive"`
 }
 
 // GetDepartmentMembersResponse - ответ на членов отдела
@@ -66,8 +127,11 @@ type CreateUserResponse struct {
 type GetUsersRequest struct {
 	Limit        int        `form:"limit,default=20"`
 	Offset       int        `form:"offset,default=0"`
-	DepartmentID *uuid.UUID `form:"department_id,omitempty"`
+	DepartmentID *uuid.UUID `form:"-"`
 	IsActive     *bool      `form:"is_active,omitempty"`
+	Email        *string    `form:"email,omitempty"`
+	// Без is_active и include_inactive список содержит только активных
+	IncludeInactive bool `form:"include_inactive"`
 }
 
 // GetUsersResponse - ответ на список пользователей
@@ -76,12 +140,22 @@ type GetUsersResponse struct {
 	Total int            `json:"total"`
 }
 
+// UpdateUserRequest - частичное изменение пользователя. department_id: null выводит его
+// из отдела, пустые position и avatar_url очищают поля
+type UpdateUserRequest struct {
+	FirstName    *string             `json:"first_name,omitempty"`
+	LastName     *string             `json:"last_name,omitempty"`
+	Position     *string             `json:"position,omitempty"`
+	AvatarURL    *string             `json:"avatar_url,omitempty"`
+	DepartmentID Nullable[uuid.UUID] `json:"department_id"`
+}
+
 // GetUserRequest - запрос на получение пользователя (по ID в пути)
 type GetUserRequest struct {
 	UserID uuid.UUID `uri:"user_id" binding:"required"`
 }
 
-// RemoveUserRequest - запрос на удаление пользователя (по ID в пути)
+// RemoveUserRequest - запрос на увольнение пользователя (по ID в пути)
 type RemoveUserRequest struct {
 	UserID uuid.UUID `uri:"user_id" binding:"required"`
 }
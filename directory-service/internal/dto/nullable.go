@@ -0,0 +1,25 @@
+package dto
+
+import "encoding/json"
+
+// Nullable различает в PATCH-запросе отсутствующее поле (Set = false) и явный null
+type Nullable[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	n.Set = true
+	if string(data) == "null" {
+		n.Value = nil
+		return nil
+	}
+
+	var value T
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	n.Value = &value
+
+	return nil
+}
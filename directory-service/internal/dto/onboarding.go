@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OnboardingRequest - приём сотрудника: карточка в справочнике, аккаунт и, по желанию,
+// чат отдела и приглашение со ссылкой для задания пароля. Password и SendInvitation
+// взаимоисключающие: пароль задаёт либо администратор, либо сам сотрудник по ссылке
+type OnboardingRequest struct {
+	Email               string     `json:"email" binding:"required,email"`
+	FirstName           string     `json:"first_name" binding:"required"`
+	LastName            string     `json:"last_name" binding:"required"`
+	Position            *string    `json:"position,omitempty"`
+	DepartmentID        *uuid.UUID `json:"department_id,omitempty"`
+	AvatarURL           *string    `json:"avatar_url,omitempty"`
+	Role                string     `json:"role,omitempty" binding:"omitempty,oneof=user support admin moderator"`
+	Password            *string    `json:"password,omitempty"`
+	AddToDepartmentChat bool       `json:"add_to_department_chat"`
+	SendInvitation      bool       `json:"send_invitation"`
+}
+
+type OnboardingResponse struct {
+	UserID           uuid.UUID             `json:"user_id"`
+	AccountID        string                `json:"account_id,omitempty"`
+	Email            string                `json:"email"`
+	Role             string                `json:"role,omitempty"`
+	DepartmentChatID *uuid.UUID            `json:"department_chat_id,omitempty"`
+	Invitation       *OnboardingInvitation `json:"invitation,omitempty"`
+	CompletedSteps   []string              `json:"completed_steps"`
+}
+
+type OnboardingInvitation struct {
+	SentTo    string    `json:"sent_to"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
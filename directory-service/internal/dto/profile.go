@@ -0,0 +1,73 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProfileResponse - карточка сотрудника для самообслуживания и поиска коллег.
+// Скрытые правилами видимости поля не попадают в ответ
+type ProfileResponse struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	Email          *string    `json:"email,omitempty"`
+	Position       *string    `json:"position,omitempty"`
+	DepartmentID   *uuid.UUID `json:"department_id,omitempty"`
+	DepartmentName *string    `json:"department_name,omitempty"`
+	AvatarURL      *string    `json:"avatar_url,omitempty"`
+	IsActive       *bool      `json:"is_active,omitempty"`
+	CreatedAt      *time.Time `json:"created_at,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateMyProfileRequest - изменение своей карточки. Пустая строка очищает поле
+type UpdateMyProfileRequest struct {
+	FirstName *string `json:"first_name,omitempty"`
+	LastName  *string `json:"last_name,omitempty"`
+	Position  *string `json:"position,omitempty"`
+	AvatarURL *string `json:"avatar_url,omitempty"`
+}
+
+// GetColleaguesRequest - список активных коллег (с пагинацией и фильтром по отделу)
+type GetColleaguesRequest struct {
+	Limit        int        `form:"limit,default=20" binding:"min=1,max=100"`
+	Offset       int        `form:"offset,default=0" binding:"min=0"`
+	DepartmentID *uuid.UUID `form:"-"`
+}
+
+type GetColleaguesResponse struct {
+	Users []*ProfileResponse `json:"users"`
+	Total int                `json:"total"`
+}
+
+// SearchUsersRequest - поиск коллег по имени, email и должности. subtree расширяет
+// фильтр по отделу на все вложенные отделы
+type SearchUsersRequest struct {
+	Query           string     `form:"q" binding:"required,min=2,max=100"`
+	DepartmentID    *uuid.UUID `form:"-"`
+	Subtree         bool       `form:"subtree,default=false"`
+	IncludeInactive bool       `form:"include_inactive,default=false"`
+	Limit           int        `form:"limit,default=10" binding:"min=1,max=50"`
+}
+
+type UserSearchResult struct {
+	*ProfileResponse
+	Score float64 `json:"score"`
+}
+
+type SearchUsersResponse struct {
+	Users []*UserSearchResult `json:"users"`
+}
+
+// Manager - руководитель в цепочке подчинения и отдел, которым он руководит
+type Manager struct {
+	*ProfileResponse
+	HeadOf *DepartmentRef `json:"head_of,omitempty"`
+}
+
+// ManagerChainResponse - руководители от непосредственного до верхнего уровня
+type ManagerChainResponse struct {
+	Managers []*Manager `json:"managers"`
+}
@@ -0,0 +1,50 @@
+package dto
+
+import "github.com/I-Van-Radkov/corporate-messenger/directory-service/pkg/scim"
+
+// SCIMUser - ресурс User из RFC 7643. userName совпадает с рабочим email,
+// groups - только для чтения и содержит отдел сотрудника
+type SCIMUser struct {
+	Schemas     []string         `json:"schemas"`
+	ID          string           `json:"id,omitempty"`
+	ExternalID  string           `json:"externalId,omitempty"`
+	UserName    string           `json:"userName"`
+	Name        *SCIMName        `json:"name,omitempty"`
+	DisplayName string           `json:"displayName,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	Emails      []SCIMMultiValue `json:"emails,omitempty"`
+	Photos      []SCIMMultiValue `json:"photos,omitempty"`
+	Roles       []SCIMMultiValue `json:"roles,omitempty"`
+	Groups      []SCIMMember     `json:"groups,omitempty"`
+	Active      *scim.Boolean    `json:"active,omitempty"`
+	Password    string           `json:"password,omitempty"`
+	Meta        *scim.Meta       `json:"meta,omitempty"`
+}
+
+type SCIMName struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	Formatted  string `json:"formatted,omitempty"`
+}
+
+type SCIMMultiValue struct {
+	Value   string       `json:"value"`
+	Type    string       `json:"type,omitempty"`
+	Primary scim.Boolean `json:"primary,omitempty"`
+}
+
+// SCIMGroup - ресурс Group; группы соответствуют отделам
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []SCIMMember `json:"members,omitempty"`
+	Meta        *scim.Meta   `json:"meta,omitempty"`
+}
+
+type SCIMMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SyncRequest - ручной запуск синхронизации с каталогом; по умолчанию только отчёт
+type SyncRequest struct {
+	DryRun bool `form:"dry_run,default=true"`
+}
+
+// SyncReport - отчёт о синхронизации (в режиме dry-run - о том, что было бы изменено)
+type SyncReport struct {
+	DryRun      bool             `json:"dry_run"`
+	StartedAt   time.Time        `json:"started_at"`
+	FinishedAt  time.Time        `json:"finished_at"`
+	Departments SyncObjectReport `json:"departments"`
+	Users       SyncObjectReport `json:"users"`
+	Skipped     []SyncSkipped    `json:"skipped"`
+}
+
+type SyncObjectReport struct {
+	Created     []SyncChange `json:"created"`
+	Updated     []SyncChange `json:"updated"`
+	Deactivated []SyncChange `json:"deactivated,omitempty"`
+	Unchanged   int          `json:"unchanged"`
+}
+
+// SyncChange - созданный или изменённый объект; Fields заполняется для изменений
+type SyncChange struct {
+	ID         uuid.UUID     `json:"id"`
+	ExternalID string        `json:"external_id,omitempty"`
+	DN         string        `json:"dn,omitempty"`
+	Name       string        `json:"name"`
+	Reason     string        `json:"reason,omitempty"`
+	Fields     []FieldChange `json:"fields,omitempty"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// SyncSkipped - запись каталога, которую нельзя перенести
+type SyncSkipped struct {
+	DN     string `json:"dn"`
+	Reason string `json:"reason"`
+}
@@ -9,10 +9,29 @@ var (
 	ErrDepartmentHasChildren = errors.New("department has child departments")
 	ErrCircularReference     = errors.New("circular reference detected")
 	ErrSelfParentReference   = errors.New("cannot set department as its own parent")
+	ErrInvalidReassignTarget = errors.New("cannot reassign to the department itself or its subdepartment")
 
 	// User errors
-	ErrUserNotFound    = errors.New("user not found")
-	ErrUserEmailExists = errors.New("user with this email already exists")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrUserEmailExists   = errors.New("user with this email already exists")
+	ErrAccountSyncFailed = errors.New("failed to update account in identity service")
+
+	// Sync errors
+	ErrSyncDisabled     = errors.New("directory sync is disabled")
+	ErrSyncInProgress   = errors.New("directory sync is already running")
+	ErrSyncSourceFailed = errors.New("failed to read external directory")
+	ErrSyncEmptySource  = errors.New("external directory returned no users")
+
+	// Onboarding errors
+	ErrIdempotencyKeyRequired = errors.New("Idempotency-Key header is required")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was already used for a different request")
+	ErrOnboardingInProgress   = errors.New("onboarding with this idempotency key is in progress")
+	ErrOnboardingIncomplete   = errors.New("onboarding is incomplete, retry with the same idempotency key")
+	ErrInvitationsDisabled    = errors.New("invitations are disabled")
+	ErrAccountEmailExists     = errors.New("account with this email already exists")
+
+	// Profile errors
+	ErrFieldNotEditable = errors.New("field cannot be changed in self-service")
 
 	// Common errors
 	ErrInvalidUUID      = errors.New("invalid uuid format")
@@ -10,10 +10,55 @@ type Department struct {
 	DepartmentID uuid.UUID  `json:"department_id" db:"department_id"`
 	Name         string     `json:"name" db:"name"`
 	ParentID     *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	HeadUserID   *uuid.UUID `json:"head_user_id,omitempty" db:"head_user_id"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty" db:"archived_at"`
 
 	Parent   *Department   `json:"parent,omitempty" db:"-"`
 	Children []*Department `json:"children,omitempty" db:"-"`
 	Users    []*User       `json:"users,omitempty" db:"-"`
 }
+
+// IsArchived - отдел удалён в архив: он скрыт из оргструктуры, но история сотрудников сохранена
+func (d *Department) IsArchived() bool {
+	return d.ArchivedAt != nil
+}
+
+// DepartmentNode - отдел в выборке поддерева: глубина от корня выборки и численность
+// активных сотрудников в самом отделе и во всём его поддереве
+type DepartmentNode struct {
+	Department
+
+	Depth            int
+	MemberCount      int
+	TotalMemberCount int
+}
+
+// DepartmentRemoval - удаление отдела. Без ReassignTo отдел с активными сотрудниками
+// или дочерними отделами не удаляется; с ReassignTo они переносятся в указанный отдел.
+// Archive оставляет запись с отметкой ArchivedAt вместо удаления
+type DepartmentRemoval struct {
+	DepartmentID uuid.UUID
+	ReassignTo   *uuid.UUID
+	Archive      bool
+	RemovedAt    time.Time
+
+	// DetachUsers оставляет активных сотрудников без отдела вместо отказа в удалении
+	DetachUsers bool
+	// UnlinkSource - источник, чья связь с отделом снимается вместе с архивацией
+	UnlinkSource string
+}
+
+// DepartmentRemovalResult - итог удаления. При Removed = false отдел не изменён,
+// причина указана флагами
+type DepartmentRemovalResult struct {
+	Removed         bool
+	NotFound        bool
+	HasActiveUsers  bool
+	HasChildren     bool
+	TargetNotFound  bool
+	TargetInSubtree bool
+	MovedUsers      int64
+	MovedChildren   int64
+}
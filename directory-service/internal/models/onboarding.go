@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OnboardingStatus string
+
+const (
+	OnboardingInProgress OnboardingStatus = "in_progress"
+	// Необязательный шаг не выполнился; повтор с тем же ключом продолжит с него
+	OnboardingFailed    OnboardingStatus = "failed"
+	OnboardingCompleted OnboardingStatus = "completed"
+)
+
+// Шаги онбординга в порядке выполнения
+const (
+	OnboardingStepUser       = "directory_user"
+	OnboardingStepAccount    = "account"
+	OnboardingStepChat       = "department_chat"
+	OnboardingStepInvitation = "invitation"
+)
+
+// OnboardingRequest - запись о запросе онбординга по ключу идемпотентности. UserID
+// выбирается при первом запросе, чтобы повторы создавали того же сотрудника
+type OnboardingRequest struct {
+	IdempotencyKey string           `db:"idempotency_key"`
+	RequestHash    string           `db:"request_hash"`
+	UserID         uuid.UUID        `db:"user_id"`
+	Status         OnboardingStatus `db:"status"`
+	Result         []byte           `db:"result"`
+	CreatedAt      time.Time        `db:"created_at"`
+	UpdatedAt      time.Time        `db:"updated_at"`
+}
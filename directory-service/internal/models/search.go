@@ -0,0 +1,28 @@
+package models
+
+import "github.com/google/uuid"
+
+// Поля, по которым ищутся пользователи
+const (
+	UserSearchName     = "name"
+	UserSearchEmail    = "email"
+	UserSearchPosition = "position"
+)
+
+// UserSearch - параметры нечёткого поиска. Terms - варианты запроса (исходный
+// и транслитерации), совпадение с любым из них засчитывается
+type UserSearch struct {
+	Terms         []string
+	Fields        []string
+	DepartmentID  *uuid.UUID
+	Subtree       bool
+	ActiveOnly    bool
+	MinSimilarity float64
+	Limit         int
+}
+
+// UserMatch - найденный пользователь и релевантность от 0 до 1
+type UserMatch struct {
+	User  *User
+	Score float64
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Источники записей, связанных с внешними системами
+const (
+	SyncSourceLDAP = "ldap"
+	SyncSourceSCIM = "scim"
+)
+
+type SyncObjectType string
+
+const (
+	SyncObjectUser       SyncObjectType = "user"
+	SyncObjectDepartment SyncObjectType = "department"
+)
+
+// SyncLink связывает запись внешнего каталога с локальным пользователем или отделом
+type SyncLink struct {
+	Source     string         `db:"source"`
+	ObjectType SyncObjectType `db:"object_type"`
+	ExternalID string         `db:"external_id"`
+	LocalID    uuid.UUID      `db:"local_id"`
+	SyncedAt   time.Time      `db:"synced_at"`
+}
+
+// GroupChange - создание или замена группы SCIM: отдел, полный состав и externalId
+// применяются атомарно
+type GroupChange struct {
+	Department *Department
+	Create     bool
+	MemberIDs  []uuid.UUID
+	ExternalID string
+}
+
+// SyncChanges - изменения одного прогона синхронизации, применяемые атомарно.
+// Создаваемые отделы упорядочены от родителей к детям
+type SyncChanges struct {
+	CreateDepartments []*Department
+	UpdateDepartments []*Department
+	CreateUsers       []*User
+	UpdateUsers       []*User
+	DeactivateUsers   []uuid.UUID
+	Links             []*SyncLink
+	UpdatedAt         time.Time
+}
+
+func (c *SyncChanges) IsEmpty() bool {
+	return len(c.CreateDepartments) == 0 &&
+		len(c.UpdateDepartments) == 0 &&
+		len(c.CreateUsers) == 0 &&
+		len(c.UpdateUsers) == 0 &&
+		len(c.DeactivateUsers) == 0 &&
+		len(c.Links) == 0
+}
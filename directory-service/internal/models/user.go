@@ -17,6 +17,8 @@ type User struct {
 	IsActive     bool       `json:"is_active" db:"is_active"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	// Момент увольнения; запись остаётся, чтобы имя было видно в старых чатах
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty" db:"deactivated_at"`
 
 	Department *Department `json:"department,omitempty" db:"-"`
 }
@@ -0,0 +1,35 @@
+package usecase
+
+import "time"
+
+// SyncConfig - расписание синхронизации с LDAP. При нулевом интервале синхронизация
+// запускается только вручную
+type SyncConfig struct {
+	Interval time.Duration `env:"LDAP_SYNC_INTERVAL" env-default:"1h"`
+	DryRun   bool          `env:"LDAP_SYNC_DRY_RUN" env-default:"false"`
+}
+
+// SCIMConfig - провайдер SCIM. Без токена эндпоинты /scim/v2 отключены
+type SCIMConfig struct {
+	Token      string `env:"SCIM_BEARER_TOKEN"`
+	BaseURL    string `env:"SCIM_BASE_URL" env-default:"http://localhost:8080/api/v1/scim/v2"`
+	MaxResults int    `env:"SCIM_MAX_RESULTS" env-default:"200"`
+}
+
+// OnboardingConfig - приём сотрудников. Ссылка приглашения - SetPasswordURL с токеном
+// в параметре token; ключ идемпотентности действует IdempotencyTTL, а прерванный
+// запрос можно повторить через LeaseTimeout
+type OnboardingConfig struct {
+	SetPasswordURL string        `env:"ONBOARDING_SET_PASSWORD_URL" env-default:"http://localhost:3000/set-password"`
+	IdempotencyTTL time.Duration `env:"ONBOARDING_IDEMPOTENCY_TTL" env-default:"24h"`
+	LeaseTimeout   time.Duration `env:"ONBOARDING_LEASE_TIMEOUT" env-default:"2m"`
+}
+
+// ProfileConfig - самообслуживание сотрудников. PrivateFields скрываются от всех,
+// кроме администраторов и самого сотрудника; EditableFields сотрудник меняет сам.
+// SearchMinSimilarity - порог триграммного сходства для поиска коллег
+type ProfileConfig struct {
+	PrivateFields       []string `env:"DIRECTORY_PRIVATE_FIELDS" env-separator:"," env-default:"created_at,updated_at"`
+	EditableFields      []string `env:"DIRECTORY_SELF_EDITABLE_FIELDS" env-separator:"," env-default:"position,avatar_url"`
+	SearchMinSimilarity float64  `env:"DIRECTORY_SEARCH_MIN_SIMILARITY" env-default:"0.3"`
+}
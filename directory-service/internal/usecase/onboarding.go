@@ -0,0 +1,350 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/chat"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/mailer"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type OnboardingRepo interface {
+	CreateUser(ctx context.Context, user *models.User) (uuid.UUID, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUsers(ctx context.Context, filter *dto.GetUsersRequest) ([]*models.User, int, error)
+	PurgeUser(ctx context.Context, id uuid.UUID) error
+	GetDepartmentByID(ctx context.Context, id uuid.UUID) (*models.Department, error)
+
+	ClaimOnboarding(ctx context.Context, rec *models.OnboardingRequest, staleBefore, expiredBefore time.Time) (*models.OnboardingRequest, bool, error)
+	SaveOnboarding(ctx context.Context, rec *models.OnboardingRequest) error
+	DeleteOnboarding(ctx context.Context, key string) error
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg *mailer.Message) error
+}
+
+// OnboardingUsecase - приём сотрудника как сага: карточка в справочнике, аккаунт в
+// identity-service, чат отдела, приглашение. Если identity-service отказал в аккаунте,
+// карточка удаляется и ключ идемпотентности освобождается. Остальные сбои откат не
+// вызывают: повтор с тем же ключом продолжит с невыполненного шага
+type OnboardingUsecase struct {
+	repo     OnboardingRepo
+	accounts identity.Client
+	chats    chat.Client
+	mailer   Mailer
+	cfg      OnboardingConfig
+
+	now func() time.Time
+}
+
+// NewOnboardingUsecase - mailer может быть nil: тогда приглашения отключены
+func NewOnboardingUsecase(repo OnboardingRepo, accounts identity.Client, chats chat.Client, mailer Mailer, cfg OnboardingConfig) *OnboardingUsecase {
+	return &OnboardingUsecase{
+		repo:     repo,
+		accounts: accounts,
+		chats:    chats,
+		mailer:   mailer,
+		cfg:      cfg,
+		now:      time.Now,
+	}
+}
+
+// Onboard выполняет онбординг. replayed=true - запрос с этим ключом уже выполнен,
+// возвращён сохранённый результат
+func (u *OnboardingUsecase) Onboard(ctx context.Context, key string, req *dto.OnboardingRequest) (*dto.OnboardingResponse, bool, error) {
+	if key == "" {
+		return nil, false, errModels.ErrIdempotencyKeyRequired
+	}
+	if req.SendInvitation && u.mailer == nil {
+		return nil, false, errModels.ErrInvitationsDisabled
+	}
+	// Приглашение задаёт пароль по ссылке: сброс заменил бы переданный пароль случайным
+	if req.SendInvitation && req.Password != nil {
+		return nil, false, fmt.Errorf("%w: password and send_invitation are mutually exclusive", errModels.ErrValidationFailed)
+	}
+	if req.AddToDepartmentChat && req.DepartmentID == nil {
+		return nil, false, fmt.Errorf("%w: department_id is required to add to department chat", errModels.ErrValidationFailed)
+	}
+
+	hash, err := onboardingHash(req)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := u.now()
+	rec, claimed, err := u.repo.ClaimOnboarding(ctx, &models.OnboardingRequest{
+		IdempotencyKey: key,
+		RequestHash:    hash,
+		UserID:         uuid.New(),
+		Status:         models.OnboardingInProgress,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, now.Add(-u.cfg.LeaseTimeout), now.Add(-u.cfg.IdempotencyTTL))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	if rec.RequestHash != hash {
+		return nil, false, errModels.ErrIdempotencyKeyReused
+	}
+
+	result := &dto.OnboardingResponse{
+		UserID:         rec.UserID,
+		Email:          req.Email,
+		CompletedSteps: []string{},
+	}
+	if len(rec.Result) > 0 {
+		if err := json.Unmarshal(rec.Result, result); err != nil {
+			return nil, false, fmt.Errorf("failed to decode onboarding result: %w", err)
+		}
+	}
+
+	if !claimed {
+		if rec.Status == models.OnboardingCompleted {
+			return result, true, nil
+		}
+		return nil, false, errModels.ErrOnboardingInProgress
+	}
+
+	// Начатую сагу доводим до конца или откатываем, даже если клиент отключился
+	ctx = context.WithoutCancel(ctx)
+
+	if err := u.run(ctx, rec, req, result); err != nil {
+		return nil, false, err
+	}
+
+	return result, false, nil
+}
+
+func (u *OnboardingUsecase) run(ctx context.Context, rec *models.OnboardingRequest, req *dto.OnboardingRequest, result *dto.OnboardingResponse) error {
+	done := func(step string) bool {
+		return slices.Contains(result.CompletedSteps, step)
+	}
+
+	var dep *models.Department
+	if req.DepartmentID != nil {
+		var err error
+		dep, err = u.repo.GetDepartmentByID(ctx, *req.DepartmentID)
+		if err != nil {
+			return u.suspend(ctx, rec, result, fmt.Errorf("failed to get department by id: %w", err))
+		}
+		if (dep == nil || dep.IsArchived()) && !done(models.OnboardingStepUser) {
+			return u.release(ctx, rec, errModels.ErrDepartmentNotFound)
+		}
+	}
+
+	if !done(models.OnboardingStepUser) {
+		if err := u.createUser(ctx, rec, req); err != nil {
+			return err
+		}
+		if err := u.complete(ctx, rec, result, models.OnboardingStepUser); err != nil {
+			return err
+		}
+	}
+
+	if !done(models.OnboardingStepAccount) {
+		account, err := u.accounts.ProvisionAccount(ctx, rec.UserID, &identity.ProvisionAccountRequest{
+			Email:    req.Email,
+			Role:     req.Role,
+			Password: req.Password,
+		})
+		if err != nil {
+			// Откатываться можно только на явный отказ: после таймаута или 5xx аккаунт мог
+			// быть создан, а PUT идемпотентен - повтор с тем же ключом доведёт шаг
+			if errors.Is(err, identity.ErrEmailTaken) || errors.Is(err, identity.ErrInvalidAccount) {
+				return u.compensate(ctx, rec, err)
+			}
+			return u.suspend(ctx, rec, result, fmt.Errorf("failed to provision account: %w", err))
+		}
+
+		result.AccountID = account.AccountID
+		result.Role = account.Role
+		if err := u.complete(ctx, rec, result, models.OnboardingStepAccount); err != nil {
+			return err
+		}
+	}
+
+	if req.AddToDepartmentChat && dep != nil && !done(models.OnboardingStepChat) {
+		chatID, err := u.chats.JoinDepartmentChat(ctx, dep.DepartmentID, dep.Name, rec.UserID)
+		if err != nil {
+			return u.suspend(ctx, rec, result, fmt.Errorf("failed to add user to department chat: %w", err))
+		}
+
+		result.DepartmentChatID = &chatID
+		if err := u.complete(ctx, rec, result, models.OnboardingStepChat); err != nil {
+			return err
+		}
+	}
+
+	if req.SendInvitation && !done(models.OnboardingStepInvitation) {
+		invitation, err := u.invite(ctx, rec.UserID, req)
+		if err != nil {
+			return u.suspend(ctx, rec, result, err)
+		}
+
+		result.Invitation = invitation
+		if err := u.complete(ctx, rec, result, models.OnboardingStepInvitation); err != nil {
+			return err
+		}
+	}
+
+	rec.Status = models.OnboardingCompleted
+	return u.save(ctx, rec, result)
+}
+
+// createUser создаёт карточку сотрудника. Карточка с тем же id могла остаться от
+// прерванного повтора - тогда она используется повторно
+func (u *OnboardingUsecase) createUser(ctx context.Context, rec *models.OnboardingRequest, req *dto.OnboardingRequest) error {
+	existing, err := u.repo.GetUserByID(ctx, rec.UserID)
+	if err != nil {
+		return u.suspend(ctx, rec, nil, fmt.Errorf("failed to get user by id: %w", err))
+	}
+	if existing != nil {
+		return nil
+	}
+
+	email := req.Email
+	users, _, err := u.repo.GetUsers(ctx, &dto.GetUsersRequest{Email: &email, Limit: 1})
+	if err != nil {
+		return u.suspend(ctx, rec, nil, fmt.Errorf("failed to get users by email: %w", err))
+	}
+	if len(users) > 0 {
+		return u.release(ctx, rec, errModels.ErrUserEmailExists)
+	}
+
+	now := u.now()
+	user := &models.User{
+		UserID:       rec.UserID,
+		Email:        req.Email,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Position:     req.Position,
+		DepartmentID: req.DepartmentID,
+		AvatarURL:    req.AvatarURL,
+		IsActive:     true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if _, err := u.repo.CreateUser(ctx, user); err != nil {
+		return u.suspend(ctx, rec, nil, fmt.Errorf("failed to save user: %w", err))
+	}
+
+	return nil
+}
+
+func (u *OnboardingUsecase) invite(ctx context.Context, userID uuid.UUID, req *dto.OnboardingRequest) (*dto.OnboardingInvitation, error) {
+	reset, err := u.accounts.IssuePasswordReset(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue password reset token: %w", err)
+	}
+
+	link, err := url.Parse(u.cfg.SetPasswordURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse set password url: %w", err)
+	}
+	query := link.Query()
+	query.Set("token", reset.ResetToken)
+	link.RawQuery = query.Encode()
+
+	msg := &mailer.Message{
+		To:      req.Email,
+		Subject: "Приглашение в корпоративный мессенджер",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nДля вас создана учётная запись в корпоративном мессенджере.\n"+
+			"Чтобы задать пароль, перейдите по ссылке:\n%s\n\nСсылка действительна до %s.\n",
+			req.FirstName, link.String(), reset.ExpiresAt.Format("02.01.2006 15:04 MST")),
+	}
+	if err := u.mailer.Send(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to send invitation: %w", err)
+	}
+
+	return &dto.OnboardingInvitation{
+		SentTo:    req.Email,
+		ExpiresAt: reset.ExpiresAt,
+	}, nil
+}
+
+// compensate откатывает создание карточки после отказа identity-service
+func (u *OnboardingUsecase) compensate(ctx context.Context, rec *models.OnboardingRequest, cause error) error {
+	err := fmt.Errorf("%w: %v", errModels.ErrValidationFailed, cause)
+	if errors.Is(cause, identity.ErrEmailTaken) {
+		err = errModels.ErrAccountEmailExists
+	}
+
+	if delErr := u.repo.PurgeUser(ctx, rec.UserID); delErr != nil {
+		// Карточка осталась: повтор с тем же ключом снова попробует создать аккаунт
+		return u.suspend(ctx, rec, nil, fmt.Errorf("failed to roll back user %s: %v (after: %v)", rec.UserID, delErr, cause))
+	}
+
+	return u.release(ctx, rec, err)
+}
+
+// release освобождает ключ после отката: повтор выполнит онбординг заново
+func (u *OnboardingUsecase) release(ctx context.Context, rec *models.OnboardingRequest, cause error) error {
+	if err := u.repo.DeleteOnboarding(ctx, rec.IdempotencyKey); err != nil {
+		log.Printf("failed to release idempotency key %q: %v", rec.IdempotencyKey, err)
+	}
+
+	return cause
+}
+
+// suspend сохраняет выполненные шаги и разрешает сразу повторить запрос
+func (u *OnboardingUsecase) suspend(ctx context.Context, rec *models.OnboardingRequest, result *dto.OnboardingResponse, cause error) error {
+	rec.Status = models.OnboardingFailed
+	if err := u.save(ctx, rec, result); err != nil {
+		log.Printf("failed to save onboarding %q state: %v", rec.IdempotencyKey, err)
+	}
+
+	return fmt.Errorf("%w: %v", errModels.ErrOnboardingIncomplete, cause)
+}
+
+func (u *OnboardingUsecase) complete(ctx context.Context, rec *models.OnboardingRequest, result *dto.OnboardingResponse, step string) error {
+	result.CompletedSteps = append(result.CompletedSteps, step)
+	return u.save(ctx, rec, result)
+}
+
+// save записывает состояние; result=nil оставляет сохранённый результат
+func (u *OnboardingUsecase) save(ctx context.Context, rec *models.OnboardingRequest, result *dto.OnboardingResponse) error {
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode onboarding result: %w", err)
+		}
+		rec.Result = data
+	}
+
+	rec.UpdatedAt = u.now()
+	if err := u.repo.SaveOnboarding(ctx, rec); err != nil {
+		return fmt.Errorf("failed to save onboarding state: %w", err)
+	}
+
+	return nil
+}
+
+// onboardingHash - отпечаток тела запроса: ключ нельзя переиспользовать для другого
+// сотрудника. Пароль в отпечаток не входит, чтобы его хеш не хранился рядом с ключом
+func onboardingHash(req *dto.OnboardingRequest) (string, error) {
+	fingerprint := *req
+	fingerprint.Password = nil
+
+	data, err := json.Marshal(&fingerprint)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode onboarding request: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
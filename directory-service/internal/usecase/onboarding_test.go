@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/mailer"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+)
+
+type fakeMailer struct {
+	sent []*mailer.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg *mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Пароль и приглашение вместе не принимаются: сброс пароля для ссылки приглашения
+// молча заменил бы пароль, заданный администратором
+func TestOnboardRejectsPasswordWithInvitation(t *testing.T) {
+	accounts := newFakeAccounts()
+	mail := &fakeMailer{}
+	// Репозиторий не задан: обращение к нему до проверки запроса уронит тест
+	u := NewOnboardingUsecase(nil, accounts, nil, mail, OnboardingConfig{})
+
+	password := "Str0ng-password"
+	_, _, err := u.Onboard(context.Background(), "key-1", &dto.OnboardingRequest{
+		Email:          "ivan@example.com",
+		FirstName:      "Ivan",
+		LastName:       "Petrov",
+		Password:       &password,
+		SendInvitation: true,
+	})
+	if !errors.Is(err, errModels.ErrValidationFailed) {
+		t.Fatalf("expected ErrValidationFailed, got %v", err)
+	}
+	if len(accounts.provisioned) != 0 || len(mail.sent) != 0 {
+		t.Fatal("rejected request reached identity-service or mailer")
+	}
+}
@@ -0,0 +1,431 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/pkg/translit"
+	"github.com/google/uuid"
+)
+
+// Поля карточки, которыми управляют правила видимости и самообслуживания
+const (
+	ProfileFieldFirstName  = "first_name"
+	ProfileFieldLastName   = "last_name"
+	ProfileFieldEmail      = "email"
+	ProfileFieldPosition   = "position"
+	ProfileFieldDepartment = "department"
+	ProfileFieldAvatarURL  = "avatar_url"
+	ProfileFieldIsActive   = "is_active"
+	ProfileFieldCreatedAt  = "created_at"
+	ProfileFieldUpdatedAt  = "updated_at"
+)
+
+type ProfileRepo interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUsers(ctx context.Context, filter *dto.GetUsersRequest) ([]*models.User, int, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	GetDepartmentByID(ctx context.Context, id uuid.UUID) (*models.Department, error)
+	SearchUsers(ctx context.Context, search *models.UserSearch) ([]*models.UserMatch, error)
+	GetDepartmentPath(ctx context.Context, id uuid.UUID) ([]*models.Department, error)
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.User, error)
+}
+
+// Viewer - пользователь, от имени которого запрашивается карточка
+type Viewer struct {
+	UserID  uuid.UUID
+	IsAdmin bool
+}
+
+type ProfileUsecase struct {
+	repo          ProfileRepo
+	private       map[string]bool
+	editable      map[string]bool
+	minSimilarity float64
+}
+
+func NewProfileUsecase(repo ProfileRepo, cfg ProfileConfig) *ProfileUsecase {
+	return &ProfileUsecase{
+		repo:          repo,
+		private:       fieldSet(cfg.PrivateFields),
+		editable:      fieldSet(cfg.EditableFields),
+		minSimilarity: cfg.SearchMinSimilarity,
+	}
+}
+
+func (u *ProfileUsecase) GetMyProfile(ctx context.Context, viewer Viewer) (*dto.ProfileResponse, error) {
+	user, err := u.repo.GetUserByID(ctx, viewer.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	return u.toProfile(ctx, user, viewer, map[uuid.UUID]string{})
+}
+
+// UpdateMyProfile меняет только разрешённые для самообслуживания поля
+func (u *ProfileUsecase) UpdateMyProfile(ctx context.Context, viewer Viewer, req *dto.UpdateMyProfileRequest) (*dto.ProfileResponse, error) {
+	changes := map[string]*string{
+		ProfileFieldFirstName: req.FirstName,
+		ProfileFieldLastName:  req.LastName,
+		ProfileFieldPosition:  req.Position,
+		ProfileFieldAvatarURL: req.AvatarURL,
+	}
+
+	var denied []string
+	for field, value := range changes {
+		if value != nil && !u.editable[field] {
+			denied = append(denied, field)
+		}
+	}
+	if len(denied) > 0 {
+		slices.Sort(denied)
+		return nil, fmt.Errorf("%w: %s", errModels.ErrFieldNotEditable, strings.Join(denied, ", "))
+	}
+
+	if req.FirstName != nil && strings.TrimSpace(*req.FirstName) == "" {
+		return nil, fmt.Errorf("%w: first_name must not be empty", errModels.ErrValidationFailed)
+	}
+	if req.LastName != nil && strings.TrimSpace(*req.LastName) == "" {
+		return nil, fmt.Errorf("%w: last_name must not be empty", errModels.ErrValidationFailed)
+	}
+	if req.AvatarURL != nil {
+		if err := validateAvatarURL(*req.AvatarURL); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := u.repo.GetUserByID(ctx, viewer.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	if req.FirstName != nil {
+		user.FirstName = strings.TrimSpace(*req.FirstName)
+	}
+	if req.LastName != nil {
+		user.LastName = strings.TrimSpace(*req.LastName)
+	}
+	if req.Position != nil {
+		user.Position = optionalString(*req.Position)
+	}
+	if req.AvatarURL != nil {
+		user.AvatarURL = optionalString(*req.AvatarURL)
+	}
+	user.UpdatedAt = time.Now()
+
+	if err := u.repo.UpdateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return u.toProfile(ctx, user, viewer, map[uuid.UUID]string{})
+}
+
+// GetColleague - карточка коллеги; деактивированные сотрудники видны только администраторам
+func (u *ProfileUsecase) GetColleague(ctx context.Context, viewer Viewer, id uuid.UUID) (*dto.ProfileResponse, error) {
+	user, err := u.repo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+	// Уволенный остаётся в истории чатов: коллегам отдаём только имя
+	if !user.IsActive && !viewer.IsAdmin && user.UserID != viewer.UserID {
+		return formerColleague(user), nil
+	}
+
+	return u.toProfile(ctx, user, viewer, map[uuid.UUID]string{})
+}
+
+func (u *ProfileUsecase) ListColleagues(ctx context.Context, viewer Viewer, req *dto.GetColleaguesRequest) (*dto.GetColleaguesResponse, error) {
+	if req.DepartmentID != nil {
+		if err := u.checkDepartmentFilter(viewer); err != nil {
+			return nil, err
+		}
+	}
+
+	active := true
+	users, total, err := u.repo.GetUsers(ctx, &dto.GetUsersRequest{
+		Limit:        req.Limit,
+		Offset:       req.Offset,
+		DepartmentID: req.DepartmentID,
+		IsActive:     &active,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	depNames := make(map[uuid.UUID]string)
+	profiles := make([]*dto.ProfileResponse, 0, len(users))
+	for _, user := range users {
+		profile, err := u.toProfile(ctx, user, viewer, depNames)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return &dto.GetColleaguesResponse{
+		Users: profiles,
+		Total: total,
+	}, nil
+}
+
+// SearchColleagues - нечёткий поиск для выбора собеседника и @-упоминаний. Запрос
+// транслитерируется, поэтому "Ivan" находит "Иван". Скрытые от зрителя поля в поиске
+// не участвуют, иначе по ним можно было бы подбирать значения
+func (u *ProfileUsecase) SearchColleagues(ctx context.Context, viewer Viewer, req *dto.SearchUsersRequest) (*dto.SearchUsersResponse, error) {
+	terms := translit.Variants(req.Query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: q must not be empty", errModels.ErrValidationFailed)
+	}
+
+	if req.DepartmentID != nil {
+		if err := u.checkDepartmentFilter(viewer); err != nil {
+			return nil, err
+		}
+
+		dep, err := u.repo.GetDepartmentByID(ctx, *req.DepartmentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get department: %w", err)
+		}
+		if dep == nil {
+			return nil, errModels.ErrDepartmentNotFound
+		}
+	}
+
+	fields := []string{models.UserSearchName}
+	if viewer.IsAdmin || !u.private[ProfileFieldEmail] {
+		fields = append(fields, models.UserSearchEmail)
+	}
+	if viewer.IsAdmin || !u.private[ProfileFieldPosition] {
+		fields = append(fields, models.UserSearchPosition)
+	}
+
+	matches, err := u.repo.SearchUsers(ctx, &models.UserSearch{
+		Terms:         terms,
+		Fields:        fields,
+		DepartmentID:  req.DepartmentID,
+		Subtree:       req.Subtree,
+		ActiveOnly:    !(viewer.IsAdmin && req.IncludeInactive),
+		MinSimilarity: u.minSimilarity,
+		Limit:         req.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	depNames := make(map[uuid.UUID]string)
+	results := make([]*dto.UserSearchResult, 0, len(matches))
+	for _, match := range matches {
+		profile, err := u.toProfile(ctx, match.User, viewer, depNames)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &dto.UserSearchResult{
+			ProfileResponse: profile,
+			Score:           match.Score,
+		})
+	}
+
+	return &dto.SearchUsersResponse{Users: results}, nil
+}
+
+// GetManagerChain - руководители сотрудника снизу вверх: глава его отдела, затем главы
+// вышестоящих отделов. Руководитель отдела подчиняется главе родительского отдела;
+// незанятые и деактивированные руководители пропускаются. Если отдел скрыт от зрителя,
+// отделы руководителей в ответ не попадают
+func (u *ProfileUsecase) GetManagerChain(ctx context.Context, viewer Viewer, userID uuid.UUID) (*dto.ManagerChainResponse, error) {
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || (!user.IsActive && !viewer.IsAdmin && user.UserID != viewer.UserID) {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	resp := &dto.ManagerChainResponse{Managers: []*dto.Manager{}}
+	if user.DepartmentID == nil {
+		return resp, nil
+	}
+
+	path, err := u.repo.GetDepartmentPath(ctx, *user.DepartmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department path: %w", err)
+	}
+
+	seen := map[uuid.UUID]bool{user.UserID: true}
+	var chain []*models.Department
+	for i := len(path) - 1; i >= 0; i-- {
+		head := path[i].HeadUserID
+		if head == nil || seen[*head] {
+			continue
+		}
+		seen[*head] = true
+		chain = append(chain, path[i])
+	}
+
+	headIDs := make([]uuid.UUID, 0, len(chain))
+	for _, dep := range chain {
+		headIDs = append(headIDs, *dep.HeadUserID)
+	}
+	heads, err := u.repo.GetUsersByIDs(ctx, headIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department heads: %w", err)
+	}
+	headByID := make(map[uuid.UUID]*models.User, len(heads))
+	for _, head := range heads {
+		headByID[head.UserID] = head
+	}
+
+	showDepartments := viewer.UserID == user.UserID || !u.departmentPrivate(viewer)
+
+	depNames := make(map[uuid.UUID]string)
+	for _, dep := range chain {
+		head, ok := headByID[*dep.HeadUserID]
+		if !ok || !head.IsActive {
+			continue
+		}
+
+		profile, err := u.toProfile(ctx, head, viewer, depNames)
+		if err != nil {
+			return nil, err
+		}
+		manager := &dto.Manager{ProfileResponse: profile}
+		if showDepartments {
+			manager.HeadOf = &dto.DepartmentRef{
+				DepartmentID: dep.DepartmentID,
+				Name:         dep.Name,
+			}
+		}
+		resp.Managers = append(resp.Managers, manager)
+	}
+
+	return resp, nil
+}
+
+// departmentPrivate - отдел коллег скрыт от зрителя
+func (u *ProfileUsecase) departmentPrivate(viewer Viewer) bool {
+	return !viewer.IsAdmin && u.private[ProfileFieldDepartment]
+}
+
+// checkDepartmentFilter запрещает отбор по скрытому отделу: по составу выборки
+// можно было бы узнать отдел коллеги
+func (u *ProfileUsecase) checkDepartmentFilter(viewer Viewer) error {
+	if u.departmentPrivate(viewer) {
+		return fmt.Errorf("%w: department is private, filtering by it is not allowed", errModels.ErrForbidden)
+	}
+
+	return nil
+}
+
+// toProfile собирает карточку с учётом видимости полей. Имя и фамилия видны всегда,
+// иначе коллегу не найти. depNames кэширует названия отделов в пределах запроса
+func (u *ProfileUsecase) toProfile(ctx context.Context, user *models.User, viewer Viewer, depNames map[uuid.UUID]string) (*dto.ProfileResponse, error) {
+	showAll := viewer.IsAdmin || viewer.UserID == user.UserID
+	visible := func(field string) bool {
+		return showAll || !u.private[field]
+	}
+
+	profile := &dto.ProfileResponse{
+		UserID:    user.UserID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
+
+	if visible(ProfileFieldEmail) {
+		profile.Email = &user.Email
+	}
+	if visible(ProfileFieldPosition) {
+		profile.Position = user.Position
+	}
+	if visible(ProfileFieldAvatarURL) {
+		profile.AvatarURL = user.AvatarURL
+	}
+	if visible(ProfileFieldIsActive) {
+		profile.IsActive = &user.IsActive
+	}
+	if visible(ProfileFieldCreatedAt) {
+		profile.CreatedAt = &user.CreatedAt
+	}
+	if visible(ProfileFieldUpdatedAt) {
+		profile.UpdatedAt = &user.UpdatedAt
+	}
+
+	if user.DepartmentID != nil && visible(ProfileFieldDepartment) {
+		profile.DepartmentID = user.DepartmentID
+
+		name, ok := depNames[*user.DepartmentID]
+		if !ok {
+			dep, err := u.repo.GetDepartmentByID(ctx, *user.DepartmentID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get department: %w", err)
+			}
+			if dep != nil {
+				name = dep.Name
+			}
+			depNames[*user.DepartmentID] = name
+		}
+		if name != "" {
+			profile.DepartmentName = &name
+		}
+	}
+
+	return profile, nil
+}
+
+func formerColleague(user *models.User) *dto.ProfileResponse {
+	inactive := false
+	return &dto.ProfileResponse{
+		UserID:    user.UserID,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		IsActive:  &inactive,
+	}
+}
+
+func fieldSet(fields []string) map[string]bool {
+	set := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			set[field] = true
+		}
+	}
+	return set
+}
+
+// validateAvatarURL допускает пустую строку (очистка поля) и абсолютные http(s)-ссылки
+func validateAvatarURL(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := url.ParseRequestURI(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: avatar_url must be an http(s) URL", errModels.ErrValidationFailed)
+	}
+
+	return nil
+}
+
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
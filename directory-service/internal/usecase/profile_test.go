@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type fakeProfileRepo struct {
+	users       map[uuid.UUID]*models.User
+	departments map[uuid.UUID]*models.Department
+	queried     bool
+}
+
+func (r *fakeProfileRepo) GetUserByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	return r.users[id], nil
+}
+
+func (r *fakeProfileRepo) GetUsers(_ context.Context, _ *dto.GetUsersRequest) ([]*models.User, int, error) {
+	r.queried = true
+	return nil, 0, nil
+}
+
+func (r *fakeProfileRepo) UpdateUser(_ context.Context, _ *models.User) error {
+	return nil
+}
+
+func (r *fakeProfileRepo) GetDepartmentByID(_ context.Context, id uuid.UUID) (*models.Department, error) {
+	return r.departments[id], nil
+}
+
+func (r *fakeProfileRepo) SearchUsers(_ context.Context, _ *models.UserSearch) ([]*models.UserMatch, error) {
+	r.queried = true
+	return nil, nil
+}
+
+// GetDepartmentPath возвращает путь от корня до отдела
+func (r *fakeProfileRepo) GetDepartmentPath(_ context.Context, id uuid.UUID) ([]*models.Department, error) {
+	var path []*models.Department
+	for dep := r.departments[id]; dep != nil; {
+		path = append([]*models.Department{dep}, path...)
+		if dep.ParentID == nil {
+			break
+		}
+		dep = r.departments[*dep.ParentID]
+	}
+	return path, nil
+}
+
+func (r *fakeProfileRepo) GetUsersByIDs(_ context.Context, ids []uuid.UUID) ([]*models.User, error) {
+	var users []*models.User
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+type profileTestEnv struct {
+	repo     *fakeProfileRepo
+	employee *models.User
+	head     *models.User
+	dep      *models.Department
+}
+
+// newProfileTestEnv - сотрудник в отделе, которым руководит head
+func newProfileTestEnv() *profileTestEnv {
+	dep := &models.Department{DepartmentID: uuid.New(), Name: "Security"}
+	head := &models.User{UserID: uuid.New(), FirstName: "Olga", LastName: "Smirnova", DepartmentID: &dep.DepartmentID, IsActive: true}
+	employee := &models.User{UserID: uuid.New(), FirstName: "Ivan", LastName: "Petrov", DepartmentID: &dep.DepartmentID, IsActive: true}
+	dep.HeadUserID = &head.UserID
+
+	return &profileTestEnv{
+		repo: &fakeProfileRepo{
+			users:       map[uuid.UUID]*models.User{head.UserID: head, employee.UserID: employee},
+			departments: map[uuid.UUID]*models.Department{dep.DepartmentID: dep},
+		},
+		employee: employee,
+		head:     head,
+		dep:      dep,
+	}
+}
+
+// Скрытый отдел нельзя узнать перебором фильтра department_id
+func TestDepartmentFilterRejectedWhenDepartmentPrivate(t *testing.T) {
+	tests := []struct {
+		name      string
+		private   []string
+		isAdmin   bool
+		wantError bool
+	}{
+		{name: "private department, colleague", private: []string{ProfileFieldDepartment}, wantError: true},
+		{name: "private department, admin", private: []string{ProfileFieldDepartment}, isAdmin: true},
+		{name: "public department, colleague"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newProfileTestEnv()
+			u := NewProfileUsecase(env.repo, ProfileConfig{PrivateFields: tt.private})
+			viewer := Viewer{UserID: uuid.New(), IsAdmin: tt.isAdmin}
+
+			_, listErr := u.ListColleagues(context.Background(), viewer, &dto.GetColleaguesRequest{
+				Limit:        20,
+				DepartmentID: &env.dep.DepartmentID,
+			})
+			_, searchErr := u.SearchColleagues(context.Background(), viewer, &dto.SearchUsersRequest{
+				Query:        "Ivan",
+				DepartmentID: &env.dep.DepartmentID,
+				Subtree:      true,
+				Limit:        10,
+			})
+
+			for name, err := range map[string]error{"list": listErr, "search": searchErr} {
+				if tt.wantError && !errors.Is(err, errModels.ErrForbidden) {
+					t.Errorf("%s: expected ErrForbidden, got %v", name, err)
+				}
+				if !tt.wantError && err != nil {
+					t.Errorf("%s: unexpected error: %v", name, err)
+				}
+			}
+			if tt.wantError && env.repo.queried {
+				t.Fatal("repository must not be queried with a hidden department filter")
+			}
+		})
+	}
+}
+
+// Цепочка руководителей не раскрывает отделы, если они скрыты от зрителя
+func TestManagerChainHidesPrivateDepartment(t *testing.T) {
+	tests := []struct {
+		name       string
+		private    []string
+		self       bool
+		isAdmin    bool
+		wantHeadOf bool
+	}{
+		{name: "private department, colleague", private: []string{ProfileFieldDepartment}},
+		{name: "private department, own chain", private: []string{ProfileFieldDepartment}, self: true, wantHeadOf: true},
+		{name: "private department, admin", private: []string{ProfileFieldDepartment}, isAdmin: true, wantHeadOf: true},
+		{name: "public department, colleague", wantHeadOf: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newProfileTestEnv()
+			u := NewProfileUsecase(env.repo, ProfileConfig{PrivateFields: tt.private})
+			viewer := Viewer{UserID: uuid.New(), IsAdmin: tt.isAdmin}
+			if tt.self {
+				viewer.UserID = env.employee.UserID
+			}
+
+			resp, err := u.GetManagerChain(context.Background(), viewer, env.employee.UserID)
+			if err != nil {
+				t.Fatalf("GetManagerChain: %v", err)
+			}
+			if len(resp.Managers) != 1 || resp.Managers[0].UserID != env.head.UserID {
+				t.Fatalf("expected the department head in the chain, got %+v", resp.Managers)
+			}
+
+			manager := resp.Managers[0]
+			if tt.wantHeadOf {
+				if manager.HeadOf == nil || manager.HeadOf.DepartmentID != env.dep.DepartmentID {
+					t.Fatalf("expected head_of %s, got %+v", env.dep.DepartmentID, manager.HeadOf)
+				}
+				return
+			}
+			if manager.HeadOf != nil {
+				t.Errorf("head_of must be hidden, got %+v", manager.HeadOf)
+			}
+			if manager.DepartmentID != nil || manager.DepartmentName != nil {
+				t.Errorf("manager department must be hidden, got %v %v", manager.DepartmentID, manager.DepartmentName)
+			}
+		})
+	}
+}
@@ -0,0 +1,781 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/pkg/scim"
+	"github.com/google/uuid"
+)
+
+// Роли аккаунтов identity-service, которые можно назначить через roles
+var scimAccountRoles = map[string]bool{
+	"user":      true,
+	"support":   true,
+	"admin":     true,
+	"moderator": true,
+}
+
+type SCIMRepo interface {
+	CreateUser(ctx context.Context, user *models.User) (uuid.UUID, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetUsers(ctx context.Context, filter *dto.GetUsersRequest) ([]*models.User, int, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	PurgeUser(ctx context.Context, id uuid.UUID) error
+	DeactivateUser(ctx context.Context, id uuid.UUID, at time.Time) error
+	FindUsersByFilter(ctx context.Context, filter scim.Filter, limit, offset int) ([]*models.User, int, error)
+
+	GetDepartmentByID(ctx context.Context, id uuid.UUID) (*models.Department, error)
+	FindDepartmentsByFilter(ctx context.Context, filter scim.Filter, limit, offset int) ([]*models.Department, int, error)
+	GetAllDepartmentMembers(ctx context.Context, depID uuid.UUID) ([]*models.User, error)
+	SaveGroup(ctx context.Context, change *models.GroupChange) (bool, error)
+	RemoveDepartment(ctx context.Context, removal *models.DepartmentRemoval) (*models.DepartmentRemovalResult, error)
+
+	FindSyncLink(ctx context.Context, source string, objectType models.SyncObjectType, externalID string) (*models.SyncLink, error)
+	GetExternalIDs(ctx context.Context, source string, objectType models.SyncObjectType, localIDs []uuid.UUID) (map[uuid.UUID]string, error)
+	SetExternalID(ctx context.Context, source string, objectType models.SyncObjectType, localID uuid.UUID, externalID string, syncedAt time.Time) error
+}
+
+// SCIMUsecase - SCIM-провайдер поверх каталога: User - сотрудник каталога вместе с
+// аккаунтом identity-service, Group - отдел. externalId хранится в directory_sync_links
+type SCIMUsecase struct {
+	repo     SCIMRepo
+	accounts identity.Client
+	cfg      SCIMConfig
+
+	now func() time.Time
+}
+
+func NewSCIMUsecase(repo SCIMRepo, accounts identity.Client, cfg SCIMConfig) *SCIMUsecase {
+	return &SCIMUsecase{
+		repo:     repo,
+		accounts: accounts,
+		cfg:      cfg,
+		now:      time.Now,
+	}
+}
+
+// scimUserFields - проверенные атрибуты ресурса User
+type scimUserFields struct {
+	email     string
+	firstName string
+	lastName  string
+	position  *string
+	avatarURL *string
+	active    bool
+	role      string
+	password  *string
+}
+
+func (u *SCIMUsecase) ListUsers(ctx context.Context, req *scim.ListRequest) (*scim.ListResponse, error) {
+	filter, limit, offset, err := u.listParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	users, total, err := u.repo.FindUsersByFilter(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+
+	resources, err := u.userResources(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := newListResponse(total, offset, len(resources))
+	for _, resource := range resources {
+		resp.Resources = append(resp.Resources, resource)
+	}
+
+	return resp, nil
+}
+
+func (u *SCIMUsecase) GetUser(ctx context.Context, id string) (*dto.SCIMUser, error) {
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.userResource(ctx, user)
+}
+
+func (u *SCIMUsecase) CreateUser(ctx context.Context, in *dto.SCIMUser) (*dto.SCIMUser, error) {
+	fields, err := scimUserInput(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.ensureEmailFree(ctx, fields.email, uuid.Nil); err != nil {
+		return nil, err
+	}
+	if err := u.ensureExternalIDFree(ctx, models.SyncObjectUser, in.ExternalID, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	user := &models.User{
+		UserID:    uuid.New(),
+		CreatedAt: now,
+	}
+	fields.applyTo(user, now)
+
+	if _, err := u.repo.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to save user: %w", err)
+	}
+
+	if err := u.provisionUser(ctx, user, in.ExternalID, fields); err != nil {
+		// Без аккаунта сотрудник не сможет войти: откатываем создание, провайдер повторит запрос
+		if delErr := u.repo.PurgeUser(ctx, user.UserID); delErr != nil {
+			return nil, fmt.Errorf("failed to roll back user %s: %v (after: %w)", user.UserID, delErr, err)
+		}
+		if linkErr := u.repo.SetExternalID(ctx, models.SyncSourceSCIM, models.SyncObjectUser, user.UserID, "", now); linkErr != nil {
+			return nil, fmt.Errorf("failed to roll back user link %s: %v (after: %w)", user.UserID, linkErr, err)
+		}
+		return nil, err
+	}
+
+	return u.userResource(ctx, user)
+}
+
+// ReplaceUser - PUT: ресурс целиком заменяет атрибуты пользователя
+func (u *SCIMUsecase) ReplaceUser(ctx context.Context, id string, in *dto.SCIMUser) (*dto.SCIMUser, error) {
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.updateUser(ctx, user, in)
+}
+
+func (u *SCIMUsecase) PatchUser(ctx context.Context, id string, req *scim.PatchRequest) (*dto.SCIMUser, error) {
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := u.userResource(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched dto.SCIMUser
+	if err := applyPatch(current, req, &patched); err != nil {
+		return nil, err
+	}
+	if patched.ID != current.ID {
+		return nil, scim.BadRequest(scim.TypeMutability, "attribute id is read-only")
+	}
+
+	return u.updateUser(ctx, user, &patched)
+}
+
+// DeleteUser увольняет сотрудника: запись остаётся неактивной, аккаунт деактивируется,
+// а externalId освобождается для повторного создания
+func (u *SCIMUsecase) DeleteUser(ctx context.Context, id string) error {
+	user, err := u.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = u.accounts.DeprovisionAccount(ctx, user.UserID)
+	if err != nil && !errors.Is(err, identity.ErrAccountNotFound) {
+		return fmt.Errorf("failed to deprovision account: %w", err)
+	}
+
+	now := u.now()
+	if err := u.repo.DeactivateUser(ctx, user.UserID, now); err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+
+	return u.repo.SetExternalID(ctx, models.SyncSourceSCIM, models.SyncObjectUser, user.UserID, "", now)
+}
+
+func (u *SCIMUsecase) ListGroups(ctx context.Context, req *scim.ListRequest) (*scim.ListResponse, error) {
+	filter, limit, offset, err := u.listParams(req)
+	if err != nil {
+		return nil, err
+	}
+
+	deps, total, err := u.repo.FindDepartmentsByFilter(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find departments: %w", err)
+	}
+
+	resources, err := u.groupResources(ctx, deps, !req.Excludes("members"))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := newListResponse(total, offset, len(resources))
+	for _, resource := range resources {
+		resp.Resources = append(resp.Resources, resource)
+	}
+
+	return resp, nil
+}
+
+func (u *SCIMUsecase) GetGroup(ctx context.Context, id string, withMembers bool) (*dto.SCIMGroup, error) {
+	dep, err := u.getDepartment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := u.groupResources(ctx, []*models.Department{dep}, withMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	return resources[0], nil
+}
+
+// CreateGroup создаёт корневой отдел; члены группы переводятся в него
+func (u *SCIMUsecase) CreateGroup(ctx context.Context, in *dto.SCIMGroup) (*dto.SCIMGroup, error) {
+	name := strings.TrimSpace(in.DisplayName)
+	if name == "" {
+		return nil, scim.BadRequest(scim.TypeInvalidValue, "displayName is required")
+	}
+	memberIDs, err := u.memberIDs(ctx, in.Members)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.ensureExternalIDFree(ctx, models.SyncObjectDepartment, in.ExternalID, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	dep := &models.Department{
+		DepartmentID: uuid.New(),
+		Name:         name,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if _, err := u.repo.SaveGroup(ctx, &models.GroupChange{
+		Department: dep,
+		Create:     true,
+		MemberIDs:  memberIDs,
+		ExternalID: in.ExternalID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to save group: %w", err)
+	}
+
+	return u.GetGroup(ctx, dep.DepartmentID.String(), true)
+}
+
+func (u *SCIMUsecase) ReplaceGroup(ctx context.Context, id string, in *dto.SCIMGroup) (*dto.SCIMGroup, error) {
+	dep, err := u.getDepartment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.updateGroup(ctx, dep, in)
+}
+
+func (u *SCIMUsecase) PatchGroup(ctx context.Context, id string, req *scim.PatchRequest) (*dto.SCIMGroup, error) {
+	dep, err := u.getDepartment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := u.groupResources(ctx, []*models.Department{dep}, true)
+	if err != nil {
+		return nil, err
+	}
+	current := resources[0]
+
+	var patched dto.SCIMGroup
+	if err := applyPatch(current, req, &patched); err != nil {
+		return nil, err
+	}
+	if patched.ID != current.ID {
+		return nil, scim.BadRequest(scim.TypeMutability, "attribute id is read-only")
+	}
+
+	return u.updateGroup(ctx, dep, &patched)
+}
+
+// DeleteGroup архивирует отдел, как удаление отдела с archive: действующие сотрудники
+// остаются без отдела, уволенные сохраняют его для истории, externalId освобождается.
+// Отдел с действующими дочерними отделами не удаляется
+func (u *SCIMUsecase) DeleteGroup(ctx context.Context, id string) error {
+	dep, err := u.getDepartment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	result, err := u.repo.RemoveDepartment(ctx, &models.DepartmentRemoval{
+		DepartmentID: dep.DepartmentID,
+		Archive:      true,
+		DetachUsers:  true,
+		UnlinkSource: models.SyncSourceSCIM,
+		RemovedAt:    u.now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove department: %w", err)
+	}
+
+	switch {
+	case result.NotFound:
+		return errModels.ErrDepartmentNotFound
+	case result.HasChildren:
+		return errModels.ErrDepartmentHasChildren
+	}
+
+	return nil
+}
+
+func (u *SCIMUsecase) updateUser(ctx context.Context, user *models.User, in *dto.SCIMUser) (*dto.SCIMUser, error) {
+	fields, err := scimUserInput(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.ensureEmailFree(ctx, fields.email, user.UserID); err != nil {
+		return nil, err
+	}
+	if err := u.ensureExternalIDFree(ctx, models.SyncObjectUser, in.ExternalID, user.UserID); err != nil {
+		return nil, err
+	}
+
+	// Сначала аккаунт: identity-service проверяет пароль и email, и отказ не должен
+	// оставить каталог наполовину обновлённым
+	now := u.now()
+	updated := *user
+	fields.applyTo(&updated, now)
+
+	if err := u.provisionUser(ctx, &updated, in.ExternalID, fields); err != nil {
+		return nil, err
+	}
+	if err := u.repo.UpdateUser(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return u.userResource(ctx, &updated)
+}
+
+// provisionUser сохраняет externalId и приводит аккаунт identity-service к состоянию ресурса
+func (u *SCIMUsecase) provisionUser(ctx context.Context, user *models.User, externalID string, fields *scimUserFields) error {
+	if err := u.repo.SetExternalID(ctx, models.SyncSourceSCIM, models.SyncObjectUser, user.UserID, externalID, user.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to save external id: %w", err)
+	}
+
+	active := fields.active
+	_, err := u.accounts.ProvisionAccount(ctx, user.UserID, &identity.ProvisionAccountRequest{
+		Email:    fields.email,
+		Role:     fields.role,
+		IsActive: &active,
+		Password: fields.password,
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, identity.ErrEmailTaken):
+		return scim.NewError(http.StatusConflict, scim.TypeUniqueness, "userName %q is used by another account", fields.email)
+	case errors.Is(err, identity.ErrInvalidAccount):
+		return scim.BadRequest(scim.TypeInvalidValue, "%s", err.Error())
+	default:
+		return fmt.Errorf("failed to provision account: %w", err)
+	}
+}
+
+func (u *SCIMUsecase) updateGroup(ctx context.Context, dep *models.Department, in *dto.SCIMGroup) (*dto.SCIMGroup, error) {
+	name := strings.TrimSpace(in.DisplayName)
+	if name == "" {
+		return nil, scim.BadRequest(scim.TypeInvalidValue, "displayName is required")
+	}
+	memberIDs, err := u.memberIDs(ctx, in.Members)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.ensureExternalIDFree(ctx, models.SyncObjectDepartment, in.ExternalID, dep.DepartmentID); err != nil {
+		return nil, err
+	}
+
+	updated := *dep
+	updated.Name = name
+	updated.UpdatedAt = u.now()
+
+	saved, err := u.repo.SaveGroup(ctx, &models.GroupChange{
+		Department: &updated,
+		MemberIDs:  memberIDs,
+		ExternalID: in.ExternalID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save group: %w", err)
+	}
+	if !saved {
+		return nil, errModels.ErrDepartmentNotFound
+	}
+
+	return u.GetGroup(ctx, dep.DepartmentID.String(), true)
+}
+
+func (u *SCIMUsecase) listParams(req *scim.ListRequest) (scim.Filter, int, int, error) {
+	var filter scim.Filter
+	if req.Filter != "" {
+		f, err := scim.ParseFilter(req.Filter)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		filter = f
+	}
+
+	limit := req.Count
+	if limit < 0 {
+		limit = 0
+	}
+	if u.cfg.MaxResults > 0 && limit > u.cfg.MaxResults {
+		limit = u.cfg.MaxResults
+	}
+
+	offset := req.StartIndex - 1
+	if offset < 0 {
+		offset = 0
+	}
+
+	return filter, limit, offset, nil
+}
+
+func newListResponse(total, offset, count int) *scim.ListResponse {
+	return &scim.ListResponse{
+		Schemas:      []string{scim.SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   offset + 1,
+		ItemsPerPage: count,
+		Resources:    make([]any, 0, count),
+	}
+}
+
+func (u *SCIMUsecase) getUser(ctx context.Context, id string) (*models.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	user, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+	if user == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+func (u *SCIMUsecase) getDepartment(ctx context.Context, id string) (*models.Department, error) {
+	depID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errModels.ErrDepartmentNotFound
+	}
+
+	dep, err := u.repo.GetDepartmentByID(ctx, depID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department by id: %w", err)
+	}
+	if dep == nil || dep.IsArchived() {
+		return nil, errModels.ErrDepartmentNotFound
+	}
+
+	return dep, nil
+}
+
+func (u *SCIMUsecase) ensureEmailFree(ctx context.Context, email string, owner uuid.UUID) error {
+	users, _, err := u.repo.GetUsers(ctx, &dto.GetUsersRequest{Email: &email, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("failed to get users by email: %w", err)
+	}
+	if len(users) > 0 && users[0].UserID != owner {
+		return scim.NewError(http.StatusConflict, scim.TypeUniqueness, "userName %q is already taken", email)
+	}
+
+	return nil
+}
+
+func (u *SCIMUsecase) ensureExternalIDFree(ctx context.Context, objectType models.SyncObjectType, externalID string, owner uuid.UUID) error {
+	if externalID == "" {
+		return nil
+	}
+
+	link, err := u.repo.FindSyncLink(ctx, models.SyncSourceSCIM, objectType, externalID)
+	if err != nil {
+		return fmt.Errorf("failed to get sync link: %w", err)
+	}
+	if link != nil && link.LocalID != owner {
+		return scim.NewError(http.StatusConflict, scim.TypeUniqueness, "externalId %q is already taken", externalID)
+	}
+
+	return nil
+}
+
+// memberIDs проверяет, что все члены группы - существующие пользователи
+func (u *SCIMUsecase) memberIDs(ctx context.Context, members []dto.SCIMMember) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool, len(members))
+	ids := make([]uuid.UUID, 0, len(members))
+
+	for _, member := range members {
+		id, err := uuid.Parse(member.Value)
+		if err != nil {
+			return nil, scim.BadRequest(scim.TypeInvalidValue, "unknown member %q", member.Value)
+		}
+		if seen[id] {
+			continue
+		}
+
+		user, err := u.repo.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user by id: %w", err)
+		}
+		if user == nil {
+			return nil, scim.BadRequest(scim.TypeInvalidValue, "unknown member %q", member.Value)
+		}
+
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (u *SCIMUsecase) userResource(ctx context.Context, user *models.User) (*dto.SCIMUser, error) {
+	resources, err := u.userResources(ctx, []*models.User{user})
+	if err != nil {
+		return nil, err
+	}
+
+	return resources[0], nil
+}
+
+func (u *SCIMUsecase) userResources(ctx context.Context, users []*models.User) ([]*dto.SCIMUser, error) {
+	externalIDs, err := u.repo.GetExternalIDs(ctx, models.SyncSourceSCIM, models.SyncObjectUser, userIDs(users))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get external ids: %w", err)
+	}
+
+	deps := make(map[uuid.UUID]*models.Department)
+	resources := make([]*dto.SCIMUser, 0, len(users))
+
+	for _, user := range users {
+		formatted := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		active := scim.Boolean(user.IsActive)
+
+		resource := &dto.SCIMUser{
+			Schemas:    []string{scim.SchemaUser},
+			ID:         user.UserID.String(),
+			ExternalID: externalIDs[user.UserID],
+			UserName:   user.Email,
+			Name: &dto.SCIMName{
+				GivenName:  user.FirstName,
+				FamilyName: user.LastName,
+				Formatted:  formatted,
+			},
+			DisplayName: formatted,
+			Emails:      []dto.SCIMMultiValue{{Value: user.Email, Type: "work", Primary: true}},
+			Active:      &active,
+			Meta: &scim.Meta{
+				ResourceType: "User",
+				Created:      user.CreatedAt,
+				LastModified: user.UpdatedAt,
+				Location:     u.cfg.BaseURL + "/Users/" + user.UserID.String(),
+			},
+		}
+		if user.Position != nil {
+			resource.Title = *user.Position
+		}
+		if user.AvatarURL != nil {
+			resource.Photos = []dto.SCIMMultiValue{{Value: *user.AvatarURL, Type: "photo", Primary: true}}
+		}
+
+		if user.DepartmentID != nil {
+			dep, ok := deps[*user.DepartmentID]
+			if !ok {
+				dep, err = u.repo.GetDepartmentByID(ctx, *user.DepartmentID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get department by id: %w", err)
+				}
+				deps[*user.DepartmentID] = dep
+			}
+			if dep != nil {
+				resource.Groups = []dto.SCIMMember{{
+					Value:   dep.DepartmentID.String(),
+					Display: dep.Name,
+					Ref:     u.cfg.BaseURL + "/Groups/" + dep.DepartmentID.String(),
+				}}
+			}
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+func (u *SCIMUsecase) groupResources(ctx context.Context, deps []*models.Department, withMembers bool) ([]*dto.SCIMGroup, error) {
+	ids := make([]uuid.UUID, 0, len(deps))
+	for _, dep := range deps {
+		ids = append(ids, dep.DepartmentID)
+	}
+
+	externalIDs, err := u.repo.GetExternalIDs(ctx, models.SyncSourceSCIM, models.SyncObjectDepartment, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get external ids: %w", err)
+	}
+
+	resources := make([]*dto.SCIMGroup, 0, len(deps))
+	for _, dep := range deps {
+		resource := &dto.SCIMGroup{
+			Schemas:     []string{scim.SchemaGroup},
+			ID:          dep.DepartmentID.String(),
+			ExternalID:  externalIDs[dep.DepartmentID],
+			DisplayName: dep.Name,
+			Meta: &scim.Meta{
+				ResourceType: "Group",
+				Created:      dep.CreatedAt,
+				LastModified: dep.UpdatedAt,
+				Location:     u.cfg.BaseURL + "/Groups/" + dep.DepartmentID.String(),
+			},
+		}
+
+		if withMembers {
+			members, err := u.repo.GetAllDepartmentMembers(ctx, dep.DepartmentID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get group members: %w", err)
+			}
+			for _, member := range members {
+				resource.Members = append(resource.Members, dto.SCIMMember{
+					Value:   member.UserID.String(),
+					Display: strings.TrimSpace(member.FirstName + " " + member.LastName),
+					Ref:     u.cfg.BaseURL + "/Users/" + member.UserID.String(),
+				})
+			}
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// scimUserInput проверяет ресурс User и извлекает из него поля каталога и аккаунта
+func scimUserInput(in *dto.SCIMUser) (*scimUserFields, error) {
+	if strings.TrimSpace(in.UserName) == "" {
+		return nil, scim.BadRequest(scim.TypeInvalidValue, "userName is required")
+	}
+
+	// userName провайдера может быть UPN, а не почтой: рабочий email берём из emails
+	email := primaryValue(in.Emails)
+	if email == "" {
+		email = strings.TrimSpace(in.UserName)
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return nil, scim.BadRequest(scim.TypeInvalidValue, "%q is not a valid email", email)
+	}
+
+	fields := &scimUserFields{
+		email:  strings.ToLower(email),
+		active: in.Active == nil || bool(*in.Active),
+	}
+
+	if in.Name != nil {
+		fields.firstName = strings.TrimSpace(in.Name.GivenName)
+		fields.lastName = strings.TrimSpace(in.Name.FamilyName)
+	}
+	if fields.firstName == "" && fields.lastName == "" {
+		first, last, _ := strings.Cut(strings.TrimSpace(in.DisplayName), " ")
+		fields.firstName, fields.lastName = first, strings.TrimSpace(last)
+	}
+
+	if title := strings.TrimSpace(in.Title); title != "" {
+		fields.position = &title
+	}
+	if photo := primaryValue(in.Photos); photo != "" {
+		fields.avatarURL = &photo
+	}
+
+	if role := strings.ToLower(primaryValue(in.Roles)); role != "" {
+		if !scimAccountRoles[role] {
+			return nil, scim.BadRequest(scim.TypeInvalidValue, "unknown role %q", role)
+		}
+		fields.role = role
+	}
+
+	if in.Password != "" {
+		password := in.Password
+		fields.password = &password
+	}
+
+	return fields, nil
+}
+
+func (f *scimUserFields) applyTo(user *models.User, now time.Time) {
+	user.Email = f.email
+	user.FirstName = f.firstName
+	user.LastName = f.lastName
+	user.Position = f.position
+	user.AvatarURL = f.avatarURL
+	user.IsActive = f.active
+	user.UpdatedAt = now
+}
+
+// primaryValue - значение основного элемента многозначного атрибута, иначе первого
+func primaryValue(values []dto.SCIMMultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return strings.TrimSpace(v.Value)
+		}
+	}
+	if len(values) > 0 {
+		return strings.TrimSpace(values[0].Value)
+	}
+
+	return ""
+}
+
+// applyPatch применяет PATCH-операции к JSON-представлению ресурса и разбирает результат в out
+func applyPatch(resource any, req *scim.PatchRequest, out any) error {
+	if len(req.Operations) == 0 {
+		return scim.BadRequest(scim.TypeInvalidValue, "no patch operations")
+	}
+
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return fmt.Errorf("failed to encode resource: %w", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to decode resource: %w", err)
+	}
+
+	if err := scim.ApplyPatch(doc, req.Operations); err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode patched resource: %w", err)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return scim.BadRequest(scim.TypeInvalidValue, "patched resource is invalid: %v", err)
+	}
+
+	return nil
+}
+
+func userIDs(users []*models.User) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.UserID)
+	}
+
+	return ids
+}
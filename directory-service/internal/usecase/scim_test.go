@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// fakeSCIMRepo записывает изменения групп. Остальные методы не реализованы: отдельная
+// запись в обход SaveGroup и RemoveDepartment уронит тест
+type fakeSCIMRepo struct {
+	SCIMRepo
+
+	departments map[uuid.UUID]*models.Department
+	users       map[uuid.UUID]*models.User
+
+	removal      *models.DepartmentRemoval
+	removeResult *models.DepartmentRemovalResult
+	changes      []*models.GroupChange
+	saveOK       bool
+}
+
+func (r *fakeSCIMRepo) GetDepartmentByID(_ context.Context, id uuid.UUID) (*models.Department, error) {
+	return r.departments[id], nil
+}
+
+func (r *fakeSCIMRepo) GetUserByID(_ context.Context, id uuid.UUID) (*models.User, error) {
+	return r.users[id], nil
+}
+
+func (r *fakeSCIMRepo) FindSyncLink(context.Context, string, models.SyncObjectType, string) (*models.SyncLink, error) {
+	return nil, nil
+}
+
+func (r *fakeSCIMRepo) GetExternalIDs(context.Context, string, models.SyncObjectType, []uuid.UUID) (map[uuid.UUID]string, error) {
+	return map[uuid.UUID]string{}, nil
+}
+
+func (r *fakeSCIMRepo) GetAllDepartmentMembers(context.Context, uuid.UUID) ([]*models.User, error) {
+	return nil, nil
+}
+
+func (r *fakeSCIMRepo) RemoveDepartment(_ context.Context, removal *models.DepartmentRemoval) (*models.DepartmentRemovalResult, error) {
+	r.removal = removal
+	return r.removeResult, nil
+}
+
+func (r *fakeSCIMRepo) SaveGroup(_ context.Context, change *models.GroupChange) (bool, error) {
+	r.changes = append(r.changes, change)
+	return r.saveOK, nil
+}
+
+func newFakeSCIMRepo() (*fakeSCIMRepo, *models.Department) {
+	dep := &models.Department{DepartmentID: uuid.New(), Name: "Security", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	return &fakeSCIMRepo{
+		departments: map[uuid.UUID]*models.Department{dep.DepartmentID: dep},
+		users:       make(map[uuid.UUID]*models.User),
+		saveOK:      true,
+	}, dep
+}
+
+// Удаление группы - архивация отдела в одной транзакции RemoveDepartment
+func TestDeleteGroupArchivesDepartment(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  *models.DepartmentRemovalResult
+		wantErr error
+	}{
+		{name: "archived", result: &models.DepartmentRemovalResult{Removed: true, MovedUsers: 2}},
+		{name: "active child departments", result: &models.DepartmentRemovalResult{HasChildren: true}, wantErr: errModels.ErrDepartmentHasChildren},
+		{name: "archived concurrently", result: &models.DepartmentRemovalResult{NotFound: true}, wantErr: errModels.ErrDepartmentNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, dep := newFakeSCIMRepo()
+			repo.removeResult = tt.result
+			u := NewSCIMUsecase(repo, nil, SCIMConfig{})
+
+			err := u.DeleteGroup(context.Background(), dep.DepartmentID.String())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteGroup error = %v, want %v", err, tt.wantErr)
+			}
+
+			removal := repo.removal
+			if removal == nil || removal.DepartmentID != dep.DepartmentID {
+				t.Fatalf("department was not removed through RemoveDepartment: %+v", removal)
+			}
+			if !removal.Archive || !removal.DetachUsers || removal.ReassignTo != nil {
+				t.Errorf("expected archive with detached users, got %+v", removal)
+			}
+			if removal.UnlinkSource != models.SyncSourceSCIM {
+				t.Errorf("expected the SCIM link to be released, got %q", removal.UnlinkSource)
+			}
+		})
+	}
+}
+
+func TestDeleteGroupIgnoresArchivedDepartment(t *testing.T) {
+	repo, dep := newFakeSCIMRepo()
+	archivedAt := time.Now()
+	dep.ArchivedAt = &archivedAt
+	u := NewSCIMUsecase(repo, nil, SCIMConfig{})
+
+	err := u.DeleteGroup(context.Background(), dep.DepartmentID.String())
+	if !errors.Is(err, errModels.ErrDepartmentNotFound) {
+		t.Fatalf("expected ErrDepartmentNotFound, got %v", err)
+	}
+	if repo.removal != nil {
+		t.Fatal("archived department must not be removed again")
+	}
+}
+
+// Замена группы - одно изменение с полным составом и externalId
+func TestReplaceGroupSavesAtomically(t *testing.T) {
+	repo, dep := newFakeSCIMRepo()
+	member := &models.User{UserID: uuid.New(), IsActive: true}
+	repo.users[member.UserID] = member
+	u := NewSCIMUsecase(repo, nil, SCIMConfig{})
+
+	in := &dto.SCIMGroup{
+		DisplayName: " Infosec ",
+		ExternalID:  "grp-1",
+		Members:     []dto.SCIMMember{{Value: member.UserID.String()}, {Value: member.UserID.String()}},
+	}
+	if _, err := u.ReplaceGroup(context.Background(), dep.DepartmentID.String(), in); err != nil {
+		t.Fatalf("ReplaceGroup: %v", err)
+	}
+
+	if len(repo.changes) != 1 {
+		t.Fatalf("expected a single group change, got %d", len(repo.changes))
+	}
+	change := repo.changes[0]
+	if change.Create || change.Department.DepartmentID != dep.DepartmentID || change.Department.Name != "Infosec" {
+		t.Errorf("unexpected department change: create=%v %+v", change.Create, change.Department)
+	}
+	if len(change.MemberIDs) != 1 || change.MemberIDs[0] != member.UserID || change.ExternalID != "grp-1" {
+		t.Errorf("unexpected members or external id: %v %q", change.MemberIDs, change.ExternalID)
+	}
+
+	// Отдел архивирован между чтением и записью
+	repo.saveOK = false
+	if _, err := u.ReplaceGroup(context.Background(), dep.DepartmentID.String(), in); !errors.Is(err, errModels.ErrDepartmentNotFound) {
+		t.Fatalf("expected ErrDepartmentNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,571 @@
+package usecase
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/ldap"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type DirectorySource interface {
+	FetchDirectory(ctx context.Context) (*ldap.Snapshot, error)
+}
+
+type SyncRepo interface {
+	GetAllDepartments(ctx context.Context) ([]*models.Department, error)
+	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	GetSyncLinks(ctx context.Context, source string) ([]*models.SyncLink, error)
+	ApplySyncChanges(ctx context.Context, changes *models.SyncChanges) error
+}
+
+// DirectorySync переносит отделы и пользователей из LDAP. Связь записей каталога с
+// локальными хранится в directory_sync_links; при первой встрече пользователь
+// сопоставляется по email, отдел - по имени и родителю. Увольнение, восстановление и
+// смена email переносятся и в аккаунт identity-service
+type DirectorySync struct {
+	repo     SyncRepo
+	source   DirectorySource
+	accounts identity.Client
+	cfg      SyncConfig
+
+	running sync.Mutex
+
+	now func() time.Time
+}
+
+func NewDirectorySync(repo SyncRepo, source DirectorySource, accounts identity.Client, cfg SyncConfig) *DirectorySync {
+	return &DirectorySync{
+		repo:     repo,
+		source:   source,
+		accounts: accounts,
+		cfg:      cfg,
+		now:      time.Now,
+	}
+}
+
+// Run запускает синхронизацию сразу и затем по расписанию
+func (s *DirectorySync) Run(ctx context.Context) {
+	if s.source == nil || s.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Sync(ctx, s.cfg.DryRun); err != nil {
+			log.Printf("failed to sync directory: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync сверяет каталог с базой. В режиме dryRun изменения только попадают в отчёт
+func (s *DirectorySync) Sync(ctx context.Context, dryRun bool) (*dto.SyncReport, error) {
+	if s.source == nil {
+		return nil, errors.ErrSyncDisabled
+	}
+	if !s.running.TryLock() {
+		return nil, errors.ErrSyncInProgress
+	}
+	defer s.running.Unlock()
+
+	startedAt := s.now()
+
+	snapshot, err := s.source.FetchDirectory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errors.ErrSyncSourceFailed, err)
+	}
+	// Пустой ответ скорее означает ошибку фильтра, чем увольнение всех сотрудников
+	if len(snapshot.Users) == 0 {
+		return nil, errors.ErrSyncEmptySource
+	}
+
+	departments, err := s.repo.GetAllDepartments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get departments: %w", err)
+	}
+	users, err := s.repo.GetAllUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	links, err := s.repo.GetSyncLinks(ctx, models.SyncSourceLDAP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync links: %w", err)
+	}
+
+	plan := newSyncPlan(startedAt, departments, users, links)
+	for _, dep := range snapshot.Departments {
+		plan.department(dep)
+	}
+	for _, user := range snapshot.Users {
+		plan.user(user)
+	}
+	plan.deactivateMissing()
+
+	if !dryRun {
+		s.syncAccounts(ctx, plan)
+	}
+
+	report := plan.report
+	report.DryRun = dryRun
+
+	if !dryRun && !plan.changes.IsEmpty() {
+		if err := s.repo.ApplySyncChanges(ctx, plan.changes); err != nil {
+			return nil, fmt.Errorf("failed to apply directory changes: %w", err)
+		}
+	}
+
+	report.FinishedAt = s.now()
+
+	log.Printf("directory sync (dry run: %t): departments +%d ~%d, users +%d ~%d -%d, skipped %d",
+		dryRun,
+		len(report.Departments.Created), len(report.Departments.Updated),
+		len(report.Users.Created), len(report.Users.Updated), len(report.Users.Deactivated),
+		len(report.Skipped),
+	)
+
+	return report, nil
+}
+
+// syncAccounts приводит аккаунты identity-service к изменениям плана до записи в базу,
+// как и ручное увольнение и восстановление: сначала закрывается или открывается вход.
+// Сотрудник, чей аккаунт обновить не удалось, убирается из прогона и попадёт в следующий
+func (s *DirectorySync) syncAccounts(ctx context.Context, plan *syncPlan) {
+	for _, id := range slices.Clone(plan.changes.DeactivateUsers) {
+		err := s.accounts.DeprovisionAccount(ctx, id)
+		if err != nil && !stdErrors.Is(err, identity.ErrAccountNotFound) {
+			log.Printf("failed to deprovision account of user %s: %v", id, err)
+			plan.dropUser(id, "failed to deactivate account")
+		}
+	}
+
+	for _, updated := range slices.Clone(plan.changes.UpdateUsers) {
+		if err := s.syncAccount(ctx, plan.users[updated.UserID], updated); err != nil {
+			reason := "failed to update account"
+			if stdErrors.Is(err, identity.ErrEmailTaken) {
+				reason = "email already used by another account"
+			} else {
+				log.Printf("failed to update account of user %s: %v", updated.UserID, err)
+			}
+			plan.dropUser(updated.UserID, reason)
+		}
+	}
+}
+
+func (s *DirectorySync) syncAccount(ctx context.Context, current, updated *models.User) error {
+	if !current.IsActive && updated.IsActive {
+		err := s.accounts.ReactivateAccount(ctx, updated.UserID)
+		if err != nil && !stdErrors.Is(err, identity.ErrAccountNotFound) {
+			return err
+		}
+	}
+
+	if strings.EqualFold(current.Email, updated.Email) {
+		return nil
+	}
+
+	active := updated.IsActive
+	_, err := s.accounts.ProvisionAccount(ctx, updated.UserID, &identity.ProvisionAccountRequest{
+		Email:    updated.Email,
+		IsActive: &active,
+	})
+	return err
+}
+
+// syncPlan накапливает изменения и отчёт одного прогона
+type syncPlan struct {
+	now     time.Time
+	report  *dto.SyncReport
+	changes *models.SyncChanges
+
+	departments  map[uuid.UUID]*models.Department
+	users        map[uuid.UUID]*models.User
+	usersByEmail map[string]*models.User
+
+	links     []*models.SyncLink
+	linked    map[models.SyncObjectType]map[string]uuid.UUID
+	claimed   map[uuid.UUID]bool
+	depIDs    map[string]uuid.UUID
+	seenUsers map[uuid.UUID]bool
+	// Записи каталога, чей отдел в архиве (или не создан из-за архивного родителя):
+	// ссылки на них не меняют текущий отдел сотрудников и дочерних отделов
+	archived map[string]bool
+}
+
+func newSyncPlan(now time.Time, departments []*models.Department, users []*models.User, links []*models.SyncLink) *syncPlan {
+	p := &syncPlan{
+		now: now,
+		report: &dto.SyncReport{
+			StartedAt:   now,
+			Departments: newSyncObjectReport(),
+			Users:       newSyncObjectReport(),
+			Skipped:     []dto.SyncSkipped{},
+		},
+		changes: &models.SyncChanges{UpdatedAt: now},
+
+		departments:  make(map[uuid.UUID]*models.Department, len(departments)),
+		users:        make(map[uuid.UUID]*models.User, len(users)),
+		usersByEmail: make(map[string]*models.User, len(users)),
+
+		links: links,
+		linked: map[models.SyncObjectType]map[string]uuid.UUID{
+			models.SyncObjectDepartment: {},
+			models.SyncObjectUser:       {},
+		},
+		claimed:   make(map[uuid.UUID]bool, len(links)),
+		depIDs:    make(map[string]uuid.UUID),
+		seenUsers: make(map[uuid.UUID]bool),
+		archived:  make(map[string]bool),
+	}
+
+	for _, dep := range departments {
+		p.departments[dep.DepartmentID] = dep
+	}
+	for _, user := range users {
+		p.users[user.UserID] = user
+		p.usersByEmail[strings.ToLower(user.Email)] = user
+	}
+	for _, link := range links {
+		if m, ok := p.linked[link.ObjectType]; ok {
+			m[link.ExternalID] = link.LocalID
+			p.claimed[link.LocalID] = true
+		}
+	}
+
+	return p
+}
+
+func newSyncObjectReport() dto.SyncObjectReport {
+	return dto.SyncObjectReport{
+		Created: []dto.SyncChange{},
+		Updated: []dto.SyncChange{},
+	}
+}
+
+func (p *syncPlan) department(rec *ldap.Department) {
+	parentID, parentArchived := p.departmentRef(rec.ParentID)
+
+	dep := p.findDepartment(rec, parentID)
+	// Архивный отдел удалён вручную: синхронизация его не восстанавливает
+	if dep != nil && dep.IsArchived() {
+		p.archived[rec.ExternalID] = true
+		p.skip(rec.DN, "department archived")
+		return
+	}
+	if parentArchived {
+		if dep == nil {
+			p.archived[rec.ExternalID] = true
+			p.skip(rec.DN, "parent department archived")
+			return
+		}
+		parentID = dep.ParentID
+	}
+
+	change := dto.SyncChange{ExternalID: rec.ExternalID, DN: rec.DN, Name: rec.Name}
+
+	switch {
+	case dep == nil:
+		dep = &models.Department{
+			DepartmentID: uuid.New(),
+			Name:         rec.Name,
+			ParentID:     parentID,
+			CreatedAt:    p.now,
+			UpdatedAt:    p.now,
+		}
+		p.departments[dep.DepartmentID] = dep
+		p.changes.CreateDepartments = append(p.changes.CreateDepartments, dep)
+
+		change.ID = dep.DepartmentID
+		p.report.Departments.Created = append(p.report.Departments.Created, change)
+	default:
+		var fields []dto.FieldChange
+		fields = appendChange(fields, "name", dep.Name, rec.Name)
+		fields = appendChange(fields, "parent_id", formatUUID(dep.ParentID), formatUUID(parentID))
+
+		if len(fields) == 0 {
+			p.report.Departments.Unchanged++
+			break
+		}
+
+		updated := *dep
+		updated.Name = rec.Name
+		updated.ParentID = parentID
+		updated.UpdatedAt = p.now
+		p.changes.UpdateDepartments = append(p.changes.UpdateDepartments, &updated)
+
+		change.ID = dep.DepartmentID
+		change.Fields = fields
+		p.report.Departments.Updated = append(p.report.Departments.Updated, change)
+	}
+
+	p.claimed[dep.DepartmentID] = true
+	p.depIDs[rec.ExternalID] = dep.DepartmentID
+	p.link(models.SyncObjectDepartment, rec.ExternalID, dep.DepartmentID)
+}
+
+// departmentRef - локальный отдел по внешнему идентификатору. archived сообщает, что
+// отдел в архиве: тогда текущий отдел ссылающейся записи не меняется
+func (p *syncPlan) departmentRef(externalID string) (id *uuid.UUID, archived bool) {
+	if externalID == "" {
+		return nil, false
+	}
+	if p.archived[externalID] {
+		return nil, true
+	}
+	if depID, ok := p.depIDs[externalID]; ok {
+		return &depID, false
+	}
+
+	return nil, false
+}
+
+// findDepartment ищет отдел по связи, затем среди несвязанных - по имени и родителю
+func (p *syncPlan) findDepartment(rec *ldap.Department, parentID *uuid.UUID) *models.Department {
+	if id, ok := p.linked[models.SyncObjectDepartment][rec.ExternalID]; ok {
+		if dep, ok := p.departments[id]; ok {
+			return dep
+		}
+	}
+
+	for _, dep := range p.departments {
+		if p.claimed[dep.DepartmentID] || dep.IsArchived() {
+			continue
+		}
+		if strings.EqualFold(dep.Name, rec.Name) && formatUUID(dep.ParentID) == formatUUID(parentID) {
+			return dep
+		}
+	}
+
+	return nil
+}
+
+func (p *syncPlan) user(rec *ldap.User) {
+	if rec.Email == "" {
+		p.skip(rec.DN, "missing email")
+		return
+	}
+
+	departmentID, departmentArchived := p.departmentRef(rec.DepartmentID)
+
+	user, linked := p.findUser(rec)
+	if user == nil {
+		if linked {
+			p.skip(rec.DN, "email already linked to another directory entry")
+			return
+		}
+		if rec.Disabled {
+			p.skip(rec.DN, "account disabled in directory")
+			return
+		}
+
+		user = &models.User{
+			UserID:       uuid.New(),
+			Email:        rec.Email,
+			FirstName:    rec.FirstName,
+			LastName:     rec.LastName,
+			Position:     rec.Position,
+			DepartmentID: departmentID,
+			IsActive:     true,
+			CreatedAt:    p.now,
+			UpdatedAt:    p.now,
+		}
+		p.users[user.UserID] = user
+		p.usersByEmail[strings.ToLower(user.Email)] = user
+		p.changes.CreateUsers = append(p.changes.CreateUsers, user)
+		p.report.Users.Created = append(p.report.Users.Created, userChange(user.UserID, rec))
+	} else {
+		if owner, ok := p.usersByEmail[strings.ToLower(rec.Email)]; ok && owner.UserID != user.UserID {
+			p.skip(rec.DN, "email already used by another user")
+			return
+		}
+		if departmentArchived {
+			departmentID = user.DepartmentID
+		}
+		p.updateUser(user, rec, departmentID)
+	}
+
+	p.claimed[user.UserID] = true
+	p.seenUsers[user.UserID] = true
+	p.link(models.SyncObjectUser, rec.ExternalID, user.UserID)
+}
+
+// findUser ищет пользователя по связи, затем по email. linked сообщает, что найденный
+// по email пользователь уже связан с другой записью каталога
+func (p *syncPlan) findUser(rec *ldap.User) (user *models.User, linked bool) {
+	if id, ok := p.linked[models.SyncObjectUser][rec.ExternalID]; ok {
+		if user, ok := p.users[id]; ok {
+			return user, false
+		}
+	}
+
+	user, ok := p.usersByEmail[strings.ToLower(rec.Email)]
+	if !ok {
+		return nil, false
+	}
+	if p.claimed[user.UserID] {
+		return nil, true
+	}
+
+	return user, false
+}
+
+func (p *syncPlan) updateUser(user *models.User, rec *ldap.User, departmentID *uuid.UUID) {
+	var fields []dto.FieldChange
+	fields = appendChange(fields, "email", user.Email, rec.Email)
+	fields = appendChange(fields, "first_name", user.FirstName, rec.FirstName)
+	fields = appendChange(fields, "last_name", user.LastName, rec.LastName)
+	fields = appendChange(fields, "position", formatString(user.Position), formatString(rec.Position))
+	fields = appendChange(fields, "department_id", formatUUID(user.DepartmentID), formatUUID(departmentID))
+
+	isActive := user.IsActive
+	if !rec.Disabled && !user.IsActive {
+		fields = appendChange(fields, "is_active", "false", "true")
+		isActive = true
+	}
+
+	if len(fields) > 0 {
+		updated := *user
+		updated.Email = rec.Email
+		updated.FirstName = rec.FirstName
+		updated.LastName = rec.LastName
+		updated.Position = rec.Position
+		updated.DepartmentID = departmentID
+		updated.IsActive = isActive
+		updated.UpdatedAt = p.now
+		p.changes.UpdateUsers = append(p.changes.UpdateUsers, &updated)
+
+		change := userChange(user.UserID, rec)
+		change.Fields = fields
+		p.report.Users.Updated = append(p.report.Users.Updated, change)
+	}
+
+	if rec.Disabled && user.IsActive {
+		p.deactivate(user, rec.ExternalID, rec.DN, "disabled in directory")
+		return
+	}
+	if len(fields) == 0 {
+		p.report.Users.Unchanged++
+	}
+}
+
+// deactivateMissing отключает связанных пользователей, которых больше нет в каталоге
+func (p *syncPlan) deactivateMissing() {
+	for _, link := range p.links {
+		if link.ObjectType != models.SyncObjectUser || p.seenUsers[link.LocalID] {
+			continue
+		}
+
+		user, ok := p.users[link.LocalID]
+		if !ok || !user.IsActive {
+			continue
+		}
+
+		p.seenUsers[user.UserID] = true
+		p.deactivate(user, link.ExternalID, "", "removed from directory")
+	}
+}
+
+func (p *syncPlan) deactivate(user *models.User, externalID, dn, reason string) {
+	p.changes.DeactivateUsers = append(p.changes.DeactivateUsers, user.UserID)
+	p.report.Users.Deactivated = append(p.report.Users.Deactivated, dto.SyncChange{
+		ID:         user.UserID,
+		ExternalID: externalID,
+		DN:         dn,
+		Name:       strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Reason:     reason,
+	})
+}
+
+// dropUser исключает изменения пользователя из прогона и переносит его в пропущенные
+func (p *syncPlan) dropUser(id uuid.UUID, reason string) {
+	dn := ""
+	p.changes.UpdateUsers = slices.DeleteFunc(p.changes.UpdateUsers, func(user *models.User) bool {
+		return user.UserID == id
+	})
+	p.changes.DeactivateUsers = slices.DeleteFunc(p.changes.DeactivateUsers, func(userID uuid.UUID) bool {
+		return userID == id
+	})
+
+	dropChange := func(change dto.SyncChange) bool {
+		if change.ID != id {
+			return false
+		}
+		if change.DN != "" {
+			dn = change.DN
+		}
+		return true
+	}
+	p.report.Users.Updated = slices.DeleteFunc(p.report.Users.Updated, dropChange)
+	p.report.Users.Deactivated = slices.DeleteFunc(p.report.Users.Deactivated, dropChange)
+
+	p.skip(dn, reason)
+}
+
+func (p *syncPlan) link(objectType models.SyncObjectType, externalID string, localID uuid.UUID) {
+	if id, ok := p.linked[objectType][externalID]; ok && id == localID {
+		return
+	}
+
+	p.linked[objectType][externalID] = localID
+	p.changes.Links = append(p.changes.Links, &models.SyncLink{
+		Source:     models.SyncSourceLDAP,
+		ObjectType: objectType,
+		ExternalID: externalID,
+		LocalID:    localID,
+		SyncedAt:   p.now,
+	})
+}
+
+func (p *syncPlan) skip(dn, reason string) {
+	p.report.Skipped = append(p.report.Skipped, dto.SyncSkipped{DN: dn, Reason: reason})
+}
+
+func userChange(id uuid.UUID, rec *ldap.User) dto.SyncChange {
+	return dto.SyncChange{
+		ID:         id,
+		ExternalID: rec.ExternalID,
+		DN:         rec.DN,
+		Name:       strings.TrimSpace(rec.FirstName + " " + rec.LastName),
+	}
+}
+
+func appendChange(fields []dto.FieldChange, field, oldValue, newValue string) []dto.FieldChange {
+	if oldValue == newValue {
+		return fields
+	}
+
+	return append(fields, dto.FieldChange{Field: field, Old: oldValue, New: newValue})
+}
+
+func formatString(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+func formatUUID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+
+	return id.String()
+}
@@ -0,0 +1,574 @@
+package usecase
+
+import (
+	"context"
+	stdErrors "errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/identity"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/ldap"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/ldap/ldaptest"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	testBindDN   = "cn=sync,dc=example,dc=com"
+	testPassword = "secret"
+	testBaseDN   = "dc=example,dc=com"
+)
+
+// fakeSyncRepo хранит каталог в памяти и применяет изменения так же, как ApplySyncChanges
+type fakeSyncRepo struct {
+	departments map[uuid.UUID]*models.Department
+	users       map[uuid.UUID]*models.User
+	links       []*models.SyncLink
+	applied     int
+}
+
+func newFakeSyncRepo() *fakeSyncRepo {
+	return &fakeSyncRepo{
+		departments: make(map[uuid.UUID]*models.Department),
+		users:       make(map[uuid.UUID]*models.User),
+	}
+}
+
+func (r *fakeSyncRepo) GetAllDepartments(context.Context) ([]*models.Department, error) {
+	var deps []*models.Department
+	for _, dep := range r.departments {
+		copied := *dep
+		deps = append(deps, &copied)
+	}
+	return deps, nil
+}
+
+func (r *fakeSyncRepo) GetAllUsers(context.Context) ([]*models.User, error) {
+	var users []*models.User
+	for _, user := range r.users {
+		copied := *user
+		users = append(users, &copied)
+	}
+	return users, nil
+}
+
+func (r *fakeSyncRepo) GetSyncLinks(_ context.Context, source string) ([]*models.SyncLink, error) {
+	var links []*models.SyncLink
+	for _, link := range r.links {
+		if link.Source == source {
+			copied := *link
+			links = append(links, &copied)
+		}
+	}
+	return links, nil
+}
+
+func (r *fakeSyncRepo) ApplySyncChanges(_ context.Context, changes *models.SyncChanges) error {
+	r.applied++
+
+	for _, dep := range changes.CreateDepartments {
+		if dep.ParentID != nil && r.departments[*dep.ParentID] == nil {
+			return stdErrors.New("parent department is created after its child")
+		}
+		r.departments[dep.DepartmentID] = dep
+	}
+	for _, dep := range changes.UpdateDepartments {
+		r.departments[dep.DepartmentID] = dep
+	}
+	for _, user := range changes.CreateUsers {
+		r.users[user.UserID] = user
+	}
+	for _, user := range changes.UpdateUsers {
+		r.users[user.UserID] = user
+	}
+	for _, id := range changes.DeactivateUsers {
+		r.users[id].IsActive = false
+	}
+	for _, link := range changes.Links {
+		r.links = slices.DeleteFunc(r.links, func(l *models.SyncLink) bool {
+			return l.Source == link.Source && l.ObjectType == link.ObjectType && l.ExternalID == link.ExternalID
+		})
+		r.links = append(r.links, link)
+	}
+
+	return nil
+}
+
+func (r *fakeSyncRepo) addUser(email string, active bool) *models.User {
+	user := &models.User{UserID: uuid.New(), Email: email, FirstName: "Old", LastName: "Name", IsActive: active}
+	r.users[user.UserID] = user
+	return user
+}
+
+func (r *fakeSyncRepo) link(objectType models.SyncObjectType, externalID string, localID uuid.UUID) {
+	r.links = append(r.links, &models.SyncLink{
+		Source: models.SyncSourceLDAP, ObjectType: objectType, ExternalID: externalID, LocalID: localID,
+	})
+}
+
+func (r *fakeSyncRepo) departmentByName(name string) *models.Department {
+	for _, dep := range r.departments {
+		if dep.Name == name {
+			return dep
+		}
+	}
+	return nil
+}
+
+func (r *fakeSyncRepo) userByEmail(email string) *models.User {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user
+		}
+	}
+	return nil
+}
+
+// fakeAccounts записывает вызовы identity-service; emailTaken - занятые адреса,
+// failing - пользователи, на которых identity-service отвечает ошибкой
+type fakeAccounts struct {
+	identity.Client
+
+	provisioned   map[uuid.UUID]string
+	deprovisioned []uuid.UUID
+	reactivated   []uuid.UUID
+	emailTaken    map[string]bool
+	failing       map[uuid.UUID]error
+}
+
+func newFakeAccounts() *fakeAccounts {
+	return &fakeAccounts{
+		provisioned: make(map[uuid.UUID]string),
+		emailTaken:  make(map[string]bool),
+		failing:     make(map[uuid.UUID]error),
+	}
+}
+
+func (a *fakeAccounts) DeprovisionAccount(_ context.Context, userID uuid.UUID) error {
+	if err := a.failing[userID]; err != nil {
+		return err
+	}
+	a.deprovisioned = append(a.deprovisioned, userID)
+	return nil
+}
+
+func (a *fakeAccounts) ReactivateAccount(_ context.Context, userID uuid.UUID) error {
+	if err := a.failing[userID]; err != nil {
+		return err
+	}
+	a.reactivated = append(a.reactivated, userID)
+	return nil
+}
+
+func (a *fakeAccounts) ProvisionAccount(_ context.Context, userID uuid.UUID, req *identity.ProvisionAccountRequest) (*identity.AccountResponse, error) {
+	if a.emailTaken[req.Email] {
+		return nil, identity.ErrEmailTaken
+	}
+	a.provisioned[userID] = req.Email
+	return &identity.AccountResponse{UserID: userID.String(), Email: req.Email}, nil
+}
+
+type syncTestEnv struct {
+	server   *ldaptest.Server
+	repo     *fakeSyncRepo
+	accounts *fakeAccounts
+	sync     *DirectorySync
+}
+
+func newSyncTestEnv(t *testing.T) *syncTestEnv {
+	t.Helper()
+
+	server, err := ldaptest.NewServer(testBindDN, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	source := ldap.NewClient(ldap.LDAPConfig{
+		URL:              server.URL(),
+		BindDN:           testBindDN,
+		BindPassword:     testPassword,
+		BaseDN:           testBaseDN,
+		UserFilter:       "(objectClass=person)",
+		DepartmentFilter: "(objectClass=organizationalUnit)",
+		PageSize:         100,
+		Timeout:          5 * time.Second,
+		Attributes: ldap.AttributeMapping{
+			UserID:             "entryUUID",
+			Email:              "mail",
+			FirstName:          "givenName",
+			LastName:           "sn",
+			Position:           "title",
+			UserAccountControl: "userAccountControl",
+			DepartmentID:       "entryUUID",
+			DepartmentName:     "ou",
+		},
+	})
+
+	env := &syncTestEnv{
+		server:   server,
+		repo:     newFakeSyncRepo(),
+		accounts: newFakeAccounts(),
+	}
+	env.sync = NewDirectorySync(env.repo, source, env.accounts, SyncConfig{})
+
+	return env
+}
+
+func (e *syncTestEnv) putDepartment(t *testing.T, dn, id, name string) {
+	t.Helper()
+
+	err := e.server.Put(dn, map[string][]string{
+		"objectClass": {"organizationalUnit"},
+		"entryUUID":   {id},
+		"ou":          {name},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *syncTestEnv) putUser(t *testing.T, dn, id, email string, disabled bool) {
+	t.Helper()
+
+	uac := "512"
+	if disabled {
+		uac = "514"
+	}
+	err := e.server.Put(dn, map[string][]string{
+		"objectClass":        {"person"},
+		"entryUUID":          {id},
+		"mail":               {email},
+		"givenName":          {"Ivan"},
+		"sn":                 {"Petrov"},
+		"title":              {"Engineer"},
+		"userAccountControl": {uac},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *syncTestEnv) run(t *testing.T, dryRun bool) {
+	t.Helper()
+
+	if _, err := e.sync.Sync(context.Background(), dryRun); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncBuildsDepartmentHierarchy(t *testing.T) {
+	env := newSyncTestEnv(t)
+	env.putDepartment(t, "ou=Backend,ou=Engineering,dc=example,dc=com", "dep-backend", "Backend")
+	env.putDepartment(t, "ou=Engineering,dc=example,dc=com", "dep-eng", "Engineering")
+	env.putDepartment(t, "ou=Sales,dc=example,dc=com", "dep-sales", "Sales")
+	env.putUser(t, "cn=ivan,ou=Backend,ou=Engineering,dc=example,dc=com", "user-ivan", "ivan@example.com", false)
+	env.putUser(t, "cn=anna,ou=Sales,dc=example,dc=com", "user-anna", "anna@example.com", false)
+	// Контейнер без отдела не разрывает цепочку до ближайшего отдела
+	env.putUser(t, "cn=olga,cn=Users,ou=Engineering,dc=example,dc=com", "user-olga", "olga@example.com", false)
+
+	env.run(t, false)
+
+	eng, backend, sales := env.repo.departmentByName("Engineering"), env.repo.departmentByName("Backend"), env.repo.departmentByName("Sales")
+	if eng == nil || backend == nil || sales == nil {
+		t.Fatalf("departments not created: %+v", env.repo.departments)
+	}
+	if eng.ParentID != nil || sales.ParentID != nil {
+		t.Fatal("root departments got a parent")
+	}
+	if backend.ParentID == nil || *backend.ParentID != eng.DepartmentID {
+		t.Fatal("backend is not under engineering")
+	}
+
+	wantDepartments := map[string]uuid.UUID{
+		"ivan@example.com": backend.DepartmentID,
+		"anna@example.com": sales.DepartmentID,
+		"olga@example.com": eng.DepartmentID,
+	}
+	for email, depID := range wantDepartments {
+		user := env.repo.userByEmail(email)
+		if user == nil || !user.IsActive || user.DepartmentID == nil || *user.DepartmentID != depID {
+			t.Fatalf("unexpected user %s: %+v", email, user)
+		}
+	}
+
+	// Повторный прогон по тому же каталогу ничего не меняет
+	report, err := env.sync.Sync(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.repo.applied != 1 || report.Users.Unchanged != 3 || report.Departments.Unchanged != 3 {
+		t.Fatalf("second run changed data: applied %d, report %+v", env.repo.applied, report)
+	}
+}
+
+func TestSyncUpsertsUserByLink(t *testing.T) {
+	env := newSyncTestEnv(t)
+	user := env.repo.addUser("old@example.com", true)
+	env.repo.link(models.SyncObjectUser, "user-ivan", user.UserID)
+	env.putUser(t, "cn=ivan,dc=example,dc=com", "user-ivan", "ivan@example.com", false)
+
+	env.run(t, false)
+
+	if len(env.repo.users) != 1 {
+		t.Fatalf("got %d users, want 1", len(env.repo.users))
+	}
+	updated := env.repo.users[user.UserID]
+	if updated.Email != "ivan@example.com" || updated.FirstName != "Ivan" || updated.LastName != "Petrov" {
+		t.Fatalf("user not updated: %+v", updated)
+	}
+	// Вход по новому email должен работать сразу
+	if env.accounts.provisioned[user.UserID] != "ivan@example.com" {
+		t.Fatalf("account email not updated: %v", env.accounts.provisioned)
+	}
+}
+
+func TestSyncUpsertsUserByEmail(t *testing.T) {
+	env := newSyncTestEnv(t)
+	user := env.repo.addUser("Ivan@Example.com", true)
+	env.putUser(t, "cn=ivan,dc=example,dc=com", "user-ivan", "ivan@example.com", false)
+
+	env.run(t, false)
+
+	if len(env.repo.users) != 1 || env.repo.users[user.UserID].FirstName != "Ivan" {
+		t.Fatalf("user not matched by email: %+v", env.repo.users)
+	}
+	links, _ := env.repo.GetSyncLinks(context.Background(), models.SyncSourceLDAP)
+	if !slices.ContainsFunc(links, func(l *models.SyncLink) bool {
+		return l.ExternalID == "user-ivan" && l.LocalID == user.UserID
+	}) {
+		t.Fatal("user not linked to the directory entry")
+	}
+	// Регистр адреса - не смена email
+	if len(env.accounts.provisioned) != 0 {
+		t.Fatalf("account updated for the same email: %v", env.accounts.provisioned)
+	}
+}
+
+func TestSyncSkipsUserWhenAccountEmailTaken(t *testing.T) {
+	env := newSyncTestEnv(t)
+	user := env.repo.addUser("old@example.com", true)
+	env.repo.link(models.SyncObjectUser, "user-ivan", user.UserID)
+	env.putUser(t, "cn=ivan,dc=example,dc=com", "user-ivan", "ivan@example.com", false)
+	env.putUser(t, "cn=anna,dc=example,dc=com", "user-anna", "anna@example.com", false)
+	env.accounts.emailTaken["ivan@example.com"] = true
+
+	report, err := env.sync.Sync(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env.repo.users[user.UserID].Email != "old@example.com" {
+		t.Fatal("directory email changed while the account kept the old one")
+	}
+	if env.repo.userByEmail("anna@example.com") == nil {
+		t.Fatal("other changes were not applied")
+	}
+	if len(report.Users.Updated) != 0 || len(report.Skipped) != 1 || report.Skipped[0].DN != "cn=ivan,dc=example,dc=com" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestSyncDeactivatesMissingUsers(t *testing.T) {
+	env := newSyncTestEnv(t)
+	gone := env.repo.addUser("gone@example.com", true)
+	env.repo.link(models.SyncObjectUser, "user-gone", gone.UserID)
+	// Несвязанные с каталогом пользователи заведены вручную и не трогаются
+	local := env.repo.addUser("local@example.com", true)
+	env.putUser(t, "cn=ivan,dc=example,dc=com", "user-ivan", "ivan@example.com", false)
+	env.putUser(t, "cn=anna,dc=example,dc=com", "user-anna", "anna@example.com", true)
+
+	report, err := env.sync.Sync(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if env.repo.users[gone.UserID].IsActive {
+		t.Fatal("user removed from directory is still active")
+	}
+	if !env.repo.users[local.UserID].IsActive {
+		t.Fatal("unlinked user deactivated")
+	}
+	if env.repo.userByEmail("anna@example.com") != nil {
+		t.Fatal("disabled directory user created")
+	}
+	if len(report.Users.Deactivated) != 1 || report.Users.Deactivated[0].ID != gone.UserID {
+		t.Fatalf("unexpected report %+v", report.Users.Deactivated)
+	}
+	if !slices.Equal(env.accounts.deprovisioned, []uuid.UUID{gone.UserID}) {
+		t.Fatalf("accounts deprovisioned: %v", env.accounts.deprovisioned)
+	}
+}
+
+func TestSyncDeactivationFollowsAccount(t *testing.T) {
+	tests := []struct {
+		name       string
+		accountErr error
+		wantActive bool
+	}{
+		{name: "deprovisioned"},
+		{name: "no account", accountErr: identity.ErrAccountNotFound},
+		{name: "identity unavailable", accountErr: stdErrors.New("identity service returned 503"), wantActive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newSyncTestEnv(t)
+			disabled := env.repo.addUser("ivan@example.com", true)
+			env.repo.link(models.SyncObjectUser, "user-ivan", disabled.UserID)
+			gone := env.repo.addUser("gone@example.com", true)
+			env.repo.link(models.SyncObjectUser, "user-gone", gone.UserID)
+			env.putUser(t, "cn=ivan,dc=example,dc=com", "user-ivan", "ivan@example.com", true)
+			env.putUser(t, "cn=anna,dc=example,dc=com", "user-anna", "anna@example.com", false)
+			env.accounts.failing[disabled.UserID] = tt.accountErr
+			env.accounts.failing[gone.UserID] = tt.accountErr
+
+			report, err := env.sync.Sync(context.Background(), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Пока вход не закрыт, сотрудник остаётся активным и в следующий прогон
+			for _, user := range []*models.User{disabled, gone} {
+				if env.repo.users[user.UserID].IsActive != tt.wantActive {
+					t.Fatalf("user %s active = %v, want %v", user.Email, !tt.wantActive, tt.wantActive)
+				}
+			}
+			if tt.wantActive && (len(report.Users.Deactivated) != 0 || len(report.Skipped) != 2) {
+				t.Fatalf("unexpected report %+v", report)
+			}
+			if env.repo.userByEmail("anna@example.com") == nil {
+				t.Fatal("other changes were not applied")
+			}
+		})
+	}
+}
+
+func TestSyncReactivatesAccount(t *testing.T) {
+	env := newSyncTestEnv(t)
+	returned := env.repo.addUser("ivan@example.com", false)
+	env.repo.link(models.SyncObjectUser, "user-ivan", returned.UserID)
+	env.putUser(t, "cn=ivan,dc=example,dc=com", "user-ivan", "ivan@example.com", false)
+
+	env.accounts.failing[returned.UserID] = stdErrors.New("identity service returned 503")
+	env.run(t, false)
+	if env.repo.users[returned.UserID].IsActive {
+		t.Fatal("user reactivated while the account stayed closed")
+	}
+
+	delete(env.accounts.failing, returned.UserID)
+	env.run(t, false)
+	if !env.repo.users[returned.UserID].IsActive {
+		t.Fatal("user not reactivated")
+	}
+	if !slices.Equal(env.accounts.reactivated, []uuid.UUID{returned.UserID}) {
+		t.Fatalf("accounts reactivated: %v", env.accounts.reactivated)
+	}
+}
+
+func TestSyncRejectsEmptySource(t *testing.T) {
+	env := newSyncTestEnv(t)
+	user := env.repo.addUser("ivan@example.com", true)
+	env.repo.link(models.SyncObjectUser, "user-ivan", user.UserID)
+	env.putDepartment(t, "ou=Engineering,dc=example,dc=com", "dep-eng", "Engineering")
+
+	if _, err := env.sync.Sync(context.Background(), false); !stdErrors.Is(err, errors.ErrSyncEmptySource) {
+		t.Fatalf("expected ErrSyncEmptySource, got %v", err)
+	}
+	if env.repo.applied != 0 || !user.IsActive {
+		t.Fatal("empty directory changed data")
+	}
+}
+
+func TestSyncDryRunDoesNotWrite(t *testing.T) {
+	env := newSyncTestEnv(t)
+	gone := env.repo.addUser("gone@example.com", true)
+	env.repo.link(models.SyncObjectUser, "user-gone", gone.UserID)
+	renamed := env.repo.addUser("old@example.com", true)
+	env.repo.link(models.SyncObjectUser, "user-ivan", renamed.UserID)
+	env.putDepartment(t, "ou=Engineering,dc=example,dc=com", "dep-eng", "Engineering")
+	env.putUser(t, "cn=ivan,ou=Engineering,dc=example,dc=com", "user-ivan", "ivan@example.com", false)
+	env.putUser(t, "cn=anna,ou=Engineering,dc=example,dc=com", "user-anna", "anna@example.com", false)
+
+	report, err := env.sync.Sync(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !report.DryRun || len(report.Departments.Created) != 1 || len(report.Users.Created) != 1 ||
+		len(report.Users.Updated) != 1 || len(report.Users.Deactivated) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if env.repo.applied != 0 || len(env.accounts.provisioned) != 0 || len(env.accounts.deprovisioned) != 0 {
+		t.Fatal("dry run wrote changes")
+	}
+	if len(env.repo.users) != 2 || len(env.repo.departments) != 0 || !gone.IsActive || renamed.Email != "old@example.com" {
+		t.Fatal("dry run changed data")
+	}
+}
+
+func TestSyncSkipsArchivedDepartments(t *testing.T) {
+	env := newSyncTestEnv(t)
+	archivedAt := time.Now()
+	archived := &models.Department{DepartmentID: uuid.New(), Name: "Legacy", ArchivedAt: &archivedAt}
+	// Сотрудники и дочерний отдел при архивации перенесены в Support
+	support := &models.Department{DepartmentID: uuid.New(), Name: "Support"}
+	team := &models.Department{DepartmentID: uuid.New(), Name: "Team", ParentID: &support.DepartmentID}
+	for _, dep := range []*models.Department{archived, support, team} {
+		env.repo.departments[dep.DepartmentID] = dep
+	}
+	env.repo.link(models.SyncObjectDepartment, "dep-legacy", archived.DepartmentID)
+	env.repo.link(models.SyncObjectDepartment, "dep-team", team.DepartmentID)
+	moved := env.repo.addUser("ivan@example.com", true)
+	moved.DepartmentID = &support.DepartmentID
+	env.repo.link(models.SyncObjectUser, "user-ivan", moved.UserID)
+
+	env.putDepartment(t, "ou=Legacy Renamed,dc=example,dc=com", "dep-legacy", "Legacy Renamed")
+	env.putDepartment(t, "ou=Team,ou=Legacy Renamed,dc=example,dc=com", "dep-team", "Team")
+	env.putDepartment(t, "ou=New,ou=Legacy Renamed,dc=example,dc=com", "dep-new", "New")
+	// Несвязанный отдел с именем архивного не сопоставляется с ним
+	env.putDepartment(t, "ou=Legacy,dc=example,dc=com", "dep-other", "Legacy")
+	env.putUser(t, "cn=ivan,ou=Legacy Renamed,dc=example,dc=com", "user-ivan", "ivan@example.com", false)
+	env.putUser(t, "cn=anna,ou=Legacy Renamed,dc=example,dc=com", "user-anna", "anna@example.com", false)
+
+	report, err := env.sync.Sync(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := env.repo.departments[archived.DepartmentID]; got.Name != "Legacy" || !got.IsArchived() {
+		t.Fatalf("archived department changed: %+v", got)
+	}
+	if got := env.repo.departments[team.DepartmentID]; got.ParentID == nil || *got.ParentID != support.DepartmentID {
+		t.Fatal("child of an archived department moved")
+	}
+	if env.repo.departmentByName("New") != nil {
+		t.Fatal("department created under an archived parent")
+	}
+	var legacy int
+	for _, dep := range env.repo.departments {
+		if dep.Name == "Legacy" && !dep.IsArchived() {
+			legacy++
+		}
+	}
+	if legacy != 1 {
+		t.Fatal("unlinked department matched an archived one instead of being created")
+	}
+	if user := env.repo.users[moved.UserID]; user.DepartmentID == nil || *user.DepartmentID != support.DepartmentID {
+		t.Fatal("user moved back to an archived department")
+	}
+	if anna := env.repo.userByEmail("anna@example.com"); anna == nil || anna.DepartmentID != nil {
+		t.Fatalf("unexpected new user in archived department: %+v", anna)
+	}
+
+	skipped := map[string]string{}
+	for _, s := range report.Skipped {
+		skipped[s.DN] = s.Reason
+	}
+	if skipped["ou=Legacy Renamed,dc=example,dc=com"] != "department archived" ||
+		skipped["ou=New,ou=Legacy Renamed,dc=example,dc=com"] != "parent department archived" {
+		t.Fatalf("unexpected skipped %+v", report.Skipped)
+	}
+}
@@ -2,9 +2,13 @@ package usecase
 
 import (
 	"context"
+	stdErrors "errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/clients/identity"
 	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/dto"
 	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/errors"
 	"github.com/I-Van-Radkov/corporate-messenger/directory-service/internal/models"
@@ -14,14 +18,19 @@ import (
 type DirectoryRepo interface {
 	CreateDepartment(ctx context.Context, dep *models.Department) (uuid.UUID, error)
 	GetDepartmentByID(ctx context.Context, id uuid.UUID) (*models.Department, error)
-	GetDepartments(ctx context.Context, limit, offset int) ([]*models.Department, int, error)
-	DeleteDepartment(ctx context.Context, id uuid.UUID) error
-	GetDepartmentMembers(ctx context.Context, depID uuid.UUID, limit, offset int) ([]*models.User, int, error)
+	GetDepartments(ctx context.Context, limit, offset int, includeArchived bool) ([]*models.Department, int, error)
+	RemoveDepartment(ctx context.Context, removal *models.DepartmentRemoval) (*models.DepartmentRemovalResult, error)
+	MoveDepartment(ctx context.Context, dep *models.Department) (bool, error)
+	GetDepartmentSubtree(ctx context.Context, id uuid.UUID, maxDepth int) ([]*models.DepartmentNode, error)
+	GetDepartmentPath(ctx context.Context, id uuid.UUID) ([]*models.Department, error)
+	GetDepartmentMembers(ctx context.Context, depID uuid.UUID, limit, offset int, includeInactive bool) ([]*models.User, int, error)
 
 	CreateUser(ctx context.Context, user *models.User) (uuid.UUID, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetUsers(ctx context.Context, filter *dto.GetUsersRequest) ([]*models.User, int, error)
-	DeleteUser(ctx context.Context, id uuid.UUID) error
+	UpdateUser(ctx context.Context, user *models.User) error
+	DeactivateUser(ctx context.Context, id uuid.UUID, at time.Time) error
+	ReactivateUser(ctx context.Context, id uuid.UUID, at time.Time) error
 
 	// Для дерева отделов
 	GetAllDepartments(ctx context.Context) ([]*models.Department, error)
@@ -29,11 +38,13 @@ type DirectoryRepo interface {
 
 type DirectoryUsecase struct {
 	Repository DirectoryRepo
+	accounts   identity.Client
 }
 
-func NewDirectoryUsecase(repository DirectoryRepo) *DirectoryUsecase {
+func NewDirectoryUsecase(repository DirectoryRepo, accounts identity.Client) *DirectoryUsecase {
 	return &DirectoryUsecase{
 		Repository: repository,
+		accounts:   accounts,
 	}
 }
 
@@ -44,22 +55,25 @@ func (u *DirectoryUsecase) CreateDepartment(ctx context.Context, req *dto.Create
 		DepartmentID: uuid.New(),
 		Name:         req.Name,
 		ParentID:     req.ParentID,
+		HeadUserID:   req.HeadUserID,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
 
+	if req.HeadUserID != nil {
+		if err := u.checkDepartmentHead(ctx, *req.HeadUserID); err != nil {
+			return nil, err
+		}
+	}
+
 	if req.ParentID != nil {
 		parent, err := u.Repository.GetDepartmentByID(ctx, *req.ParentID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get parent department: %w", err)
 		}
-		if parent == nil {
+		if parent == nil || parent.IsArchived() {
 			return nil, errors.ErrDepartmentNotFound
 		}
-
-		if parent.ParentID != nil && *parent.ParentID == dep.DepartmentID {
-			return nil, errors.ErrCircularReference
-		}
 	}
 
 	id, err := u.Repository.CreateDepartment(ctx, dep)
@@ -80,6 +94,9 @@ func (u *DirectoryUsecase) GetDepartments(ctx context.Context, req *dto.GetDepar
 		if err != nil {
 			return nil, fmt.Errorf("failed to get all departments")
 		}
+		if !req.IncludeArchived {
+			allDeps = slices.DeleteFunc(allDeps, (*models.Department).IsArchived)
+		}
 		tree := buildDepartmentTree(allDeps)
 
 		return &dto.GetDepartmentsResponse{
@@ -88,7 +105,7 @@ func (u *DirectoryUsecase) GetDepartments(ctx context.Context, req *dto.GetDepar
 		}, nil
 	}
 
-	deps, total, err := u.Repository.GetDepartments(ctx, req.Limit, req.Offset)
+	deps, total, err := u.Repository.GetDepartments(ctx, req.Limit, req.Offset, req.IncludeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all departments")
 	}
@@ -99,6 +116,164 @@ func (u *DirectoryUsecase) GetDepartments(ctx context.Context, req *dto.GetDepar
 	}, nil
 }
 
+// UpdateDepartment переименовывает отдел и/или переносит его под другого родителя.
+// Перенос в собственное поддерево отклоняется с ErrCircularReference
+func (u *DirectoryUsecase) UpdateDepartment(ctx context.Context, id uuid.UUID, req *dto.UpdateDepartmentRequest) (*models.Department, error) {
+	dep, err := u.Repository.GetDepartmentByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department by id: %w", err)
+	}
+	if dep == nil || dep.IsArchived() {
+		return nil, errors.ErrDepartmentNotFound
+	}
+
+	updated := *dep
+	if req.Name != nil {
+		updated.Name = strings.TrimSpace(*req.Name)
+		if updated.Name == "" {
+			return nil, fmt.Errorf("%w: name must not be empty", errors.ErrValidationFailed)
+		}
+	}
+	if req.ParentID.Set {
+		updated.ParentID = req.ParentID.Value
+	}
+	if req.HeadUserID.Set {
+		updated.HeadUserID = req.HeadUserID.Value
+	}
+
+	if updated.HeadUserID != nil && !sameUUID(updated.HeadUserID, dep.HeadUserID) {
+		if err := u.checkDepartmentHead(ctx, *updated.HeadUserID); err != nil {
+			return nil, err
+		}
+	}
+
+	if updated.ParentID != nil && !sameUUID(updated.ParentID, dep.ParentID) {
+		if *updated.ParentID == dep.DepartmentID {
+			return nil, errors.ErrSelfParentReference
+		}
+
+		parent, err := u.Repository.GetDepartmentByID(ctx, *updated.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent department: %w", err)
+		}
+		if parent == nil || parent.IsArchived() {
+			return nil, fmt.Errorf("parent %w", errors.ErrDepartmentNotFound)
+		}
+	}
+
+	if updated.Name == dep.Name && sameUUID(updated.ParentID, dep.ParentID) && sameUUID(updated.HeadUserID, dep.HeadUserID) {
+		return dep, nil
+	}
+	updated.UpdatedAt = time.Now()
+
+	moved, err := u.Repository.MoveDepartment(ctx, &updated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update department: %w", err)
+	}
+	if !moved {
+		return nil, errors.ErrCircularReference
+	}
+
+	return &updated, nil
+}
+
+// checkDepartmentHead - руководителем можно назначить только активного пользователя
+func (u *DirectoryUsecase) checkDepartmentHead(ctx context.Context, userID uuid.UUID) error {
+	head, err := u.Repository.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get department head: %w", err)
+	}
+	if head == nil {
+		return fmt.Errorf("department head: %w", errors.ErrUserNotFound)
+	}
+	if !head.IsActive {
+		return fmt.Errorf("%w: department head must be an active user", errors.ErrValidationFailed)
+	}
+
+	return nil
+}
+
+// GetDepartmentSubtree - отдел с вложенными отделами и численностью по каждому поддереву
+func (u *DirectoryUsecase) GetDepartmentSubtree(ctx context.Context, id uuid.UUID, req *dto.GetDepartmentSubtreeRequest) (*dto.DepartmentTreeNode, error) {
+	nodes, err := u.Repository.GetDepartmentSubtree(ctx, id, req.Depth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department subtree: %w", err)
+	}
+	if len(nodes) == 0 {
+		return nil, errors.ErrDepartmentNotFound
+	}
+
+	// Узлы отсортированы по глубине, поэтому родитель всегда обработан раньше детей
+	byID := make(map[uuid.UUID]*dto.DepartmentTreeNode, len(nodes))
+	var root *dto.DepartmentTreeNode
+	for _, node := range nodes {
+		treeNode := &dto.DepartmentTreeNode{
+			DepartmentID:     node.DepartmentID,
+			Name:             node.Name,
+			ParentID:         node.ParentID,
+			HeadUserID:       node.HeadUserID,
+			Depth:            node.Depth,
+			MemberCount:      node.MemberCount,
+			TotalMemberCount: node.TotalMemberCount,
+			Children:         []*dto.DepartmentTreeNode{},
+		}
+		byID[node.DepartmentID] = treeNode
+
+		if node.Depth == 0 {
+			root = treeNode
+			continue
+		}
+		if parent, ok := byID[*node.ParentID]; ok {
+			parent.Children = append(parent.Children, treeNode)
+		}
+	}
+
+	return root, nil
+}
+
+// GetDepartmentAncestors - вышестоящие отделы от непосредственного родителя к корню
+func (u *DirectoryUsecase) GetDepartmentAncestors(ctx context.Context, id uuid.UUID) (*dto.GetDepartmentAncestorsResponse, error) {
+	path, err := u.Repository.GetDepartmentPath(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department ancestors: %w", err)
+	}
+	if len(path) == 0 {
+		return nil, errors.ErrDepartmentNotFound
+	}
+
+	ancestors := make([]*models.Department, 0, len(path)-1)
+	for i := len(path) - 2; i >= 0; i-- {
+		ancestors = append(ancestors, path[i])
+	}
+
+	return &dto.GetDepartmentAncestorsResponse{
+		Ancestors: ancestors,
+	}, nil
+}
+
+// GetDepartmentPath - хлебные крошки от корня до отдела
+func (u *DirectoryUsecase) GetDepartmentPath(ctx context.Context, id uuid.UUID) (*dto.GetDepartmentPathResponse, error) {
+	path, err := u.Repository.GetDepartmentPath(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department path: %w", err)
+	}
+	if len(path) == 0 {
+		return nil, errors.ErrDepartmentNotFound
+	}
+
+	refs := make([]*dto.DepartmentRef, 0, len(path))
+	for _, dep := range path {
+		refs = append(refs, &dto.DepartmentRef{
+			DepartmentID: dep.DepartmentID,
+			Name:         dep.Name,
+		})
+	}
+
+	return &dto.GetDepartmentPathResponse{
+		Path: refs,
+	}, nil
+}
+
 func buildDepartmentTree(deps []*models.Department) []*models.Department {
 	depMap := make(map[uuid.UUID]*models.Department)
 	for _, dep := range deps {
@@ -120,16 +295,44 @@ func buildDepartmentTree(deps []*models.Department) []*models.Department {
 	return roots
 }
 
-func (u *DirectoryUsecase) RemoveDepartment(ctx context.Context, id uuid.UUID) error {
-	dep, err := u.Repository.GetDepartmentByID(ctx, id)
+// RemoveDepartment удаляет отдел. Без reassign_to отдел с активными сотрудниками или
+// дочерними отделами не удаляется; в режиме архива уволенные сотрудники остаются
+// привязаны к отделу, чтобы не терять историю
+func (u *DirectoryUsecase) RemoveDepartment(ctx context.Context, id uuid.UUID, req *dto.RemoveDepartmentQuery) (*dto.RemoveDepartmentResponse, error) {
+	if req.ReassignTo != nil && *req.ReassignTo == id {
+		return nil, errors.ErrInvalidReassignTarget
+	}
+
+	result, err := u.Repository.RemoveDepartment(ctx, &models.DepartmentRemoval{
+		DepartmentID: id,
+		ReassignTo:   req.ReassignTo,
+		Archive:      req.Archive,
+		RemovedAt:    time.Now(),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get department by id: %w", err)
+		return nil, fmt.Errorf("failed to remove department: %w", err)
 	}
-	if dep == nil {
-		return errors.ErrDepartmentNotFound
+
+	switch {
+	case result.NotFound:
+		return nil, errors.ErrDepartmentNotFound
+	case result.TargetNotFound:
+		return nil, fmt.Errorf("reassignment target %w", errors.ErrDepartmentNotFound)
+	case result.TargetInSubtree:
+		return nil, errors.ErrInvalidReassignTarget
+	case result.HasChildren:
+		return nil, errors.ErrDepartmentHasChildren
+	case result.HasActiveUsers:
+		return nil, errors.ErrDepartmentHasUsers
 	}
 
-	return u.Repository.DeleteDepartment(ctx, id)
+	return &dto.RemoveDepartmentResponse{
+		DepartmentID:  id,
+		Archived:      req.Archive,
+		ReassignedTo:  req.ReassignTo,
+		MovedUsers:    result.MovedUsers,
+		MovedChildren: result.MovedChildren,
+	}, nil
 }
 
 func (u *DirectoryUsecase) GetDepartmentMembers(ctx context.Context, req *dto.GetDepartmentMembersRequest) (*dto.GetDepartmentMembersResponse, error) {
@@ -141,7 +344,7 @@ func (u *DirectoryUsecase) GetDepartmentMembers(ctx context.Context, req *dto.Ge
 		return nil, errors.ErrDepartmentNotFound
 	}
 
-	members, total, err := u.Repository.GetDepartmentMembers(ctx, req.DepartmentID, req.Limit, req.Offset)
+	members, total, err := u.Repository.GetDepartmentMembers(ctx, req.DepartmentID, req.Limit, req.Offset, req.IncludeInactive)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get members of department: %w", err)
 	}
@@ -173,7 +376,7 @@ func (u *DirectoryUsecase) CreateUser(ctx context.Context, req *dto.CreateUserRe
 		if err != nil {
 			return nil, fmt.Errorf("failed to get department by id: %w", err)
 		}
-		if dep == nil {
+		if dep == nil || dep.IsArchived() {
 			return nil, errors.ErrDepartmentNotFound
 		}
 	}
@@ -199,6 +402,12 @@ func (u *DirectoryUsecase) GetUsers(ctx context.Context, req *dto.GetUsersReques
 		}
 	}
 
+	// Уволенные видны только по явному запросу
+	if req.IsActive == nil && !req.IncludeInactive {
+		active := true
+		req.IsActive = &active
+	}
+
 	users, total, err := u.Repository.GetUsers(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get users: %w", err)
@@ -228,13 +437,139 @@ func (u *DirectoryUsecase) GetUser(ctx context.Context, id uuid.UUID) (*dto.User
 	}, nil
 }
 
-func (u *DirectoryUsecase) RemoveUser(ctx context.Context, id uuid.UUID) error {
+// UpdateUser меняет карточку пользователя и переводит его в другой отдел
+func (u *DirectoryUsecase) UpdateUser(ctx context.Context, id uuid.UUID, req *dto.UpdateUserRequest) (*models.User, error) {
+	user, err := u.Repository.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+
+	updated := *user
+	if req.FirstName != nil {
+		updated.FirstName = strings.TrimSpace(*req.FirstName)
+		if updated.FirstName == "" {
+			return nil, fmt.Errorf("%w: first_name must not be empty", errors.ErrValidationFailed)
+		}
+	}
+	if req.LastName != nil {
+		updated.LastName = strings.TrimSpace(*req.LastName)
+		if updated.LastName == "" {
+			return nil, fmt.Errorf("%w: last_name must not be empty", errors.ErrValidationFailed)
+		}
+	}
+	if req.Position != nil {
+		updated.Position = optionalString(*req.Position)
+	}
+	if req.AvatarURL != nil {
+		if err := validateAvatarURL(*req.AvatarURL); err != nil {
+			return nil, err
+		}
+		updated.AvatarURL = optionalString(*req.AvatarURL)
+	}
+	if req.DepartmentID.Set {
+		updated.DepartmentID = req.DepartmentID.Value
+	}
+
+	if updated.DepartmentID != nil && !sameUUID(updated.DepartmentID, user.DepartmentID) {
+		dep, err := u.Repository.GetDepartmentByID(ctx, *updated.DepartmentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get department by id: %w", err)
+		}
+		if dep == nil || dep.IsArchived() {
+			return nil, errors.ErrDepartmentNotFound
+		}
+	}
+
+	if updated.FirstName == user.FirstName && updated.LastName == user.LastName &&
+		sameString(updated.Position, user.Position) && sameString(updated.AvatarURL, user.AvatarURL) &&
+		sameUUID(updated.DepartmentID, user.DepartmentID) {
+		return user, nil
+	}
+	updated.UpdatedAt = time.Now()
+
+	if err := u.Repository.UpdateUser(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// RemoveUser увольняет сотрудника. Запись не удаляется: на user_id ссылаются аккаунт
+// и история чатов, где бывший сотрудник должен оставаться узнаваемым по имени.
+// Сначала закрывается вход, чтобы неактивный в каталоге сотрудник не остался с доступом
+func (u *DirectoryUsecase) RemoveUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	user, err := u.Repository.GetUserByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return errors.ErrUserNotFound
+		return nil, errors.ErrUserNotFound
+	}
+	if !user.IsActive {
+		return user, nil
+	}
+
+	err = u.accounts.DeprovisionAccount(ctx, id)
+	if err != nil && !stdErrors.Is(err, identity.ErrAccountNotFound) {
+		return nil, fmt.Errorf("%w: %v", errors.ErrAccountSyncFailed, err)
+	}
+
+	now := time.Now()
+	if err := u.Repository.DeactivateUser(ctx, id, now); err != nil {
+		return nil, fmt.Errorf("failed to deactivate user: %w", err)
+	}
+
+	user.IsActive = false
+	user.DeactivatedAt = &now
+	user.UpdatedAt = now
+
+	return user, nil
+}
+
+// ReactivateUser возвращает уволенного сотрудника. Руководство отделами, снятое
+// при увольнении, не восстанавливается
+func (u *DirectoryUsecase) ReactivateUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := u.Repository.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.ErrUserNotFound
+	}
+	if user.IsActive {
+		return user, nil
+	}
+
+	err = u.accounts.ReactivateAccount(ctx, id)
+	if err != nil && !stdErrors.Is(err, identity.ErrAccountNotFound) {
+		return nil, fmt.Errorf("%w: %v", errors.ErrAccountSyncFailed, err)
+	}
+
+	now := time.Now()
+	if err := u.Repository.ReactivateUser(ctx, id, now); err != nil {
+		return nil, fmt.Errorf("failed to reactivate user: %w", err)
+	}
+
+	user.IsActive = true
+	user.DeactivatedAt = nil
+	user.UpdatedAt = now
+
+	return user, nil
+}
+
+func sameUUID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
-	return u.Repository.DeleteUser(ctx, id)
+	return *a == *b
 }
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_position_trgm_idx;
+DROP INDEX IF EXISTS users_email_local_part_trgm_idx;
+DROP INDEX IF EXISTS users_last_first_name_trgm_idx;
+DROP INDEX IF EXISTS users_first_last_name_trgm_idx;
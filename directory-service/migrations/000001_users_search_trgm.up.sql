@@ -0,0 +1,15 @@
+-- Нечёткий поиск коллег: операторы word_similarity и триграммные индексы по тем же
+-- выражениям, что и в DirectoryRepo.SearchUsers
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS users_first_last_name_trgm_idx
+    ON users USING gin ((first_name || ' ' || last_name) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_last_first_name_trgm_idx
+    ON users USING gin ((last_name || ' ' || first_name) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_email_local_part_trgm_idx
+    ON users USING gin (split_part(email, '@', 1) gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_position_trgm_idx
+    ON users USING gin (coalesce(position, '') gin_trgm_ops);
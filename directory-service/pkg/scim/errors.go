@@ -0,0 +1,55 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Значения scimType из RFC 7644, раздел 3.12
+const (
+	TypeInvalidFilter = "invalidFilter"
+	TypeInvalidPath   = "invalidPath"
+	TypeNoTarget      = "noTarget"
+	TypeInvalidValue  = "invalidValue"
+	TypeInvalidSyntax = "invalidSyntax"
+	TypeUniqueness    = "uniqueness"
+	TypeMutability    = "mutability"
+)
+
+// Error - ошибка протокола SCIM с HTTP-статусом и scimType
+type Error struct {
+	Status   int
+	ScimType string
+	Detail   string
+}
+
+func (e *Error) Error() string {
+	if e.ScimType == "" {
+		return e.Detail
+	}
+
+	return fmt.Sprintf("%s: %s", e.ScimType, e.Detail)
+}
+
+// Response - тело ответа с ошибкой
+func (e *Error) Response() *ErrorResponse {
+	return &ErrorResponse{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(e.Status),
+		ScimType: e.ScimType,
+		Detail:   e.Detail,
+	}
+}
+
+func NewError(status int, scimType, format string, args ...any) *Error {
+	return &Error{
+		Status:   status,
+		ScimType: scimType,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
+
+func BadRequest(scimType, format string, args ...any) *Error {
+	return NewError(http.StatusBadRequest, scimType, format, args...)
+}
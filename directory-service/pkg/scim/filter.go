@@ -0,0 +1,509 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// Операторы сравнения фильтров
+const (
+	OpEqual          = "eq"
+	OpNotEqual       = "ne"
+	OpContains       = "co"
+	OpStartsWith     = "sw"
+	OpEndsWith       = "ew"
+	OpPresent        = "pr"
+	OpGreater        = "gt"
+	OpGreaterOrEqual = "ge"
+	OpLess           = "lt"
+	OpLessOrEqual    = "le"
+)
+
+var compareOps = map[string]bool{
+	OpEqual: true, OpNotEqual: true, OpContains: true, OpStartsWith: true, OpEndsWith: true,
+	OpGreater: true, OpGreaterOrEqual: true, OpLess: true, OpLessOrEqual: true,
+}
+
+// Filter - узел разобранного фильтра: *LogicalExpr, *NotExpr, *AttrExpr или *ValuePathExpr
+type Filter interface {
+	filter()
+}
+
+// LogicalExpr - "and" или "or" двух фильтров
+type LogicalExpr struct {
+	Op          string
+	Left, Right Filter
+}
+
+type NotExpr struct {
+	Filter Filter
+}
+
+// AttrExpr - сравнение атрибута со значением. Value - string, float64, bool или nil
+type AttrExpr struct {
+	Path  AttrPath
+	Op    string
+	Value any
+}
+
+// ValuePathExpr - фильтр по элементам многозначного атрибута: emails[type eq "work"]
+type ValuePathExpr struct {
+	Attr   string
+	Filter Filter
+}
+
+func (*LogicalExpr) filter()   {}
+func (*NotExpr) filter()       {}
+func (*AttrExpr) filter()      {}
+func (*ValuePathExpr) filter() {}
+
+// AttrPath - путь к атрибуту без URN схемы: name.givenName -> {name, givenName}
+type AttrPath struct {
+	Attr    string
+	SubAttr string
+}
+
+// String - путь в нижнем регистре для сопоставления без учёта регистра
+func (p AttrPath) String() string {
+	if p.SubAttr == "" {
+		return strings.ToLower(p.Attr)
+	}
+
+	return strings.ToLower(p.Attr + "." + p.SubAttr)
+}
+
+// ParseAttrPath разбирает путь атрибута, отбрасывая URN схемы
+func ParseAttrPath(path string) (AttrPath, error) {
+	if idx := strings.LastIndex(path, ":"); idx >= 0 {
+		path = path[idx+1:]
+	}
+
+	attr, sub, _ := strings.Cut(path, ".")
+	if attr == "" || !isAttrName(attr) || (sub != "" && !isAttrName(sub)) || strings.Contains(sub, ".") {
+		return AttrPath{}, BadRequest(TypeInvalidPath, "invalid attribute path %q", path)
+	}
+
+	return AttrPath{Attr: attr, SubAttr: sub}, nil
+}
+
+func isAttrName(name string) bool {
+	for i, r := range name {
+		if !(unicode.IsLetter(r) || r == '$' || (i > 0 && (unicode.IsDigit(r) || r == '_' || r == '-'))) {
+			return false
+		}
+	}
+
+	return name != ""
+}
+
+// ParseFilter разбирает выражение фильтра из RFC 7644, раздел 3.4.2.2
+func ParseFilter(input string) (Filter, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, BadRequest(TypeInvalidFilter, "unexpected %q", p.peek().text)
+	}
+
+	return f, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(input); {
+		ch := input[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case strings.IndexByte("()[]", ch) >= 0:
+			tokens = append(tokens, token{kind: tokenPunct, text: string(ch)})
+			i++
+		case ch == '"':
+			end := i + 1
+			for ; end < len(input); end++ {
+				if input[end] == '\\' {
+					end++
+					continue
+				}
+				if input[end] == '"' {
+					break
+				}
+			}
+			if end >= len(input) {
+				return nil, BadRequest(TypeInvalidFilter, "unterminated string")
+			}
+
+			var value string
+			if err := json.Unmarshal([]byte(input[i:end+1]), &value); err != nil {
+				return nil, BadRequest(TypeInvalidFilter, "invalid string %s", input[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value})
+			i = end + 1
+		default:
+			end := i
+			for end < len(input) && strings.IndexByte(" \t\n\r()[]\"", input[end]) < 0 {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: input[i:end]})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() token {
+	if p.done() {
+		return token{}
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) isKeyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *filterParser) expect(punct string) error {
+	t := p.next()
+	if t.kind != tokenPunct || t.text != punct {
+		return BadRequest(TypeInvalidFilter, "expected %q", punct)
+	}
+
+	return nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "or", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "and", Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	if p.isKeyword("not") {
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &NotExpr{Filter: inner}, nil
+	}
+
+	if t := p.peek(); t.kind == tokenPunct && t.text == "(" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+
+	return p.parseAttrExpr()
+}
+
+func (p *filterParser) parseAttrExpr() (Filter, error) {
+	t := p.next()
+	if t.kind != tokenWord {
+		return nil, BadRequest(TypeInvalidFilter, "expected attribute path")
+	}
+	path, err := ParseAttrPath(t.text)
+	if err != nil {
+		return nil, BadRequest(TypeInvalidFilter, "invalid attribute path %q", t.text)
+	}
+
+	if next := p.peek(); next.kind == tokenPunct && next.text == "[" {
+		if path.SubAttr != "" {
+			return nil, BadRequest(TypeInvalidFilter, "invalid value path %q", t.text)
+		}
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return &ValuePathExpr{Attr: path.Attr, Filter: inner}, nil
+	}
+
+	opToken := p.next()
+	op := strings.ToLower(opToken.text)
+	if opToken.kind != tokenWord {
+		return nil, BadRequest(TypeInvalidFilter, "expected operator after %q", t.text)
+	}
+	if op == OpPresent {
+		return &AttrExpr{Path: path, Op: op}, nil
+	}
+	if !compareOps[op] {
+		return nil, BadRequest(TypeInvalidFilter, "unknown operator %q", opToken.text)
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	return &AttrExpr{Path: path, Op: op, Value: value}, nil
+}
+
+func (p *filterParser) parseValue() (any, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return t.text, nil
+	case tokenWord:
+		switch strings.ToLower(t.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+
+		var number float64
+		if err := json.Unmarshal([]byte(t.text), &number); err == nil {
+			return number, nil
+		}
+	}
+
+	return nil, BadRequest(TypeInvalidFilter, "invalid comparison value %q", t.text)
+}
+
+// Match проверяет фильтр на ресурсе в виде JSON-объекта. Имена атрибутов и строковые
+// значения сравниваются без учёта регистра
+func Match(f Filter, resource map[string]any) bool {
+	switch expr := f.(type) {
+	case *LogicalExpr:
+		if expr.Op == "and" {
+			return Match(expr.Left, resource) && Match(expr.Right, resource)
+		}
+		return Match(expr.Left, resource) || Match(expr.Right, resource)
+	case *NotExpr:
+		return !Match(expr.Filter, resource)
+	case *ValuePathExpr:
+		for _, elem := range asList(lookup(resource, expr.Attr)) {
+			if obj, ok := elem.(map[string]any); ok && Match(expr.Filter, obj) {
+				return true
+			}
+		}
+		return false
+	case *AttrExpr:
+		for _, value := range resolve(resource, expr.Path) {
+			if compare(value, expr.Op, expr.Value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// resolve возвращает значения по пути; у многозначных атрибутов - значения всех элементов
+func resolve(resource map[string]any, path AttrPath) []any {
+	values := asList(lookup(resource, path.Attr))
+	if path.SubAttr == "" {
+		return values
+	}
+
+	var result []any
+	for _, value := range values {
+		if obj, ok := value.(map[string]any); ok {
+			result = append(result, asList(lookup(obj, path.SubAttr))...)
+		}
+	}
+
+	return result
+}
+
+func compare(actual any, op string, expected any) bool {
+	if op == OpPresent {
+		switch v := actual.(type) {
+		case nil:
+			return false
+		case string:
+			return v != ""
+		default:
+			return true
+		}
+	}
+
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return false
+		}
+		a, e = strings.ToLower(a), strings.ToLower(e)
+		switch op {
+		case OpEqual:
+			return a == e
+		case OpNotEqual:
+			return a != e
+		case OpContains:
+			return strings.Contains(a, e)
+		case OpStartsWith:
+			return strings.HasPrefix(a, e)
+		case OpEndsWith:
+			return strings.HasSuffix(a, e)
+		case OpGreater:
+			return a > e
+		case OpGreaterOrEqual:
+			return a >= e
+		case OpLess:
+			return a < e
+		case OpLessOrEqual:
+			return a <= e
+		}
+	case bool:
+		e, ok := expected.(bool)
+		if !ok {
+			return false
+		}
+		switch op {
+		case OpEqual:
+			return a == e
+		case OpNotEqual:
+			return a != e
+		}
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case OpEqual:
+			return a == e
+		case OpNotEqual:
+			return a != e
+		case OpGreater:
+			return a > e
+		case OpGreaterOrEqual:
+			return a >= e
+		case OpLess:
+			return a < e
+		case OpLessOrEqual:
+			return a <= e
+		}
+	case nil:
+		return op == OpNotEqual && expected != nil
+	}
+
+	return false
+}
+
+// lookup ищет ключ объекта без учёта регистра
+func lookup(obj map[string]any, name string) any {
+	if value, ok := obj[name]; ok {
+		return value
+	}
+	for key, value := range obj {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+func lookupKey(obj map[string]any, name string) string {
+	if _, ok := obj[name]; ok {
+		return name
+	}
+	for key := range obj {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+
+	return name
+}
+
+func asList(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []any:
+		return v
+	default:
+		return []any{v}
+	}
+}
@@ -0,0 +1,298 @@
+package scim
+
+import (
+	"strings"
+)
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// patchPath - цель операции: attr, attr.sub, attr[filter] или attr[filter].sub
+type patchPath struct {
+	attr    string
+	subAttr string
+	filter  Filter
+}
+
+func parsePatchPath(path string) (*patchPath, error) {
+	open := strings.IndexByte(path, '[')
+	if open < 0 {
+		attrPath, err := ParseAttrPath(path)
+		if err != nil {
+			return nil, err
+		}
+		return &patchPath{attr: attrPath.Attr, subAttr: attrPath.SubAttr}, nil
+	}
+
+	closing := strings.LastIndexByte(path, ']')
+	if closing < open {
+		return nil, BadRequest(TypeInvalidPath, "invalid path %q", path)
+	}
+
+	attrPath, err := ParseAttrPath(path[:open])
+	if err != nil || attrPath.SubAttr != "" {
+		return nil, BadRequest(TypeInvalidPath, "invalid path %q", path)
+	}
+
+	filter, err := ParseFilter(path[open+1 : closing])
+	if err != nil {
+		return nil, BadRequest(TypeInvalidPath, "invalid filter in path %q", path)
+	}
+
+	result := &patchPath{attr: attrPath.Attr, filter: filter}
+	if rest := path[closing+1:]; rest != "" {
+		if !strings.HasPrefix(rest, ".") || !isAttrName(rest[1:]) {
+			return nil, BadRequest(TypeInvalidPath, "invalid path %q", path)
+		}
+		result.subAttr = rest[1:]
+	}
+
+	return result, nil
+}
+
+// ApplyPatch применяет операции PATCH (RFC 7644, раздел 3.5.2) к ресурсу в виде
+// JSON-объекта. Имена атрибутов сопоставляются без учёта регистра
+func ApplyPatch(resource map[string]any, ops []PatchOperation) error {
+	if len(ops) == 0 {
+		return BadRequest(TypeInvalidValue, "no patch operations")
+	}
+
+	for _, op := range ops {
+		var err error
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			err = applySet(resource, strings.ToLower(op.Op), op.Path, op.Value)
+		case "remove":
+			err = applyRemove(resource, op.Path, op.Value)
+		default:
+			err = BadRequest(TypeInvalidSyntax, "unsupported patch operation %q", op.Op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applySet(resource map[string]any, op, path string, value any) error {
+	if path == "" {
+		// Без пути значение - объект, каждый ключ которого задаёт свой атрибут
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return BadRequest(TypeInvalidValue, "%s without path requires an object value", op)
+		}
+		for key, attrValue := range obj {
+			if nested, ok := attrValue.(map[string]any); ok && strings.HasPrefix(strings.ToLower(key), "urn:") {
+				// Атрибуты расширения схемы: {"urn:...:User": {"department": ...}}
+				for nestedKey, nestedValue := range nested {
+					if err := applySet(resource, op, key+":"+nestedKey, nestedValue); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := applySet(resource, op, key, attrValue); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	target, err := parsePatchPath(path)
+	if err != nil {
+		return err
+	}
+	key := lookupKey(resource, target.attr)
+
+	if target.filter != nil {
+		return setFiltered(resource, key, target, value)
+	}
+
+	if target.subAttr == "" {
+		existing := resource[key]
+		switch current := existing.(type) {
+		case []any:
+			if op == "add" {
+				resource[key] = append(current, asList(value)...)
+				return nil
+			}
+		case map[string]any:
+			if incoming, ok := value.(map[string]any); ok && op == "add" {
+				for k, v := range incoming {
+					current[lookupKey(current, k)] = v
+				}
+				return nil
+			}
+		}
+		resource[key] = value
+		return nil
+	}
+
+	switch container := resource[key].(type) {
+	case map[string]any:
+		container[lookupKey(container, target.subAttr)] = value
+	case []any:
+		for _, elem := range container {
+			if obj, ok := elem.(map[string]any); ok {
+				obj[lookupKey(obj, target.subAttr)] = value
+			}
+		}
+	default:
+		resource[key] = map[string]any{target.subAttr: value}
+	}
+
+	return nil
+}
+
+func setFiltered(resource map[string]any, key string, target *patchPath, value any) error {
+	list := asList(resource[key])
+
+	matched := false
+	for _, elem := range list {
+		obj, ok := elem.(map[string]any)
+		if !ok || !Match(target.filter, obj) {
+			continue
+		}
+		matched = true
+
+		if target.subAttr != "" {
+			obj[lookupKey(obj, target.subAttr)] = value
+			continue
+		}
+		incoming, ok := value.(map[string]any)
+		if !ok {
+			return BadRequest(TypeInvalidValue, "value for %q must be an object", key)
+		}
+		for k, v := range incoming {
+			obj[lookupKey(obj, k)] = v
+		}
+	}
+	if matched {
+		return nil
+	}
+
+	// Подходящего элемента нет: создаём его из условий фильтра, если они однозначны
+	elem, ok := elementFromFilter(target.filter)
+	if !ok {
+		return BadRequest(TypeNoTarget, "no %s value matches the filter", key)
+	}
+	if target.subAttr != "" {
+		elem[target.subAttr] = value
+	} else if incoming, ok := value.(map[string]any); ok {
+		for k, v := range incoming {
+			elem[k] = v
+		}
+	}
+	resource[key] = append(list, elem)
+
+	return nil
+}
+
+// elementFromFilter строит элемент из фильтра вида a eq "x" and b eq "y"
+func elementFromFilter(f Filter) (map[string]any, bool) {
+	switch expr := f.(type) {
+	case *AttrExpr:
+		if expr.Op != OpEqual || expr.Path.SubAttr != "" {
+			return nil, false
+		}
+		return map[string]any{expr.Path.Attr: expr.Value}, true
+	case *LogicalExpr:
+		if expr.Op != "and" {
+			return nil, false
+		}
+		left, ok := elementFromFilter(expr.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := elementFromFilter(expr.Right)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range right {
+			left[k] = v
+		}
+		return left, true
+	default:
+		return nil, false
+	}
+}
+
+func applyRemove(resource map[string]any, path string, value any) error {
+	if path == "" {
+		return BadRequest(TypeNoTarget, "remove requires a path")
+	}
+
+	target, err := parsePatchPath(path)
+	if err != nil {
+		return err
+	}
+	key := lookupKey(resource, target.attr)
+
+	if target.filter != nil {
+		list := asList(resource[key])
+		kept := make([]any, 0, len(list))
+		for _, elem := range list {
+			obj, ok := elem.(map[string]any)
+			if !ok || !Match(target.filter, obj) {
+				kept = append(kept, elem)
+				continue
+			}
+			if target.subAttr != "" {
+				delete(obj, lookupKey(obj, target.subAttr))
+				kept = append(kept, obj)
+			}
+		}
+		resource[key] = kept
+		return nil
+	}
+
+	if target.subAttr != "" {
+		switch container := resource[key].(type) {
+		case map[string]any:
+			delete(container, lookupKey(container, target.subAttr))
+		case []any:
+			for _, elem := range container {
+				if obj, ok := elem.(map[string]any); ok {
+					delete(obj, lookupKey(obj, target.subAttr))
+				}
+			}
+		}
+		return nil
+	}
+
+	// Удаление перечисленных элементов: {"path": "members", "value": [{"value": "id"}]}
+	if current, ok := resource[key].([]any); ok && value != nil {
+		removed := make(map[string]bool)
+		for _, elem := range asList(value) {
+			if obj, ok := elem.(map[string]any); ok {
+				if id, ok := lookup(obj, "value").(string); ok {
+					removed[strings.ToLower(id)] = true
+				}
+			}
+		}
+
+		kept := make([]any, 0, len(current))
+		for _, elem := range current {
+			if obj, ok := elem.(map[string]any); ok {
+				if id, ok := lookup(obj, "value").(string); ok && removed[strings.ToLower(id)] {
+					continue
+				}
+			}
+			kept = append(kept, elem)
+		}
+		resource[key] = kept
+		return nil
+	}
+
+	delete(resource, key)
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package scim - общие части протокола SCIM 2.0 (RFC 7643, RFC 7644): фильтры,
+// PATCH-операции, ответы со списками и ошибками
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	ContentType = "application/scim+json"
+)
+
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+type ErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// ListRequest - параметры запроса списка ресурсов; startIndex начинается с 1
+type ListRequest struct {
+	Filter             string `form:"filter"`
+	StartIndex         int    `form:"startIndex,default=1"`
+	Count              int    `form:"count,default=100"`
+	ExcludedAttributes string `form:"excludedAttributes"`
+}
+
+// Excludes сообщает, что клиент попросил не возвращать атрибут
+func (r *ListRequest) Excludes(attr string) bool {
+	for _, excluded := range strings.Split(r.ExcludedAttributes, ",") {
+		if strings.EqualFold(strings.TrimSpace(excluded), attr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Boolean принимает как JSON-булево, так и строки "true"/"false": так их присылают
+// некоторые провайдеры (Azure AD)
+type Boolean bool
+
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = Boolean(v)
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			*b = true
+		case "false":
+			*b = false
+		default:
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+// Package translit переводит имена между кириллицей и латиницей, чтобы поиск
+// по справочнику находил "Иван" по запросу "Ivan" и наоборот
+package translit
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Буквосочетания проверяются от длинных к коротким
+var latinToCyrillic = []struct {
+	latin    string
+	cyrillic string
+}{
+	{"shch", "щ"},
+	{"sch", "щ"},
+	{"zh", "ж"}, {"kh", "х"}, {"ts", "ц"}, {"ch", "ч"}, {"sh", "ш"},
+	{"yu", "ю"}, {"ya", "я"}, {"yo", "ё"}, {"ye", "е"},
+	{"ju", "ю"}, {"ja", "я"},
+	{"a", "а"}, {"b", "б"}, {"c", "к"}, {"d", "д"}, {"e", "е"}, {"f", "ф"},
+	{"g", "г"}, {"h", "х"}, {"i", "и"}, {"j", "й"}, {"k", "к"}, {"l", "л"},
+	{"m", "м"}, {"n", "н"}, {"o", "о"}, {"p", "п"}, {"q", "к"}, {"r", "р"},
+	{"s", "с"}, {"t", "т"}, {"u", "у"}, {"v", "в"}, {"w", "в"}, {"x", "кс"},
+	{"z", "з"},
+}
+
+// ToLatin транслитерирует кириллицу в нижнем регистре; остальные символы не меняются
+func ToLatin(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if latin, ok := cyrillicToLatin[r]; ok {
+			b.WriteString(latin)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ToCyrillic транслитерирует латиницу в нижнем регистре. "y" после гласной читается
+// как "й" (Sergey -> сергей), иначе как "ы"
+func ToCyrillic(s string) string {
+	s = strings.ToLower(s)
+
+	var b strings.Builder
+	var prev rune
+	for i := 0; i < len(s); {
+		matched := false
+		for _, pair := range latinToCyrillic {
+			if strings.HasPrefix(s[i:], pair.latin) {
+				b.WriteString(pair.cyrillic)
+				prev = rune(pair.latin[len(pair.latin)-1])
+				i += len(pair.latin)
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+
+		if s[i] == 'y' {
+			// "y" без пары из таблицы: после гласной и перед гласной - "й"
+			if isVowel(prev) || (i+1 < len(s) && isLatinVowel(s[i+1])) {
+				b.WriteString("й")
+			} else {
+				b.WriteString("ы")
+			}
+			prev = 'y'
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteRune(r)
+		prev = r
+		i += size
+	}
+	return b.String()
+}
+
+// Variants - запрос в нижнем регистре и его транслитерации без повторов
+func Variants(s string) []string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return nil
+	}
+
+	variants := []string{s}
+	for _, v := range []string{ToLatin(s), ToCyrillic(s)} {
+		if v != "" && !slices.Contains(variants, v) {
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
+
+func isVowel(r rune) bool {
+	return r < unicode.MaxASCII && isLatinVowel(byte(r))
+}
+
+func isLatinVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
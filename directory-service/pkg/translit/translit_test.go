@@ -0,0 +1,74 @@
+package translit
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestToLatin(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Иван", want: "ivan"},
+		{in: "Щукин", want: "shchukin"},
+		{in: "Юлия", want: "yuliya"},
+		{in: "Хабибуллин", want: "khabibullin"},
+		{in: "Цой", want: "tsoy"},
+		{in: "Объём", want: "obem"},
+		{in: "Ivan-Иван 2", want: "ivan-ivan 2"},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := ToLatin(tt.in); got != tt.want {
+			t.Errorf("ToLatin(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToCyrillic(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Ivan", want: "иван"},
+		{in: "Sergey", want: "сергей"},
+		{in: "Andrey", want: "андрей"},
+		{in: "Tsoy", want: "цой"},
+		{in: "Zhukov", want: "жуков"},
+		{in: "Shchukin", want: "щукин"},
+		{in: "Yana", want: "яна"},
+		{in: "Maya", want: "мая"},
+		{in: "Ivanov-Petrov", want: "иванов-петров"},
+		{in: "Иван", want: "иван"},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := ToCyrillic(tt.in); got != tt.want {
+			t.Errorf("ToCyrillic(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "Ivan", want: []string{"ivan", "иван"}},
+		{in: "  Иван ", want: []string{"иван", "ivan"}},
+		{in: "Sergey", want: []string{"sergey", "сергей"}},
+		{in: "Сергей", want: []string{"сергей", "sergey"}},
+		{in: "42", want: []string{"42"}},
+		{in: "", want: nil},
+		{in: "   ", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := Variants(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("Variants(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ключ advisory lock, под которым инстансы identity-service ротируют ключи по очереди
+const keyRotationLockID = 7310331
+
+// KeyRepo хранит закрытые ключи зашифрованными, aad - kid ключа
+type KeyRepo struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+	secrets *utils.SecretCipher
+}
+
+func NewKeyRepo(db *pgxpool.Pool, secrets *utils.SecretCipher) *KeyRepo {
+	return &KeyRepo{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		secrets: secrets,
+	}
+}
+
+// ListKeys возвращает неистёкшие ключи в порядке активации
+func (r *KeyRepo) ListKeys(ctx context.Context, now time.Time) ([]*models.SigningKey, error) {
+	query, args, err := r.builder.
+		Select("kid", "algorithm", "private_key", "created_at", "activates_at", "expires_at").
+		From("signing_keys").
+		Where(squirrel.Or{squirrel.Eq{"expires_at": nil}, squirrel.Gt{"expires_at": now}}).
+		OrderBy("activates_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.SigningKey{}
+	var legacy []*models.SigningKey
+	for rows.Next() {
+		var key models.SigningKey
+		var stored []byte
+		err := rows.Scan(&key.KID, &key.Algorithm, &stored, &key.CreatedAt, &key.ActivatesAt, &key.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+
+		key.PrivateKey, err = r.secrets.Open(stored, key.KID)
+		if errors.Is(err, utils.ErrSecretNotSealed) {
+			// Ключ записан до включения шифрования
+			key.PrivateKey = stored
+			legacy = append(legacy, &key)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decrypt signing key %s: %w", key.KID, err)
+		}
+		keys = append(keys, &key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read signing keys: %w", err)
+	}
+	rows.Close()
+
+	for _, key := range legacy {
+		if err := r.sealLegacyKey(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	return keys, nil
+}
+
+// sealLegacyKey перезаписывает незашифрованный ключ зашифрованным
+func (r *KeyRepo) sealLegacyKey(ctx context.Context, key *models.SigningKey) error {
+	sealed, err := r.secrets.Seal(key.PrivateKey, key.KID)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt signing key %s: %w", key.KID, err)
+	}
+
+	query, args, err := r.builder.
+		Update("signing_keys").
+		Set("private_key", sealed).
+		Where(squirrel.Eq{"kid": key.KID, "private_key": key.PrivateKey}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to encrypt signing key %s: %w", key.KID, err)
+	}
+
+	return nil
+}
+
+// AddKey добавляет следующий ключ, если последним по-прежнему остаётся previousKID
+// (пустой - ключей ещё нет), и ограничивает срок публикации предыдущего.
+// added=false - другой инстанс уже выполнил ротацию
+func (r *KeyRepo) AddKey(ctx context.Context, next *models.SigningKey, previousKID string, previousExpiresAt time.Time) (added bool, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", keyRotationLockID); err != nil {
+		return false, fmt.Errorf("failed to acquire key rotation lock: %w", err)
+	}
+
+	var latestKID string
+	err = tx.QueryRow(ctx, "SELECT COALESCE((SELECT kid FROM signing_keys ORDER BY activates_at DESC LIMIT 1), '')").Scan(&latestKID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get latest signing key: %w", err)
+	}
+	if latestKID != previousKID {
+		return false, nil
+	}
+
+	sealed, err := r.secrets.Seal(next.PrivateKey, next.KID)
+	if err != nil {
+		return false, fmt.Errorf("failed to encrypt signing key: %w", err)
+	}
+
+	insertQuery, insertArgs, err := r.builder.
+		Insert("signing_keys").
+		Columns("kid", "algorithm", "private_key", "created_at", "activates_at", "expires_at").
+		Values(next.KID, next.Algorithm, sealed, next.CreatedAt, next.ActivatesAt, next.ExpiresAt).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build insert query: %w", err)
+	}
+	if _, err := tx.Exec(ctx, insertQuery, insertArgs...); err != nil {
+		return false, fmt.Errorf("failed to insert signing key: %w", err)
+	}
+
+	if previousKID != "" {
+		updateQuery, updateArgs, err := r.builder.
+			Update("signing_keys").
+			Set("expires_at", previousExpiresAt).
+			Where(squirrel.Eq{"kid": previousKID}).
+			ToSql()
+		if err != nil {
+			return false, fmt.Errorf("failed to build update query: %w", err)
+		}
+		if _, err := tx.Exec(ctx, updateQuery, updateArgs...); err != nil {
+			return false, fmt.Errorf("failed to expire previous signing key: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
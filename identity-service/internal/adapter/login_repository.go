@@ -0,0 +1,117 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type LoginRepo struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+}
+
+func NewLoginRepo(db *pgxpool.Pool) *LoginRepo {
+	return &LoginRepo{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *LoginRepo) GetThrottle(ctx context.Context, key string) (*models.LoginThrottle, error) {
+	query, args, err := r.builder.
+		Select("key", "failures", "last_failure_at", "blocked_until").
+		From("login_throttles").
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var throttle models.LoginThrottle
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&throttle.Key, &throttle.Failures, &throttle.LastFailureAt, &throttle.BlockedUntil,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan login throttle: %w", err)
+	}
+
+	return &throttle, nil
+}
+
+// RegisterFailure атомарно увеличивает счётчик неудач. Если предыдущая неудача была
+// раньше windowStart, счёт начинается заново. Возвращает новое число неудач
+func (r *LoginRepo) RegisterFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+	var failures int
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO login_throttles (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failure_at < $3 THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING failures`, key, now, windowStart).Scan(&failures)
+	if err != nil {
+		return 0, fmt.Errorf("failed to register login failure: %w", err)
+	}
+
+	return failures, nil
+}
+
+func (r *LoginRepo) SetBlockedUntil(ctx context.Context, key string, blockedUntil time.Time) error {
+	query, args, err := r.builder.
+		Update("login_throttles").
+		Set("blocked_until", blockedUntil).
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to block login: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LoginRepo) ResetThrottle(ctx context.Context, key string) error {
+	query, args, err := r.builder.
+		Delete("login_throttles").
+		Where(squirrel.Eq{"key": key}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to reset login throttle: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LoginRepo) RecordAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	query, args, err := r.builder.
+		Insert("login_attempts").
+		Columns("account_id", "email", "ip", "user_agent", "result", "attempted_at").
+		Values(attempt.AccountID, attempt.Email, attempt.IP, attempt.UserAgent, attempt.Result, attempt.AttemptedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MFARepo хранит секреты TOTP зашифрованными, aad - account_id
+type MFARepo struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+	secrets *utils.SecretCipher
+}
+
+func NewMFARepo(db *pgxpool.Pool, secrets *utils.SecretCipher) *MFARepo {
+	return &MFARepo{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+		secrets: secrets,
+	}
+}
+
+func (r *MFARepo) GetTOTP(ctx context.Context, accountID uuid.UUID) (*models.TOTPCredential, error) {
+	query, args, err := r.builder.
+		Select("account_id", "secret", "confirmed_at", "last_used_step", "created_at").
+		From("totp_credentials").
+		Where(squirrel.Eq{"account_id": accountID}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var cred models.TOTPCredential
+	var stored string
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&cred.AccountID, &stored, &cred.ConfirmedAt, &cred.LastUsedStep, &cred.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan totp credential: %w", err)
+	}
+
+	cred.Secret, err = r.secrets.OpenString(stored, accountID.String())
+	if errors.Is(err, utils.ErrSecretNotSealed) {
+		// Секрет записан до включения шифрования
+		cred.Secret = stored
+		if err := r.sealLegacySecret(ctx, accountID, stored); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+
+	return &cred, nil
+}
+
+// sealLegacySecret перезаписывает незашифрованный секрет зашифрованным
+func (r *MFARepo) sealLegacySecret(ctx context.Context, accountID uuid.UUID, secret string) error {
+	sealed, err := r.secrets.SealString(secret, accountID.String())
+	if err != nil {
+		return fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	query, args, err := r.builder.
+		Update("totp_credentials").
+		Set("secret", sealed).
+		Where(squirrel.Eq{"account_id": accountID, "secret": secret}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	return nil
+}
+
+// SavePendingTOTP сохраняет новый неподтверждённый секрет. Подключённый второй фактор
+// не перезаписывается: возвращает false
+func (r *MFARepo) SavePendingTOTP(ctx context.Context, cred *models.TOTPCredential) (bool, error) {
+	sealed, err := r.secrets.SealString(cred.Secret, cred.AccountID.String())
+	if err != nil {
+		return false, fmt.Errorf("failed to encrypt totp secret: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO totp_credentials (account_id, secret, confirmed_at, last_used_step, created_at)
+		VALUES ($1, $2, NULL, 0, $3)
+		ON CONFLICT (account_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			last_used_step = 0,
+			created_at = EXCLUDED.created_at
+		WHERE totp_credentials.confirmed_at IS NULL`,
+		cred.AccountID, sealed, cred.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to save totp credential: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// ConfirmTOTP включает второй фактор и заменяет коды восстановления
+func (r *MFARepo) ConfirmTOTP(ctx context.Context, accountID uuid.UUID, step int64, now time.Time, recoveryHashes []string) (bool, error) {
+	confirmQuery, confirmArgs, err := r.builder.
+		Update("totp_credentials").
+		Set("confirmed_at", now).
+		Set("last_used_step", step).
+		Where(squirrel.Eq{"account_id": accountID, "confirmed_at": nil}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, confirmQuery, confirmArgs...)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm totp credential: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := r.replaceRecoveryCodes(ctx, tx, accountID, now, recoveryHashes); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// UseTOTPStep фиксирует использованный интервал. Код того же или более раннего
+// интервала повторно не принимается
+func (r *MFARepo) UseTOTPStep(ctx context.Context, accountID uuid.UUID, step int64) (bool, error) {
+	query, args, err := r.builder.
+		Update("totp_credentials").
+		Set("last_used_step", step).
+		Where(squirrel.Eq{"account_id": accountID}).
+		Where(squirrel.Lt{"last_used_step": step}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update totp step: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// DeleteTOTP отключает второй фактор вместе с кодами восстановления
+func (r *MFARepo) DeleteTOTP(ctx context.Context, accountID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, table := range []string{"recovery_codes", "totp_credentials"} {
+		query, args, err := r.builder.
+			Delete(table).
+			Where(squirrel.Eq{"account_id": accountID}).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build delete query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UseRecoveryCode гасит код восстановления. Каждый код срабатывает один раз
+func (r *MFARepo) UseRecoveryCode(ctx context.Context, accountID uuid.UUID, codeHash string, now time.Time) (bool, error) {
+	query, args, err := r.builder.
+		Update("recovery_codes").
+		Set("used_at", now).
+		Where(squirrel.Eq{"account_id": accountID, "code_hash": codeHash, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *MFARepo) replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, accountID uuid.UUID, now time.Time, hashes []string) error {
+	query, args, err := r.builder.
+		Delete("recovery_codes").
+		Where(squirrel.Eq{"account_id": accountID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	insert := r.builder.
+		Insert("recovery_codes").
+		Columns("code_hash", "account_id", "created_at")
+	for _, hash := range hashes {
+		insert = insert.Values(hash, accountID, now)
+	}
+
+	query, args, err = insert.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MFARepo) CreateChallenge(ctx context.Context, challenge *models.LoginChallenge) error {
+	query, args, err := r.builder.
+		Insert("login_challenges").
+		Columns("challenge_hash", "account_id", "user_agent", "ip", "attempts", "expires_at", "created_at").
+		Values(challenge.ChallengeHash, challenge.AccountID, challenge.UserAgent, challenge.IP, 0, challenge.ExpiresAt, challenge.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert login challenge: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MFARepo) FindChallenge(ctx context.Context, challengeHash string) (*models.LoginChallenge, error) {
+	query, args, err := r.builder.
+		Select("challenge_hash", "account_id", "user_agent", "ip", "attempts", "expires_at", "used_at", "created_at").
+		From("login_challenges").
+		Where(squirrel.Eq{"challenge_hash": challengeHash}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var challenge models.LoginChallenge
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&challenge.ChallengeHash, &challenge.AccountID, &challenge.UserAgent, &challenge.IP,
+		&challenge.Attempts, &challenge.ExpiresAt, &challenge.UsedAt, &challenge.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan login challenge: %w", err)
+	}
+
+	return &challenge, nil
+}
+
+func (r *MFARepo) RegisterChallengeFailure(ctx context.Context, challengeHash string) error {
+	query, args, err := r.builder.
+		Update("login_challenges").
+		Set("attempts", squirrel.Expr("attempts + 1")).
+		Where(squirrel.Eq{"challenge_hash": challengeHash}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update login challenge: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeChallenge атомарно завершает шаг входа: из параллельных запросов пройдёт один
+func (r *MFARepo) ConsumeChallenge(ctx context.Context, challengeHash string, now time.Time) (bool, error) {
+	query, args, err := r.builder.
+		Update("login_challenges").
+		Set("used_at", now).
+		Where(squirrel.Eq{"challenge_hash": challengeHash, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to consume login challenge: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,113 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PasswordRepo struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+}
+
+func NewPasswordRepo(db *pgxpool.Pool) *PasswordRepo {
+	return &PasswordRepo{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+// CreateResetToken сохраняет новый токен сброса и гасит ранее выданные неиспользованные,
+// чтобы действовал только последний
+func (r *PasswordRepo) CreateResetToken(ctx context.Context, token *models.PasswordResetToken) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query, args, err := r.builder.
+		Update("password_reset_tokens").
+		Set("used_at", token.CreatedAt).
+		Where(squirrel.Eq{"account_id": token.AccountID, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to invalidate reset tokens: %w", err)
+	}
+
+	query, args, err = r.builder.
+		Insert("password_reset_tokens").
+		Columns("token_hash", "account_id", "expires_at", "created_at").
+		Values(token.TokenHash, token.AccountID, token.ExpiresAt, token.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert reset token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeResetToken атомарно помечает токен использованным. Возвращает nil, если токен
+// неизвестен, уже использован или истёк
+func (r *PasswordRepo) ConsumeResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error) {
+	query, args, err := r.builder.
+		Update("password_reset_tokens").
+		Set("used_at", now).
+		Where(squirrel.Eq{"token_hash": tokenHash, "used_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		Suffix("RETURNING token_hash, account_id, expires_at, used_at, created_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	var token models.PasswordResetToken
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&token.TokenHash, &token.AccountID, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume reset token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// DeleteResetTokens удаляет все токены сброса аккаунта
+func (r *PasswordRepo) DeleteResetTokens(ctx context.Context, accountID uuid.UUID) error {
+	query, args, err := r.builder.
+		Delete("password_reset_tokens").
+		Where(squirrel.Eq{"account_id": accountID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete reset tokens: %w", err)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
 	"github.com/Masterminds/squirrel"
@@ -173,6 +174,42 @@ func (r *AuthRepo) UpdateAccount(ctx context.Context, account *models.Account) e
 	return nil
 }
 
+func (r *AuthRepo) UpdateLastLogin(ctx context.Context, accountID uuid.UUID, at time.Time) error {
+	query, args, err := r.builder.
+		Update("accounts").
+		Set("last_login", at).
+		Where(squirrel.Eq{"account_id": accountID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update last login: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AuthRepo) UpdatePassword(ctx context.Context, accountID uuid.UUID, passwordHash string) error {
+	query, args, err := r.builder.
+		Update("accounts").
+		Set("password_hash", passwordHash).
+		Where(squirrel.Eq{"account_id": accountID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return nil
+}
+
 func (r *AuthRepo) DeactivateAccount(ctx context.Context, accountID uuid.UUID) error {
 	query, args, err := r.builder.
 		Update("accounts").
@@ -0,0 +1,278 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SessionRepo struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+}
+
+func NewSessionRepo(db *pgxpool.Pool) *SessionRepo {
+	return &SessionRepo{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+var sessionColumns = []string{"session_id", "account_id", "user_id", "user_agent", "ip", "created_at", "last_used_at", "expires_at", "revoked_at"}
+
+func scanSession(row pgx.Row) (*models.Session, error) {
+	var session models.Session
+	err := row.Scan(
+		&session.SessionID, &session.AccountID, &session.UserID,
+		&session.UserAgent, &session.IP,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// CreateSession сохраняет сессию вместе с её первым refresh-токеном
+func (r *SessionRepo) CreateSession(ctx context.Context, session *models.Session, token *models.RefreshToken) error {
+	sessionQuery, sessionArgs, err := r.builder.
+		Insert("sessions").
+		Columns(sessionColumns...).
+		Values(session.SessionID, session.AccountID, session.UserID, session.UserAgent, session.IP,
+			session.CreatedAt, session.LastUsedAt, session.ExpiresAt, session.RevokedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert session query: %w", err)
+	}
+
+	tokenQuery, tokenArgs, err := r.insertRefreshToken(token)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sessionQuery, sessionArgs...); err != nil {
+		return fmt.Errorf("failed to insert session: %w", err)
+	}
+	if _, err := tx.Exec(ctx, tokenQuery, tokenArgs...); err != nil {
+		return fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepo) FindSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error) {
+	query, args, err := r.builder.
+		Select(sessionColumns...).
+		From("sessions").
+		Where(squirrel.Eq{"session_id": sessionID}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	session, err := scanSession(r.db.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan session: %w", err)
+	}
+
+	return session, nil
+}
+
+// ListActiveSessions возвращает неотозванные и неистёкшие сессии аккаунта
+func (r *SessionRepo) ListActiveSessions(ctx context.Context, accountID uuid.UUID, now time.Time) ([]*models.Session, error) {
+	query, args, err := r.builder.
+		Select(sessionColumns...).
+		From("sessions").
+		Where(squirrel.Eq{"account_id": accountID, "revoked_at": nil}).
+		Where(squirrel.Gt{"expires_at": now}).
+		OrderBy("last_used_at DESC").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *SessionRepo) RevokeSession(ctx context.Context, sessionID uuid.UUID, now time.Time) error {
+	query, args, err := r.builder.
+		Update("sessions").
+		Set("revoked_at", now).
+		Where(squirrel.Eq{"session_id": sessionID, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build revoke query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAccountSessions отзывает все сессии аккаунта (деактивация)
+func (r *SessionRepo) RevokeAccountSessions(ctx context.Context, accountID uuid.UUID, now time.Time) error {
+	query, args, err := r.builder.
+		Update("sessions").
+		Set("revoked_at", now).
+		Where(squirrel.Eq{"account_id": accountID, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build revoke query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to revoke account sessions: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions отзывает все сессии аккаунта, кроме текущей
+func (r *SessionRepo) RevokeOtherSessions(ctx context.Context, accountID, keepSessionID uuid.UUID, now time.Time) error {
+	query, args, err := r.builder.
+		Update("sessions").
+		Set("revoked_at", now).
+		Where(squirrel.Eq{"account_id": accountID, "revoked_at": nil}).
+		Where(squirrel.NotEq{"session_id": keepSessionID}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build revoke query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to revoke other sessions: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SessionRepo) FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	query, args, err := r.builder.
+		Select("token_hash", "session_id", "expires_at", "used_at", "created_at").
+		From("refresh_tokens").
+		Where(squirrel.Eq{"token_hash": tokenHash}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var token models.RefreshToken
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&token.TokenHash, &token.SessionID, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken помечает старый токен использованным и выдаёт сессии новый.
+// rotated=false - старый токен уже был использован (повторное предъявление)
+func (r *SessionRepo) RotateRefreshToken(ctx context.Context, oldHash string, next *models.RefreshToken) (rotated bool, err error) {
+	markQuery, markArgs, err := r.builder.
+		Update("refresh_tokens").
+		Set("used_at", next.CreatedAt).
+		Where(squirrel.Eq{"token_hash": oldHash, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tokenQuery, tokenArgs, err := r.insertRefreshToken(next)
+	if err != nil {
+		return false, err
+	}
+
+	touchQuery, touchArgs, err := r.builder.
+		Update("sessions").
+		Set("last_used_at", next.CreatedAt).
+		Set("expires_at", next.ExpiresAt).
+		Where(squirrel.Eq{"session_id": next.SessionID}).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Условный UPDATE атомарен: из двух параллельных refresh одним токеном пройдёт только один
+	tag, err := tx.Exec(ctx, markQuery, markArgs...)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, tokenQuery, tokenArgs...); err != nil {
+		return false, fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+	if _, err := tx.Exec(ctx, touchQuery, touchArgs...); err != nil {
+		return false, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *SessionRepo) insertRefreshToken(token *models.RefreshToken) (string, []any, error) {
+	query, args, err := r.builder.
+		Insert("refresh_tokens").
+		Columns("token_hash", "session_id", "expires_at", "used_at", "created_at").
+		Values(token.TokenHash, token.SessionID, token.ExpiresAt, token.UsedAt, token.CreatedAt).
+		ToSql()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build insert refresh token query: %w", err)
+	}
+
+	return query, args, nil
+}
@@ -0,0 +1,110 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type SSORepo struct {
+	db      *pgxpool.Pool
+	builder squirrel.StatementBuilderType
+}
+
+func NewSSORepo(db *pgxpool.Pool) *SSORepo {
+	return &SSORepo{
+		db:      db,
+		builder: squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar),
+	}
+}
+
+func (r *SSORepo) SaveState(ctx context.Context, state *models.SSOLoginState) error {
+	query, args, err := r.builder.
+		Insert("sso_login_states").
+		Columns("state_hash", "nonce", "code_verifier", "expires_at", "created_at").
+		Values(state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt, state.CreatedAt).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to insert sso state: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeState забирает state и удаляет его: callback с тем же state второй раз не пройдёт
+func (r *SSORepo) ConsumeState(ctx context.Context, stateHash string) (*models.SSOLoginState, error) {
+	query, args, err := r.builder.
+		Delete("sso_login_states").
+		Where(squirrel.Eq{"state_hash": stateHash}).
+		Suffix("RETURNING state_hash, nonce, code_verifier, expires_at, created_at").
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	var state models.SSOLoginState
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&state.StateHash, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt, &state.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume sso state: %w", err)
+	}
+
+	return &state, nil
+}
+
+func (r *SSORepo) FindIdentity(ctx context.Context, issuer, subject string) (*models.ExternalIdentity, error) {
+	query, args, err := r.builder.
+		Select("issuer", "subject", "account_id", "email", "created_at", "last_login_at").
+		From("external_identities").
+		Where(squirrel.Eq{"issuer": issuer, "subject": subject}).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var identity models.ExternalIdentity
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&identity.Issuer, &identity.Subject, &identity.AccountID,
+		&identity.Email, &identity.CreatedAt, &identity.LastLoginAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan external identity: %w", err)
+	}
+
+	return &identity, nil
+}
+
+// LinkIdentity привязывает учётную запись провайдера к аккаунту или обновляет время входа
+func (r *SSORepo) LinkIdentity(ctx context.Context, identity *models.ExternalIdentity) error {
+	query, args, err := r.builder.
+		Insert("external_identities").
+		Columns("issuer", "subject", "account_id", "email", "created_at", "last_login_at").
+		Values(identity.Issuer, identity.Subject, identity.AccountID, identity.Email, identity.CreatedAt, identity.LastLoginAt).
+		Suffix("ON CONFLICT (issuer, subject) DO UPDATE SET email = EXCLUDED.email, last_login_at = EXCLUDED.last_login_at").
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to link external identity: %w", err)
+	}
+
+	return nil
+}
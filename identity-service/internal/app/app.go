@@ -14,6 +14,7 @@ import (
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/config"
 	v1 "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/controller/http/v1"
 	postgres "github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/db"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
 )
 
 type App struct {
@@ -27,8 +28,13 @@ func NewApp(cfg *config.Config) (*App, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	server := v1.NewServer(cfg.Port, cfg.ReadTimeout, cfg.WriteTimeout, cfg.DirectoryServiceConfig, db.Pool)
-	err = server.RegisterHandlers(cfg.AuthConfig)
+	secrets, err := utils.NewSecretCipher(cfg.SecretsConfig.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init secrets encryption: %w", err)
+	}
+
+	server := v1.NewServer(cfg.Port, cfg.ReadTimeout, cfg.WriteTimeout, cfg.DirectoryServiceConfig, cfg.ChatServiceConfig, cfg.OIDCConfig, cfg.TrustedProxies, db.Pool)
+	err = server.RegisterHandlers(cfg.AuthConfig, cfg.KeysConfig, secrets)
 	if err != nil {
 		return nil, fmt.Errorf("failed to register handlers: %w", err)
 	}
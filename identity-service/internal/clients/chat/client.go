@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Client interface {
+	NotifyAccountDeactivated(ctx context.Context, userID uuid.UUID) error
+}
+
+type HTTPClient struct {
+	baseURL       string
+	internalToken string
+	httpClient    *http.Client
+	timeout       time.Duration
+}
+
+func NewHTTPClient(cfg ChatServiceConfig) *HTTPClient {
+	return &HTTPClient{
+		baseURL:       cfg.URL,
+		internalToken: cfg.InternalToken,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		timeout: cfg.Timeout,
+	}
+}
+
+// NotifyAccountDeactivated просит chat-service закрыть websocket-сессии пользователя
+func (c *HTTPClient) NotifyAccountDeactivated(ctx context.Context, userID uuid.UUID) error {
+	url := fmt.Sprintf("%s/accounts/%s/deactivated", c.baseURL, userID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Internal-Token", c.internalToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("chat service returned %d", resp.StatusCode)
+	}
+
+	return nil
+}
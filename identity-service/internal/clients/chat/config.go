@@ -0,0 +1,9 @@
+package chat
+
+import "time"
+
+type ChatServiceConfig struct {
+	URL           string        `env:"CHAT_INTERNAL_URL" env-default:"http://localhost:8083/internal"`
+	Timeout       time.Duration `env:"CHAT_SERVICE_TIMEOUT" env-default:"5s"`
+	InternalToken string        `env:"INTERNAL_API_TOKEN"`
+}
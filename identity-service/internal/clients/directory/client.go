@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 )
@@ -12,6 +13,7 @@ import (
 type Client interface {
 	GetUserByID(ctx context.Context, userID string) (*UserResponse, error)
 	UserExists(ctx context.Context, userID string) (bool, error)
+	FindUserByEmail(ctx context.Context, email string) (*UserResponse, error)
 }
 
 type HTTPClient struct {
@@ -72,3 +74,38 @@ func (c *HTTPClient) UserExists(ctx context.Context, userID string) (bool, error
 
 	return true, nil
 }
+
+// FindUserByEmail ищет сотрудника по email. Если такого нет, возвращает nil
+func (c *HTTPClient) FindUserByEmail(ctx context.Context, email string) (*UserResponse, error) {
+	usersURL := strings.TrimSuffix(strings.Split(c.baseURL, "*user_id*")[0], "/")
+
+	query := url.Values{}
+	query.Set("email", email)
+	query.Set("limit", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, usersURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("directory service returned %d", resp.StatusCode)
+	}
+
+	var usersResp UsersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&usersResp); err != nil {
+		return nil, fmt.Errorf("failed to decode data to UsersResponse: %w", err)
+	}
+
+	if len(usersResp.Users) == 0 {
+		return nil, nil
+	}
+
+	return usersResp.Users[0], nil
+}
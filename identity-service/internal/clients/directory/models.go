@@ -15,6 +15,11 @@ type UserResponse struct {
 	DepartmentID *uuid.UUID `json:"department,omitempty"`
 }
 
+type UsersResponse struct {
+	Users []*UserResponse `json:"users"`
+	Total int             `json:"total"`
+}
+
 var (
 	ErrUserNotFound = errors.New("user not found")
 )
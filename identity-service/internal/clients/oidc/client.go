@@ -0,0 +1,306 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Минимальный интервал между перечитываниями JWKS провайдера при неизвестном kid
+const jwksMinRefreshInterval = 10 * time.Second
+
+// Client - authorization code flow с PKCE (RFC 7636). Эндпоинты провайдера берутся из
+// discovery-документа, подпись id_token проверяется по его JWKS
+type Client struct {
+	cfg        OIDCConfig
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *discoveryDocument
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+func NewClient(cfg OIDCConfig) *Client {
+	return &Client{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		keys: make(map[string]crypto.PublicKey),
+	}
+}
+
+// AuthCodeURL - адрес страницы входа провайдера, куда перенаправляется пользователь
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.cfg.ClientID)
+	query.Set("redirect_uri", c.cfg.RedirectURL)
+	query.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return doc.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange обменивает код авторизации на id_token и возвращает его проверенные claims
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	doc, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode token response: %v", ErrExchangeFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: provider returned %d %s %s", ErrExchangeFailed, resp.StatusCode, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in response", ErrExchangeFailed)
+	}
+
+	return c.verifyIDToken(ctx, doc, tokens.IDToken, nonce)
+}
+
+func (c *Client) verifyIDToken(ctx context.Context, doc *discoveryDocument, rawToken, nonce string) (*Claims, error) {
+	keyfunc := func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.lookupKey(ctx, doc, kid)
+	}
+
+	token, err := jwt.Parse(rawToken, keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidIDToken
+	}
+
+	// nonce связывает id_token с нашим запросом авторизации и защищает от подмены
+	if tokenNonce, _ := mapClaims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	subject, _ := mapClaims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+
+	claims := &Claims{
+		Issuer:  doc.Issuer,
+		Subject: subject,
+	}
+	claims.Email, _ = mapClaims["email"].(string)
+	claims.Name, _ = mapClaims["name"].(string)
+
+	// Некоторые провайдеры отдают email_verified строкой
+	switch verified := mapClaims["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = verified
+	case string:
+		claims.EmailVerified = verified == "true"
+	}
+
+	claims.Groups = stringList(mapClaims[c.cfg.GroupsClaim])
+	claims.AMR = stringList(mapClaims["amr"])
+
+	return claims, nil
+}
+
+// stringList - claim-массив строк; одиночную строку провайдеры иногда отдают без массива
+func stringList(value any) []string {
+	switch value := value.(type) {
+	case []any:
+		var list []string
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	case string:
+		return []string{value}
+	default:
+		return nil
+	}
+}
+
+func (c *Client) discover(ctx context.Context) (*discoveryDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovery != nil {
+		return c.discovery, nil
+	}
+
+	issuer := strings.TrimSuffix(c.cfg.IssuerURL, "/")
+
+	var doc discoveryDocument
+	if err := c.getJSON(ctx, issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+	}
+
+	// Спецификация требует точного совпадения issuer с адресом, по которому его искали
+	if strings.TrimSuffix(doc.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch: expected %s, got %s", issuer, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery document is incomplete")
+	}
+
+	c.discovery = &doc
+	return c.discovery, nil
+}
+
+// lookupKey ищет ключ по kid и перечитывает JWKS, если провайдер сменил ключи
+func (c *Client) lookupKey(ctx context.Context, doc *discoveryDocument, kid string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.findKey(kid); ok {
+		return key, nil
+	}
+
+	if time.Since(c.keysFetchedAt) < jwksMinRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set jwks
+	if err := c.getJSON(ctx, doc.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		parsed, err := parseJWK(k)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = parsed
+	}
+
+	c.keys = keys
+	c.keysFetchedAt = time.Now()
+
+	if key, ok := c.findKey(kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// findKey - без kid допустим только единственный ключ провайдера
+func (c *Client) findKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" {
+		if len(c.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, dst any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("provider returned %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateCodeVerifier - случайный code_verifier для PKCE
+func GenerateCodeVerifier() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// CodeChallengeS256 - code_challenge по методу S256
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
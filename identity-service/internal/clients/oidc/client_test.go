@@ -0,0 +1,244 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "corporate-messenger"
+	testClientSecret = "secret"
+	testRedirectURL  = "http://localhost/callback"
+)
+
+func newTestProvider(t *testing.T) (*oidctest.Provider, *Client) {
+	t.Helper()
+
+	provider, err := oidctest.NewProvider(testClientID, testClientSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(provider.Close)
+
+	client := NewClient(OIDCConfig{
+		IssuerURL:    provider.Issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+		GroupsClaim:  "groups",
+		Timeout:      5 * time.Second,
+	})
+
+	return provider, client
+}
+
+func authorize(t *testing.T, provider *oidctest.Provider, authURL string) (code, state string) {
+	t.Helper()
+
+	code, state, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code, state
+}
+
+func TestExchangeReturnsVerifiedClaims(t *testing.T) {
+	provider, client := newTestProvider(t)
+	provider.SetUser(oidctest.User{
+		Subject:       "subject-1",
+		Email:         "ivan@example.com",
+		EmailVerified: true,
+		Name:          "Ivan",
+		Groups:        []string{"messenger-admins", "staff"},
+		AMR:           []string{"pwd", "otp"},
+	})
+	ctx := context.Background()
+
+	verifier, _ := GenerateCodeVerifier()
+	authURL, err := client.AuthCodeURL(ctx, "state-1", "nonce-1", CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := authorize(t, provider, authURL)
+	if state != "state-1" {
+		t.Fatalf("state = %q", state)
+	}
+
+	claims, err := client.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("exchange: %v", err)
+	}
+
+	want := &Claims{
+		Issuer:        provider.Issuer(),
+		Subject:       "subject-1",
+		Email:         "ivan@example.com",
+		EmailVerified: true,
+		Name:          "Ivan",
+		Groups:        []string{"messenger-admins", "staff"},
+		AMR:           []string{"pwd", "otp"},
+	}
+	if !reflect.DeepEqual(claims, want) {
+		t.Fatalf("got %+v, want %+v", claims, want)
+	}
+}
+
+func TestExchangeRejectsNonceMismatch(t *testing.T) {
+	provider, client := newTestProvider(t)
+	provider.SetUser(oidctest.User{Subject: "subject-1"})
+	ctx := context.Background()
+
+	verifier, _ := GenerateCodeVerifier()
+	authURL, err := client.AuthCodeURL(ctx, "state", "nonce-1", CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := authorize(t, provider, authURL)
+
+	if _, err := client.Exchange(ctx, code, verifier, "nonce-2"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+	}
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	provider, client := newTestProvider(t)
+	provider.SetUser(oidctest.User{Subject: "subject-1"})
+	ctx := context.Background()
+
+	verifier, _ := GenerateCodeVerifier()
+	authURL, err := client.AuthCodeURL(ctx, "state", "nonce", CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := authorize(t, provider, authURL)
+
+	other, _ := GenerateCodeVerifier()
+	if _, err := client.Exchange(ctx, code, other, "nonce"); !errors.Is(err, ErrExchangeFailed) {
+		t.Fatalf("expected ErrExchangeFailed, got %v", err)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	provider, client := newTestProvider(t)
+	ctx := context.Background()
+
+	doc, err := client.discover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   provider.Issuer(),
+			"aud":   testClientID,
+			"sub":   "subject-1",
+			"iat":   now.Unix(),
+			"exp":   now.Add(5 * time.Minute).Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(jwt.MapClaims)
+		wantErr bool
+		check   func(*testing.T, *Claims)
+	}{
+		{name: "valid", mutate: func(jwt.MapClaims) {}},
+		{name: "wrong audience", mutate: func(c jwt.MapClaims) { c["aud"] = "other-client" }, wantErr: true},
+		{name: "audience list with client", mutate: func(c jwt.MapClaims) { c["aud"] = []string{"other", testClientID} }},
+		{name: "wrong issuer", mutate: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "expired", mutate: func(c jwt.MapClaims) { c["exp"] = now.Add(-2 * time.Minute).Unix() }, wantErr: true},
+		{name: "expired within leeway", mutate: func(c jwt.MapClaims) { c["exp"] = now.Add(-30 * time.Second).Unix() }},
+		{name: "no expiry", mutate: func(c jwt.MapClaims) { delete(c, "exp") }, wantErr: true},
+		{name: "issued in the future", mutate: func(c jwt.MapClaims) { c["iat"] = now.Add(time.Hour).Unix() }, wantErr: true},
+		{name: "nonce mismatch", mutate: func(c jwt.MapClaims) { c["nonce"] = "other" }, wantErr: true},
+		{name: "no nonce", mutate: func(c jwt.MapClaims) { delete(c, "nonce") }, wantErr: true},
+		{name: "no subject", mutate: func(c jwt.MapClaims) { delete(c, "sub") }, wantErr: true},
+		{
+			name:   "string email_verified and single group",
+			mutate: func(c jwt.MapClaims) { c["email_verified"] = "true"; c["groups"] = "staff"; c["amr"] = "mfa" },
+			check: func(t *testing.T, claims *Claims) {
+				if !claims.EmailVerified || !reflect.DeepEqual(claims.Groups, []string{"staff"}) || !reflect.DeepEqual(claims.AMR, []string{"mfa"}) {
+					t.Fatalf("unexpected claims %+v", claims)
+				}
+			},
+		},
+		{
+			name:   "unverified email",
+			mutate: func(c jwt.MapClaims) { c["email"] = "ivan@example.com"; c["email_verified"] = false },
+			check: func(t *testing.T, claims *Claims) {
+				if claims.EmailVerified {
+					t.Fatal("email reported verified")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.mutate(claims)
+
+			raw, err := provider.SignIDToken(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := client.verifyIDToken(ctx, doc, raw, "nonce")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Subject != "subject-1" || got.Issuer != provider.Issuer() {
+				t.Fatalf("unexpected claims %+v", got)
+			}
+			if tt.check != nil {
+				tt.check(t, got)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsForeignSignature(t *testing.T) {
+	provider, client := newTestProvider(t)
+	ctx := context.Background()
+
+	doc, err := client.discover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Токен другого провайдера с тем же kid не проходит проверку подписи
+	other, err := oidctest.NewProvider(testClientID, testClientSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	raw, err := other.SignIDToken(jwt.MapClaims{
+		"iss": provider.Issuer(), "aud": testClientID, "sub": "subject-1", "nonce": "nonce",
+		"iat": time.Now().Unix(), "exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.verifyIDToken(ctx, doc, raw, "nonce"); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package oidc
+
+import "time"
+
+// OIDCConfig - корпоративный провайдер OpenID Connect для входа через SSO
+type OIDCConfig struct {
+	Enabled      bool          `env:"OIDC_ENABLED" env-default:"false"`
+	IssuerURL    string        `env:"OIDC_ISSUER_URL"`
+	ClientID     string        `env:"OIDC_CLIENT_ID"`
+	ClientSecret string        `env:"OIDC_CLIENT_SECRET"`
+	RedirectURL  string        `env:"OIDC_REDIRECT_URL" env-default:"http://localhost:8080/api/v1/auth/oidc/callback"`
+	Scopes       []string      `env:"OIDC_SCOPES" env-default:"openid,email,profile" env-separator:","`
+	GroupsClaim  string        `env:"OIDC_GROUPS_CLAIM" env-default:"groups"`
+	Timeout      time.Duration `env:"OIDC_TIMEOUT" env-default:"10s"`
+}
@@ -0,0 +1,38 @@
+package oidc
+
+import "errors"
+
+// Claims - проверенные данные пользователя из id_token
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+	// Методы аутентификации у провайдера (amr, RFC 8176)
+	AMR []string
+}
+
+// discoveryDocument - нужная нам часть /.well-known/openid-configuration
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+var (
+	ErrExchangeFailed = errors.New("failed to exchange authorization code")
+	ErrInvalidIDToken = errors.New("invalid id_token")
+)
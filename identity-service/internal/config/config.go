@@ -5,9 +5,12 @@ import (
 	"os"
 	"time"
 
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/chat"
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/directory"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/oidc"
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/usecase"
 	postgres "github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/db"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
@@ -18,11 +21,23 @@ type Config struct {
 
 	GHTimeout time.Duration `env:"GRACEFUL_SHUTDOWN_TIMEOUT" env-default:"15s"`
 
+	// Адреса api-gateway: только от них принимается X-Real-IP с адресом клиента,
+	// по которому ограничиваются попытки входа. Остальным доверять нельзя
+	TrustedProxies []string `env:"TRUSTED_PROXIES" env-separator:"," env-default:"127.0.0.1,::1"`
+
 	postgres.PostgresConfig
 
 	directory.DirectoryServiceConfig
 
+	chat.ChatServiceConfig
+
+	oidc.OIDCConfig
+
 	usecase.AuthConfig
+
+	usecase.KeysConfig
+
+	utils.SecretsConfig
 }
 
 func ParseConfigFromEnv() (*Config, error) {
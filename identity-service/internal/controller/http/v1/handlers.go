@@ -2,34 +2,69 @@ package v1
 
 import (
 	"context"
+	stdErrors "errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/usecase"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 type AuthUsecase interface {
 	CreateAccount(ctx context.Context, req *dto.CreateAccountRequest) (*dto.AccountResponse, error)
-	Login(ctx context.Context, req *dto.LoginRequest) (string, error)
+	Login(ctx context.Context, req *dto.LoginRequest) (*dto.LoginResult, error)
+	CompleteLogin(ctx context.Context, req *dto.MFALoginRequest) (*dto.LoginResult, error)
+	EnrollDuringLogin(ctx context.Context, challengeToken string) (*dto.TOTPEnrollment, error)
+	Refresh(ctx context.Context, refreshToken string) (*dto.TokenPair, error)
+	Logout(ctx context.Context, refreshToken string) error
 
 	IntrospectToken(ctx context.Context, req *dto.IntrospectRequest) (*dto.IntrospectResponse, error)
 
+	ListSessions(ctx context.Context, claims *models.TokenClaims) ([]*dto.SessionResponse, error)
+	RevokeSession(ctx context.Context, claims *models.TokenClaims, sessionID uuid.UUID) error
+
 	ListAccounts(ctx context.Context) ([]*dto.AccountResponse, error)
 	UpdateAccount(ctx context.Context, accountID uuid.UUID, req *dto.UpdateAccountRequest) (*dto.AccountResponse, error)
 	DeactivateAccount(ctx context.Context, accountID uuid.UUID) error
+	UnlockAccount(ctx context.Context, accountID uuid.UUID) error
+
+	ProvisionAccount(ctx context.Context, userID uuid.UUID, req *dto.ProvisionAccountRequest) (*dto.AccountResponse, bool, error)
+	DeprovisionAccount(ctx context.Context, userID uuid.UUID) error
+	ReactivateAccount(ctx context.Context, userID uuid.UUID) error
+	IssuePasswordReset(ctx context.Context, userID uuid.UUID) (*dto.PasswordResetResponse, error)
+
+	ChangePassword(ctx context.Context, claims *models.TokenClaims, req *dto.ChangePasswordRequest) error
+	ForcePasswordReset(ctx context.Context, accountID uuid.UUID) (*dto.PasswordResetResponse, error)
+	ResetPassword(ctx context.Context, req *dto.ResetPasswordRequest) error
+
+	EnrollTOTP(ctx context.Context, claims *models.TokenClaims) (*dto.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, claims *models.TokenClaims, code string) (*dto.RecoveryCodesResponse, error)
+	DisableTOTP(ctx context.Context, claims *models.TokenClaims, code string) error
+
+	StartSSO(ctx context.Context) (string, error)
+	CompleteSSO(ctx context.Context, req *dto.SSOCallbackRequest) (*dto.LoginResult, error)
+}
+
+type KeySet interface {
+	JWKS() (*dto.JWKS, error)
 }
 
 type AuthHandlers struct {
-	authUsecase AuthUsecase
+	authUsecase    AuthUsecase
+	passwordPolicy usecase.PasswordPolicyConfig
 }
 
-func NewAuthHandlers(authUsecase AuthUsecase) *AuthHandlers {
+func NewAuthHandlers(authUsecase AuthUsecase, passwordPolicy usecase.PasswordPolicyConfig) *AuthHandlers {
 	return &AuthHandlers{
-		authUsecase: authUsecase,
+		authUsecase:    authUsecase,
+		passwordPolicy: passwordPolicy,
 	}
 }
 
@@ -37,7 +72,7 @@ func (h *AuthHandlers) CreateAccountHandler(c *gin.Context) {
 	var req dto.CreateAccountRequest
 
 	if err := c.ShouldBind(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindingError(err, h.passwordPolicy)})
 		return
 	}
 
@@ -64,33 +99,230 @@ func (h *AuthHandlers) LoginHandler(c *gin.Context) {
 		return
 	}
 
-	log.Println(req.Email, req.Password)
+	req.UserAgent = c.Request.UserAgent()
+	req.IP = c.ClientIP()
 
-	tokenString, err := h.authUsecase.Login(c.Request.Context(), &req)
+	result, err := h.authUsecase.Login(c.Request.Context(), &req)
 	if err != nil {
-		if err == errors.ErrInvalidPassword {
+		var throttled *errors.ThrottledError
+		if stdErrors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		if err == errors.ErrInvalidCredentials {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
+		if err == errors.ErrAccountInactive {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Пароль верный, но нужен второй фактор: токены выдаст /auth/login/2fa
+	if result.Challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "two-factor authentication required",
+			"mfa_required": true,
+			"challenge":    result.Challenge,
+		})
+		return
+	}
+
+	setTokenCookies(c, result.Tokens)
+	c.JSON(http.StatusOK, gin.H{"message": "login successful", "tokens": result.Tokens})
+}
+
+func (h *AuthHandlers) LoginMFAHandler(c *gin.Context) {
+	var req dto.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code or recovery_code required"})
+		return
+	}
+
+	result, err := h.authUsecase.CompleteLogin(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	setTokenCookies(c, result.Tokens)
+
+	resp := gin.H{"message": "login successful", "tokens": result.Tokens}
+	if len(result.RecoveryCodes) > 0 {
+		resp["recovery_codes"] = result.RecoveryCodes
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandlers) LoginMFAEnrollHandler(c *gin.Context) {
+	var req dto.MFAChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.authUsecase.EnrollDuringLogin(c.Request.Context(), req.ChallengeToken)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *AuthHandlers) EnrollTOTPHandler(c *gin.Context) {
+	enrollment, err := h.authUsecase.EnrollTOTP(c.Request.Context(), tokenClaims(c))
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *AuthHandlers) ConfirmTOTPHandler(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authUsecase.ConfirmTOTP(c.Request.Context(), tokenClaims(c), req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandlers) DisableTOTPHandler(c *gin.Context) {
+	var req dto.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUsecase.DisableTOTP(c.Request.Context(), tokenClaims(c), req.Code); err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func mfaErrorStatus(err error) int {
+	switch err {
+	case errors.ErrInvalidChallenge, errors.ErrInvalidMFACode:
+		return http.StatusUnauthorized
+	case errors.ErrAccountInactive, errors.ErrMFARequired:
+		return http.StatusForbidden
+	case errors.ErrMFANotEnrolled:
+		return http.StatusBadRequest
+	case errors.ErrMFAAlreadyEnabled:
+		return http.StatusConflict
+	case errors.ErrUserNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *AuthHandlers) RefreshHandler(c *gin.Context) {
+	refreshToken, ok := refreshTokenFromRequest(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh token required"})
+		return
+	}
+
+	tokens, err := h.authUsecase.Refresh(c.Request.Context(), refreshToken)
+	if err != nil {
+		switch err {
+		case errors.ErrInvalidToken, errors.ErrSessionRevoked, errors.ErrRefreshTokenReused:
+			clearTokenCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.ErrAccountInactive:
+			clearTokenCookies(c)
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	setTokenCookies(c, tokens)
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (h *AuthHandlers) LogoutHandler(c *gin.Context) {
+	refreshToken, ok := refreshTokenFromRequest(c)
+	if ok {
+		// Выход идемпотентен: неизвестный токен считаем уже отозванным
+		err := h.authUsecase.Logout(c.Request.Context(), refreshToken)
+		if err != nil && err != errors.ErrInvalidToken {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	clearTokenCookies(c)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandlers) ListSessionsHandler(c *gin.Context) {
+	sessions, err := h.authUsecase.ListSessions(c.Request.Context(), tokenClaims(c))
+	if err != nil {
 		if err == errors.ErrUserNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions, "total": len(sessions)})
+}
+
+func (h *AuthHandlers) RevokeSessionHandler(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session_id"})
+		return
+	}
+
+	if err := h.authUsecase.RevokeSession(c.Request.Context(), tokenClaims(c), sessionID); err != nil {
+		if err == errors.ErrSessionNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	http.SetCookie(c.Writer, &http.Cookie{
-		Name:     "Bearer",
-		Value:    tokenString,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteStrictMode,
-		Expires:  time.Now().Add(24 * time.Hour),
-	})
-	c.JSON(http.StatusOK, gin.H{"message": "login successful"})
+	c.Status(http.StatusNoContent)
+}
+
+// JWKSHandler отдаёт публичные ключи проверки access-токенов
+func JWKSHandler(keySet KeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jwks, err := keySet.JWKS()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwks)
+	}
 }
 
 func (h *AuthHandlers) IntrospectToken(c *gin.Context) {
@@ -174,3 +406,311 @@ func (h *AuthHandlers) DeactivateAccountHandler(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+const (
+	accessCookieName  = "Bearer"
+	refreshCookieName = "Refresh"
+)
+
+func setTokenCookies(c *gin.Context, tokens *dto.TokenPair) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     accessCookieName,
+		Value:    tokens.AccessToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second),
+	})
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     refreshCookieName,
+		Value:    tokens.RefreshToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   false,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  tokens.RefreshExpiresAt,
+	})
+}
+
+func clearTokenCookies(c *gin.Context) {
+	for _, name := range []string{accessCookieName, refreshCookieName} {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+			MaxAge:   -1,
+		})
+	}
+}
+
+// refreshTokenFromRequest берёт refresh-токен из тела запроса (мобильные клиенты) или из cookie
+func refreshTokenFromRequest(c *gin.Context) (string, bool) {
+	if c.Request.ContentLength > 0 {
+		var req dto.RefreshRequest
+		if err := c.ShouldBindJSON(&req); err == nil && req.RefreshToken != "" {
+			return req.RefreshToken, true
+		}
+	}
+
+	if cookie, err := c.Cookie(refreshCookieName); err == nil && cookie != "" {
+		return cookie, true
+	}
+
+	return "", false
+}
+
+func tokenClaims(c *gin.Context) *models.TokenClaims {
+	claims, _ := c.Get("token_claims")
+	return claims.(*models.TokenClaims)
+}
+
+func (h *AuthHandlers) UnlockAccountHandler(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+		return
+	}
+
+	if err := h.authUsecase.UnlockAccount(c.Request.Context(), accountID); err != nil {
+		if err == errors.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandlers) ChangePasswordHandler(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindingError(err, h.passwordPolicy)})
+		return
+	}
+
+	if err := h.authUsecase.ChangePassword(c.Request.Context(), tokenClaims(c), &req); err != nil {
+		var throttled *errors.ThrottledError
+		if stdErrors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		switch err {
+		case errors.ErrInvalidPassword:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.ErrPasswordUnchanged:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandlers) ForcePasswordResetHandler(c *gin.Context) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+		return
+	}
+
+	resp, err := h.authUsecase.ForcePasswordReset(c.Request.Context(), accountID)
+	if err != nil {
+		if err == errors.ErrUserNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandlers) ResetPasswordHandler(c *gin.Context) {
+	var req dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindingError(err, h.passwordPolicy)})
+		return
+	}
+
+	if err := h.authUsecase.ResetPassword(c.Request.Context(), &req); err != nil {
+		switch err {
+		case errors.ErrInvalidResetToken:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.ErrAccountInactive:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	clearTokenCookies(c)
+	c.Status(http.StatusNoContent)
+}
+
+// SSOLoginHandler перенаправляет пользователя на страницу входа корпоративного провайдера
+func (h *AuthHandlers) SSOLoginHandler(c *gin.Context) {
+	authURL, err := h.authUsecase.StartSSO(c.Request.Context())
+	if err != nil {
+		if err == errors.ErrSSODisabled {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// SSOCallbackHandler - сюда провайдер возвращает пользователя с кодом авторизации
+func (h *AuthHandlers) SSOCallbackHandler(c *gin.Context) {
+	var req dto.SSOCallbackRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Error != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": errors.ErrSSOFailed.Error(), "reason": req.Error, "description": req.ErrorDescription})
+		return
+	}
+	if req.Code == "" || req.State == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and state required"})
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IP = c.ClientIP()
+
+	result, err := h.authUsecase.CompleteSSO(c.Request.Context(), &req)
+	if err != nil {
+		switch {
+		case err == errors.ErrSSODisabled:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err == errors.ErrInvalidSSOState, stdErrors.Is(err, errors.ErrSSOFailed):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case err == errors.ErrSSOEmailUnverified, err == errors.ErrAccountInactive:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case err == errors.ErrUserNotFound:
+			c.JSON(http.StatusForbidden, gin.H{"error": "no employee with this email in directory"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Провайдер пустил, но нужен второй фактор: токены выдаст /auth/login/2fa
+	if result.Challenge != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "two-factor authentication required",
+			"mfa_required": true,
+			"challenge":    result.Challenge,
+		})
+		return
+	}
+
+	setTokenCookies(c, result.Tokens)
+	c.JSON(http.StatusOK, gin.H{"message": "login successful", "tokens": result.Tokens})
+}
+
+// ProvisionAccountHandler - создание или обновление аккаунта сотрудника из directory-service
+func (h *AuthHandlers) ProvisionAccountHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req dto.ProvisionAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": bindingError(err, h.passwordPolicy)})
+		return
+	}
+
+	resp, created, err := h.authUsecase.ProvisionAccount(c.Request.Context(), userID, &req)
+	if err != nil {
+		if stdErrors.Is(err, errors.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, resp)
+}
+
+func (h *AuthHandlers) DeprovisionAccountHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	if err := h.authUsecase.DeprovisionAccount(c.Request.Context(), userID); err != nil {
+		if stdErrors.Is(err, errors.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandlers) ReactivateAccountHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	if err := h.authUsecase.ReactivateAccount(c.Request.Context(), userID); err != nil {
+		if stdErrors.Is(err, errors.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// IssuePasswordResetHandler - токен задания пароля для приглашения нового сотрудника
+func (h *AuthHandlers) IssuePasswordResetHandler(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	resp, err := h.authUsecase.IssuePasswordReset(c.Request.Context(), userID)
+	if err != nil {
+		if stdErrors.Is(err, errors.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
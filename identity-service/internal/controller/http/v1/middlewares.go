@@ -1,11 +1,59 @@
 package v1
 
 import (
+	"context"
+	"crypto/subtle"
+	"log"
 	"net/http"
+	"strings"
 
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*models.TokenClaims, error)
+}
+
+// RequireAccessToken проверяет access-токен пользователя из Authorization или cookie.
+// Маршруты /auth публичны на gateway, поэтому проверка выполняется здесь
+func RequireAccessToken(authenticator TokenAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := ""
+		if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			token = parts[1]
+		} else if cookie, err := c.Cookie(accessCookieName); err == nil {
+			token = cookie
+		}
+
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization required"})
+			return
+		}
+
+		claims, err := authenticator.Authenticate(c.Request.Context(), token)
+		if err != nil {
+			switch err {
+			case errors.ErrInvalidToken, errors.ErrTokenExpired, errors.ErrSessionRevoked:
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			case errors.ErrAccountInactive:
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			default:
+				log.Println(err.Error())
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify token"})
+			}
+			return
+		}
+
+		c.Set("user_id", claims.UserID.String())
+		c.Set("user_role", string(claims.Role))
+		c.Set("token_claims", claims)
+
+		c.Next()
+	}
+}
+
 func ExtractUserInfoMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userID := c.GetHeader("X-User-ID")
@@ -36,7 +84,7 @@ func RequireRoles(allowedRoles ...ClientRole) gin.HandlerFunc {
 		}
 
 		for _, allowedRole := range allowedRoles {
-			if userRole == allowedRole {
+			if userRole == string(allowedRole) {
 				c.Next()
 				return
 			}
@@ -55,3 +103,16 @@ func RequireAdminOnly() gin.HandlerFunc {
 func RequireStaff() gin.HandlerFunc {
 	return RequireRoles(adminRole, moderatorRole, supportRole)
 }
+
+// RequireInternalToken пропускает только вызовы других сервисов с общим секретом
+func RequireInternalToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader("X-Internal-Token")
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Internal access only"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -7,19 +7,29 @@ import (
 	"time"
 
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/adapter"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/chat"
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/directory"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/oidc"
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/usecase"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Server struct {
-	srv       *http.Server
-	dirClient directory.Client
-	db        *pgxpool.Pool
+	srv        *http.Server
+	dirClient  directory.Client
+	chatClient chat.Client
+	oidcClient *oidc.Client
+	db         *pgxpool.Pool
+
+	trustedProxies []string
+
+	// Останавливает фоновые задачи (ротацию ключей)
+	stopBackground context.CancelFunc
 }
 
-func NewServer(port int, readTimeout, writeTimeout time.Duration, dirCfg directory.DirectoryServiceConfig, db *pgxpool.Pool) *Server {
+func NewServer(port int, readTimeout, writeTimeout time.Duration, dirCfg directory.DirectoryServiceConfig, chatCfg chat.ChatServiceConfig, oidcCfg oidc.OIDCConfig, trustedProxies []string, db *pgxpool.Pool) *Server {
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%v", port),
 		ReadTimeout:  readTimeout,
@@ -28,21 +38,62 @@ func NewServer(port int, readTimeout, writeTimeout time.Duration, dirCfg directo
 	}
 
 	dirClient := directory.NewHTTPClient(dirCfg)
+	chatClient := chat.NewHTTPClient(chatCfg)
+
+	// SSO включается только при настроенном провайдере
+	var oidcClient *oidc.Client
+	if oidcCfg.Enabled {
+		oidcClient = oidc.NewClient(oidcCfg)
+	}
 
 	return &Server{
-		srv:       srv,
-		dirClient: dirClient,
-		db:        db,
+		srv:        srv,
+		dirClient:  dirClient,
+		chatClient: chatClient,
+		oidcClient: oidcClient,
+		db:         db,
+
+		trustedProxies: trustedProxies,
 	}
 }
 
-func (s *Server) RegisterHandlers(authCfg usecase.AuthConfig) error {
+func (s *Server) RegisterHandlers(authCfg usecase.AuthConfig, keysCfg usecase.KeysConfig, secrets *utils.SecretCipher) error {
+	keyManager := usecase.NewKeyManager(adapter.NewKeyRepo(s.db, secrets), keysCfg)
+	if err := keyManager.Init(context.Background()); err != nil {
+		return fmt.Errorf("failed to init signing keys: %w", err)
+	}
+
+	backgroundCtx, cancel := context.WithCancel(context.Background())
+	s.stopBackground = cancel
+	go keyManager.Run(backgroundCtx)
+
 	authrepo := adapter.NewAuthRepo(s.db)
-	authUsecase := usecase.NewAuthUsecase(authrepo, s.dirClient, authCfg)
+	sessionRepo := adapter.NewSessionRepo(s.db)
+	loginRepo := adapter.NewLoginRepo(s.db)
+	passwordRepo := adapter.NewPasswordRepo(s.db)
+	mfaRepo := adapter.NewMFARepo(s.db, secrets)
+	ssoRepo := adapter.NewSSORepo(s.db)
+
+	var ssoProvider usecase.SSOProvider
+	if s.oidcClient != nil {
+		ssoProvider = s.oidcClient
+	}
+
+	authUsecase := usecase.NewAuthUsecase(authrepo, sessionRepo, loginRepo, passwordRepo, mfaRepo, ssoRepo, ssoProvider, s.dirClient, s.chatClient, keyManager, authCfg)
+
+	if err := RegisterPasswordPolicy(authCfg.PasswordPolicy); err != nil {
+		return fmt.Errorf("failed to register password policy: %w", err)
+	}
 
-	authHandlers := NewAuthHandlers(authUsecase)
+	authHandlers := NewAuthHandlers(authUsecase, authCfg.PasswordPolicy)
 
 	router := gin.Default()
+	// Адрес клиента для ограничения попыток входа выставляет api-gateway,
+	// X-Forwarded-For клиент может подделать
+	router.RemoteIPHeaders = []string{"X-Real-IP"}
+	if err := router.SetTrustedProxies(s.trustedProxies); err != nil {
+		return fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "http://localhost:3000")
 		c.Header("Access-Control-Allow-Credentials", "true")
@@ -56,20 +107,55 @@ func (s *Server) RegisterHandlers(authCfg usecase.AuthConfig) error {
 		c.Next()
 	})
 
+	router.GET("/.well-known/jwks.json", JWKSHandler(keyManager))
+
 	adminAvail := router.Group("/admin")
 	adminAvail.Use(ExtractUserInfoMiddleware())
 	adminAvail.Use(RequireAdminOnly())
 	{
-		adminAvail.POST("/accounts", authHandlers.CreateAccountHandler)           // Create
-		adminAvail.GET("/accounts", authHandlers.ListAccountsHandler)             // List
-		adminAvail.PUT("/accounts/:id", authHandlers.UpdateAccountHandler)        // Update
-		adminAvail.DELETE("/accounts/:id", authHandlers.DeactivateAccountHandler) // Deactivate (soft delete)
+		adminAvail.POST("/accounts", authHandlers.CreateAccountHandler)                         // Create
+		adminAvail.GET("/accounts", authHandlers.ListAccountsHandler)                           // List
+		adminAvail.PUT("/accounts/:id", authHandlers.UpdateAccountHandler)                      // Update
+		adminAvail.DELETE("/accounts/:id", authHandlers.DeactivateAccountHandler)               // Deactivate (soft delete)
+		adminAvail.POST("/accounts/:id/unlock", authHandlers.UnlockAccountHandler)              // Unlock login
+		adminAvail.POST("/accounts/:id/password-reset", authHandlers.ForcePasswordResetHandler) // Force password reset
 	}
+	internal := router.Group("/internal")
+	internal.Use(RequireInternalToken(authCfg.InternalToken))
+	{
+		internal.PUT("/accounts/:user_id", authHandlers.ProvisionAccountHandler)
+		internal.DELETE("/accounts/:user_id", authHandlers.DeprovisionAccountHandler)
+		internal.POST("/accounts/:user_id/reactivate", authHandlers.ReactivateAccountHandler)
+		internal.POST("/accounts/:user_id/password-reset", authHandlers.IssuePasswordResetHandler)
+	}
+
 	userAvail := router.Group("/auth")
 	{
 		userAvail.POST("/login", authHandlers.LoginHandler)
+		userAvail.POST("/login/2fa", authHandlers.LoginMFAHandler)
+		userAvail.POST("/login/2fa/enroll", authHandlers.LoginMFAEnrollHandler)
+		userAvail.GET("/oidc/login", authHandlers.SSOLoginHandler)
+		userAvail.GET("/oidc/callback", authHandlers.SSOCallbackHandler)
 		userAvail.POST("/introspect", authHandlers.IntrospectToken)
-		// userAvail.POST("/logout")
+		userAvail.POST("/refresh", authHandlers.RefreshHandler)
+		userAvail.POST("/logout", authHandlers.LogoutHandler)
+		userAvail.POST("/password/reset", authHandlers.ResetPasswordHandler)
+		userAvail.POST("/password/change", RequireAccessToken(authUsecase), authHandlers.ChangePasswordHandler)
+
+		sessions := userAvail.Group("/sessions")
+		sessions.Use(RequireAccessToken(authUsecase))
+		{
+			sessions.GET("", authHandlers.ListSessionsHandler)
+			sessions.DELETE("/:id", authHandlers.RevokeSessionHandler)
+		}
+
+		mfa := userAvail.Group("/2fa")
+		mfa.Use(RequireAccessToken(authUsecase))
+		{
+			mfa.POST("/enroll", authHandlers.EnrollTOTPHandler)
+			mfa.POST("/confirm", authHandlers.ConfirmTOTPHandler)
+			mfa.DELETE("", authHandlers.DisableTOTPHandler)
+		}
 	}
 
 	s.srv.Handler = router
@@ -82,5 +168,9 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Stop(ctx context.Context) error {
+	if s.stopBackground != nil {
+		s.stopBackground()
+	}
+
 	return s.srv.Shutdown(ctx)
 }
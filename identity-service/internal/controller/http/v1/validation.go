@@ -0,0 +1,38 @@
+package v1
+
+import (
+	stdErrors "errors"
+	"fmt"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/usecase"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const passwordTag = "password"
+
+// RegisterPasswordPolicy подключает политику паролей как правило binding:"password"
+func RegisterPasswordPolicy(policy usecase.PasswordPolicyConfig) error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return fmt.Errorf("unexpected validator engine %T", binding.Validator.Engine())
+	}
+
+	return v.RegisterValidation(passwordTag, func(fl validator.FieldLevel) bool {
+		return policy.Check(fl.Field().String())
+	})
+}
+
+// bindingError заменяет непонятное сообщение валидатора о пароле требованиями политики
+func bindingError(err error, policy usecase.PasswordPolicyConfig) string {
+	var validationErrs validator.ValidationErrors
+	if stdErrors.As(err, &validationErrs) {
+		for _, fieldErr := range validationErrs {
+			if fieldErr.Tag() == passwordTag {
+				return policy.Describe()
+			}
+		}
+	}
+
+	return err.Error()
+}
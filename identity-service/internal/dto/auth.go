@@ -12,10 +12,10 @@ const (
 )
 
 type CreateAccountRequest struct {
-	UserID   string      `json:"user_id" validate:"required,uuid"`
-	Email    string      `json:"email" validate:"required,email"`
-	Password string      `json:"password" validate:"required,min=8"`
-	Role     AccountRole `json:"role" validate:"required,oneof=user support admin moderator"`
+	UserID   string      `json:"user_id" binding:"required,uuid"`
+	Email    string      `json:"email" binding:"required,email"`
+	Password string      `json:"password" binding:"required,password"`
+	Role     AccountRole `json:"role" binding:"required,oneof=user support admin moderator"`
 }
 
 type AccountResponse struct {
@@ -31,14 +31,126 @@ type AccountResponse struct {
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+
+	// Заполняются хендлером из запроса, описывают устройство сессии
+	UserAgent string `json:"-"`
+	IP        string `json:"-"`
+}
+
+// LoginResult - итог шага входа: либо пара токенов, либо запрос второго фактора
+type LoginResult struct {
+	Tokens    *TokenPair
+	Challenge *MFAChallenge
+
+	// Выдаются один раз, если второй фактор подключён прямо во время входа
+	RecoveryCodes []string
+}
+
+type MFAChallenge struct {
+	ChallengeToken     string `json:"challenge_token"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ExpiresIn          int64  `json:"expires_in"`
+}
+
+// MFALoginRequest - второй шаг входа: код из аутентификатора или код восстановления
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// SSOCallbackRequest - параметры возврата от OIDC-провайдера
+type SSOCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+
+	UserAgent string `form:"-"`
+	IP        string `form:"-"`
+}
+
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int64     `json:"expires_in"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	SessionID        string    `json:"session_id"`
+}
+
+// ChangePasswordRequest - смена пароля владельцем аккаунта
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,password"`
+}
+
+// ResetPasswordRequest - завершение сброса пароля по одноразовому токену
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,password"`
+}
+
+type PasswordResetResponse struct {
+	ResetToken string    `json:"reset_token"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type SessionResponse struct {
+	SessionID  string    `json:"session_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
 }
 
 type IntrospectRequest struct {
-	Token string `json:"token"`
+	Token string `json:"token" form:"token"`
 }
 
+// Причины неактивности токена (расширение RFC 7662)
+const (
+	ReasonInvalid         = "invalid"
+	ReasonExpired         = "expired"
+	ReasonSessionRevoked  = "session_revoked"
+	ReasonAccountInactive = "account_inactive"
+)
+
+// IntrospectResponse - ответ в формате RFC 7662. Для неактивного токена заполняются
+// только active и reason
 type IntrospectResponse struct {
-	Active bool `json:"active"`
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Role      string `json:"user_role,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sid       string `json:"sid,omitempty"`
+	Reason    string `json:"reason,omitempty"`
 }
 
 type UpdateAccountRequest struct {
@@ -46,3 +158,32 @@ type UpdateAccountRequest struct {
 	Role     AccountRole `json:"role,omitempty" validate:"omitempty,oneof=user support admin moderator"`
 	IsActive *bool       `json:"is_active,omitempty"`
 }
+
+// ProvisionAccountRequest - создание или обновление аккаунта сотрудника по запросу
+// directory-service (SCIM). Без пароля аккаунт входит только через SSO или сброс пароля
+type ProvisionAccountRequest struct {
+	Email    string      `json:"email" binding:"required,email"`
+	Role     AccountRole `json:"role,omitempty" binding:"omitempty,oneof=user support admin moderator"`
+	IsActive *bool       `json:"is_active,omitempty"`
+	Password *string     `json:"password,omitempty" binding:"omitempty,password"`
+}
+
+// JWK - публичная часть ключа подписи в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// OKP (Ed25519)
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
@@ -1,11 +1,51 @@
 package errors
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrUserNotFound    = errors.New("user not found")
 	ErrInvalidPassword = errors.New("invalid password")
+	ErrEmailTaken      = errors.New("account with this email already exists")
 
 	ErrTokenExpired = errors.New("token is expired")
 	ErrInvalidToken = errors.New("invalid token")
+
+	ErrAccountInactive    = errors.New("account is deactivated")
+	ErrSessionRevoked     = errors.New("session is revoked")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+	// Единая ошибка входа: не раскрывает, существует ли аккаунт
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrTooManyAttempts    = errors.New("too many login attempts")
+
+	ErrPasswordUnchanged = errors.New("new password must differ from the current one")
+	ErrInvalidResetToken = errors.New("reset token is invalid or expired")
+
+	ErrInvalidChallenge  = errors.New("login challenge is invalid or expired")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnrolled    = errors.New("two-factor enrollment is not started")
+	ErrMFARequired       = errors.New("two-factor authentication is mandatory for this role")
+
+	ErrSSODisabled        = errors.New("sso login is not configured")
+	ErrInvalidSSOState    = errors.New("sso state is invalid or expired")
+	ErrSSOFailed          = errors.New("sso authentication failed")
+	ErrSSOEmailUnverified = errors.New("identity provider did not confirm the email")
 )
+
+// ThrottledError - вход временно запрещён после неудачных попыток
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginThrottle - счётчик неудачных входов по ключу (email или IP)
+type LoginThrottle struct {
+	Key           string     `json:"key" db:"key"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at" db:"last_failure_at"`
+	BlockedUntil  *time.Time `json:"blocked_until,omitempty" db:"blocked_until"`
+}
+
+type LoginResult string
+
+const (
+	LoginSucceeded       LoginResult = "success"
+	LoginFailed          LoginResult = "invalid_credentials"
+	LoginThrottled       LoginResult = "throttled"
+	LoginAccountInactive LoginResult = "account_inactive"
+	LoginMFARequired     LoginResult = "mfa_required"
+	LoginMFAFailed       LoginResult = "mfa_failed"
+)
+
+// LoginAttempt - запись журнала входов
+type LoginAttempt struct {
+	AccountID   *uuid.UUID  `json:"account_id,omitempty" db:"account_id"`
+	Email       string      `json:"email" db:"email"`
+	IP          string      `json:"ip" db:"ip"`
+	UserAgent   string      `json:"user_agent" db:"user_agent"`
+	Result      LoginResult `json:"result" db:"result"`
+	AttemptedAt time.Time   `json:"attempted_at" db:"attempted_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TOTPCredential - секрет второго фактора аккаунта. Пока ConfirmedAt пуст, идёт подключение
+type TOTPCredential struct {
+	AccountID    uuid.UUID  `db:"account_id"`
+	Secret       string     `db:"secret"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
+
+func (c *TOTPCredential) IsEnabled() bool {
+	return c != nil && c.ConfirmedAt != nil
+}
+
+// LoginChallenge - промежуточный шаг входа: пароль проверен, ждём второй фактор.
+// В БД хранится только хеш токена
+type LoginChallenge struct {
+	ChallengeHash string     `db:"challenge_hash"`
+	AccountID     uuid.UUID  `db:"account_id"`
+	UserAgent     string     `db:"user_agent"`
+	IP            string     `db:"ip"`
+	Attempts      int        `db:"attempts"`
+	ExpiresAt     time.Time  `db:"expires_at"`
+	UsedAt        *time.Time `db:"used_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+func (c *LoginChallenge) IsActive(now time.Time, maxAttempts int) bool {
+	return c.UsedAt == nil && now.Before(c.ExpiresAt) && c.Attempts < maxAttempts
+}
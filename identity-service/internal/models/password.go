@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordResetToken - одноразовый токен сброса пароля. В БД хранится только хеш
+type PasswordResetToken struct {
+	TokenHash string     `db:"token_hash"`
+	AccountID uuid.UUID  `db:"account_id"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session - вход пользователя с конкретного устройства. Access-токены несут её id (claim sid)
+type Session struct {
+	SessionID  uuid.UUID  `json:"session_id" db:"session_id"`
+	AccountID  uuid.UUID  `json:"account_id" db:"account_id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IP         string     `json:"ip" db:"ip"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+func (s *Session) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken - одноразовый refresh-токен сессии. Хранится только хеш
+type RefreshToken struct {
+	TokenHash string     `json:"-" db:"token_hash"`
+	SessionID uuid.UUID  `json:"session_id" db:"session_id"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// TokenClaims - проверенные claims access-токена
+type TokenClaims struct {
+	UserID    uuid.UUID
+	Role      AccountRole
+	SessionID uuid.UUID
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// SigningKey - ключ подписи access-токенов. Ключ подписывает с ActivatesAt до активации
+// следующего и публикуется в JWKS до ExpiresAt, пока живут подписанные им токены
+type SigningKey struct {
+	KID         string     `json:"kid" db:"kid"`
+	Algorithm   string     `json:"alg" db:"algorithm"`
+	PrivateKey  []byte     `json:"-" db:"private_key"` // PKCS#8 DER
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	ActivatesAt time.Time  `json:"activates_at" db:"activates_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+}
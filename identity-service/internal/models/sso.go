@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SSOLoginState - незавершённый вход через OIDC-провайдера. Хранится до возврата
+// пользователя на callback; state в БД только в виде хеша
+type SSOLoginState struct {
+	StateHash    string    `db:"state_hash"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	ExpiresAt    time.Time `db:"expires_at"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// ExternalIdentity - привязка учётной записи провайдера (issuer + subject) к аккаунту
+type ExternalIdentity struct {
+	Issuer      string    `db:"issuer"`
+	Subject     string    `db:"subject"`
+	AccountID   uuid.UUID `db:"account_id"`
+	Email       string    `db:"email"`
+	CreatedAt   time.Time `db:"created_at"`
+	LastLoginAt time.Time `db:"last_login_at"`
+}
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/google/uuid"
+)
+
+type cachedAccount struct {
+	account   *models.Account
+	expiresAt time.Time
+}
+
+// accountCache - короткий кэш аккаунтов для проверки токенов. Изменения аккаунта через
+// этот инстанс сбрасывают запись сразу, изменения через другие - не позже чем через TTL
+type accountCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]cachedAccount
+}
+
+func newAccountCache(ttl time.Duration) *accountCache {
+	return &accountCache{
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]cachedAccount),
+	}
+}
+
+func (c *accountCache) get(userID uuid.UUID, now time.Time) (*models.Account, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok || !now.Before(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.account, true
+}
+
+func (c *accountCache) set(userID uuid.UUID, account *models.Account, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[userID] = cachedAccount{account: account, expiresAt: now.Add(c.ttl)}
+}
+
+func (c *accountCache) invalidate(userID uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, userID)
+}
@@ -1,8 +1,184 @@
 package usecase
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+)
 
 type AuthConfig struct {
-	JwtSecret    string        `env:"JWT_SECRET,required"`
 	JwtExpiresIn time.Duration `env:"JWT_EXPIRES_IN" env-default:"15m"`
+
+	RefreshExpiresIn time.Duration `env:"REFRESH_TOKEN_EXPIRES_IN" env-default:"720h"`
+
+	// Сколько проверка токена может полагаться на закэшированное состояние аккаунта
+	AccountCacheTTL time.Duration `env:"ACCOUNT_CACHE_TTL" env-default:"5s"`
+
+	// Общий секрет для вызовов /internal от других сервисов
+	InternalToken string `env:"INTERNAL_API_TOKEN"`
+
+	// Срок действия одноразового токена сброса пароля
+	PasswordResetTTL time.Duration `env:"PASSWORD_RESET_TOKEN_TTL" env-default:"24h"`
+
+	LoginProtection LoginProtectionConfig
+
+	PasswordPolicy PasswordPolicyConfig
+
+	// Параметры argon2id для новых хешей; устаревшие хеши пересчитываются при входе
+	PasswordHash utils.PasswordHashParams
+
+	MFA MFAConfig
+
+	SSO SSOConfig
+}
+
+// SSOConfig - сопоставление входа через OIDC с аккаунтами. RoleMapping задаётся как
+// "группа:роль,группа:роль"; роль пользователя без подходящих групп - DefaultRole.
+// SyncRole переносит роль из групп в существующий аккаунт при каждом входе; без
+// RoleMapping роль не синхронизируется, иначе все администраторы стали бы DefaultRole
+type SSOConfig struct {
+	RoleMapping          map[string]string `env:"OIDC_ROLE_MAPPING" env-separator:","`
+	DefaultRole          string            `env:"OIDC_DEFAULT_ROLE" env-default:"user"`
+	SyncRole             bool              `env:"OIDC_SYNC_ROLE" env-default:"false"`
+	RequireVerifiedEmail bool              `env:"OIDC_REQUIRE_VERIFIED_EMAIL" env-default:"true"`
+	StateTTL             time.Duration     `env:"OIDC_STATE_TTL" env-default:"10m"`
+
+	// Второй фактор у SSO-входа запрашивается так же, как у входа по паролю. С TrustProviderMFA
+	// локальный TOTP пропускается, если amr в id_token содержит один из MFAMethods
+	TrustProviderMFA bool     `env:"OIDC_TRUST_PROVIDER_MFA" env-default:"false"`
+	MFAMethods       []string `env:"OIDC_MFA_AMR" env-default:"mfa,otp,hwk" env-separator:","`
+}
+
+// SyncsRole - роль существующего аккаунта берётся из групп провайдера
+func (c SSOConfig) SyncsRole() bool {
+	return c.SyncRole && len(c.RoleMapping) > 0
+}
+
+// ProviderVerifiedMFA - провайдеру доверено проверять второй фактор и он подтвердил это в amr
+func (c SSOConfig) ProviderVerifiedMFA(amr []string) bool {
+	if !c.TrustProviderMFA {
+		return false
+	}
+
+	for _, method := range amr {
+		for _, accepted := range c.MFAMethods {
+			if strings.TrimSpace(accepted) == method {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MFAConfig - двухфакторная аутентификация (TOTP). Для ролей из RequiredRoles второй
+// фактор обязателен: без него вход не завершится, пока TOTP не будет подключён
+type MFAConfig struct {
+	RequiredRoles []string      `env:"MFA_REQUIRED_ROLES" env-default:"admin" env-separator:","`
+	Issuer        string        `env:"MFA_ISSUER" env-default:"Corporate Messenger"`
+	ChallengeTTL  time.Duration `env:"MFA_CHALLENGE_TTL" env-default:"5m"`
+	MaxAttempts   int           `env:"MFA_MAX_ATTEMPTS" env-default:"5"`
+	// Сколько соседних 30-секундных интервалов принимать из-за расхождения часов
+	Skew          int `env:"MFA_TOTP_SKEW" env-default:"1"`
+	RecoveryCodes int `env:"MFA_RECOVERY_CODES" env-default:"10"`
+}
+
+func (c MFAConfig) IsRequired(role models.AccountRole) bool {
+	for _, required := range c.RequiredRoles {
+		if strings.TrimSpace(required) == string(role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoginProtectionConfig - защита от перебора паролей. После каждой неудачи следующая
+// попытка возможна через BaseDelay*2^(n-1) (не больше MaxDelay), после MaxFailures
+// неудач ключ блокируется на LockoutDuration. Счётчик сбрасывается успешным входом
+// или через FailureWindow без неудач
+type LoginProtectionConfig struct {
+	MaxFailures     int           `env:"LOGIN_MAX_FAILURES" env-default:"5"`
+	IPMaxFailures   int           `env:"LOGIN_IP_MAX_FAILURES" env-default:"50"`
+	BaseDelay       time.Duration `env:"LOGIN_BASE_DELAY" env-default:"1s"`
+	MaxDelay        time.Duration `env:"LOGIN_MAX_DELAY" env-default:"30s"`
+	LockoutDuration time.Duration `env:"LOGIN_LOCKOUT_DURATION" env-default:"15m"`
+	FailureWindow   time.Duration `env:"LOGIN_FAILURE_WINDOW" env-default:"1h"`
+}
+
+// PasswordPolicyConfig - требования к новым паролям (создание аккаунта, смена, сброс)
+type PasswordPolicyConfig struct {
+	MinLength     int  `env:"PASSWORD_MIN_LENGTH" env-default:"10"`
+	MaxLength     int  `env:"PASSWORD_MAX_LENGTH" env-default:"128"`
+	RequireUpper  bool `env:"PASSWORD_REQUIRE_UPPER" env-default:"true"`
+	RequireLower  bool `env:"PASSWORD_REQUIRE_LOWER" env-default:"true"`
+	RequireDigit  bool `env:"PASSWORD_REQUIRE_DIGIT" env-default:"true"`
+	RequireSymbol bool `env:"PASSWORD_REQUIRE_SYMBOL" env-default:"false"`
+}
+
+// Check проверяет пароль на соответствие политике
+func (p PasswordPolicyConfig) Check(password string) bool {
+	length := len([]rune(password))
+	if length < p.MinLength || (p.MaxLength > 0 && length > p.MaxLength) {
+		return false
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	return (upper || !p.RequireUpper) &&
+		(lower || !p.RequireLower) &&
+		(digit || !p.RequireDigit) &&
+		(symbol || !p.RequireSymbol)
+}
+
+// Describe - требования политики человеческим языком, для ответа клиенту
+func (p PasswordPolicyConfig) Describe() string {
+	var required []string
+	if p.RequireUpper {
+		required = append(required, "an upper-case letter")
+	}
+	if p.RequireLower {
+		required = append(required, "a lower-case letter")
+	}
+	if p.RequireDigit {
+		required = append(required, "a digit")
+	}
+	if p.RequireSymbol {
+		required = append(required, "a symbol")
+	}
+
+	desc := fmt.Sprintf("password must be at least %d characters long", p.MinLength)
+	if p.MaxLength > 0 {
+		desc = fmt.Sprintf("password must be %d-%d characters long", p.MinLength, p.MaxLength)
+	}
+	if len(required) > 0 {
+		desc += " and contain " + strings.Join(required, ", ")
+	}
+
+	return desc
+}
+
+// KeysConfig - ключи подписи access-токенов. Overlap должен превышать срок жизни
+// access-токена и TTL кеша JWKS у проверяющих сервисов
+type KeysConfig struct {
+	Algorithm        string        `env:"JWT_SIGNING_ALG" env-default:"EdDSA"`
+	RotationInterval time.Duration `env:"JWT_KEY_ROTATION_INTERVAL" env-default:"720h"`
+	Overlap          time.Duration `env:"JWT_KEY_OVERLAP" env-default:"1h"`
+	CheckInterval    time.Duration `env:"JWT_KEY_CHECK_INTERVAL" env-default:"1m"`
 }
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type KeyRepo interface {
+	ListKeys(ctx context.Context, now time.Time) ([]*models.SigningKey, error)
+	AddKey(ctx context.Context, next *models.SigningKey, previousKID string, previousExpiresAt time.Time) (bool, error)
+}
+
+type signingKey struct {
+	kid         string
+	alg         string
+	method      jwt.SigningMethod
+	signer      crypto.Signer
+	activatesAt time.Time
+	expiresAt   *time.Time
+}
+
+// KeyManager хранит ключи подписи и ротирует их по расписанию.
+//
+// Ротация: за KeysConfig.Overlap до окончания срока текущего ключа создаётся следующий
+// с отложенной активацией - он сразу попадает в JWKS, и проверяющие сервисы успевают его
+// закешировать. Предыдущий ключ остаётся в JWKS ещё Overlap после активации следующего,
+// пока не истекут подписанные им токены
+type KeyManager struct {
+	repo KeyRepo
+	cfg  KeysConfig
+
+	mu   sync.RWMutex
+	keys []*signingKey
+
+	now func() time.Time
+}
+
+func NewKeyManager(repo KeyRepo, cfg KeysConfig) *KeyManager {
+	return &KeyManager{
+		repo: repo,
+		cfg:  cfg,
+		now:  time.Now,
+	}
+}
+
+// Init загружает ключи и создаёт первый, если их ещё нет
+func (m *KeyManager) Init(ctx context.Context) error {
+	if m.cfg.Overlap >= m.cfg.RotationInterval {
+		return fmt.Errorf("key overlap %s must be shorter than rotation interval %s", m.cfg.Overlap, m.cfg.RotationInterval)
+	}
+
+	return m.rotateIfDue(ctx)
+}
+
+// Run периодически перечитывает ключи (их мог ротировать другой инстанс) и ротирует свои
+func (m *KeyManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.rotateIfDue(ctx); err != nil {
+				log.Printf("failed to rotate signing keys: %v", err)
+			}
+		}
+	}
+}
+
+func (m *KeyManager) rotateIfDue(ctx context.Context) error {
+	if err := m.reload(ctx); err != nil {
+		return err
+	}
+
+	now := m.now()
+
+	m.mu.RLock()
+	var latest *signingKey
+	if len(m.keys) > 0 {
+		latest = m.keys[len(m.keys)-1]
+	}
+	m.mu.RUnlock()
+
+	var previousKID string
+	var activatesAt time.Time
+	switch {
+	case latest == nil:
+		activatesAt = now
+	case now.Before(latest.activatesAt.Add(m.cfg.RotationInterval - m.cfg.Overlap)):
+		return nil
+	default:
+		previousKID = latest.kid
+		activatesAt = latest.activatesAt.Add(m.cfg.RotationInterval)
+		if activatesAt.Before(now) {
+			activatesAt = now
+		}
+	}
+
+	der, err := utils.GenerateSigningKey(m.cfg.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	next := &models.SigningKey{
+		KID:         uuid.NewString(),
+		Algorithm:   m.cfg.Algorithm,
+		PrivateKey:  der,
+		CreatedAt:   now,
+		ActivatesAt: activatesAt,
+	}
+
+	added, err := m.repo.AddKey(ctx, next, previousKID, activatesAt.Add(m.cfg.Overlap))
+	if err != nil {
+		return fmt.Errorf("failed to save signing key: %w", err)
+	}
+	if added {
+		log.Printf("new signing key %s (%s) activates at %s", next.KID, next.Algorithm, activatesAt.Format(time.RFC3339))
+	}
+
+	return m.reload(ctx)
+}
+
+func (m *KeyManager) reload(ctx context.Context) error {
+	stored, err := m.repo.ListKeys(ctx, m.now())
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	keys := make([]*signingKey, 0, len(stored))
+	for _, key := range stored {
+		signer, err := utils.ParseSigningKey(key.Algorithm, key.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key %s: %w", key.KID, err)
+		}
+		method, err := utils.SigningMethod(key.Algorithm)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key %s: %w", key.KID, err)
+		}
+
+		keys = append(keys, &signingKey{
+			kid:         key.KID,
+			alg:         key.Algorithm,
+			method:      method,
+			signer:      signer,
+			activatesAt: key.ActivatesAt,
+			expiresAt:   key.ExpiresAt,
+		})
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+
+	return nil
+}
+
+// current - последний активированный ключ
+func (m *KeyManager) current() (*signingKey, error) {
+	now := m.now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		if !m.keys[i].activatesAt.After(now) {
+			return m.keys[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no active signing key")
+}
+
+// Keyfunc выбирает ключ проверки по kid из заголовка токена
+func (m *KeyManager) Keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	for _, key := range m.keys {
+		if key.kid != kid {
+			continue
+		}
+		if key.expiresAt != nil && !now.Before(*key.expiresAt) {
+			break
+		}
+		if t.Method.Alg() != key.alg {
+			return nil, fmt.Errorf("unexpected signing method %v for key %s", t.Header["alg"], kid)
+		}
+		return key.signer.Public(), nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// JWKS - публичные ключи: текущий, следующий (до активации) и предыдущие в окне перекрытия
+func (m *KeyManager) JWKS() (*dto.JWKS, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	jwks := &dto.JWKS{Keys: make([]dto.JWK, 0, len(m.keys))}
+	for _, key := range m.keys {
+		jwk, err := utils.PublicJWK(key.kid, key.alg, key.signer)
+		if err != nil {
+			return nil, err
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks, nil
+}
+
+func (m *KeyManager) SignAccessToken(userID uuid.UUID, role models.AccountRole, sessionID uuid.UUID, issuedAt time.Time, expiresIn time.Duration) (string, error) {
+	key, err := m.current()
+	if err != nil {
+		return "", err
+	}
+
+	return utils.SignToken(userID, role, sessionID, key.method, key.kid, key.signer, issuedAt, expiresIn)
+}
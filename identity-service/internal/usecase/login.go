@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type LoginRepo interface {
+	GetThrottle(ctx context.Context, key string) (*models.LoginThrottle, error)
+	RegisterFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error)
+	SetBlockedUntil(ctx context.Context, key string, blockedUntil time.Time) error
+	ResetThrottle(ctx context.Context, key string) error
+
+	RecordAttempt(ctx context.Context, attempt *models.LoginAttempt) error
+}
+
+// Счётчики ведутся по email, а не по аккаунту: несуществующий email ограничивается
+// так же, как существующий, и по ответам нельзя понять, есть ли такой аккаунт
+func emailThrottleKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkThrottle возвращает ThrottledError, если вход по одному из ключей временно запрещён
+func (u *AuthUsecase) checkThrottle(ctx context.Context, keys ...string) error {
+	now := u.now()
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		throttle, err := u.loginRepo.GetThrottle(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get login throttle: %w", err)
+		}
+		if throttle == nil || throttle.BlockedUntil == nil || !now.Before(*throttle.BlockedUntil) {
+			continue
+		}
+		if wait := throttle.BlockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &errModels.ThrottledError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func (u *AuthUsecase) registerFailure(ctx context.Context, key string, maxFailures int) error {
+	cfg := u.authCfg.LoginProtection
+	now := u.now()
+
+	failures, err := u.loginRepo.RegisterFailure(ctx, key, now, now.Add(-cfg.FailureWindow))
+	if err != nil {
+		return err
+	}
+
+	var blockFor time.Duration
+	if failures >= maxFailures {
+		blockFor = cfg.LockoutDuration
+	} else {
+		blockFor = cfg.BaseDelay << (failures - 1)
+		if blockFor <= 0 || blockFor > cfg.MaxDelay {
+			blockFor = cfg.MaxDelay
+		}
+	}
+
+	return u.loginRepo.SetBlockedUntil(ctx, key, now.Add(blockFor))
+}
+
+// recordAttempt пишет попытку в журнал. Сбой журнала не должен мешать входу
+func (u *AuthUsecase) recordAttempt(ctx context.Context, input *dto.LoginRequest, accountID *uuid.UUID, result models.LoginResult) {
+	err := u.loginRepo.RecordAttempt(ctx, &models.LoginAttempt{
+		AccountID:   accountID,
+		Email:       input.Email,
+		IP:          input.IP,
+		UserAgent:   input.UserAgent,
+		Result:      result,
+		AttemptedAt: u.now(),
+	})
+	if err != nil {
+		log.Printf("failed to record login attempt: %v", err)
+	}
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// verifyDummyPassword тратит на несуществующий email столько же времени, сколько на
+// проверку настоящего пароля, чтобы время ответа не выдавало наличие аккаунта
+func verifyDummyPassword(password string, params utils.PasswordHashParams) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword(uuid.NewString(), params)
+	})
+
+	if dummyHash != "" {
+		_, _ = utils.VerifyPassword(password, dummyHash)
+	}
+}
+
+// UnlockAccount снимает блокировку входа, наложенную после неудачных попыток
+func (u *AuthUsecase) UnlockAccount(ctx context.Context, accountID uuid.UUID) error {
+	account, err := u.authrepo.FindByID(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return errModels.ErrUserNotFound
+	}
+
+	if err := u.loginRepo.ResetThrottle(ctx, emailThrottleKey(account.Email)); err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type MFARepo interface {
+	GetTOTP(ctx context.Context, accountID uuid.UUID) (*models.TOTPCredential, error)
+	SavePendingTOTP(ctx context.Context, cred *models.TOTPCredential) (bool, error)
+	ConfirmTOTP(ctx context.Context, accountID uuid.UUID, step int64, now time.Time, recoveryHashes []string) (bool, error)
+	UseTOTPStep(ctx context.Context, accountID uuid.UUID, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, accountID uuid.UUID) error
+	UseRecoveryCode(ctx context.Context, accountID uuid.UUID, codeHash string, now time.Time) (bool, error)
+
+	CreateChallenge(ctx context.Context, challenge *models.LoginChallenge) error
+	FindChallenge(ctx context.Context, challengeHash string) (*models.LoginChallenge, error)
+	RegisterChallengeFailure(ctx context.Context, challengeHash string) error
+	ConsumeChallenge(ctx context.Context, challengeHash string, now time.Time) (bool, error)
+}
+
+// secondFactorChallenge начинает шаг второго фактора, если у аккаунта подключён TOTP или
+// роль его требует. nil - второй фактор не нужен, можно выдавать токены
+func (u *AuthUsecase) secondFactorChallenge(ctx context.Context, account *models.Account, input *dto.LoginRequest) (*dto.LoginResult, error) {
+	cred, err := u.mfaRepo.GetTOTP(ctx, account.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp credential: %w", err)
+	}
+	if !cred.IsEnabled() && !u.authCfg.MFA.IsRequired(account.Role) {
+		return nil, nil
+	}
+
+	return u.startChallenge(ctx, account, input, !cred.IsEnabled())
+}
+
+// startChallenge - пароль проверен, но нужен второй фактор. Клиент получает токен шага
+// входа; если TOTP ещё не подключён, а роль его требует, сначала придётся подключить
+func (u *AuthUsecase) startChallenge(ctx context.Context, account *models.Account, input *dto.LoginRequest, enrollmentRequired bool) (*dto.LoginResult, error) {
+	now := u.now()
+
+	challengeToken, challengeHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	challenge := &models.LoginChallenge{
+		ChallengeHash: challengeHash,
+		AccountID:     account.AccountID,
+		UserAgent:     input.UserAgent,
+		IP:            input.IP,
+		ExpiresAt:     now.Add(u.authCfg.MFA.ChallengeTTL),
+		CreatedAt:     now,
+	}
+	if err := u.mfaRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, fmt.Errorf("failed to save login challenge: %w", err)
+	}
+
+	u.recordAttempt(ctx, input, &account.AccountID, models.LoginMFARequired)
+
+	return &dto.LoginResult{
+		Challenge: &dto.MFAChallenge{
+			ChallengeToken:     challengeToken,
+			EnrollmentRequired: enrollmentRequired,
+			ExpiresIn:          int64(u.authCfg.MFA.ChallengeTTL.Seconds()),
+		},
+	}, nil
+}
+
+// CompleteLogin - второй шаг входа. При обязательном подключении код подтверждает
+// новый секрет, и вместе с токенами выдаются коды восстановления
+func (u *AuthUsecase) CompleteLogin(ctx context.Context, input *dto.MFALoginRequest) (*dto.LoginResult, error) {
+	challengeHash := utils.HashRefreshToken(input.ChallengeToken)
+
+	challenge, account, err := u.loadChallenge(ctx, challengeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := u.mfaRepo.GetTOTP(ctx, account.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp credential: %w", err)
+	}
+
+	attempt := &dto.LoginRequest{Email: account.Email, UserAgent: challenge.UserAgent, IP: challenge.IP}
+
+	// Неверные коды копятся в том же счётчике по email, что и неверные пароли
+	emailKey := emailThrottleKey(account.Email)
+	if err := u.checkThrottle(ctx, emailKey); err != nil {
+		u.recordAttempt(ctx, attempt, &account.AccountID, models.LoginThrottled)
+		return nil, err
+	}
+
+	var recoveryCodes []string
+	if cred.IsEnabled() {
+		ok, err := u.verifySecondFactor(ctx, cred, input.Code, input.RecoveryCode)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, u.failChallenge(ctx, challengeHash, attempt, account)
+		}
+	} else {
+		if cred == nil {
+			return nil, errModels.ErrMFANotEnrolled
+		}
+
+		recoveryCodes, err = u.confirmEnrollment(ctx, cred, input.Code)
+		if err == errModels.ErrInvalidMFACode {
+			return nil, u.failChallenge(ctx, challengeHash, attempt, account)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	consumed, err := u.mfaRepo.ConsumeChallenge(ctx, challengeHash, u.now())
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errModels.ErrInvalidChallenge
+	}
+
+	if err := u.loginRepo.ResetThrottle(ctx, emailKey); err != nil {
+		return nil, fmt.Errorf("failed to reset login throttle: %w", err)
+	}
+
+	tokens, err := u.finishLogin(ctx, account, attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResult{Tokens: tokens, RecoveryCodes: recoveryCodes}, nil
+}
+
+// EnrollDuringLogin выдаёт секрет TOTP по токену шага входа - для ролей, которым
+// второй фактор обязателен, а он ещё не подключён
+func (u *AuthUsecase) EnrollDuringLogin(ctx context.Context, challengeToken string) (*dto.TOTPEnrollment, error) {
+	_, account, err := u.loadChallenge(ctx, utils.HashRefreshToken(challengeToken))
+	if err != nil {
+		return nil, err
+	}
+
+	return u.beginEnrollment(ctx, account)
+}
+
+// EnrollTOTP начинает подключение второго фактора для вошедшего пользователя
+func (u *AuthUsecase) EnrollTOTP(ctx context.Context, claims *models.TokenClaims) (*dto.TOTPEnrollment, error) {
+	account, err := u.accountByClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.beginEnrollment(ctx, account)
+}
+
+// ConfirmTOTP завершает подключение кодом из аутентификатора и выдаёт коды восстановления
+func (u *AuthUsecase) ConfirmTOTP(ctx context.Context, claims *models.TokenClaims, code string) (*dto.RecoveryCodesResponse, error) {
+	account, err := u.accountByClaims(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := u.mfaRepo.GetTOTP(ctx, account.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get totp credential: %w", err)
+	}
+	if cred.IsEnabled() {
+		return nil, errModels.ErrMFAAlreadyEnabled
+	}
+	if cred == nil {
+		return nil, errModels.ErrMFANotEnrolled
+	}
+
+	codes, err := u.confirmEnrollment(ctx, cred, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP отключает второй фактор. Для ролей с обязательным 2FA запрещено
+func (u *AuthUsecase) DisableTOTP(ctx context.Context, claims *models.TokenClaims, code string) error {
+	account, err := u.accountByClaims(ctx, claims)
+	if err != nil {
+		return err
+	}
+
+	if u.authCfg.MFA.IsRequired(account.Role) {
+		return errModels.ErrMFARequired
+	}
+
+	cred, err := u.mfaRepo.GetTOTP(ctx, account.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to get totp credential: %w", err)
+	}
+	if !cred.IsEnabled() {
+		return errModels.ErrMFANotEnrolled
+	}
+
+	ok, err := u.verifySecondFactor(ctx, cred, code, "")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errModels.ErrInvalidMFACode
+	}
+
+	return u.mfaRepo.DeleteTOTP(ctx, account.AccountID)
+}
+
+func (u *AuthUsecase) loadChallenge(ctx context.Context, challengeHash string) (*models.LoginChallenge, *models.Account, error) {
+	challenge, err := u.mfaRepo.FindChallenge(ctx, challengeHash)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge == nil || !challenge.IsActive(u.now(), u.authCfg.MFA.MaxAttempts) {
+		return nil, nil, errModels.ErrInvalidChallenge
+	}
+
+	account, err := u.authrepo.FindByID(ctx, challenge.AccountID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find account: %w", err)
+	}
+	if account == nil || !account.IsActive {
+		return nil, nil, errModels.ErrAccountInactive
+	}
+
+	return challenge, account, nil
+}
+
+func (u *AuthUsecase) failChallenge(ctx context.Context, challengeHash string, attempt *dto.LoginRequest, account *models.Account) error {
+	u.recordAttempt(ctx, attempt, &account.AccountID, models.LoginMFAFailed)
+
+	if err := u.mfaRepo.RegisterChallengeFailure(ctx, challengeHash); err != nil {
+		return err
+	}
+	// Лимит попыток шага ограничивает один вызов; новый шаг получается повторным
+	// входом, поэтому неудача учитывается и в блокировке по email
+	if err := u.registerFailure(ctx, emailThrottleKey(account.Email), u.authCfg.LoginProtection.MaxFailures); err != nil {
+		return fmt.Errorf("failed to register login failure: %w", err)
+	}
+
+	return errModels.ErrInvalidMFACode
+}
+
+func (u *AuthUsecase) beginEnrollment(ctx context.Context, account *models.Account) (*dto.TOTPEnrollment, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := u.mfaRepo.SavePendingTOTP(ctx, &models.TOTPCredential{
+		AccountID: account.AccountID,
+		Secret:    secret,
+		CreatedAt: u.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, errModels.ErrMFAAlreadyEnabled
+	}
+
+	return &dto.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(u.authCfg.MFA.Issuer, account.Email, secret),
+	}, nil
+}
+
+func (u *AuthUsecase) confirmEnrollment(ctx context.Context, cred *models.TOTPCredential, code string) ([]string, error) {
+	now := u.now()
+
+	step, ok, err := utils.MatchTOTP(cred.Secret, code, now, u.authCfg.MFA.Skew)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errModels.ErrInvalidMFACode
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes(u.authCfg.MFA.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmed, err := u.mfaRepo.ConfirmTOTP(ctx, cred.AccountID, step, now, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, errModels.ErrMFAAlreadyEnabled
+	}
+
+	return codes, nil
+}
+
+// verifySecondFactor проверяет код TOTP (однократно на интервал) или код восстановления
+func (u *AuthUsecase) verifySecondFactor(ctx context.Context, cred *models.TOTPCredential, code, recoveryCode string) (bool, error) {
+	now := u.now()
+
+	if recoveryCode != "" {
+		return u.mfaRepo.UseRecoveryCode(ctx, cred.AccountID, utils.HashRecoveryCode(recoveryCode), now)
+	}
+
+	step, ok, err := utils.MatchTOTP(cred.Secret, code, now, u.authCfg.MFA.Skew)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	return u.mfaRepo.UseTOTPStep(ctx, cred.AccountID, step)
+}
+
+func (u *AuthUsecase) accountByClaims(ctx context.Context, claims *models.TokenClaims) (*models.Account, error) {
+	account, err := u.authrepo.FindByUserID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account by user id: %w", err)
+	}
+	if account == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	return account, nil
+}
@@ -0,0 +1,284 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// fakeMFARepo повторяет условия MFARepo: интервал TOTP принимается, только если он
+// позже последнего использованного, код восстановления - один раз
+type fakeMFARepo struct {
+	MFARepo
+
+	cred          *models.TOTPCredential
+	lastStep      int64
+	recoveryCodes map[string]bool
+	challenges    []*models.LoginChallenge
+}
+
+func (r *fakeMFARepo) GetTOTP(context.Context, uuid.UUID) (*models.TOTPCredential, error) {
+	return r.cred, nil
+}
+
+func (r *fakeMFARepo) CreateChallenge(_ context.Context, challenge *models.LoginChallenge) error {
+	r.challenges = append(r.challenges, challenge)
+	return nil
+}
+
+func (r *fakeMFARepo) FindChallenge(_ context.Context, challengeHash string) (*models.LoginChallenge, error) {
+	for _, challenge := range r.challenges {
+		if challenge.ChallengeHash == challengeHash {
+			return challenge, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeMFARepo) RegisterChallengeFailure(ctx context.Context, challengeHash string) error {
+	challenge, _ := r.FindChallenge(ctx, challengeHash)
+	challenge.Attempts++
+	return nil
+}
+
+func (r *fakeMFARepo) ConsumeChallenge(ctx context.Context, challengeHash string, now time.Time) (bool, error) {
+	challenge, _ := r.FindChallenge(ctx, challengeHash)
+	if challenge == nil || challenge.UsedAt != nil {
+		return false, nil
+	}
+	challenge.UsedAt = &now
+	return true, nil
+}
+
+func (r *fakeMFARepo) UseTOTPStep(_ context.Context, _ uuid.UUID, step int64) (bool, error) {
+	if step <= r.lastStep {
+		return false, nil
+	}
+	r.lastStep = step
+	return true, nil
+}
+
+func (r *fakeMFARepo) UseRecoveryCode(_ context.Context, _ uuid.UUID, codeHash string, _ time.Time) (bool, error) {
+	if used, ok := r.recoveryCodes[codeHash]; !ok || used {
+		return false, nil
+	}
+	r.recoveryCodes[codeHash] = true
+	return true, nil
+}
+
+func (r *fakeMFARepo) ConfirmTOTP(_ context.Context, _ uuid.UUID, step int64, _ time.Time, hashes []string) (bool, error) {
+	r.lastStep = step
+	r.recoveryCodes = make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		r.recoveryCodes[hash] = false
+	}
+	return true, nil
+}
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func newMFATestUsecase(repo *fakeMFARepo) (*AuthUsecase, *testClock) {
+	clock := &testClock{now: time.Unix(1234567890, 0)}
+	u := &AuthUsecase{
+		mfaRepo: repo,
+		authCfg: AuthConfig{MFA: MFAConfig{Skew: 1, RecoveryCodes: 3}},
+	}
+	u.SetClock(clock.Now)
+
+	return u, clock
+}
+
+func totpCode(t *testing.T, at time.Time, delta int64) string {
+	t.Helper()
+
+	code, err := utils.TOTPCode(testTOTPSecret, utils.TOTPStep(at)+delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestVerifySecondFactorRejectsReplay(t *testing.T) {
+	repo := &fakeMFARepo{}
+	u, clock := newMFATestUsecase(repo)
+	cred := &models.TOTPCredential{AccountID: uuid.New(), Secret: testTOTPSecret}
+	ctx := context.Background()
+
+	code := totpCode(t, clock.now, 0)
+	if ok, err := u.verifySecondFactor(ctx, cred, code, ""); err != nil || !ok {
+		t.Fatalf("valid code rejected: %v, %v", ok, err)
+	}
+
+	// Тот же код в том же интервале и в следующем (попадает в окно skew)
+	if ok, _ := u.verifySecondFactor(ctx, cred, code, ""); ok {
+		t.Fatal("code replayed within its step")
+	}
+	clock.now = clock.now.Add(utils.TOTPPeriod)
+	if ok, _ := u.verifySecondFactor(ctx, cred, code, ""); ok {
+		t.Fatal("code replayed in the next step")
+	}
+
+	// Код более раннего интервала после использованного тоже не принимается
+	if ok, _ := u.verifySecondFactor(ctx, cred, totpCode(t, clock.now, -1), ""); ok {
+		t.Fatal("code of an earlier step accepted")
+	}
+
+	if ok, err := u.verifySecondFactor(ctx, cred, totpCode(t, clock.now, 0), ""); err != nil || !ok {
+		t.Fatalf("fresh code rejected: %v, %v", ok, err)
+	}
+}
+
+func TestVerifySecondFactorSkew(t *testing.T) {
+	tests := []struct {
+		name   string
+		delta  int64
+		wantOK bool
+	}{
+		{name: "clock behind", delta: -1, wantOK: true},
+		{name: "clock ahead", delta: 1, wantOK: true},
+		{name: "too old", delta: -2},
+		{name: "too new", delta: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, clock := newMFATestUsecase(&fakeMFARepo{})
+			cred := &models.TOTPCredential{AccountID: uuid.New(), Secret: testTOTPSecret}
+
+			ok, err := u.verifySecondFactor(context.Background(), cred, totpCode(t, clock.now, tt.delta), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRecoveryCodesAreSingleUse(t *testing.T) {
+	repo := &fakeMFARepo{}
+	u, clock := newMFATestUsecase(repo)
+	cred := &models.TOTPCredential{AccountID: uuid.New(), Secret: testTOTPSecret}
+	ctx := context.Background()
+
+	if _, err := u.confirmEnrollment(ctx, cred, "000000"); err != errModels.ErrInvalidMFACode {
+		t.Fatalf("expected ErrInvalidMFACode, got %v", err)
+	}
+
+	codes, err := u.confirmEnrollment(ctx, cred, totpCode(t, clock.now, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 3 {
+		t.Fatalf("got %d recovery codes, want 3", len(codes))
+	}
+
+	// Код подтверждения подключения не годится для входа
+	if ok, _ := u.verifySecondFactor(ctx, cred, totpCode(t, clock.now, 0), ""); ok {
+		t.Fatal("enrollment code replayed at login")
+	}
+
+	for _, code := range codes {
+		if ok, err := u.verifySecondFactor(ctx, cred, "", code); err != nil || !ok {
+			t.Fatalf("recovery code %s rejected: %v, %v", code, ok, err)
+		}
+		if ok, _ := u.verifySecondFactor(ctx, cred, "", code); ok {
+			t.Fatalf("recovery code %s accepted twice", code)
+		}
+	}
+
+	if ok, _ := u.verifySecondFactor(ctx, cred, "", "aaaa-bbbb"); ok {
+		t.Fatal("unknown recovery code accepted")
+	}
+}
+
+// Знающий пароль не может перебирать коды, заново входя после каждой серии: неверные
+// коды блокируют вход по email, а пароль без второго фактора счётчик не сбрасывает
+func TestMFAFailuresCountInLoginThrottle(t *testing.T) {
+	env := newSSOTestEnv(t, SSOConfig{})
+	env.u.authCfg.LoginProtection = LoginProtectionConfig{
+		MaxFailures:     3,
+		IPMaxFailures:   50,
+		BaseDelay:       time.Second,
+		MaxDelay:        30 * time.Second,
+		LockoutDuration: 15 * time.Minute,
+		FailureWindow:   time.Hour,
+	}
+	ctx := context.Background()
+
+	hash, err := utils.HashPassword("Str0ng-password", env.u.authCfg.PasswordHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account := &models.Account{AccountID: uuid.New(), UserID: uuid.New(), Email: "ivan@example.com", PasswordHash: hash, Role: models.RoleUser, IsActive: true}
+	env.accounts.accounts[account.AccountID] = account
+	confirmedAt := env.clock.now
+	env.mfa.cred = &models.TOTPCredential{AccountID: account.AccountID, Secret: testTOTPSecret, ConfirmedAt: &confirmedAt}
+
+	login := func() (*dto.LoginResult, error) {
+		return env.u.Login(ctx, &dto.LoginRequest{Email: account.Email, Password: "Str0ng-password", IP: "10.0.0.1"})
+	}
+	emailKey := emailThrottleKey(account.Email)
+
+	for i := 1; i <= 3; i++ {
+		result, err := login()
+		if err != nil {
+			t.Fatalf("login %d: %v", i, err)
+		}
+		if result.Challenge == nil {
+			t.Fatalf("login %d: expected a second factor challenge", i)
+		}
+		if got := env.logins.failures(emailKey); got != i-1 {
+			t.Fatalf("login %d: password step reset the throttle to %d failures", i, got)
+		}
+
+		_, err = env.u.CompleteLogin(ctx, &dto.MFALoginRequest{
+			ChallengeToken: result.Challenge.ChallengeToken,
+			Code:           totpCode(t, env.clock.now, 10),
+		})
+		if !errors.Is(err, errModels.ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: expected ErrInvalidMFACode, got %v", i, err)
+		}
+
+		env.clock.now = env.clock.now.Add(env.u.authCfg.LoginProtection.MaxDelay)
+	}
+
+	var throttled *errModels.ThrottledError
+	if _, err := login(); !errors.As(err, &throttled) {
+		t.Fatalf("expected the account to be locked after MFA failures, got %v", err)
+	}
+
+	// После блокировки верный код завершает вход и только тогда сбрасывает счётчик
+	env.clock.now = env.clock.now.Add(env.u.authCfg.LoginProtection.LockoutDuration)
+	result, err := login()
+	if err != nil {
+		t.Fatalf("login after lockout: %v", err)
+	}
+	if got := env.logins.failures(emailKey); got != 3 {
+		t.Fatalf("password step reset the throttle to %d failures", got)
+	}
+	result, err = env.u.CompleteLogin(ctx, &dto.MFALoginRequest{
+		ChallengeToken: result.Challenge.ChallengeToken,
+		Code:           totpCode(t, env.clock.now, 0),
+	})
+	if err != nil || result.Tokens == nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if got := env.logins.failures(emailKey); got != 0 {
+		t.Fatalf("expected the throttle to be reset after the second factor, got %d failures", got)
+	}
+}
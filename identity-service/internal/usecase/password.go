@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type PasswordRepo interface {
+	CreateResetToken(ctx context.Context, token *models.PasswordResetToken) error
+	ConsumeResetToken(ctx context.Context, tokenHash string, now time.Time) (*models.PasswordResetToken, error)
+	DeleteResetTokens(ctx context.Context, accountID uuid.UUID) error
+}
+
+// ChangePassword меняет пароль владельцем аккаунта. Текущий пароль обязателен, его
+// перебор ограничивается так же, как вход. Остальные сессии отзываются
+func (u *AuthUsecase) ChangePassword(ctx context.Context, claims *models.TokenClaims, input *dto.ChangePasswordRequest) error {
+	account, err := u.authrepo.FindByUserID(ctx, claims.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to find account by user id: %w", err)
+	}
+	if account == nil {
+		return errModels.ErrUserNotFound
+	}
+
+	emailKey := emailThrottleKey(account.Email)
+	if err := u.checkThrottle(ctx, emailKey); err != nil {
+		return err
+	}
+
+	ok, err := utils.VerifyPassword(input.CurrentPassword, account.PasswordHash)
+	if errors.Is(err, utils.ErrInvalidPasswordHash) {
+		// Как и при входе: испорченный хеш для клиента - неверный текущий пароль
+		log.Printf("account %s has malformed password hash: %v", account.AccountID, err)
+		ok, err = false, nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		if err := u.registerFailure(ctx, emailKey, u.authCfg.LoginProtection.MaxFailures); err != nil {
+			return fmt.Errorf("failed to register login failure: %w", err)
+		}
+		return errModels.ErrInvalidPassword
+	}
+
+	if input.NewPassword == input.CurrentPassword {
+		return errModels.ErrPasswordUnchanged
+	}
+
+	if err := u.setPassword(ctx, account, input.NewPassword); err != nil {
+		return err
+	}
+
+	if err := u.sessionRepo.RevokeOtherSessions(ctx, account.AccountID, claims.SessionID, u.now()); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	// Выданный ранее токен сброса после смены пароля больше не нужен
+	if err := u.passwordRepo.DeleteResetTokens(ctx, account.AccountID); err != nil {
+		return fmt.Errorf("failed to delete reset tokens: %w", err)
+	}
+
+	return nil
+}
+
+// ForcePasswordReset - принудительный сброс администратором. Текущий пароль перестаёт
+// действовать, все сессии отзываются, а для задания нового выдаётся одноразовый токен
+func (u *AuthUsecase) ForcePasswordReset(ctx context.Context, accountID uuid.UUID) (*dto.PasswordResetResponse, error) {
+	account, err := u.authrepo.FindByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	// Заменяем пароль случайным, который никто не знает
+	if err := u.setPassword(ctx, account, uuid.NewString()); err != nil {
+		return nil, err
+	}
+
+	now := u.now()
+	if err := u.sessionRepo.RevokeAccountSessions(ctx, account.AccountID, now); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	resetToken, resetHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	token := &models.PasswordResetToken{
+		TokenHash: resetHash,
+		AccountID: account.AccountID,
+		ExpiresAt: now.Add(u.authCfg.PasswordResetTTL),
+		CreatedAt: now,
+	}
+	if err := u.passwordRepo.CreateResetToken(ctx, token); err != nil {
+		return nil, fmt.Errorf("failed to save reset token: %w", err)
+	}
+
+	return &dto.PasswordResetResponse{
+		ResetToken: resetToken,
+		ExpiresAt:  token.ExpiresAt,
+	}, nil
+}
+
+// ResetPassword задаёт новый пароль по токену сброса. Токен одноразовый
+func (u *AuthUsecase) ResetPassword(ctx context.Context, input *dto.ResetPasswordRequest) error {
+	now := u.now()
+
+	token, err := u.passwordRepo.ConsumeResetToken(ctx, utils.HashRefreshToken(input.Token), now)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return errModels.ErrInvalidResetToken
+	}
+
+	account, err := u.authrepo.FindByID(ctx, token.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to find account: %w", err)
+	}
+	if account == nil || !account.IsActive {
+		return errModels.ErrAccountInactive
+	}
+
+	if err := u.setPassword(ctx, account, input.NewPassword); err != nil {
+		return err
+	}
+
+	if err := u.sessionRepo.RevokeAccountSessions(ctx, account.AccountID, now); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	// Новый пароль снимает блокировку входа, накопленную старым
+	if err := u.loginRepo.ResetThrottle(ctx, emailThrottleKey(account.Email)); err != nil {
+		return fmt.Errorf("failed to reset login throttle: %w", err)
+	}
+
+	return nil
+}
+
+func (u *AuthUsecase) setPassword(ctx context.Context, account *models.Account, password string) error {
+	passwordHash, err := utils.HashPassword(password, u.authCfg.PasswordHash)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := u.authrepo.UpdatePassword(ctx, account.AccountID, passwordHash); err != nil {
+		return err
+	}
+	account.PasswordHash = passwordHash
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// Испорченный хеш при смене пароля - неверный текущий пароль, а не 500
+func TestChangePasswordMalformedHash(t *testing.T) {
+	account := &models.Account{
+		AccountID:    uuid.New(),
+		UserID:       uuid.New(),
+		Email:        "ivan@example.com",
+		PasswordHash: "not-a-hash",
+		IsActive:     true,
+	}
+	logins := &fakeLoginRepo{}
+	u := &AuthUsecase{
+		authrepo:  &fakeAuthRepo{accounts: map[uuid.UUID]*models.Account{account.AccountID: account}},
+		loginRepo: logins,
+		authCfg: AuthConfig{LoginProtection: LoginProtectionConfig{
+			MaxFailures: 5, BaseDelay: time.Second, MaxDelay: 30 * time.Second,
+		}},
+		now: time.Now,
+	}
+
+	err := u.ChangePassword(context.Background(),
+		&models.TokenClaims{UserID: account.UserID, SessionID: uuid.New()},
+		&dto.ChangePasswordRequest{CurrentPassword: "Str0ng-password", NewPassword: "N3w-str0ng-password"})
+	if !errors.Is(err, errModels.ErrInvalidPassword) {
+		t.Fatalf("expected ErrInvalidPassword, got %v", err)
+	}
+	if got := logins.failures(emailThrottleKey(account.Email)); got != 1 {
+		t.Fatalf("expected the failure to be counted, got %d", got)
+	}
+	if account.PasswordHash != "not-a-hash" {
+		t.Fatal("password must not change")
+	}
+}
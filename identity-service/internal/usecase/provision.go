@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+// ProvisionAccount создаёт аккаунт сотрудника или приводит существующий к переданному
+// состоянию. Вызывается directory-service, поэтому наличие сотрудника не перепроверяется.
+// created сообщает, что аккаунт был создан
+func (u *AuthUsecase) ProvisionAccount(ctx context.Context, userID uuid.UUID, input *dto.ProvisionAccountRequest) (*dto.AccountResponse, bool, error) {
+	account, err := u.authrepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find account: %w", err)
+	}
+
+	owner, err := u.authrepo.FindByEmail(ctx, input.Email)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to find account by email from db: %w", err)
+	}
+	if owner != nil && owner.UserID != userID {
+		return nil, false, errModels.ErrEmailTaken
+	}
+
+	if account != nil {
+		resp, err := u.UpdateAccount(ctx, account.AccountID, &dto.UpdateAccountRequest{
+			Email:    &input.Email,
+			Role:     input.Role,
+			IsActive: input.IsActive,
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		if input.Password != nil {
+			if err := u.setPassword(ctx, account, *input.Password); err != nil {
+				return nil, false, err
+			}
+			if err := u.sessionRepo.RevokeAccountSessions(ctx, account.AccountID, u.now()); err != nil {
+				return nil, false, fmt.Errorf("failed to revoke sessions: %w", err)
+			}
+		}
+
+		return resp, false, nil
+	}
+
+	// Без пароля ставим случайный: войти можно через SSO или после сброса пароля
+	password := uuid.NewString()
+	if input.Password != nil {
+		password = *input.Password
+	}
+	passwordHash, err := utils.HashPassword(password, u.authCfg.PasswordHash)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	role := models.RoleUser
+	if input.Role != "" {
+		role = models.AccountRole(input.Role)
+	}
+	isActive := true
+	if input.IsActive != nil {
+		isActive = *input.IsActive
+	}
+
+	account = &models.Account{
+		AccountID:    uuid.New(),
+		UserID:       userID,
+		Email:        input.Email,
+		PasswordHash: passwordHash,
+		Role:         role,
+		IsActive:     isActive,
+		CreatedAt:    u.now(),
+	}
+	if err := u.authrepo.Create(ctx, account); err != nil {
+		return nil, false, fmt.Errorf("failed to save account to db: %w", err)
+	}
+
+	return &dto.AccountResponse{
+		AccountID: account.AccountID.String(),
+		UserID:    account.UserID.String(),
+		Email:     account.Email,
+		Role:      dto.AccountRole(account.Role),
+		IsActive:  account.IsActive,
+		CreatedAt: account.CreatedAt,
+	}, true, nil
+}
+
+// DeprovisionAccount деактивирует аккаунт сотрудника, уволенного в справочнике
+func (u *AuthUsecase) DeprovisionAccount(ctx context.Context, userID uuid.UUID) error {
+	account, err := u.authrepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find account: %w", err)
+	}
+	if account == nil {
+		return errModels.ErrUserNotFound
+	}
+	if !account.IsActive {
+		// Повтор доводит отключение, если прошлый вызов не достучался до chat-service
+		return u.afterDeactivation(ctx, account)
+	}
+
+	return u.DeactivateAccount(ctx, account.AccountID)
+}
+
+// ReactivateAccount снова открывает вход сотруднику, восстановленному в справочнике
+func (u *AuthUsecase) ReactivateAccount(ctx context.Context, userID uuid.UUID) error {
+	account, err := u.authrepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find account: %w", err)
+	}
+	if account == nil {
+		return errModels.ErrUserNotFound
+	}
+	if account.IsActive {
+		return nil
+	}
+
+	active := true
+	_, err = u.UpdateAccount(ctx, account.AccountID, &dto.UpdateAccountRequest{IsActive: &active})
+	return err
+}
+
+// IssuePasswordReset выдаёт одноразовый токен для задания пароля сотрудником:
+// используется для приглашения при онбординге
+func (u *AuthUsecase) IssuePasswordReset(ctx context.Context, userID uuid.UUID) (*dto.PasswordResetResponse, error) {
+	account, err := u.authrepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+	if account == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	return u.ForcePasswordReset(ctx, account.AccountID)
+}
@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+func (u *AuthUsecase) startSession(ctx context.Context, account *models.Account, userAgent, ip string) (*dto.TokenPair, error) {
+	now := u.now()
+
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		SessionID:  uuid.New(),
+		AccountID:  account.AccountID,
+		UserID:     account.UserID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(u.authCfg.RefreshExpiresIn),
+	}
+	token := &models.RefreshToken{
+		TokenHash: refreshHash,
+		SessionID: session.SessionID,
+		ExpiresAt: session.ExpiresAt,
+		CreatedAt: now,
+	}
+
+	if err := u.sessionRepo.CreateSession(ctx, session, token); err != nil {
+		return nil, fmt.Errorf("failed to save session to db: %w", err)
+	}
+
+	return u.issueTokens(account, session.SessionID, refreshToken, token)
+}
+
+// Refresh обменивает refresh-токен на новую пару. Старый токен становится одноразово
+// использованным; его повторное предъявление означает кражу и отзывает всю сессию
+func (u *AuthUsecase) Refresh(ctx context.Context, refreshToken string) (*dto.TokenPair, error) {
+	now := u.now()
+	oldHash := utils.HashRefreshToken(refreshToken)
+
+	stored, err := u.sessionRepo.FindRefreshToken(ctx, oldHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	if stored == nil {
+		return nil, errModels.ErrInvalidToken
+	}
+
+	if stored.UsedAt != nil {
+		return nil, u.revokeOnReuse(ctx, stored.SessionID)
+	}
+
+	session, err := u.sessionRepo.FindSession(ctx, stored.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil || !session.IsActive(now) || !now.Before(stored.ExpiresAt) {
+		return nil, errModels.ErrSessionRevoked
+	}
+
+	account, err := u.authrepo.FindByID(ctx, session.AccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+	if account == nil || !account.IsActive {
+		return nil, errModels.ErrAccountInactive
+	}
+
+	nextToken, nextHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	next := &models.RefreshToken{
+		TokenHash: nextHash,
+		SessionID: session.SessionID,
+		ExpiresAt: now.Add(u.authCfg.RefreshExpiresIn),
+		CreatedAt: now,
+	}
+
+	rotated, err := u.sessionRepo.RotateRefreshToken(ctx, oldHash, next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		// Токен успели использовать между чтением и ротацией
+		return nil, u.revokeOnReuse(ctx, session.SessionID)
+	}
+
+	return u.issueTokens(account, session.SessionID, nextToken, next)
+}
+
+func (u *AuthUsecase) revokeOnReuse(ctx context.Context, sessionID uuid.UUID) error {
+	if err := u.sessionRepo.RevokeSession(ctx, sessionID, u.now()); err != nil {
+		return fmt.Errorf("failed to revoke session after refresh token reuse: %w", err)
+	}
+
+	return errModels.ErrRefreshTokenReused
+}
+
+// Logout отзывает сессию, которой принадлежит refresh-токен
+func (u *AuthUsecase) Logout(ctx context.Context, refreshToken string) error {
+	stored, err := u.sessionRepo.FindRefreshToken(ctx, utils.HashRefreshToken(refreshToken))
+	if err != nil {
+		return fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	if stored == nil {
+		return errModels.ErrInvalidToken
+	}
+
+	if err := u.sessionRepo.RevokeSession(ctx, stored.SessionID, u.now()); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (u *AuthUsecase) ListSessions(ctx context.Context, claims *models.TokenClaims) ([]*dto.SessionResponse, error) {
+	account, err := u.authrepo.FindByUserID(ctx, claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+	if account == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	sessions, err := u.sessionRepo.ListActiveSessions(ctx, account.AccountID, u.now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	responses := make([]*dto.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = &dto.SessionResponse{
+			SessionID:  session.SessionID.String(),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.SessionID == claims.SessionID,
+		}
+	}
+
+	return responses, nil
+}
+
+// RevokeSession отзывает одну из сессий пользователя. Чужие сессии не видны
+func (u *AuthUsecase) RevokeSession(ctx context.Context, claims *models.TokenClaims, sessionID uuid.UUID) error {
+	session, err := u.sessionRepo.FindSession(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil || session.UserID != claims.UserID {
+		return errModels.ErrSessionNotFound
+	}
+
+	if err := u.sessionRepo.RevokeSession(ctx, sessionID, u.now()); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (u *AuthUsecase) issueTokens(account *models.Account, sessionID uuid.UUID, refreshToken string, stored *models.RefreshToken) (*dto.TokenPair, error) {
+	accessToken, err := u.keys.SignAccessToken(account.UserID, account.Role, sessionID, u.now(), u.authCfg.JwtExpiresIn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT: %w", err)
+	}
+
+	return &dto.TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(u.authCfg.JwtExpiresIn.Seconds()),
+		RefreshExpiresAt: stored.ExpiresAt,
+		SessionID:        sessionID.String(),
+	}, nil
+}
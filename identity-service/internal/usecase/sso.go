@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/oidc"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/google/uuid"
+)
+
+type SSOProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*oidc.Claims, error)
+}
+
+type SSORepo interface {
+	SaveState(ctx context.Context, state *models.SSOLoginState) error
+	ConsumeState(ctx context.Context, stateHash string) (*models.SSOLoginState, error)
+	FindIdentity(ctx context.Context, issuer, subject string) (*models.ExternalIdentity, error)
+	LinkIdentity(ctx context.Context, identity *models.ExternalIdentity) error
+}
+
+// Старшинство ролей: если группы пользователя дают несколько ролей, берётся старшая
+var roleRank = map[models.AccountRole]int{
+	models.RoleUser:      0,
+	models.RoleSupport:   1,
+	models.RoleModerator: 2,
+	models.RoleAdmin:     3,
+}
+
+// StartSSO начинает authorization code flow: сохраняет state, nonce и PKCE verifier
+// и возвращает адрес страницы входа провайдера
+func (u *AuthUsecase) StartSSO(ctx context.Context) (string, error) {
+	if u.sso == nil {
+		return "", errModels.ErrSSODisabled
+	}
+
+	state, stateHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	nonce, _, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.GenerateCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	now := u.now()
+	err = u.ssoRepo.SaveState(ctx, &models.SSOLoginState{
+		StateHash:    stateHash,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(u.authCfg.SSO.StateTTL),
+		CreatedAt:    now,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to save sso state: %w", err)
+	}
+
+	return u.sso.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(verifier))
+}
+
+// CompleteSSO завершает вход по коду с callback. Второй фактор запрашивается как при
+// входе по паролю, если только провайдеру не доверена его проверка (OIDC_TRUST_PROVIDER_MFA)
+func (u *AuthUsecase) CompleteSSO(ctx context.Context, input *dto.SSOCallbackRequest) (*dto.LoginResult, error) {
+	if u.sso == nil {
+		return nil, errModels.ErrSSODisabled
+	}
+
+	state, err := u.ssoRepo.ConsumeState(ctx, utils.HashRefreshToken(input.State))
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || !u.now().Before(state.ExpiresAt) {
+		return nil, errModels.ErrInvalidSSOState
+	}
+
+	claims, err := u.sso.Exchange(ctx, input.Code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		if errors.Is(err, oidc.ErrExchangeFailed) || errors.Is(err, oidc.ErrInvalidIDToken) {
+			return nil, fmt.Errorf("%w: %v", errModels.ErrSSOFailed, err)
+		}
+		return nil, err
+	}
+
+	account, err := u.resolveSSOAccount(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if !account.IsActive {
+		return nil, errModels.ErrAccountInactive
+	}
+
+	// Роль ведётся в провайдере через группы
+	if role := u.roleFromGroups(claims.Groups); u.authCfg.SSO.SyncsRole() && role != account.Role {
+		account.Role = role
+		if err := u.authrepo.UpdateAccount(ctx, account); err != nil {
+			return nil, err
+		}
+		u.accounts.invalidate(account.UserID)
+	}
+
+	now := u.now()
+	err = u.ssoRepo.LinkIdentity(ctx, &models.ExternalIdentity{
+		Issuer:      claims.Issuer,
+		Subject:     claims.Subject,
+		AccountID:   account.AccountID,
+		Email:       claims.Email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attempt := &dto.LoginRequest{Email: account.Email, UserAgent: input.UserAgent, IP: input.IP}
+
+	if !u.authCfg.SSO.ProviderVerifiedMFA(claims.AMR) {
+		if result, err := u.secondFactorChallenge(ctx, account, attempt); result != nil || err != nil {
+			return result, err
+		}
+	}
+
+	tokens, err := u.finishLogin(ctx, account, attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResult{Tokens: tokens}, nil
+}
+
+// resolveSSOAccount находит аккаунт по привязке провайдера, затем по email. Если
+// аккаунта нет, но сотрудник с таким email есть в справочнике - создаёт аккаунт для него
+func (u *AuthUsecase) resolveSSOAccount(ctx context.Context, claims *oidc.Claims) (*models.Account, error) {
+	identity, err := u.ssoRepo.FindIdentity(ctx, claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		account, err := u.authrepo.FindByID(ctx, identity.AccountID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find account: %w", err)
+		}
+		if account == nil {
+			return nil, errModels.ErrAccountInactive
+		}
+		return account, nil
+	}
+
+	// Без подтверждённого email нельзя сопоставить чужую учётную запись с нашей
+	if claims.Email == "" || (u.authCfg.SSO.RequireVerifiedEmail && !claims.EmailVerified) {
+		return nil, errModels.ErrSSOEmailUnverified
+	}
+
+	account, err := u.authrepo.FindByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account by email from db: %w", err)
+	}
+	if account != nil {
+		return account, nil
+	}
+
+	user, err := u.dirClient.FindUserByEmail(ctx, claims.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from directory-service: %w", err)
+	}
+	if user == nil {
+		return nil, errModels.ErrUserNotFound
+	}
+
+	// Пароль случайный: аккаунт из SSO входит только через провайдера, пока не сбросят пароль
+	passwordHash, err := utils.HashPassword(uuid.NewString(), u.authCfg.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	account = &models.Account{
+		AccountID:    uuid.New(),
+		UserID:       user.UserID,
+		Email:        claims.Email,
+		PasswordHash: passwordHash,
+		Role:         u.roleFromGroups(claims.Groups),
+		IsActive:     user.IsActive,
+		CreatedAt:    u.now(),
+	}
+	if err := u.authrepo.Create(ctx, account); err != nil {
+		return nil, fmt.Errorf("failed to save account to db: %w", err)
+	}
+
+	return account, nil
+}
+
+func (u *AuthUsecase) roleFromGroups(groups []string) models.AccountRole {
+	role := models.AccountRole(u.authCfg.SSO.DefaultRole)
+	for _, group := range groups {
+		mapped, ok := u.authCfg.SSO.RoleMapping[group]
+		if !ok {
+			continue
+		}
+		if candidate := models.AccountRole(mapped); roleRank[candidate] > roleRank[role] {
+			role = candidate
+		}
+	}
+
+	return role
+}
@@ -0,0 +1,522 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/directory"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/oidc"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/oidc/oidctest"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/models"
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/ilyakaznacheev/cleanenv"
+)
+
+type fakeAuthRepo struct {
+	AuthRepo
+
+	accounts map[uuid.UUID]*models.Account
+}
+
+func (r *fakeAuthRepo) Create(_ context.Context, account *models.Account) error {
+	r.accounts[account.AccountID] = account
+	return nil
+}
+
+func (r *fakeAuthRepo) FindByID(_ context.Context, accountID uuid.UUID) (*models.Account, error) {
+	return r.accounts[accountID], nil
+}
+
+func (r *fakeAuthRepo) FindByEmail(_ context.Context, email string) (*models.Account, error) {
+	for _, account := range r.accounts {
+		if account.Email == email {
+			return account, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAuthRepo) FindByUserID(_ context.Context, userID uuid.UUID) (*models.Account, error) {
+	for _, account := range r.accounts {
+		if account.UserID == userID {
+			return account, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAuthRepo) UpdateAccount(_ context.Context, account *models.Account) error {
+	r.accounts[account.AccountID] = account
+	return nil
+}
+
+func (r *fakeAuthRepo) UpdateLastLogin(_ context.Context, accountID uuid.UUID, at time.Time) error {
+	r.accounts[accountID].LastLogin = &at
+	return nil
+}
+
+type fakeSessionRepo struct {
+	SessionRepo
+
+	sessions []*models.Session
+}
+
+func (r *fakeSessionRepo) CreateSession(_ context.Context, session *models.Session, _ *models.RefreshToken) error {
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+// fakeLoginRepo ведёт счётчики неудач в памяти; окно неудач не учитывается
+type fakeLoginRepo struct {
+	LoginRepo
+
+	throttles map[string]*models.LoginThrottle
+}
+
+func (r *fakeLoginRepo) GetThrottle(_ context.Context, key string) (*models.LoginThrottle, error) {
+	return r.throttles[key], nil
+}
+
+func (r *fakeLoginRepo) RegisterFailure(_ context.Context, key string, now, _ time.Time) (int, error) {
+	if r.throttles == nil {
+		r.throttles = make(map[string]*models.LoginThrottle)
+	}
+	throttle, ok := r.throttles[key]
+	if !ok {
+		throttle = &models.LoginThrottle{Key: key}
+		r.throttles[key] = throttle
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = now
+	return throttle.Failures, nil
+}
+
+func (r *fakeLoginRepo) SetBlockedUntil(_ context.Context, key string, blockedUntil time.Time) error {
+	r.throttles[key].BlockedUntil = &blockedUntil
+	return nil
+}
+
+func (r *fakeLoginRepo) ResetThrottle(_ context.Context, key string) error {
+	delete(r.throttles, key)
+	return nil
+}
+
+// failures - число неудач по ключу с момента последнего сброса
+func (r *fakeLoginRepo) failures(key string) int {
+	if throttle, ok := r.throttles[key]; ok {
+		return throttle.Failures
+	}
+	return 0
+}
+
+func (r *fakeLoginRepo) RecordAttempt(context.Context, *models.LoginAttempt) error {
+	return nil
+}
+
+type fakeSSORepo struct {
+	states     map[string]*models.SSOLoginState
+	identities map[[2]string]*models.ExternalIdentity
+}
+
+func (r *fakeSSORepo) SaveState(_ context.Context, state *models.SSOLoginState) error {
+	r.states[state.StateHash] = state
+	return nil
+}
+
+func (r *fakeSSORepo) ConsumeState(_ context.Context, stateHash string) (*models.SSOLoginState, error) {
+	state := r.states[stateHash]
+	delete(r.states, stateHash)
+	return state, nil
+}
+
+func (r *fakeSSORepo) FindIdentity(_ context.Context, issuer, subject string) (*models.ExternalIdentity, error) {
+	return r.identities[[2]string{issuer, subject}], nil
+}
+
+func (r *fakeSSORepo) LinkIdentity(_ context.Context, identity *models.ExternalIdentity) error {
+	r.identities[[2]string{identity.Issuer, identity.Subject}] = identity
+	return nil
+}
+
+type fakeDirectory struct {
+	directory.Client
+
+	users map[string]*directory.UserResponse
+}
+
+func (d *fakeDirectory) FindUserByEmail(_ context.Context, email string) (*directory.UserResponse, error) {
+	return d.users[email], nil
+}
+
+type fakeKeyRepo struct {
+	keys []*models.SigningKey
+}
+
+func (r *fakeKeyRepo) ListKeys(context.Context, time.Time) ([]*models.SigningKey, error) {
+	return r.keys, nil
+}
+
+func (r *fakeKeyRepo) AddKey(_ context.Context, next *models.SigningKey, _ string, _ time.Time) (bool, error) {
+	r.keys = append(r.keys, next)
+	return true, nil
+}
+
+type ssoTestEnv struct {
+	u        *AuthUsecase
+	provider *oidctest.Provider
+	clock    *testClock
+	accounts *fakeAuthRepo
+	sessions *fakeSessionRepo
+	mfa      *fakeMFARepo
+	logins   *fakeLoginRepo
+	dir      *fakeDirectory
+}
+
+func newSSOTestEnv(t *testing.T, ssoCfg SSOConfig) *ssoTestEnv {
+	t.Helper()
+
+	provider, err := oidctest.NewProvider("messenger", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(provider.Close)
+
+	keys := NewKeyManager(&fakeKeyRepo{}, KeysConfig{Algorithm: "EdDSA", RotationInterval: time.Hour, Overlap: time.Minute})
+	if err := keys.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if ssoCfg.RoleMapping == nil {
+		ssoCfg.RoleMapping = map[string]string{"messenger-admins": "admin", "helpdesk": "support"}
+	}
+	ssoCfg.DefaultRole = "user"
+	ssoCfg.StateTTL = 10 * time.Minute
+
+	env := &ssoTestEnv{
+		provider: provider,
+		clock:    &testClock{now: time.Now()},
+		accounts: &fakeAuthRepo{accounts: make(map[uuid.UUID]*models.Account)},
+		sessions: &fakeSessionRepo{},
+		mfa:      &fakeMFARepo{},
+		logins:   &fakeLoginRepo{},
+		dir:      &fakeDirectory{users: make(map[string]*directory.UserResponse)},
+	}
+
+	sso := oidc.NewClient(oidc.OIDCConfig{
+		IssuerURL:    provider.Issuer(),
+		ClientID:     "messenger",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/callback",
+		Scopes:       []string{"openid", "email"},
+		GroupsClaim:  "groups",
+		Timeout:      5 * time.Second,
+	})
+
+	authCfg := AuthConfig{
+		JwtExpiresIn:     15 * time.Minute,
+		RefreshExpiresIn: time.Hour,
+		PasswordHash:     utils.PasswordHashParams{Time: 1, Memory: 1024, Threads: 1, KeyLen: 16, SaltLen: 16},
+		MFA:              MFAConfig{RequiredRoles: []string{"admin"}, ChallengeTTL: 5 * time.Minute, MaxAttempts: 5},
+		SSO:              ssoCfg,
+	}
+
+	env.u = NewAuthUsecase(env.accounts, env.sessions, env.logins, nil, env.mfa,
+		&fakeSSORepo{states: make(map[string]*models.SSOLoginState), identities: make(map[[2]string]*models.ExternalIdentity)},
+		sso, env.dir, nil, keys, authCfg)
+	env.u.SetClock(env.clock.Now)
+
+	return env
+}
+
+func (e *ssoTestEnv) addEmployee(email string) uuid.UUID {
+	userID := uuid.New()
+	e.dir.users[email] = &directory.UserResponse{UserID: userID, Email: email, IsActive: true}
+	return userID
+}
+
+// start проходит редирект на провайдера от имени user и возвращает запрос callback
+func (e *ssoTestEnv) start(t *testing.T, user oidctest.User) *dto.SSOCallbackRequest {
+	t.Helper()
+
+	e.provider.SetUser(user)
+
+	authURL, err := e.u.StartSSO(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := e.provider.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &dto.SSOCallbackRequest{Code: code, State: state, UserAgent: "test", IP: "127.0.0.1"}
+}
+
+func (e *ssoTestEnv) login(t *testing.T, user oidctest.User) (*dto.LoginResult, error) {
+	t.Helper()
+
+	return e.u.CompleteSSO(context.Background(), e.start(t, user))
+}
+
+func verifiedUser(email string, groups ...string) oidctest.User {
+	return oidctest.User{Subject: "sub-" + email, Email: email, EmailVerified: true, Groups: groups}
+}
+
+func TestCompleteSSOCreatesAccountWithMappedRole(t *testing.T) {
+	tests := []struct {
+		name     string
+		groups   []string
+		wantRole models.AccountRole
+	}{
+		{name: "no groups", wantRole: models.RoleUser},
+		{name: "unmapped group", groups: []string{"staff"}, wantRole: models.RoleUser},
+		{name: "mapped group", groups: []string{"staff", "helpdesk"}, wantRole: models.RoleSupport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newSSOTestEnv(t, SSOConfig{SyncRole: true, RequireVerifiedEmail: true})
+			userID := env.addEmployee("ivan@example.com")
+
+			result, err := env.login(t, verifiedUser("ivan@example.com", tt.groups...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Tokens == nil {
+				t.Fatalf("expected tokens, got %+v", result)
+			}
+
+			account, _ := env.accounts.FindByEmail(context.Background(), "ivan@example.com")
+			if account == nil || account.UserID != userID || account.Role != tt.wantRole {
+				t.Fatalf("unexpected account %+v", account)
+			}
+		})
+	}
+}
+
+func TestCompleteSSOHighestMappedRoleWins(t *testing.T) {
+	env := newSSOTestEnv(t, SSOConfig{SyncRole: true, TrustProviderMFA: true, MFAMethods: []string{"mfa"}})
+	env.addEmployee("ivan@example.com")
+
+	user := verifiedUser("ivan@example.com", "helpdesk", "messenger-admins")
+	user.AMR = []string{"mfa"}
+	if _, err := env.login(t, user); err != nil {
+		t.Fatal(err)
+	}
+
+	account, _ := env.accounts.FindByEmail(context.Background(), "ivan@example.com")
+	if account.Role != models.RoleAdmin {
+		t.Fatalf("role = %s, want admin", account.Role)
+	}
+}
+
+func TestCompleteSSOSyncsRoleOfExistingAccount(t *testing.T) {
+	for _, syncRole := range []bool{true, false} {
+		env := newSSOTestEnv(t, SSOConfig{SyncRole: syncRole})
+		account := &models.Account{AccountID: uuid.New(), UserID: uuid.New(), Email: "ivan@example.com", Role: models.RoleModerator, IsActive: true}
+		env.accounts.accounts[account.AccountID] = account
+
+		if _, err := env.login(t, verifiedUser("ivan@example.com", "helpdesk")); err != nil {
+			t.Fatal(err)
+		}
+
+		want := models.RoleModerator
+		if syncRole {
+			want = models.RoleSupport
+		}
+		if account.Role != want {
+			t.Fatalf("sync=%v: role = %s, want %s", syncRole, account.Role, want)
+		}
+	}
+}
+
+// Без настроенного OIDC_ROLE_MAPPING вход через SSO не понижает администраторов
+func TestCompleteSSOKeepsRoleUnderDefaultConfig(t *testing.T) {
+	var defaults SSOConfig
+	if err := cleanenv.ReadEnv(&defaults); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, syncRole := range []bool{defaults.SyncRole, true} {
+		env := newSSOTestEnv(t, SSOConfig{})
+		env.u.authCfg.SSO = defaults
+		env.u.authCfg.SSO.SyncRole = syncRole
+		env.u.authCfg.SSO.TrustProviderMFA = true
+		env.u.authCfg.SSO.MFAMethods = []string{"mfa"}
+
+		account := &models.Account{AccountID: uuid.New(), UserID: uuid.New(), Email: "ivan@example.com", Role: models.RoleAdmin, IsActive: true}
+		env.accounts.accounts[account.AccountID] = account
+
+		user := verifiedUser("ivan@example.com", "staff")
+		user.AMR = []string{"mfa"}
+		if _, err := env.login(t, user); err != nil {
+			t.Fatal(err)
+		}
+		if account.Role != models.RoleAdmin {
+			t.Fatalf("sync=%v: role = %s, want admin", syncRole, account.Role)
+		}
+	}
+}
+
+func TestCompleteSSORejectsUnverifiedEmail(t *testing.T) {
+	env := newSSOTestEnv(t, SSOConfig{RequireVerifiedEmail: true})
+	env.addEmployee("ivan@example.com")
+
+	user := verifiedUser("ivan@example.com")
+	user.EmailVerified = false
+
+	if _, err := env.login(t, user); !errors.Is(err, errModels.ErrSSOEmailUnverified) {
+		t.Fatalf("expected ErrSSOEmailUnverified, got %v", err)
+	}
+	if len(env.accounts.accounts) != 0 {
+		t.Fatal("account created for unverified email")
+	}
+
+	// Без требования подтверждения email сопоставляется
+	env.u.authCfg.SSO.RequireVerifiedEmail = false
+	if _, err := env.login(t, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCompleteSSORejectsUnknownEmployee(t *testing.T) {
+	env := newSSOTestEnv(t, SSOConfig{})
+
+	if _, err := env.login(t, verifiedUser("stranger@example.com")); !errors.Is(err, errModels.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestCompleteSSOState(t *testing.T) {
+	env := newSSOTestEnv(t, SSOConfig{})
+	env.addEmployee("ivan@example.com")
+	ctx := context.Background()
+
+	t.Run("expired", func(t *testing.T) {
+		req := env.start(t, verifiedUser("ivan@example.com"))
+		env.clock.now = env.clock.now.Add(10 * time.Minute)
+		defer func() { env.clock.now = env.clock.now.Add(-10 * time.Minute) }()
+
+		if _, err := env.u.CompleteSSO(ctx, req); !errors.Is(err, errModels.ErrInvalidSSOState) {
+			t.Fatalf("expected ErrInvalidSSOState, got %v", err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		req := env.start(t, verifiedUser("ivan@example.com"))
+		req.State = "forged"
+
+		if _, err := env.u.CompleteSSO(ctx, req); !errors.Is(err, errModels.ErrInvalidSSOState) {
+			t.Fatalf("expected ErrInvalidSSOState, got %v", err)
+		}
+	})
+
+	t.Run("reused", func(t *testing.T) {
+		req := env.start(t, verifiedUser("ivan@example.com"))
+		if _, err := env.u.CompleteSSO(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := env.u.CompleteSSO(ctx, req); !errors.Is(err, errModels.ErrInvalidSSOState) {
+			t.Fatalf("expected ErrInvalidSSOState, got %v", err)
+		}
+	})
+}
+
+func TestCompleteSSOLinksIdentity(t *testing.T) {
+	env := newSSOTestEnv(t, SSOConfig{})
+	env.addEmployee("ivan@example.com")
+
+	user := verifiedUser("ivan@example.com")
+	if _, err := env.login(t, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// Дальше аккаунт находится по привязке, даже если email у провайдера сменился
+	user.Email = "ivan.petrov@example.com"
+	user.EmailVerified = false
+	result, err := env.login(t, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tokens == nil || len(env.accounts.accounts) != 1 {
+		t.Fatalf("expected login into the linked account, got %+v", result)
+	}
+}
+
+func TestCompleteSSORequiresSecondFactor(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           SSOConfig
+		groups        []string
+		amr           []string
+		totpEnabled   bool
+		wantChallenge bool
+		wantEnroll    bool
+	}{
+		{name: "user without totp", wantChallenge: false},
+		{name: "user with totp", totpEnabled: true, wantChallenge: true},
+		{name: "admin must enroll", groups: []string{"messenger-admins"}, wantChallenge: true, wantEnroll: true},
+		{name: "admin with totp", groups: []string{"messenger-admins"}, totpEnabled: true, wantChallenge: true},
+		{
+			name:   "provider mfa is ignored unless trusted",
+			groups: []string{"messenger-admins"}, amr: []string{"pwd", "mfa"},
+			wantChallenge: true, wantEnroll: true,
+		},
+		{
+			name:   "trusted provider without mfa in amr",
+			cfg:    SSOConfig{TrustProviderMFA: true, MFAMethods: []string{"mfa", "otp"}},
+			groups: []string{"messenger-admins"}, amr: []string{"pwd"},
+			wantChallenge: true, wantEnroll: true,
+		},
+		{
+			name:   "trusted provider with mfa in amr",
+			cfg:    SSOConfig{TrustProviderMFA: true, MFAMethods: []string{"mfa", "otp"}},
+			groups: []string{"messenger-admins"}, amr: []string{"pwd", "otp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.SyncRole = true
+			env := newSSOTestEnv(t, tt.cfg)
+			env.addEmployee("ivan@example.com")
+			if tt.totpEnabled {
+				confirmedAt := env.clock.now
+				env.mfa.cred = &models.TOTPCredential{Secret: testTOTPSecret, ConfirmedAt: &confirmedAt}
+			}
+
+			user := verifiedUser("ivan@example.com", tt.groups...)
+			user.AMR = tt.amr
+			result, err := env.login(t, user)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tt.wantChallenge {
+				if result.Tokens == nil || result.Challenge != nil || len(env.sessions.sessions) != 1 {
+					t.Fatalf("expected tokens without challenge, got %+v", result)
+				}
+				return
+			}
+
+			if result.Challenge == nil || result.Tokens != nil {
+				t.Fatalf("expected a second factor challenge, got %+v", result)
+			}
+			if result.Challenge.EnrollmentRequired != tt.wantEnroll {
+				t.Fatalf("enrollment required = %v, want %v", result.Challenge.EnrollmentRequired, tt.wantEnroll)
+			}
+			if len(env.sessions.sessions) != 0 {
+				t.Fatal("session started before the second factor")
+			}
+			if len(env.mfa.challenges) != 1 {
+				t.Fatalf("got %d challenges, want 1", len(env.mfa.challenges))
+			}
+		})
+	}
+}
@@ -2,11 +2,12 @@ package usecase
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/chat"
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/clients/directory"
 	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
 	errModels "github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/errors"
@@ -25,22 +26,63 @@ type AuthRepo interface {
 	GetAllAccounts(ctx context.Context) ([]*models.Account, error)
 	DeactivateAccount(ctx context.Context, accountID uuid.UUID) error
 	UpdateAccount(ctx context.Context, account *models.Account) error
+	UpdateLastLogin(ctx context.Context, accountID uuid.UUID, at time.Time) error
+	UpdatePassword(ctx context.Context, accountID uuid.UUID, passwordHash string) error
+}
+
+type SessionRepo interface {
+	CreateSession(ctx context.Context, session *models.Session, token *models.RefreshToken) error
+	FindSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error)
+	ListActiveSessions(ctx context.Context, accountID uuid.UUID, now time.Time) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, sessionID uuid.UUID, now time.Time) error
+	RevokeAccountSessions(ctx context.Context, accountID uuid.UUID, now time.Time) error
+	RevokeOtherSessions(ctx context.Context, accountID, keepSessionID uuid.UUID, now time.Time) error
+
+	FindRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldHash string, next *models.RefreshToken) (bool, error)
 }
 
 type AuthUsecase struct {
-	authrepo  AuthRepo
-	dirClient directory.Client
-	authCfg   AuthConfig
+	authrepo     AuthRepo
+	sessionRepo  SessionRepo
+	loginRepo    LoginRepo
+	passwordRepo PasswordRepo
+	mfaRepo      MFARepo
+	ssoRepo      SSORepo
+	sso          SSOProvider
+	dirClient    directory.Client
+	chatClient   chat.Client
+	keys         *KeyManager
+	authCfg      AuthConfig
+
+	accounts *accountCache
+
+	now func() time.Time
 }
 
-func NewAuthUsecase(authrepo AuthRepo, dirClient directory.Client, authCfg AuthConfig) *AuthUsecase {
+func NewAuthUsecase(authrepo AuthRepo, sessionRepo SessionRepo, loginRepo LoginRepo, passwordRepo PasswordRepo, mfaRepo MFARepo, ssoRepo SSORepo, sso SSOProvider, dirClient directory.Client, chatClient chat.Client, keys *KeyManager, authCfg AuthConfig) *AuthUsecase {
 	return &AuthUsecase{
-		authrepo:  authrepo,
-		dirClient: dirClient,
-		authCfg:   authCfg,
+		authrepo:     authrepo,
+		sessionRepo:  sessionRepo,
+		loginRepo:    loginRepo,
+		passwordRepo: passwordRepo,
+		mfaRepo:      mfaRepo,
+		ssoRepo:      ssoRepo,
+		sso:          sso,
+		dirClient:    dirClient,
+		chatClient:   chatClient,
+		keys:         keys,
+		authCfg:      authCfg,
+		accounts:     newAccountCache(authCfg.AccountCacheTTL),
+		now:          time.Now,
 	}
 }
 
+// SetClock подменяет источник времени: сроки токенов, коды TOTP, блокировки входа
+func (u *AuthUsecase) SetClock(now func() time.Time) {
+	u.now = now
+}
+
 func (u *AuthUsecase) CreateAccount(ctx context.Context, input *dto.CreateAccountRequest) (*dto.AccountResponse, error) {
 	// Проверка на существование работника в directory-db
 	exists, err := u.dirClient.UserExists(ctx, input.UserID)
@@ -52,7 +94,7 @@ func (u *AuthUsecase) CreateAccount(ctx context.Context, input *dto.CreateAccoun
 	}
 
 	// Хеширование пароля
-	passwordHash, err := utils.HashPasswordBase64(input.Password)
+	passwordHash, err := utils.HashPassword(input.Password, u.authCfg.PasswordHash)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -66,7 +108,7 @@ func (u *AuthUsecase) CreateAccount(ctx context.Context, input *dto.CreateAccoun
 		Role:         models.AccountRole(input.Role),
 		IsActive:     true,
 		LastLogin:    nil,
-		CreatedAt:    time.Now(),
+		CreatedAt:    u.now(),
 	}
 
 	err = u.authrepo.Create(ctx, account)
@@ -87,52 +129,191 @@ func (u *AuthUsecase) CreateAccount(ctx context.Context, input *dto.CreateAccoun
 	return accountResponse, nil
 }
 
-func (u *AuthUsecase) Login(ctx context.Context, input *dto.LoginRequest) (string, error) {
+func (u *AuthUsecase) Login(ctx context.Context, input *dto.LoginRequest) (*dto.LoginResult, error) {
+	protection := u.authCfg.LoginProtection
+	emailKey := emailThrottleKey(input.Email)
+	ipKey := ipThrottleKey(input.IP)
+
+	// Пока действует задержка или блокировка, пароль даже не проверяем
+	if err := u.checkThrottle(ctx, emailKey, ipKey); err != nil {
+		u.recordAttempt(ctx, input, nil, models.LoginThrottled)
+		return nil, err
+	}
+
 	// Проверка на наличие аккаунта
 	account, err := u.authrepo.FindByEmail(ctx, input.Email)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", errModels.ErrUserNotFound
-		}
-
-		return "", fmt.Errorf("failed to find account by id from db: %w", err)
+		return nil, fmt.Errorf("failed to find account by email from db: %w", err)
 	}
 
 	// Подтверждение пароля
-	ok, err := utils.VerifyPassword(input.Password, account.PasswordHash)
-	if err != nil {
-		return "", fmt.Errorf("failed to verify password: %w", err)
+	ok := false
+	if account != nil {
+		ok, err = utils.VerifyPassword(input.Password, account.PasswordHash)
+		if errors.Is(err, utils.ErrInvalidPasswordHash) {
+			// Испорченный хеш не должен ронять вход: для клиента это просто неверный пароль
+			log.Printf("account %s has malformed password hash: %v", account.AccountID, err)
+			ok, err = false, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify password: %w", err)
+		}
+	} else {
+		verifyDummyPassword(input.Password, u.authCfg.PasswordHash)
 	}
+
 	if !ok {
-		return "", errModels.ErrInvalidPassword
+		var accountID *uuid.UUID
+		if account != nil {
+			accountID = &account.AccountID
+		}
+		u.recordAttempt(ctx, input, accountID, models.LoginFailed)
+
+		if err := u.registerFailure(ctx, emailKey, protection.MaxFailures); err != nil {
+			return nil, fmt.Errorf("failed to register login failure: %w", err)
+		}
+		if err := u.registerFailure(ctx, ipKey, protection.IPMaxFailures); err != nil {
+			return nil, fmt.Errorf("failed to register login failure: %w", err)
+		}
+
+		return nil, errModels.ErrInvalidCredentials
+	}
+
+	if !account.IsActive {
+		u.recordAttempt(ctx, input, &account.AccountID, models.LoginAccountInactive)
+		return nil, errModels.ErrAccountInactive
+	}
+
+	// Пароль верный - самое время пересчитать хеш со старыми параметрами
+	if utils.NeedsRehash(account.PasswordHash, u.authCfg.PasswordHash) {
+		if err := u.setPassword(ctx, account, input.Password); err != nil {
+			log.Printf("failed to rehash password of account %s: %v", account.AccountID, err)
+		}
+	}
+
+	// Счётчик по email сбрасывается только после второго фактора, иначе знающий пароль
+	// обнулял бы его перед каждой серией подбора кодов
+	if result, err := u.secondFactorChallenge(ctx, account, input); result != nil || err != nil {
+		return result, err
+	}
+
+	// Успешный вход сбрасывает счётчик по email; счётчик IP затухает сам по FailureWindow
+	if err := u.loginRepo.ResetThrottle(ctx, emailKey); err != nil {
+		return nil, fmt.Errorf("failed to reset login throttle: %w", err)
 	}
 
-	// Генерация токена
-	tokenString, err := utils.SignToken(account.UserID, account.Role, u.authCfg.JwtSecret, u.authCfg.JwtExpiresIn)
+	tokens, err := u.finishLogin(ctx, account, input)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate JWT: %w", err)
+		return nil, err
 	}
 
-	// Возврат токена
-	return tokenString, nil
+	return &dto.LoginResult{Tokens: tokens}, nil
+}
+
+// finishLogin - все проверки пройдены: отмечаем вход и открываем сессию устройства
+func (u *AuthUsecase) finishLogin(ctx context.Context, account *models.Account, input *dto.LoginRequest) (*dto.TokenPair, error) {
+	now := u.now()
+	if err := u.authrepo.UpdateLastLogin(ctx, account.AccountID, now); err != nil {
+		return nil, fmt.Errorf("failed to update last login: %w", err)
+	}
+	account.LastLogin = &now
+
+	u.recordAttempt(ctx, input, &account.AccountID, models.LoginSucceeded)
+
+	// Новая сессия устройства и пара токенов
+	return u.startSession(ctx, account, input.UserAgent, input.IP)
 }
 
 func (u *AuthUsecase) IntrospectToken(ctx context.Context, input *dto.IntrospectRequest) (*dto.IntrospectResponse, error) {
-	parsedToken, err := jwt.Parse(input.Token, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signed method: %v", t.Header["alg"])
+	claims, err := u.Authenticate(ctx, input.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, errModels.ErrInvalidToken):
+			return &dto.IntrospectResponse{Active: false, Reason: dto.ReasonInvalid}, nil
+		case errors.Is(err, errModels.ErrTokenExpired):
+			return &dto.IntrospectResponse{Active: false, Reason: dto.ReasonExpired}, nil
+		case errors.Is(err, errModels.ErrAccountInactive):
+			return &dto.IntrospectResponse{Active: false, Reason: dto.ReasonAccountInactive}, nil
+		case errors.Is(err, errModels.ErrSessionRevoked):
+			return &dto.IntrospectResponse{Active: false, Reason: dto.ReasonSessionRevoked}, nil
 		}
+		return nil, err
+	}
+
+	return &dto.IntrospectResponse{
+		Active:    true,
+		Scope:     scopesForRole(claims.Role),
+		TokenType: "access_token",
+		Sub:       claims.UserID.String(),
+		Role:      string(claims.Role),
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Sid:       claims.SessionID.String(),
+	}, nil
+}
 
-		return []byte(u.authCfg.JwtSecret), nil
-	})
+// scopesForRole - области доступа роли через пробел, как в scope RFC 7662
+func scopesForRole(role models.AccountRole) string {
+	switch role {
+	case models.RoleAdmin:
+		return "chat directory admin"
+	case models.RoleModerator, models.RoleSupport:
+		return "chat directory chat:manage"
+	default:
+		return "chat directory"
+	}
+}
 
-	output := &dto.IntrospectResponse{
-		Active: false,
+// Authenticate проверяет подпись и срок access-токена, активность аккаунта и его сессии
+func (u *AuthUsecase) Authenticate(ctx context.Context, token string) (*models.TokenClaims, error) {
+	claims, err := u.parseAccessToken(token)
+	if err != nil {
+		return nil, err
 	}
 
+	// Токен отключённого или удалённого аккаунта считаем неактивным
+	account, err := u.accountForToken(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || !account.IsActive {
+		return nil, errModels.ErrAccountInactive
+	}
+
+	session, err := u.sessionRepo.FindSession(ctx, claims.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+	if session == nil || session.AccountID != account.AccountID || !session.IsActive(u.now()) {
+		return nil, errModels.ErrSessionRevoked
+	}
+
+	return claims, nil
+}
+
+func (u *AuthUsecase) accountForToken(ctx context.Context, userID uuid.UUID) (*models.Account, error) {
+	now := u.now()
+	if account, ok := u.accounts.get(userID, now); ok {
+		return account, nil
+	}
+
+	account, err := u.authrepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find account by user id: %w", err)
+	}
+
+	u.accounts.set(userID, account, now)
+	return account, nil
+}
+
+func (u *AuthUsecase) parseAccessToken(token string) (*models.TokenClaims, error) {
+	parsedToken, err := jwt.Parse(token, u.keys.Keyfunc,
+		jwt.WithValidMethods([]string{utils.AlgRS256, utils.AlgEdDSA}),
+		jwt.WithTimeFunc(u.now),
+	)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return output, nil
+			return nil, errModels.ErrTokenExpired
 		}
 		return nil, errModels.ErrInvalidToken
 	}
@@ -141,8 +322,42 @@ func (u *AuthUsecase) IntrospectToken(ctx context.Context, input *dto.Introspect
 		return nil, errModels.ErrInvalidToken
 	}
 
-	output.Active = true
-	return output, nil
+	mapClaims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errModels.ErrInvalidToken
+	}
+
+	userIDStr, _ := mapClaims["user_id"].(string)
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		return nil, errModels.ErrInvalidToken
+	}
+
+	// Токены без сессии (выданные до появления refresh) не принимаем
+	sidStr, _ := mapClaims["sid"].(string)
+	sessionID, err := uuid.Parse(sidStr)
+	if err != nil {
+		return nil, errModels.ErrInvalidToken
+	}
+
+	role, _ := mapClaims["user_role"].(string)
+
+	exp, err := mapClaims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, errModels.ErrInvalidToken
+	}
+	iat, err := mapClaims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return nil, errModels.ErrInvalidToken
+	}
+
+	return &models.TokenClaims{
+		UserID:    userID,
+		Role:      models.AccountRole(role),
+		SessionID: sessionID,
+		IssuedAt:  iat.Time,
+		ExpiresAt: exp.Time,
+	}, nil
 }
 
 // В internal/usecase/auth_usecase.go добавь:
@@ -184,6 +399,7 @@ func (u *AuthUsecase) UpdateAccount(ctx context.Context, accountID uuid.UUID, re
 	if req.Role != "" {
 		existing.Role = models.AccountRole(req.Role)
 	}
+	wasActive := existing.IsActive
 	if req.IsActive != nil {
 		existing.IsActive = *req.IsActive
 	}
@@ -191,6 +407,13 @@ func (u *AuthUsecase) UpdateAccount(ctx context.Context, accountID uuid.UUID, re
 	if err := u.authrepo.UpdateAccount(ctx, existing); err != nil {
 		return nil, err
 	}
+	u.accounts.invalidate(existing.UserID)
+
+	if wasActive && !existing.IsActive {
+		if err := u.afterDeactivation(ctx, existing); err != nil {
+			return nil, err
+		}
+	}
 
 	return &dto.AccountResponse{
 		AccountID: existing.AccountID.String(),
@@ -212,5 +435,54 @@ func (u *AuthUsecase) DeactivateAccount(ctx context.Context, accountID uuid.UUID
 		return errModels.ErrUserNotFound
 	}
 
-	return u.authrepo.DeactivateAccount(ctx, accountID)
+	if err := u.authrepo.DeactivateAccount(ctx, accountID); err != nil {
+		return err
+	}
+	u.accounts.invalidate(existing.UserID)
+
+	return u.afterDeactivation(ctx, existing)
+}
+
+// afterDeactivation делает деактивацию немедленной: отзывает сессии (refresh больше не
+// сработает, introspect вернёт inactive) и просит chat-service закрыть websocket-соединения
+func (u *AuthUsecase) afterDeactivation(ctx context.Context, account *models.Account) error {
+	if err := u.sessionRepo.RevokeAccountSessions(ctx, account.AccountID, u.now()); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	// HTTP-запросы gateway отклоняет сразу по introspect, а открытые websocket-сессии
+	// chat-service перепроверяет лишь раз в WS_SESSION_RECHECK_INTERVAL. Поэтому сбой
+	// уведомления возвращаем: повторная деактивация доведёт отключение до конца
+	if err := u.notifyDeactivated(ctx, account.UserID); err != nil {
+		return fmt.Errorf("failed to close websocket sessions: %w", err)
+	}
+
+	return nil
+}
+
+const (
+	notifyAttempts   = 3
+	notifyRetryDelay = 200 * time.Millisecond
+)
+
+func (u *AuthUsecase) notifyDeactivated(ctx context.Context, userID uuid.UUID) error {
+	var err error
+	for attempt := range notifyAttempts {
+		if attempt > 0 {
+			timer := time.NewTimer(notifyRetryDelay << (attempt - 1))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+			case <-timer.C:
+			}
+		}
+
+		if err = u.chatClient.NotifyAccountDeactivated(ctx, userID); err == nil {
+			return nil
+		}
+		log.Printf("failed to notify chat-service about deactivation of %s (attempt %d): %v", userID, attempt+1, err)
+	}
+
+	return err
 }
@@ -4,28 +4,54 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
 
-func HashPasswordBase64(password string) (string, error) {
-	salt, err := generateSalt()
+var ErrInvalidPasswordHash = errors.New("invalid password hash format")
+
+// PasswordHashParams - параметры argon2id для новых хешей. Сохранённые хеши хранят
+// свои параметры, поэтому значения можно повышать без поломки старых паролей
+type PasswordHashParams struct {
+	Time    uint32 `env:"PASSWORD_HASH_TIME" env-default:"3"`
+	Memory  uint32 `env:"PASSWORD_HASH_MEMORY_KIB" env-default:"65536"`
+	Threads uint8  `env:"PASSWORD_HASH_THREADS" env-default:"4"`
+	KeyLen  uint32 `env:"PASSWORD_HASH_KEY_LEN" env-default:"32"`
+	SaltLen uint32 `env:"PASSWORD_HASH_SALT_LEN" env-default:"16"`
+}
+
+// Параметры хешей старого формата "salt.hash"
+var legacyHashParams = PasswordHashParams{Time: 1, Memory: 64 * 1024, Threads: 4, KeyLen: 32, SaltLen: 16}
+
+// decodedHash - разобранный хеш пароля любого поддерживаемого формата
+type decodedHash struct {
+	params PasswordHashParams
+	salt   []byte
+	hash   []byte
+	legacy bool
+}
+
+// HashPassword возвращает хеш в формате PHC: $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+func HashPassword(password string, params PasswordHashParams) (string, error) {
+	salt, err := generateSalt(params.SaltLen)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate salt: %w", err)
 	}
 
-	hashPasswordStr := getHashByte(password, salt)
+	hash := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLen)
 
-	saltBase64 := base64.StdEncoding.EncodeToString(salt)
-	hashPasswordBase64 := base64.StdEncoding.EncodeToString(hashPasswordStr)
-
-	return fmt.Sprintf("%s.%s", saltBase64, hashPasswordBase64), nil
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Time, params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
 }
 
-func generateSalt() ([]byte, error) {
-	salt := make([]byte, 16)
+func generateSalt(length uint32) ([]byte, error) {
+	salt := make([]byte, length)
 	_, err := rand.Read(salt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create salt: %w", err)
@@ -34,31 +60,99 @@ func generateSalt() ([]byte, error) {
 	return salt, nil
 }
 
-func getHashByte(password string, salt []byte) []byte {
-	hashedPassword := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
-	return hashedPassword
+// VerifyPassword сверяет пароль с хешем в формате PHC или в старом формате "salt.hash"
+func VerifyPassword(password, encodedHash string) (bool, error) {
+	decoded, err := decodePasswordHash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	p := decoded.params
+	hashedPassword := argon2.IDKey([]byte(password), decoded.salt, p.Time, p.Memory, p.Threads, uint32(len(decoded.hash)))
+
+	if subtle.ConstantTimeCompare(hashedPassword, decoded.hash) == 0 {
+		return false, nil
+	}
+
+	return true, nil
 }
 
-func VerifyPassword(newPasswordStr, realHashedPasswordBase64 string) (bool, error) {
-	parts := strings.Split(realHashedPasswordBase64, ".")
+// NeedsRehash сообщает, что хеш создан в старом формате или с другими параметрами
+func NeedsRehash(encodedHash string, params PasswordHashParams) bool {
+	decoded, err := decodePasswordHash(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	p := decoded.params
+	return decoded.legacy ||
+		p.Time != params.Time ||
+		p.Memory != params.Memory ||
+		p.Threads != params.Threads ||
+		uint32(len(decoded.hash)) != params.KeyLen ||
+		uint32(len(decoded.salt)) != params.SaltLen
+}
 
-	salt, err := base64.StdEncoding.DecodeString(parts[0])
+func decodePasswordHash(encodedHash string) (*decodedHash, error) {
+	if strings.HasPrefix(encodedHash, "$") {
+		return decodePHC(encodedHash)
+	}
+
+	return decodeLegacy(encodedHash)
+}
+
+func decodePHC(encodedHash string) (*decodedHash, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var params PasswordHashParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return nil, ErrInvalidPasswordHash
+	}
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, fmt.Errorf("failed to decode salt: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode salt: %v", ErrInvalidPasswordHash, err)
 	}
-	hashedRealPassword, err := base64.StdEncoding.DecodeString(parts[1])
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
 	if err != nil {
-		return false, fmt.Errorf("failed to decode password: %w", err)
+		return nil, fmt.Errorf("%w: failed to decode password: %v", ErrInvalidPasswordHash, err)
+	}
+	if len(salt) == 0 || len(hash) == 0 {
+		return nil, ErrInvalidPasswordHash
 	}
 
-	hashedNewPassword := getHashByte(newPasswordStr, salt)
+	return &decodedHash{params: params, salt: salt, hash: hash}, nil
+}
 
-	if len(hashedNewPassword) != len(hashedRealPassword) {
-		return false, nil
+func decodeLegacy(encodedHash string) (*decodedHash, error) {
+	parts := strings.Split(encodedHash, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidPasswordHash
 	}
-	if subtle.ConstantTimeCompare(hashedNewPassword, hashedRealPassword) == 0 {
-		return false, nil
+
+	salt, err := base64.StdEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode salt: %v", ErrInvalidPasswordHash, err)
+	}
+	hash, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to decode password: %v", ErrInvalidPasswordHash, err)
+	}
+	if len(salt) == 0 || len(hash) == 0 {
+		return nil, ErrInvalidPasswordHash
 	}
 
-	return true, nil
+	return &decodedHash{params: legacyHashParams, salt: salt, hash: hash, legacy: true}, nil
 }
@@ -0,0 +1,154 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Лёгкие параметры, чтобы тесты не тратили 64 МиБ на каждый хеш
+var testHashParams = PasswordHashParams{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+
+// legacyHash собирает хеш старого формата "salt.hash" так, как его писала прежняя версия
+func legacyHash(t *testing.T, password string) string {
+	t.Helper()
+
+	salt := make([]byte, legacyHashParams.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		t.Fatalf("rand: %v", err)
+	}
+	p := legacyHashParams
+	hash := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLen)
+
+	return base64.StdEncoding.EncodeToString(salt) + "." + base64.StdEncoding.EncodeToString(hash)
+}
+
+func TestVerifyPasswordMalformedHash(t *testing.T) {
+	valid, err := HashPassword("secret", testHashParams)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	phcParts := strings.Split(valid, "$")
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{name: "empty", hash: ""},
+		{name: "no separators", hash: "c2FsdGhhc2g"},
+		{name: "legacy with extra part", hash: "YQ==.Yg==.Yw=="},
+		{name: "legacy empty salt", hash: ".Yg=="},
+		{name: "legacy bad base64", hash: "!!!.Yg=="},
+		{name: "phc only dollar", hash: "$"},
+		{name: "phc wrong algorithm", hash: strings.Replace(valid, "argon2id", "argon2i", 1)},
+		{name: "phc wrong version", hash: strings.Replace(valid, "v=19", "v=16", 1)},
+		{name: "phc zero time", hash: strings.Replace(valid, "t=1", "t=0", 1)},
+		{name: "phc missing hash", hash: strings.Join(phcParts[:5], "$")},
+		{name: "phc bad salt", hash: strings.Join(append(phcParts[:4:4], "!!!", phcParts[5]), "$")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyPassword("secret", tt.hash)
+			if !errors.Is(err, ErrInvalidPasswordHash) {
+				t.Fatalf("expected ErrInvalidPasswordHash, got %v", err)
+			}
+			if ok {
+				t.Fatal("malformed hash must not verify")
+			}
+		})
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	phc, err := HashPassword("secret", testHashParams)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	legacy := legacyHash(t, "secret")
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		want     bool
+	}{
+		{name: "phc round trip", hash: phc, password: "secret", want: true},
+		{name: "phc wrong password", hash: phc, password: "Secret", want: false},
+		{name: "legacy", hash: legacy, password: "secret", want: true},
+		{name: "legacy wrong password", hash: legacy, password: "secret ", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyPassword(tt.password, tt.hash)
+			if err != nil {
+				t.Fatalf("VerifyPassword: %v", err)
+			}
+			if ok != tt.want {
+				t.Fatalf("VerifyPassword = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashPasswordFormat(t *testing.T) {
+	hash, err := HashPassword("secret", testHashParams)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected PHC prefix: %s", hash)
+	}
+
+	again, err := HashPassword("secret", testHashParams)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if hash == again {
+		t.Fatal("hashes of the same password must use different salts")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	current, err := HashPassword("secret", testHashParams)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	stronger := testHashParams
+	stronger.Time = 2
+	moreMemory := testHashParams
+	moreMemory.Memory = 2048
+	longerKey := testHashParams
+	longerKey.KeyLen = 64
+	longerSalt := testHashParams
+	longerSalt.SaltLen = 32
+
+	tests := []struct {
+		name   string
+		hash   string
+		params PasswordHashParams
+		want   bool
+	}{
+		{name: "current params", hash: current, params: testHashParams, want: false},
+		{name: "time raised", hash: current, params: stronger, want: true},
+		{name: "memory raised", hash: current, params: moreMemory, want: true},
+		{name: "key length changed", hash: current, params: longerKey, want: true},
+		{name: "salt length changed", hash: current, params: longerSalt, want: true},
+		{name: "legacy format", hash: legacyHash(t, "secret"), params: legacyHashParams, want: true},
+		{name: "malformed", hash: "garbage", params: testHashParams, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsRehash(tt.hash, tt.params); got != tt.want {
+				t.Fatalf("NeedsRehash = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
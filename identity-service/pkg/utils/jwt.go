@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"crypto"
 	"fmt"
 	"time"
 
@@ -9,20 +10,22 @@ import (
 	"github.com/google/uuid"
 )
 
-func SignToken(userId uuid.UUID, role models.AccountRole, jwtSecret string, jwtExpiresIn time.Duration) (string, error) {
-	now := time.Now()
-
+// SignToken подписывает access-токен текущим ключом. kid в заголовке позволяет
+// проверяющей стороне выбрать ключ из JWKS
+func SignToken(userId uuid.UUID, role models.AccountRole, sessionID uuid.UUID, method jwt.SigningMethod, kid string, key crypto.Signer, now time.Time, jwtExpiresIn time.Duration) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id":   userId,
 		"user_role": role,
+		"sid":       sessionID,
 		"iat":       now.Unix(),
 		"nbf":       now.Unix(),
 		"exp":       now.Add(jwtExpiresIn).Unix(),
 	}
 
-	tokenString := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString := jwt.NewWithClaims(method, claims)
+	tokenString.Header["kid"] = kid
 
-	signedToken, err := tokenString.SignedString([]byte(jwtSecret))
+	signedToken, err := tokenString.SignedString(key)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign token: %w", err)
 	}
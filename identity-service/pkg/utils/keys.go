@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/I-Van-Radkov/corporate-messenger/identity-service/internal/dto"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// GenerateSigningKey создаёт ключ подписи и возвращает его в PKCS#8 DER
+func GenerateSigningKey(alg string) ([]byte, error) {
+	var key crypto.Signer
+	var err error
+
+	switch alg {
+	case AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s key: %w", alg, err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	return der, nil
+}
+
+func ParseSigningKey(alg string, der []byte) (crypto.Signer, error) {
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg == AlgRS256 {
+			return key, nil
+		}
+	case ed25519.PrivateKey:
+		if alg == AlgEdDSA {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("private key does not match algorithm %q", alg)
+}
+
+func SigningMethod(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+}
+
+func PublicJWK(kid, alg string, key crypto.Signer) (dto.JWK, error) {
+	jwk := dto.JWK{
+		Kid: kid,
+		Use: "sig",
+		Alg: alg,
+	}
+
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return dto.JWK{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	return jwk, nil
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SecretsConfig - ключ шифрования секретов, хранящихся в БД (ключи подписи, секреты TOTP):
+// 32 случайных байта в base64, например `openssl rand -base64 32`
+type SecretsConfig struct {
+	EncryptionKey string `env:"SECRETS_ENCRYPTION_KEY" env-required:"true"`
+}
+
+const (
+	sealedSecretVersion byte = 1
+	sealedStringPrefix       = "enc:v1:"
+)
+
+// ErrSecretNotSealed - значение записано до включения шифрования (открытым текстом)
+var ErrSecretNotSealed = errors.New("secret is not encrypted")
+
+// SecretCipher шифрует секреты AES-256-GCM. Зашифрованное значение - версия, nonce
+// и шифротекст; aad привязывает его к записи (kid, account_id), чтобы значение
+// нельзя было перенести в чужую строку
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+func NewSecretCipher(encodedKey string) (*SecretCipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return &SecretCipher{aead: aead}, nil
+}
+
+func (c *SecretCipher) Seal(plaintext []byte, aad string) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, 1+len(nonce)+len(plaintext)+c.aead.Overhead())
+	out = append(out, sealedSecretVersion)
+	out = append(out, nonce...)
+
+	return c.aead.Seal(out, nonce, plaintext, []byte(aad)), nil
+}
+
+// Open расшифровывает значение Seal. Для незашифрованного значения возвращает ErrSecretNotSealed
+func (c *SecretCipher) Open(sealed []byte, aad string) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < 1+nonceSize+c.aead.Overhead() || sealed[0] != sealedSecretVersion {
+		return nil, ErrSecretNotSealed
+	}
+
+	plaintext, err := c.aead.Open(nil, sealed[1:1+nonceSize], sealed[1+nonceSize:], []byte(aad))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// SealString - Seal для текстовых колонок
+func (c *SecretCipher) SealString(plaintext, aad string) (string, error) {
+	sealed, err := c.Seal([]byte(plaintext), aad)
+	if err != nil {
+		return "", err
+	}
+
+	return sealedStringPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *SecretCipher) OpenString(sealed, aad string) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedStringPrefix)
+	if !ok {
+		return "", ErrSecretNotSealed
+	}
+
+	raw, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret: %w", err)
+	}
+
+	plaintext, err := c.Open(raw, aad)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
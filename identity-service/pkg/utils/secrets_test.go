@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newTestCipher(t *testing.T) *SecretCipher {
+	t.Helper()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewSecretCipher(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestNewSecretCipherRejectsBadKeys(t *testing.T) {
+	for _, key := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := NewSecretCipher(key); err == nil {
+			t.Fatalf("key %q accepted", key)
+		}
+	}
+}
+
+func TestSecretCipherRoundTrip(t *testing.T) {
+	c := newTestCipher(t)
+	plaintext := []byte{0x30, 0x2e, 0x02, 0x01, 0x00}
+
+	sealed, err := c.Seal(plaintext, "kid-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatal("sealed value contains plaintext")
+	}
+
+	opened, err := c.Open(sealed, "kid-1")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Fatalf("got %x, want %x", opened, plaintext)
+	}
+
+	// Значение другой записи не расшифровывается
+	if _, err := c.Open(sealed, "kid-2"); err == nil || errors.Is(err, ErrSecretNotSealed) {
+		t.Fatalf("expected decryption error for foreign aad, got %v", err)
+	}
+	if _, err := newTestCipher(t).Open(sealed, "kid-1"); err == nil {
+		t.Fatal("value opened with another key")
+	}
+}
+
+func TestSecretCipherDetectsLegacyValues(t *testing.T) {
+	c := newTestCipher(t)
+
+	// PKCS8 DER начинается с 0x30
+	if _, err := c.Open(bytes.Repeat([]byte{0x30}, 64), "kid"); !errors.Is(err, ErrSecretNotSealed) {
+		t.Fatalf("expected ErrSecretNotSealed for DER, got %v", err)
+	}
+	if _, err := c.OpenString("JBSWY3DPEHPK3PXP", "account"); !errors.Is(err, ErrSecretNotSealed) {
+		t.Fatalf("expected ErrSecretNotSealed for base32 secret, got %v", err)
+	}
+}
+
+func TestSecretCipherStringRoundTrip(t *testing.T) {
+	c := newTestCipher(t)
+
+	sealed, err := c.SealString("JBSWY3DPEHPK3PXP", "account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := c.OpenString(sealed, "account")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if opened != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("got %q", opened)
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры TOTP (RFC 6238) - значения по умолчанию, которые понимают все приложения-аутентификаторы
+const (
+	TOTPPeriod = 30 * time.Second
+	TOTPDigits = 6
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret возвращает 160-битный секрет в base32, как его вводят в аутентификатор
+func GenerateTOTPSecret() (string, error) {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	return totpEncoding.EncodeToString(raw), nil
+}
+
+// TOTPStep - номер 30-секундного интервала для момента t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod/time.Second)
+}
+
+// TOTPCode вычисляет код для интервала step (HOTP из RFC 4226 со счётчиком step)
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode totp secret: %w", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < TOTPDigits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod), nil
+}
+
+// MatchTOTP проверяет код в окне ±skew интервалов вокруг t и возвращает интервал,
+// которому он соответствует. Интервал нужен вызывающему, чтобы не принять код повторно
+func MatchTOTP(secret, code string, t time.Time, skew int) (int64, bool, error) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false, nil
+	}
+
+	current := TOTPStep(t)
+	for delta := -int64(skew); delta <= int64(skew); delta++ {
+		expected, err := TOTPCode(secret, current+delta)
+		if err != nil {
+			return 0, false, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return current + delta, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// TOTPURI - otpauth:// ссылка для QR-кода
+func TOTPURI(issuer, accountName, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(int(TOTPPeriod/time.Second)))
+
+	label := url.PathEscape(issuer + ":" + accountName)
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// GenerateRecoveryCodes возвращает n кодов вида xxxx-xxxx для пользователя и их хеши для БД
+func GenerateRecoveryCodes(n int) (codes []string, hashes []string, err error) {
+	codes = make([]string, 0, n)
+	hashes = make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		encoded := strings.ToLower(totpEncoding.EncodeToString(raw))
+		code := encoded[:4] + "-" + encoded[4:]
+
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode нормализует код (регистр, дефисы, пробелы) и хеширует его
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashRefreshToken(normalized)
+}
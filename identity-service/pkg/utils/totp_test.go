@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Секрет из приложения B RFC 6238 (SHA1): ASCII "12345678901234567890" в base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// Эталонные коды RFC - 8 цифр, у нас 6: младшие разряды того же значения
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "94287082"},
+		{unix: 1111111109, code: "07081804"},
+		{unix: 1111111111, code: "14050471"},
+		{unix: 1234567890, code: "89005924"},
+		{unix: 2000000000, code: "69279037"},
+		{unix: 20000000000, code: "65353130"},
+	}
+
+	for _, tt := range tests {
+		step := TOTPStep(time.Unix(tt.unix, 0))
+		got, err := TOTPCode(rfc6238Secret, step)
+		if err != nil {
+			t.Fatalf("T=%d: %v", tt.unix, err)
+		}
+		if want := tt.code[len(tt.code)-TOTPDigits:]; got != want {
+			t.Errorf("T=%d: got %s, want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestTOTPCodeAcceptsLowercaseAndPaddedSecret(t *testing.T) {
+	want, _ := TOTPCode(rfc6238Secret, 1)
+	for _, secret := range []string{strings.ToLower(rfc6238Secret), rfc6238Secret + "===="} {
+		got, err := TOTPCode(secret, 1)
+		if err != nil || got != want {
+			t.Fatalf("secret %q: got %q, %v", secret, got, err)
+		}
+	}
+
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Fatal("invalid secret accepted")
+	}
+}
+
+func TestMatchTOTPSkewWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := TOTPStep(now)
+
+	tests := []struct {
+		name   string
+		delta  int64
+		skew   int
+		wantOK bool
+	}{
+		{name: "current step", delta: 0, skew: 0, wantOK: true},
+		{name: "previous step without skew", delta: -1, skew: 0},
+		{name: "previous step", delta: -1, skew: 1, wantOK: true},
+		{name: "next step", delta: 1, skew: 1, wantOK: true},
+		{name: "outside window", delta: -2, skew: 1},
+		{name: "far future", delta: 2, skew: 1},
+		{name: "wide window", delta: -2, skew: 2, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, current+tt.delta)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			step, ok, err := MatchTOTP(rfc6238Secret, code, now, tt.skew)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != current+tt.delta {
+				t.Fatalf("matched step %d, want %d", step, current+tt.delta)
+			}
+		})
+	}
+}
+
+func TestMatchTOTPRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok, _ := MatchTOTP(rfc6238Secret, code, now, 1); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+
+	// Пробелы, которые вставляют аутентификаторы, допустимы
+	if _, ok, _ := MatchTOTP(rfc6238Secret, "287 082", now, 0); !ok {
+		t.Error("code with a space rejected")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 10 || len(hashes) != 10 {
+		t.Fatalf("got %d codes and %d hashes", len(codes), len(hashes))
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if seen[code] {
+			t.Fatalf("duplicate code %s", code)
+		}
+		seen[code] = true
+
+		if hashes[i] == code || HashRecoveryCode(code) != hashes[i] {
+			t.Fatalf("hash of %s does not match", code)
+		}
+		// Регистр, дефисы и пробелы при вводе не важны
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+		if HashRecoveryCode(typed) != hashes[i] {
+			t.Fatalf("normalized %q does not match %s", typed, code)
+		}
+	}
+}
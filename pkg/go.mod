@@ -0,0 +1,5 @@
+module github.com/I-Van-Radkov/corporate-messenger/pkg
+
+go 1.24.2
+
+require github.com/golang-jwt/jwt/v5 v5.3.0
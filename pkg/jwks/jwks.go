@@ -0,0 +1,235 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Config struct {
+	URL                string
+	Timeout            time.Duration
+	CacheTTL           time.Duration
+	MinRefreshInterval time.Duration
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// Client кэширует публичные ключи identity-service. Набор перечитывается по TTL
+// и при встрече неизвестного kid (не чаще MinRefreshInterval) - так новый ключ
+// подхватывается сразу после ротации. Загрузка идёт вне мьютекса и одна на всех:
+// ждут её только проверки токенов с неизвестным kid
+type Client struct {
+	url        string
+	httpClient *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]publicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	lastErr     error
+	refreshing  chan struct{}
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		url: cfg.URL,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+		},
+		ttl:        cfg.CacheTTL,
+		minRefresh: cfg.MinRefreshInterval,
+		keys:       make(map[string]publicKey),
+	}
+}
+
+// Keyfunc для jwt.Parse: ключ выбирается по kid, алгоритм токена должен совпадать с ключом
+func (c *Client) Keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		return nil, fmt.Errorf("token has no kid")
+	}
+
+	key, err := c.lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("unexpected signing method %v for key %s", t.Header["alg"], kid)
+	}
+
+	return key.key, nil
+}
+
+func (c *Client) lookup(kid string) (publicKey, error) {
+	c.mu.Lock()
+	key, known := c.keys[kid]
+	var done <-chan struct{}
+	if !known || time.Since(c.fetchedAt) > c.ttl {
+		done = c.startRefreshLocked()
+	}
+	c.mu.Unlock()
+
+	// Устаревший ключ отдаём сразу, обновление идёт в фоне. Если identity-service
+	// недоступен, продолжаем работать на закэшированных ключах
+	if known {
+		return key, nil
+	}
+	if done == nil {
+		return publicKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	<-done
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, known = c.keys[kid]
+	if !known {
+		if c.lastErr != nil {
+			return publicKey{}, fmt.Errorf("failed to refresh JWKS: %w", c.lastErr)
+		}
+		return publicKey{}, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	return key, nil
+}
+
+// startRefreshLocked запускает загрузку набора, если она ещё не идёт и с прошлой попытки
+// прошло не меньше minRefresh. Возвращает канал, закрываемый по окончании загрузки, или nil
+func (c *Client) startRefreshLocked() <-chan struct{} {
+	if c.refreshing != nil {
+		return c.refreshing
+	}
+	if time.Since(c.lastAttempt) < c.minRefresh {
+		return nil
+	}
+
+	c.lastAttempt = time.Now()
+	done := make(chan struct{})
+	c.refreshing = done
+
+	go func() {
+		keys, err := c.fetch()
+
+		c.mu.Lock()
+		if err == nil {
+			c.keys = keys
+			c.fetchedAt = time.Now()
+		}
+		c.lastErr = err
+		c.refreshing = nil
+		c.mu.Unlock()
+
+		close(done)
+	}()
+
+	return done
+}
+
+func (c *Client) fetch() (map[string]publicKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.httpClient.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("identity service returned %d", resp.StatusCode)
+	}
+
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// Ключ неподдерживаемого типа или повреждённый пропускаем: остальные ключи набора
+		// по-прежнему нужны для проверки токенов
+		parsed, err := parseJWK(k)
+		if err != nil {
+			log.Printf("Skipping JWKS key %q: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = publicKey{alg: k.Alg, key: parsed}
+	}
+
+	if len(keys) == 0 && len(set.Keys) > 0 {
+		return nil, fmt.Errorf("JWKS has no supported signing keys")
+	}
+
+	return keys, nil
+}
+
+func parseJWK(k jwk) (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "RSA" && k.Alg == "RS256":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case k.Kty == "OKP" && k.Crv == "Ed25519" && k.Alg == "EdDSA":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s/%s", k.Kty, k.Alg)
+	}
+}
@@ -0,0 +1,233 @@
+package jwks
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type fakeIdentity struct {
+	server  *httptest.Server
+	fetches atomic.Int32
+	release chan struct{}
+
+	mu   sync.Mutex
+	keys map[string]ed25519.PrivateKey
+	// Ключи, отдаваемые как есть, помимо keys
+	extra []jwk
+}
+
+func newFakeIdentity(t *testing.T) *fakeIdentity {
+	t.Helper()
+
+	f := &fakeIdentity{keys: make(map[string]ed25519.PrivateKey)}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.fetches.Add(1)
+		if f.release != nil {
+			<-f.release
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		var set jwks
+		for kid, priv := range f.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kty: "OKP", Crv: "Ed25519", Alg: "EdDSA", Use: "sig", Kid: kid,
+				X: base64.RawURLEncoding.EncodeToString(priv.Public().(ed25519.PublicKey)),
+			})
+		}
+		set.Keys = append(set.Keys, f.extra...)
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeIdentity) addKey(t *testing.T, kid string) ed25519.PrivateKey {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	f.keys[kid] = priv
+	f.mu.Unlock()
+
+	return priv
+}
+
+func (f *fakeIdentity) client(ttl, minRefresh time.Duration) *Client {
+	return NewClient(Config{URL: f.server.URL, Timeout: 5 * time.Second, CacheTTL: ttl, MinRefreshInterval: minRefresh})
+}
+
+func signToken(t *testing.T, kid string, priv ed25519.PrivateKey) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"sub": "user"})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestKeyfuncVerifiesToken(t *testing.T) {
+	f := newFakeIdentity(t)
+	priv := f.addKey(t, "k1")
+	c := f.client(time.Minute, 0)
+
+	if _, err := jwt.Parse(signToken(t, "k1", priv), c.Keyfunc); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+
+	// Ключ другого алгоритма с тем же kid не подходит
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "user"})
+	token.Header["kid"] = "k1"
+	signed, _ := token.SignedString([]byte("secret"))
+	if _, err := jwt.Parse(signed, c.Keyfunc); err == nil {
+		t.Fatal("token with mismatched algorithm accepted")
+	}
+}
+
+func TestUnknownKidFetchesOnceForConcurrentLookups(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.addKey(t, "k1")
+	f.release = make(chan struct{})
+	c := f.client(time.Minute, 0)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.lookup("k1")
+			errs <- err
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(f.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("lookup failed: %v", err)
+		}
+	}
+	if got := f.fetches.Load(); got != 1 {
+		t.Fatalf("expected 1 fetch, got %d", got)
+	}
+}
+
+func TestKnownKeyIsNotBlockedByRefresh(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.addKey(t, "k1")
+	c := f.client(time.Nanosecond, 0)
+
+	if _, err := c.lookup("k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Следующая загрузка зависает: устаревший известный ключ всё равно отдаётся сразу
+	f.release = make(chan struct{})
+	defer close(f.release)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.lookup("k1")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("lookup failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lookup of a cached key waited for the refresh")
+	}
+}
+
+func TestRotatedKeyIsPickedUp(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.addKey(t, "k1")
+	c := f.client(time.Hour, 0)
+
+	if _, err := c.lookup("k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	priv := f.addKey(t, "k2")
+	if _, err := jwt.Parse(signToken(t, "k2", priv), c.Keyfunc); err != nil {
+		t.Fatalf("token signed with rotated key rejected: %v", err)
+	}
+}
+
+func TestUnknownKidRefreshIsThrottled(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.addKey(t, "k1")
+	c := f.client(time.Hour, time.Hour)
+
+	if _, err := c.lookup("k1"); err != nil {
+		t.Fatal(err)
+	}
+	for range 5 {
+		if _, err := c.lookup("missing"); err == nil {
+			t.Fatal("unknown kid accepted")
+		}
+	}
+
+	if got := f.fetches.Load(); got != 1 {
+		t.Fatalf("expected 1 fetch, got %d", got)
+	}
+}
+
+// Неподдерживаемые и повреждённые ключи пропускаются, не ломая остальной набор
+func TestMixedKeySetKeepsSupportedKeys(t *testing.T) {
+	f := newFakeIdentity(t)
+	priv := f.addKey(t, "k1")
+	f.extra = []jwk{
+		{Kty: "EC", Crv: "P-256", Alg: "ES256", Use: "sig", Kid: "ec", X: "AAAA"},
+		{Kty: "RSA", Alg: "RS512", Use: "sig", Kid: "rsa512", N: "AQAB", E: "AQAB"},
+		{Kty: "OKP", Crv: "Ed25519", Alg: "EdDSA", Use: "sig", Kid: "broken", X: "c2hvcnQ"},
+		{Kty: "RSA", Alg: "RSA-OAEP", Use: "enc", Kid: "enc"},
+	}
+	c := f.client(time.Minute, 0)
+
+	if _, err := jwt.Parse(signToken(t, "k1", priv), c.Keyfunc); err != nil {
+		t.Fatalf("token signed with a supported key rejected: %v", err)
+	}
+
+	for _, kid := range []string{"ec", "rsa512", "broken", "enc"} {
+		if _, err := c.lookup(kid); err == nil {
+			t.Errorf("key %q must be skipped", kid)
+		}
+	}
+}
+
+func TestKeySetWithoutSupportedKeysFails(t *testing.T) {
+	f := newFakeIdentity(t)
+	f.extra = []jwk{{Kty: "EC", Crv: "P-256", Alg: "ES256", Use: "sig", Kid: "ec"}}
+	c := f.client(time.Minute, 0)
+
+	if _, err := c.fetch(); err == nil {
+		t.Fatal("expected an error for a key set without supported keys")
+	}
+}